@@ -0,0 +1,1259 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/example/go-api/pkg/buildinfo"
+	"github.com/example/go-api/pkg/client"
+	"github.com/example/go-api/pkg/clockcheck"
+	"github.com/example/go-api/pkg/database"
+	"github.com/example/go-api/pkg/handlers"
+	"github.com/example/go-api/pkg/httperr"
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/maintenance"
+	"github.com/example/go-api/pkg/metrics"
+	"github.com/example/go-api/pkg/middleware"
+	"github.com/example/go-api/pkg/obs"
+	"github.com/example/go-api/pkg/saturation"
+	"github.com/example/go-api/pkg/secrets"
+	"github.com/example/go-api/pkg/startup"
+	"github.com/example/go-api/pkg/tracing"
+	"github.com/example/go-api/pkg/upstream"
+	"github.com/example/go-api/pkg/userimport"
+)
+
+// Config holds application configuration
+type Config struct {
+	AppName     string
+	Version     string
+	Build       buildinfo.Info // Commit, Go version and build date alongside Version
+	Environment string
+	Port        string
+	AdminPort   string // Internal-only port for probes, metrics and debug endpoints
+	PodName     string // Included in shutdown annotations
+
+	// /metrics on the admin port is restricted to these networks and, when
+	// a password or token is set, to authenticated scrapers
+	MetricsAllowedCIDRs string
+	MetricsAuthUsername string
+	MetricsAuthPassword *secrets.Value // Loaded by NewApp from METRICS_AUTH_PASSWORD[_FILE|_VAULT]
+	MetricsAuthToken    *secrets.Value // Loaded by NewApp from METRICS_AUTH_TOKEN[_FILE|_VAULT]
+
+	LogLevel        string
+	LogLevels       string // Per-component overrides, e.g. "db=debug,client=warn"
+	LogDebugSampled bool   // Requests whose trace is sampled log at debug level
+	LogFormat       string // "json", "logfmt" or "console"
+
+	LogTimeFormat    string // "rfc3339" or "epoch"
+	LogTimePrecision string // "s", "ms", "us" or "ns"
+	LogTimeUTC       bool
+
+	TracingEnabled bool
+	OTLPEndpoint   string
+	TraceQueueSize int
+	TraceFallback  tracing.FallbackConfig
+	TraceSampling  tracing.SamplingConfig
+	TraceRules     string // Per-route sampling overrides, e.g. "/api/error=1,/api/hello=0.01"
+	SpanMetrics    bool
+	LokiURL        string // Probed at startup when set
+
+	// Logs are also pushed to LokiURL when set, for clusters without
+	// Promtail. LokiLabelFields become labels within LokiMaxStreams and
+	// LokiMetadataFields structured metadata (Loki 3).
+	// Request logs go to the tenant in TenantHeader when it is one of
+	// Tenants, others to LokiTenant.
+	LokiPush           bool
+	LokiLabelFields    []string
+	LokiMetadataFields []string
+	LokiMaxStreams     int
+	LokiTenant         string
+	TenantHeader       string
+	Tenants            []string
+	LokiWALDir         string // Unpushed lines survive restarts when set
+	LokiWALMaxMB       int
+
+	// Logs are also bulk-indexed into Elasticsearch/OpenSearch when set
+	ElasticsearchURL        string
+	ElasticsearchIndex      string
+	ElasticsearchDeadLetter string         // File for lines that could not be indexed
+	ElasticsearchAPIKey     *secrets.Value // Loaded by NewApp from ELASTICSEARCH_API_KEY[_FILE|_VAULT]
+
+	// Logs are also sent to syslog (RFC 5424) and/or journald, for hosts
+	// without a container log collector
+	SyslogAddr    string
+	SyslogNetwork string // "udp", "tcp" or "tls"
+	Journald      bool
+
+	// Logs are also shipped to a Fluentd / Fluent Bit forward input when set
+	FluentAddr       string
+	FluentTag        string
+	FluentRequireAck bool
+
+	// Logs go to an OTel Collector / Alloy / Vector OTLP gRPC receiver
+	// instead of stdout when LogOutput is "otlp"
+	LogOutput       string // "stdout" or "otlp"
+	LogOTLPEndpoint string
+	LogOTLPInsecure bool
+
+	EventLog       bool   // Also write logs to the Windows Event Log
+	EventLogSource string // Event source, registered on first start when missing
+
+	// Paths skipped by tracing, request logging and request metrics
+	TelemetryExcludePaths []string
+
+	// Browsers get an HTML error page instead of the JSON envelope when set
+	ErrorPages        bool
+	ErrorPageTemplate string // html/template file; the built-in page when empty
+
+	GrafanaURL            string         // Maintenance transitions are annotated when set
+	GrafanaToken          *secrets.Value // Loaded by NewApp from GRAFANA_API_TOKEN[_FILE|_VAULT]
+	MaintenanceRetryAfter time.Duration
+
+	StartupWaitTimeout    time.Duration
+	UpstreamProbeInterval time.Duration
+	HTTPClientTimeout     time.Duration
+	HTTPTransport         client.TransportConfig
+	EgressAllowedHosts    []string // Hosts the weather and quote clients may call
+	WeatherCacheEnabled   bool
+	WeatherCache          client.CacheConfig // In-memory cache for wttr.in responses
+	GRPCDownstreamAddr    string             // gRPC service checked by /api/dashboard, disabled when empty
+	GRPCClient            client.GRPCConfig
+	ShutdownTimeout       time.Duration
+	ReadinessLag          time.Duration // How long /ready fails before draining starts
+	LimiterEnabled        bool
+	Limiter               middleware.LimiterConfig // Adaptive concurrency limit for /api
+	CriticalPaths         []string                 // Health check paths the limiter never sheds
+	SaturationEnabled     bool
+	Saturation            saturation.Config // /ready fails while the replica is saturated
+
+	DatabaseEnabled     bool
+	Database            database.Config
+	DBReconnectInterval time.Duration
+	DBPoolMonitor       database.PoolMonitorConfig
+
+	// Per location class TTLs of the SQL and Mongo weather cache, parsed
+	// into Database.WeatherCacheTTL by NewApp, and the expired row cleanup
+	WeatherCacheClassTTLs string
+	WeatherCacheCleanup   database.WeatherCacheCleanupConfig
+
+	// Quotes and cached weather live in a document store instead of SQL
+	// when set; "mongo" needs the mongo build tag
+	DocumentStore string
+	MongoURI      *secrets.Value // Loaded by NewApp from MONGO_URI[_FILE|_VAULT]
+	MongoDatabase string
+
+	// GetUsers results are cached when UsersCache is "memory" (per replica)
+	// or "redis" (shared, needs the redis build tag)
+	UsersCache    string
+	UsersCacheTTL time.Duration
+	RedisAddr     string
+	RedisPassword *secrets.Value // Loaded by NewApp from REDIS_PASSWORD[_FILE|_VAULT]
+
+	UserImport userimport.Config // Batching and limits of POST /api/users/import
+
+	Vault                 secrets.VaultConfig // Used when VAULT_ADDR is set
+	SecretRefreshInterval time.Duration       // How often file and Vault secrets are re-read
+	DeployStateFile       string              // Last deployed version when there is no database
+	ClockCheck            clockcheck.Config   // Skew checks are off when Source is empty or "off"
+	MetricsAudit          metrics.AuditConfig // Series budgets for /admin/metrics-audit and the background audit
+
+	// Registry, when set, receives the App's HTTP, connection pool and
+	// business metrics and is what /metrics serves, so several Apps can run
+	// in one process, e.g. in tests. Package-level metrics of the other
+	// packages stay on the default registry. Never set from the environment.
+	Registry *prometheus.Registry
+}
+
+// LoadConfig reads the application configuration from environment variables
+func LoadConfig() Config {
+	dbHost := getEnvOrDefault("DB_HOST", "")
+	dbDriver := getEnvOrDefault("DB_DRIVER", database.DriverPostgres)
+	logFormat := logger.FormatJSON
+	if getEnvOrDefault("LOG_PRETTY", "false") == "true" {
+		logFormat = logger.FormatConsole // LOG_PRETTY predates LOG_FORMAT
+	}
+
+	return Config{
+		AppName:     "go-api",
+		Version:     buildinfo.Version,
+		Build:       buildinfo.Get(),
+		Environment: getEnvOrDefault("ENVIRONMENT", "development"),
+		Port:        getEnvOrDefault("PORT", "8080"),
+		AdminPort:   getEnvOrDefault("ADMIN_PORT", "9091"),
+		PodName:     getEnvOrDefault("POD_NAME", ""),
+
+		MetricsAllowedCIDRs: getEnvOrDefault("METRICS_ALLOWED_CIDRS", ""),
+		MetricsAuthUsername: getEnvOrDefault("METRICS_AUTH_USERNAME", "prometheus"),
+
+		LogLevel:        getEnvOrDefault("LOG_LEVEL", "info"),
+		LogLevels:       getEnvOrDefault("LOG_LEVELS", ""),
+		LogDebugSampled: getEnvOrDefault("LOG_DEBUG_SAMPLED", "false") == "true",
+		LogFormat:       getEnvOrDefault("LOG_FORMAT", logFormat),
+
+		LogTimeFormat:    getEnvOrDefault("LOG_TIME_FORMAT", "rfc3339"),
+		LogTimePrecision: getEnvOrDefault("LOG_TIME_PRECISION", ""),
+		LogTimeUTC:       getEnvOrDefault("LOG_TIME_UTC", "false") == "true",
+
+		TracingEnabled: getEnvOrDefault("TRACING_ENABLED", "true") == "true",
+		OTLPEndpoint:   getEnvOrDefault("OTEL_EXPORTER_OTLP_ENDPOINT", "tempo.monitoring:4317"),
+		TraceQueueSize: getEnvAsInt("OTEL_BSP_MAX_QUEUE_SIZE", 2048),
+		SpanMetrics:    getEnvOrDefault("SPAN_METRICS_ENABLED", "false") == "true",
+		TraceSampling: tracing.SamplingConfig{
+			DefaultRatio: float64(getEnvAsInt("TRACE_SAMPLE_PERCENT", 100)) / 100,
+		},
+		TraceRules: getEnvOrDefault("TRACE_SAMPLING_RULES", ""),
+		TraceFallback: tracing.FallbackConfig{
+			Enabled:       getEnvOrDefault("TRACE_FALLBACK_ENABLED", "true") == "true",
+			Path:          getEnvOrDefault("TRACE_FALLBACK_PATH", ""),
+			FailThreshold: 3,
+			RetryInterval: 30 * time.Second,
+			SampleRatio:   float64(getEnvAsInt("TRACE_FALLBACK_SAMPLE_PERCENT", 10)) / 100,
+		},
+		LokiURL: getEnvOrDefault("LOKI_URL", ""),
+
+		LokiPush:           getEnvOrDefault("LOG_LOKI_PUSH", "false") == "true",
+		LokiLabelFields:    strings.Split(getEnvOrDefault("LOG_LOKI_LABEL_FIELDS", "level,log_type"), ","),
+		LokiMetadataFields: strings.Split(getEnvOrDefault("LOG_LOKI_METADATA_FIELDS", ""), ","),
+		LokiMaxStreams:     getEnvAsInt("LOG_LOKI_MAX_STREAMS", 100),
+		LokiTenant:         getEnvOrDefault("LOG_LOKI_TENANT", ""),
+		TenantHeader:       getEnvOrDefault("TENANT_HEADER", middleware.DefaultTenantHeader),
+		Tenants:            strings.Split(getEnvOrDefault("TENANTS", ""), ","),
+		LokiWALDir:         getEnvOrDefault("LOG_LOKI_WAL_DIR", ""),
+		LokiWALMaxMB:       getEnvAsInt("LOG_LOKI_WAL_MAX_MB", 256),
+
+		ElasticsearchURL:        getEnvOrDefault("ELASTICSEARCH_URL", ""),
+		ElasticsearchIndex:      getEnvOrDefault("ELASTICSEARCH_INDEX", "logs-go-api"),
+		ElasticsearchDeadLetter: getEnvOrDefault("ELASTICSEARCH_DEAD_LETTER_PATH", ""),
+
+		SyslogAddr:    getEnvOrDefault("LOG_SYSLOG_ADDR", ""),
+		SyslogNetwork: getEnvOrDefault("LOG_SYSLOG_NETWORK", "udp"),
+		Journald:      getEnvOrDefault("LOG_JOURNALD", "false") == "true",
+
+		FluentAddr:       getEnvOrDefault("LOG_FLUENT_ADDR", ""),
+		FluentTag:        getEnvOrDefault("LOG_FLUENT_TAG", "go-api"),
+		FluentRequireAck: getEnvOrDefault("LOG_FLUENT_REQUIRE_ACK", "false") == "true",
+
+		LogOutput:       getEnvOrDefault("LOG_OUTPUT", "stdout"),
+		LogOTLPEndpoint: getEnvOrDefault("LOG_OTLP_ENDPOINT", "localhost:4317"),
+		LogOTLPInsecure: getEnvOrDefault("LOG_OTLP_INSECURE", "true") == "true",
+
+		EventLog:       getEnvOrDefault("LOG_EVENTLOG", "false") == "true",
+		EventLogSource: getEnvOrDefault("LOG_EVENTLOG_SOURCE", "go-api"),
+
+		TelemetryExcludePaths: strings.Split(getEnvOrDefault("TELEMETRY_EXCLUDE_PATHS",
+			strings.Join(middleware.DefaultExcludedPaths, ",")), ","),
+		ErrorPages:        getEnvOrDefault("ERROR_HTML_PAGES", "false") == "true",
+		ErrorPageTemplate: getEnvOrDefault("ERROR_PAGE_TEMPLATE", ""),
+
+		GrafanaURL:            getEnvOrDefault("GRAFANA_URL", ""),
+		MaintenanceRetryAfter: time.Duration(getEnvAsInt("MAINTENANCE_RETRY_AFTER", 300)) * time.Second,
+
+		StartupWaitTimeout:    time.Duration(getEnvAsInt("STARTUP_WAIT_TIMEOUT", 30)) * time.Second,
+		UpstreamProbeInterval: time.Duration(getEnvAsInt("UPSTREAM_PROBE_INTERVAL", 30)) * time.Second,
+		HTTPClientTimeout:     time.Duration(getEnvAsInt("HTTP_CLIENT_TIMEOUT", 10)) * time.Second,
+		ShutdownTimeout:       time.Duration(getEnvAsInt("SHUTDOWN_TIMEOUT", 30)) * time.Second,
+		ReadinessLag:          time.Duration(getEnvAsInt("SHUTDOWN_READINESS_LAG", 0)) * time.Second,
+		LimiterEnabled:        getEnvOrDefault("CONCURRENCY_LIMIT_ENABLED", "true") == "true",
+		Limiter: middleware.LimiterConfig{
+			InitialLimit:  getEnvAsInt("CONCURRENCY_LIMIT_INITIAL", 100),
+			MinLimit:      getEnvAsInt("CONCURRENCY_LIMIT_MIN", 10),
+			MaxLimit:      getEnvAsInt("CONCURRENCY_LIMIT_MAX", 1000),
+			LatencyTarget: time.Duration(getEnvAsInt("CONCURRENCY_LATENCY_TARGET_MS", 1000)) * time.Millisecond,
+		},
+		CriticalPaths: strings.Split(getEnvOrDefault("SHED_CRITICAL_PATHS",
+			strings.Join(middleware.DefaultExcludedPaths, ",")), ","),
+		SaturationEnabled: getEnvOrDefault("SATURATION_READINESS_ENABLED", "true") == "true",
+		Saturation: saturation.Config{
+			High:    float64(getEnvAsInt("SATURATION_HIGH_PERCENT", 90)) / 100,
+			Low:     float64(getEnvAsInt("SATURATION_LOW_PERCENT", 70)) / 100,
+			MinHold: time.Duration(getEnvAsInt("SATURATION_MIN_HOLD", 10)) * time.Second,
+		},
+		HTTPTransport: client.TransportConfig{
+			MaxIdleConnsPerHost: getEnvAsInt("HTTP_CLIENT_MAX_IDLE_CONNS_PER_HOST", 32),
+			MaxConnsPerHost:     getEnvAsInt("HTTP_CLIENT_MAX_CONNS_PER_HOST", 0),
+			IdleConnTimeout:     time.Duration(getEnvAsInt("HTTP_CLIENT_IDLE_CONN_TIMEOUT", 90)) * time.Second,
+			DisableHTTP2:        getEnvOrDefault("HTTP_CLIENT_DISABLE_HTTP2", "false") == "true",
+			ForwardHeaders:      strings.Split(getEnvOrDefault("CORRELATION_HEADERS", "X-Request-ID"), ","),
+			ForwardBaggage:      strings.Split(getEnvOrDefault("CORRELATION_BAGGAGE_KEYS", ""), ","),
+			MaxConcurrent:       getEnvAsInt("UPSTREAM_MAX_CONCURRENT", 20),
+			QueueTimeout:        time.Duration(getEnvAsInt("UPSTREAM_QUEUE_TIMEOUT_MS", 100)) * time.Millisecond,
+		},
+		EgressAllowedHosts:  strings.Split(getEnvOrDefault("EGRESS_ALLOWED_HOSTS", "wttr.in,api.quotable.io"), ","),
+		WeatherCacheEnabled: getEnvOrDefault("WEATHER_CACHE_ENABLED", "true") == "true",
+		WeatherCache: client.CacheConfig{
+			MaxEntries:           getEnvAsInt("WEATHER_CACHE_MAX_ENTRIES", 1000),
+			TTL:                  time.Duration(getEnvAsInt("WEATHER_CACHE_TTL", 300)) * time.Second,
+			StaleWhileRevalidate: time.Duration(getEnvAsInt("WEATHER_CACHE_STALE_WHILE_REVALIDATE", 600)) * time.Second,
+		},
+		GRPCDownstreamAddr: getEnvOrDefault("GRPC_DOWNSTREAM_ADDR", ""),
+		GRPCClient: client.GRPCConfig{
+			ForwardHeaders:  strings.Split(getEnvOrDefault("CORRELATION_HEADERS", "X-Request-ID"), ","),
+			MaxAttempts:     getEnvAsInt("GRPC_CLIENT_MAX_ATTEMPTS", 3),
+			InitialBackoff:  time.Duration(getEnvAsInt("GRPC_CLIENT_BACKOFF_MS", 50)) * time.Millisecond,
+			MaxBackoff:      time.Second,
+			BreakerFailures: getEnvAsInt("GRPC_CLIENT_BREAKER_FAILURES", 5),
+			BreakerOpenFor:  time.Duration(getEnvAsInt("GRPC_CLIENT_BREAKER_OPEN_TIMEOUT", 30)) * time.Second,
+		},
+
+		DocumentStore: getEnvOrDefault("DOCUMENT_STORE", ""),
+		MongoDatabase: getEnvOrDefault("MONGO_DATABASE", "goapi"),
+
+		UsersCache:    getEnvOrDefault("USERS_CACHE", ""),
+		UsersCacheTTL: time.Duration(getEnvAsInt("USERS_CACHE_TTL_SECONDS", 60)) * time.Second,
+		RedisAddr:     getEnvOrDefault("REDIS_ADDR", "redis:6379"),
+
+		UserImport: userimport.Config{
+			BatchSize: getEnvAsInt("USER_IMPORT_BATCH_SIZE", 100),
+			MaxRows:   getEnvAsInt("USER_IMPORT_MAX_ROWS", 10000),
+			MaxErrors: 100,
+		},
+
+		// SQLite is file-backed and needs no host (local development fallback)
+		DatabaseEnabled: dbHost != "" || dbDriver == database.DriverSQLite,
+		Database: database.Config{
+			Driver:           dbDriver,
+			Path:             getEnvOrDefault("DB_PATH", "go-api.db"),
+			Host:             dbHost,
+			Port:             getEnvAsInt("DB_PORT", 5432),
+			User:             getEnvOrDefault("DB_USER", "goapi"),
+			Database:         getEnvOrDefault("DB_NAME", "goapi"),
+			SSLMode:          getEnvOrDefault("DB_SSLMODE", "disable"),
+			MaxOpenConns:     25,
+			MaxIdleConns:     5,
+			MaxLifetime:      5 * time.Minute,
+			ApplicationName:  getEnvOrDefault("DB_APPLICATION_NAME", "go-api"),
+			StatementTimeout: time.Duration(getEnvAsInt("DB_STATEMENT_TIMEOUT_MS", 30000)) * time.Millisecond,
+			LockTimeout:      time.Duration(getEnvAsInt("DB_LOCK_TIMEOUT_MS", 3000)) * time.Millisecond,
+			Retry: database.RetryConfig{
+				MaxAttempts:    getEnvAsInt("DB_RETRY_MAX_ATTEMPTS", 3),
+				InitialBackoff: 50 * time.Millisecond,
+				MaxBackoff:     1 * time.Second,
+			},
+			WeatherCacheTTL: database.WeatherCacheTTL{
+				Default: time.Duration(getEnvAsInt("WEATHER_DB_CACHE_TTL", 1800)) * time.Second,
+			},
+			QueryTimeouts: database.QueryTimeouts{
+				Read:      time.Duration(getEnvAsInt("DB_READ_TIMEOUT_MS", 2000)) * time.Millisecond,
+				Write:     time.Duration(getEnvAsInt("DB_WRITE_TIMEOUT_MS", 5000)) * time.Millisecond,
+				Analytics: time.Duration(getEnvAsInt("DB_ANALYTICS_TIMEOUT_MS", 15000)) * time.Millisecond,
+			},
+			SlowQuery: database.SlowQueryConfig{
+				Threshold:       time.Duration(getEnvAsInt("DB_SLOW_QUERY_MS", 500)) * time.Millisecond,
+				Explain:         getEnvOrDefault("DB_EXPLAIN_SLOW_QUERIES", "false") == "true",
+				ExplainInterval: time.Duration(getEnvAsInt("DB_EXPLAIN_INTERVAL", 60)) * time.Second,
+			},
+		},
+		WeatherCacheClassTTLs: getEnvOrDefault("WEATHER_DB_CACHE_CLASS_TTLS", ""),
+		WeatherCacheCleanup: database.WeatherCacheCleanupConfig{
+			Interval:  time.Duration(getEnvAsInt("WEATHER_DB_CACHE_CLEANUP_INTERVAL", 300)) * time.Second,
+			BatchSize: getEnvAsInt("WEATHER_DB_CACHE_CLEANUP_BATCH", 500),
+		},
+		DBReconnectInterval: time.Duration(getEnvAsInt("DB_RECONNECT_INTERVAL", 15)) * time.Second,
+		DBPoolMonitor: database.PoolMonitorConfig{
+			Interval:          time.Duration(getEnvAsInt("DB_POOL_MONITOR_INTERVAL", 10)) * time.Second,
+			WaitWarnThreshold: time.Duration(getEnvAsInt("DB_POOL_WAIT_WARN_MS", 500)) * time.Millisecond,
+		},
+
+		Vault: secrets.VaultConfig{
+			Addr:     getEnvOrDefault("VAULT_ADDR", ""),
+			Mount:    getEnvOrDefault("VAULT_KV_MOUNT", "secret"),
+			Role:     getEnvOrDefault("VAULT_ROLE", ""),
+			AuthPath: getEnvOrDefault("VAULT_AUTH_PATH", "kubernetes"),
+		},
+		SecretRefreshInterval: time.Duration(getEnvAsInt("SECRET_REFRESH_INTERVAL", 300)) * time.Second,
+		DeployStateFile:       getEnvOrDefault("DEPLOY_STATE_FILE", ""),
+		ClockCheck: clockcheck.Config{
+			Source:    getEnvOrDefault("CLOCK_CHECK_SOURCE", "ntp://pool.ntp.org"),
+			Interval:  time.Duration(getEnvAsInt("CLOCK_CHECK_INTERVAL", 600)) * time.Second,
+			Threshold: time.Duration(getEnvAsInt("CLOCK_SKEW_THRESHOLD_MS", 1000)) * time.Millisecond,
+		},
+		MetricsAudit: metrics.AuditConfig{
+			Budget:   getEnvAsInt("METRICS_SERIES_BUDGET", 1000),
+			Budgets:  getEnvAsIntMap("METRICS_SERIES_BUDGETS"),
+			Interval: time.Duration(getEnvAsInt("METRICS_AUDIT_INTERVAL", 300)) * time.Second,
+		},
+	}
+}
+
+// App holds every long-lived component of the service and owns their
+// lifecycles. Integration tests can boot the whole stack in-process with
+// NewApp and drive it through Handler.
+type App struct {
+	cfg Config
+
+	logger         *logger.Logger
+	recentErrors   *logger.ErrorBuffer           // Included in diagnostics bundles
+	logSinks       []func(context.Context) error // Closes the extra log sinks, last on shutdown
+	logQueues      []func() float64              // Queue fill of the log sinks that buffer
+	elasticKey     atomic.Pointer[secrets.Value] // Set once secrets are loaded
+	started        time.Time
+	tracerProvider *tracing.Provider
+	weatherClient  *client.WeatherClient
+	quoteClient    *client.QuoteClient
+	metrics        *middleware.Metrics
+	appMetrics     *metrics.Registry // Business metrics declared by handlers
+	poolMetrics    *database.PoolMetrics
+	weatherMetrics *database.WeatherCacheMetrics
+	schemaMetrics  *database.SchemaMetrics
+	userCache      *database.UserCache // nil unless USERS_CACHE is set
+	importer       *userimport.Importer
+	registerer     prometheus.Registerer // cfg.Registry, or the default registry
+	gatherer       prometheus.Gatherer
+	startup        *startup.Waiter
+	maintenance    *maintenance.Mode
+	upstreams      *upstream.Prober
+	drainer        *middleware.Drainer
+	limiter        *middleware.AdaptiveLimiter // nil when CONCURRENCY_LIMIT_ENABLED=false
+	saturation     *saturation.Monitor         // nil when SATURATION_READINESS_ENABLED=false
+	terminating    atomic.Bool                 // Set on shutdown signal so /ready fails first
+	grafana        *client.GrafanaClient       // nil unless GRAFANA_URL is set
+	downstream     *client.GRPCHealthClient    // nil unless GRPC_DOWNSTREAM_ADDR is set
+	secrets        *secrets.Loader
+	clock          *clockcheck.Checker // nil when CLOCK_CHECK_SOURCE is "off"
+	scrapeAuth     middleware.ScrapeAuthConfig
+
+	db        atomic.Pointer[database.DB] // Swapped in by the startup wait or background reconnect
+	documents atomic.Value                // database.DocumentStore, set by the startup wait when DOCUMENT_STORE is set
+
+	handler http.Handler
+	server  *http.Server
+
+	// Internal-only server for probes, metrics and debug endpoints. It is
+	// never behind the ingress and outlives the public server on shutdown.
+	adminHandler http.Handler
+	adminServer  *http.Server
+
+	// Cancels background goroutines (pool monitor, reconnector) on shutdown,
+	// which then waits for them before closing what they use
+	background     context.Context
+	stopBackground context.CancelFunc
+	backgroundWG   sync.WaitGroup
+}
+
+// NewApp constructs every component from cfg without starting the server
+func NewApp(ctx context.Context, cfg Config) (*App, error) {
+	a := &App{started: time.Now()}
+	a.background, a.stopBackground = context.WithCancel(context.Background())
+
+	// Initialize structured logger for middleware
+	a.recentErrors = logger.NewErrorBuffer(100)
+	logOpts, err := a.newLogSinks(cfg)
+	if err != nil {
+		return nil, err
+	}
+	a.logger = logger.New(logger.Config{
+		AppName:       cfg.AppName,
+		Version:       cfg.Version,
+		Level:         cfg.LogLevel,
+		Levels:        cfg.LogLevels,
+		SampledDebug:  cfg.LogDebugSampled,
+		Format:        cfg.LogFormat,
+		TimeFormat:    cfg.LogTimeFormat,
+		TimePrecision: cfg.LogTimePrecision,
+		TimeUTC:       cfg.LogTimeUTC,
+		ErrorBuffer:   a.recentErrors,
+		Hooks: []logger.Hook{logger.StaticFields(map[string]string{
+			"commit":     cfg.Build.Commit,
+			"go_version": cfg.Build.GoVersion,
+			"build_date": cfg.Build.BuildDate,
+		})},
+	}, logOpts...)
+	logger.SetDefault(a.logger)
+	cfg.Database.Logger = a.logger.Named("db")
+
+	// Secrets come from files, Vault or the environment, never from defaults
+	if err = a.loadSecrets(ctx, &cfg); err != nil {
+		return nil, err
+	}
+	a.elasticKey.Store(cfg.ElasticsearchAPIKey)
+
+	cfg.TraceSampling.Routes, err = tracing.ParseRouteRules(cfg.TraceRules)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse TRACE_SAMPLING_RULES: %w", err)
+	}
+	cfg.Database.WeatherCacheTTL.Classes, err = database.ParseClassTTLs(cfg.WeatherCacheClassTTLs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse WEATHER_DB_CACHE_CLASS_TTLS: %w", err)
+	}
+
+	a.scrapeAuth = middleware.ScrapeAuthConfig{
+		Username: cfg.MetricsAuthUsername,
+		Password: cfg.MetricsAuthPassword.Reveal,
+		Token:    cfg.MetricsAuthToken.Reveal,
+	}
+	if a.scrapeAuth.AllowedNets, err = middleware.ParseAllowedNets(cfg.MetricsAllowedCIDRs); err != nil {
+		return nil, fmt.Errorf("failed to parse METRICS_ALLOWED_CIDRS: %w", err)
+	}
+
+	// Error pages are package-wide, so reset them for every App
+	var errorPage *template.Template
+	if cfg.ErrorPages {
+		if errorPage, err = httperr.LoadHTMLPage(cfg.ErrorPageTemplate); err != nil {
+			return nil, err
+		}
+	}
+	httperr.SetHTMLPage(errorPage)
+	a.cfg = cfg
+
+	// Initialize OpenTelemetry tracing
+	a.tracerProvider, err = tracing.InitTracer(ctx, tracing.Config{
+		ServiceName:    cfg.AppName,
+		ServiceVersion: cfg.Version,
+		Environment:    cfg.Environment,
+		OTLPEndpoint:   cfg.OTLPEndpoint,
+		Enabled:        cfg.TracingEnabled,
+		MaxQueueSize:   cfg.TraceQueueSize,
+		Logger:         a.logger,
+		Fallback:       cfg.TraceFallback,
+		SpanMetrics:    cfg.SpanMetrics,
+		Sampling:       cfg.TraceSampling,
+		ResourceAttributes: []attribute.KeyValue{
+			attribute.String("build.commit", cfg.Build.Commit),
+			attribute.String("build.go_version", cfg.Build.GoVersion),
+			attribute.String("build.date", cfg.Build.BuildDate),
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tracer: %w", err)
+	}
+
+	logger.Ctx(ctx).Info().
+		Bool("tracing_enabled", cfg.TracingEnabled).
+		Str("otlp_endpoint", cfg.OTLPEndpoint).
+		Msg("Tracing initialized")
+
+	// Wait for dependencies with backoff before serving traffic
+	a.startup = startup.NewWaiter(startup.Config{
+		Timeout:        cfg.StartupWaitTimeout,
+		InitialBackoff: 500 * time.Millisecond,
+		MaxBackoff:     5 * time.Second,
+	}, a.logger)
+
+	if cfg.TracingEnabled {
+		a.startup.Add("otlp", startup.TCPProbe(cfg.OTLPEndpoint))
+	}
+	if cfg.LokiURL != "" {
+		a.startup.Add("loki", startup.HTTPProbe(cfg.LokiURL+"/ready"))
+	}
+
+	// Initialize database connection (optional - gracefully degrade if unavailable)
+	if cfg.DatabaseEnabled {
+		a.startup.Add("database", func(ctx context.Context) error {
+			conn, err := database.New(ctx, a.cfg.Database)
+			if err != nil {
+				return err
+			}
+			a.db.Store(conn)
+			return nil
+		})
+	} else {
+		logger.Ctx(ctx).Info().Msg("No database configured - running without DB features")
+	}
+	if cfg.DocumentStore != "" {
+		open, ok := documentStores[cfg.DocumentStore]
+		if !ok {
+			return nil, fmt.Errorf("document store %q not available: rebuild with -tags %s", cfg.DocumentStore, cfg.DocumentStore)
+		}
+		a.startup.Add(cfg.DocumentStore, func(ctx context.Context) error {
+			docs, err := open(ctx, cfg)
+			if err != nil {
+				return err
+			}
+			a.documents.Store(docs)
+			return nil
+		})
+	}
+
+	// Initialize HTTP clients for external APIs
+	cfg.HTTPTransport.Egress = client.NewEgressPolicy(cfg.EgressAllowedHosts, a.logger.Named("client"))
+	var weatherOpts []client.Option
+	if cfg.WeatherCacheEnabled {
+		weatherOpts = append(weatherOpts, client.WithCache(cfg.WeatherCache))
+	}
+	a.weatherClient = client.NewWeatherClient(cfg.HTTPClientTimeout, cfg.HTTPTransport, weatherOpts...)
+	a.quoteClient = client.NewQuoteClient(cfg.HTTPClientTimeout, cfg.HTTPTransport)
+	if cfg.GRPCDownstreamAddr != "" {
+		cfg.GRPCClient.Logger = a.logger.Named("client")
+		downstream, err := client.NewGRPCHealthClient(ctx, cfg.GRPCDownstreamAddr, cfg.GRPCClient)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create gRPC downstream client: %w", err)
+		}
+		a.downstream = downstream
+	}
+
+	logger.Ctx(ctx).Info().
+		Dur("timeout", cfg.HTTPClientTimeout).
+		Int("max_idle_conns_per_host", cfg.HTTPTransport.MaxIdleConnsPerHost).
+		Bool("http2", !cfg.HTTPTransport.DisableHTTP2).
+		Int("max_concurrent_per_upstream", cfg.HTTPTransport.MaxConcurrent).
+		Msg("HTTP clients initialized")
+
+	// Probe upstreams in the background; results feed /ready and upstream_up
+	a.upstreams = upstream.NewProber(upstream.Config{
+		Interval: cfg.UpstreamProbeInterval,
+		Timeout:  5 * time.Second,
+		Jitter:   0.2,
+	}, a.logger)
+	a.upstreams.Add("weather_api", startup.HTTPProbe(a.weatherClient.ProbeURL()))
+	a.upstreams.Add("quote_api", startup.HTTPProbe(a.quoteClient.ProbeURL()))
+	if cfg.DatabaseEnabled {
+		a.upstreams.Add("database", func(ctx context.Context) error {
+			db := a.currentDB()
+			if db == nil {
+				return errors.New("not connected")
+			}
+			return db.PingContext(ctx)
+		})
+	}
+	if cfg.TracingEnabled {
+		a.upstreams.Add("otlp", startup.TCPProbe(cfg.OTLPEndpoint))
+	}
+	if cfg.LokiURL != "" {
+		a.upstreams.Add("loki", startup.HTTPProbe(cfg.LokiURL+"/ready"))
+	}
+
+	// Maintenance mode is toggled at runtime through the admin server
+	var annotator maintenance.Annotator
+	if cfg.GrafanaURL != "" {
+		a.grafana = client.NewGrafanaClient(cfg.GrafanaURL, cfg.GrafanaToken, cfg.HTTPClientTimeout)
+		annotator = a.grafana
+	}
+	a.maintenance = maintenance.New(a.logger, annotator, cfg.MaintenanceRetryAfter)
+	if src := cfg.ClockCheck.Source; src != "" && src != "off" {
+		a.clock = clockcheck.New(cfg.ClockCheck, a.logger)
+	}
+	a.drainer = middleware.NewDrainer()
+	if cfg.LimiterEnabled {
+		cfg.Limiter.Classify = middleware.NewClassifier(cfg.CriticalPaths)
+		a.limiter = middleware.NewAdaptiveLimiter(cfg.Limiter, a.logger)
+	}
+	if cfg.SaturationEnabled {
+		a.saturation = a.newSaturationMonitor(cfg.Saturation)
+	}
+
+	if cfg.Registry != nil {
+		// A private registry gets its own copies of the shared metrics
+		a.registerer, a.gatherer = cfg.Registry, cfg.Registry
+		if err := metrics.RegisterRuntime(cfg.Registry); err != nil {
+			return nil, fmt.Errorf("failed to register runtime metrics: %w", err)
+		}
+		a.appMetrics = metrics.New("", cfg.Registry)
+		a.poolMetrics = database.NewPoolMetrics("", cfg.Registry)
+		a.weatherMetrics = database.NewWeatherCacheMetrics("", cfg.Registry)
+		a.metrics = middleware.NewMetrics("", middleware.WithRegisterer(cfg.Registry))
+	} else {
+		// Use existing Prometheus metrics (registered in init())
+		a.registerer, a.gatherer = prometheus.DefaultRegisterer, prometheus.DefaultGatherer
+		a.appMetrics = appMetrics
+		a.poolMetrics = dbPoolMetrics
+		a.weatherMetrics = dbWeatherCacheMetrics
+		a.metrics = &middleware.Metrics{
+			RequestsTotal:     httpRequestsTotal,
+			RequestDuration:   httpRequestDuration,
+			RequestsInFlight:  httpRequestsInFlight,
+			PanicRecoveries:   panicRecoveries,
+			FirstByteDuration: httpFirstByteDuration,
+			StreamedBytes:     httpStreamedBytes,
+		}
+	}
+
+	a.schemaMetrics = database.NewSchemaMetrics("", a.registerer)
+	// Connections are opened after NewApp returns, from a.cfg
+	a.cfg.Database.QueryMetrics = database.NewQueryMetrics("", a.registerer)
+
+	if cfg.UsersCache != "" {
+		open, ok := userCaches[cfg.UsersCache]
+		if !ok {
+			return nil, fmt.Errorf("users cache %q not available: rebuild with -tags %s", cfg.UsersCache, cfg.UsersCache)
+		}
+		cache, err := open(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create users cache: %w", err)
+		}
+		a.userCache = database.NewUserCache(cache, cfg.UsersCacheTTL, database.NewUserCacheMetrics("", a.registerer))
+	}
+	a.importer = userimport.New(a.background, cfg.UserImport, a.store, a.appMetrics, a.logger.Named("import"))
+
+	// Public routes answer 503 until the startup wait has finished, during
+	// maintenance and once shutdown begins; the admin server is always
+	// served so probes and scrapes keep working
+	// Both stacks are validated here, so a misordered stack stops startup
+	presets := middleware.PresetConfig{
+		ServiceName:    cfg.AppName,
+		Logger:         a.logger.Named("http"),
+		Metrics:        a.metrics,
+		Exclude:        middleware.NewPathFilter(cfg.TelemetryExcludePaths...),
+		ForwardHeaders: cfg.HTTPTransport.ForwardHeaders,
+		TenantHeader:   cfg.TenantHeader,
+		Tenants:        cfg.Tenants,
+		Limiter:        a.limiter,
+	}
+	public := middleware.Public(presets)
+	// Streams are long by design; the limiter would read them as latency
+	presets.Limiter = nil
+	streams := middleware.Public(presets)
+	presets.Logger, presets.AuditStore = a.logger, a.store
+	admin := middleware.Admin(presets)
+	for _, stack := range []*middleware.Stack{public, streams, admin} {
+		if err := stack.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	// An outer recovery also covers routes outside the stacks, such as
+	// /health, /ready, /metrics and unmatched paths, and the gates below
+	rootRecovery := middleware.Recovery(a.logger.Named("http"), a.metrics)
+	a.handler = middleware.Chain(
+		rootRecovery,
+		a.drainer.Middleware(),
+		a.startup.Gate(),
+		a.maintenance.Middleware(),
+	)(a.routes(public, streams))
+	a.adminHandler = rootRecovery(a.adminRoutes(admin))
+
+	a.server = &http.Server{
+		Addr:         ":" + cfg.Port,
+		Handler:      a.handler,
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}
+
+	// No write timeout: pprof profiles stream for longer than 15s
+	a.adminServer = &http.Server{
+		Addr:        ":" + cfg.AdminPort,
+		Handler:     a.adminHandler,
+		ReadTimeout: 15 * time.Second,
+		IdleTimeout: 60 * time.Second,
+	}
+
+	return a, nil
+}
+
+// versionHandler reports the build of the running binary
+func (a *App) versionHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(a.cfg.Build)
+}
+
+// logLevelsHandler reports the log levels and, on PUT, replaces them with
+// the spec in the body, e.g. {"levels": "info,db=debug"}
+func (a *App) logLevelsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPut {
+		var req struct {
+			Levels string `json:"levels"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			httperr.Write(w, r, http.StatusBadRequest, "invalid request body")
+			return
+		}
+		if err := a.logger.SetLevels(req.Levels); err != nil {
+			httperr.Write(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		logger.Ctx(r.Context()).Info().Str("levels", a.logger.Levels()).Msg("Log levels changed")
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{"levels": a.logger.Levels()})
+}
+
+// routes builds the public router with the full middleware stack, and
+// streams, the same stack without the limiter, for event streams
+func (a *App) routes(stack, streams *middleware.Stack) http.Handler {
+	r := mux.NewRouter()
+
+	tracer := a.tracerProvider.Tracer()
+	imports := handlers.NewUserImportHandler(a.importer, a.store)
+
+	// Registered before /api so they are matched first
+	r.Handle("/api/users/import/{id:[0-9]+}/events", streams.Then(obs.Handler("user_import_events", imports.Events))).Methods("GET")
+
+	// API routes with the public stack: OTel -> Recovery -> Correlation ->
+	// Tenancy -> Identity -> Logging -> Metrics -> Limiter. Excluded paths still get
+	// panic recovery.
+	api := r.PathPrefix("/api").Subrouter()
+	api.Use(stack.Then)
+
+	// Existing endpoints
+	api.Handle("/hello", obs.Handler("hello", handlers.NewHelloHandler().Serve)).Methods("GET")
+	api.Handle("/error", obs.Handler("simulate_error", handlers.NewErrorHandler(a.appMetrics).Serve)).Methods("GET")
+
+	// New traced endpoints
+	weather := obs.Handler("fetch_weather", handlers.NewWeatherHandler(a.weatherClient, a.store, tracer).Serve)
+	validateLocation := handlers.ValidatePathVars(map[string]handlers.Validator{"location": handlers.ValidateLocation})
+	api.Handle("/weather/{location}", validateLocation(weather)).Methods("GET")
+	api.Handle("/weather", weather).Methods("GET")
+	api.Handle("/quote", obs.Handler("fetch_quote", handlers.NewQuoteHandler(a.quoteClient, a.store, tracer, a.appMetrics).Serve)).Methods("GET")
+	api.Handle("/quotes/stats", obs.Handler("get_quote_stats", handlers.NewQuoteStatsHandler(a.store).Serve)).Methods("GET")
+	api.Handle("/quotes/{id:[0-9]+}", obs.Handler("delete_quote", handlers.NewQuoteDeleteHandler(a.store).Serve)).Methods("DELETE")
+	api.Handle("/quotes/search", obs.Handler("search_quotes", handlers.NewQuoteSearchHandler(a.store, tracer, a.appMetrics).Serve)).Methods("GET")
+	api.Handle("/users", obs.Handler("get_users", handlers.NewUsersHandler(a.store).Serve)).Methods("GET")
+	api.Handle("/users/import", obs.Handler("import_users", imports.Start)).Methods("POST")
+	api.Handle("/users/import/{id:[0-9]+}", obs.Handler("get_user_import", imports.Status)).Methods("GET")
+	api.Handle("/users/{username}", obs.Handler("update_user", handlers.NewUserUpdateHandler(a.store, a.appMetrics).Serve)).Methods("PUT")
+	api.Handle("/users/{username}", obs.Handler("delete_user", handlers.NewUserDeleteHandler(a.store).Serve)).Methods("DELETE")
+	var downstream handlers.DownstreamChecker
+	if a.downstream != nil {
+		downstream = a.downstream
+	}
+	api.Handle("/dashboard", obs.Handler("dashboard", handlers.NewDashboardHandler(a.weatherClient, a.quoteClient, a.store, downstream, tracer).Serve)).Methods("GET")
+
+	return r
+}
+
+// adminRoutes builds the internal router for operational endpoints. None of
+// these are exposed through the public server.
+func (a *App) adminRoutes(audited *middleware.Stack) http.Handler {
+	r := mux.NewRouter()
+
+	health := handlers.NewHealthHandler(a.store, a.cfg.DatabaseEnabled, a.upstreams)
+
+	// Health and readiness endpoints (only the outer panic recovery)
+	r.HandleFunc("/health", health.Health).Methods("GET")
+	r.HandleFunc("/ready", a.readiness(health.Ready)).Methods("GET")
+	r.HandleFunc("/version", a.versionHandler).Methods("GET")
+
+	// Metrics endpoint, limited to METRICS_ALLOWED_CIDRS and the scrape
+	// credentials when set
+	// OpenMetrics exposition is needed for exemplars
+	r.Handle("/metrics", middleware.ScrapeAuth(a.scrapeAuth, a.logger.Named("http"))(promhttp.InstrumentMetricHandler(
+		a.registerer,
+		promhttp.HandlerFor(a.gatherer, promhttp.HandlerOpts{EnableOpenMetrics: true}),
+	)))
+
+	// Admin and profiling endpoints are traced and audited
+	admin := r.PathPrefix("/admin").Subrouter()
+	admin.Use(audited.Then)
+	admin.HandleFunc("/dependencies", a.dependenciesHandler).Methods("GET")
+	admin.Handle("/maintenance", a.maintenance.Handler()).Methods("GET", "PUT")
+	admin.HandleFunc("/diagnostics", a.diagnosticsHandler).Methods("GET")
+	admin.HandleFunc("/log-levels", a.logLevelsHandler).Methods("GET", "PUT")
+	admin.HandleFunc("/metrics-audit", a.metricsAuditHandler).Methods("GET")
+	admin.HandleFunc("/cache/users/invalidate", a.invalidateUsersCacheHandler).Methods("POST")
+
+	// Profiling
+	debug := r.PathPrefix("/debug/pprof").Subrouter()
+	debug.Use(audited.Then)
+	debug.HandleFunc("/cmdline", pprof.Cmdline)
+	debug.HandleFunc("/profile", pprof.Profile)
+	debug.HandleFunc("/symbol", pprof.Symbol)
+	debug.HandleFunc("/trace", pprof.Trace)
+	debug.PathPrefix("/").HandlerFunc(pprof.Index)
+
+	return r
+}
+
+// readiness reports not ready while the startup wait is running, once
+// shutdown has been signalled and during maintenance, so the pod is taken
+// out of the Service endpoints; otherwise it defers to next. The admin
+// server is not behind the public gates, so each is checked here.
+func (a *App) readiness(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var reason string
+		switch saturated := a.saturationReason(); {
+		case !a.startup.Ready():
+			reason = "waiting for dependencies"
+		case a.terminating.Load():
+			reason = "shutting down"
+		case a.maintenance.Enabled():
+			reason = "maintenance"
+		case saturated != "":
+			reason = saturated
+		default:
+			next(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusServiceUnavailable)
+		fmt.Fprintf(w, `{"status":"not ready","reason":%q}`, reason)
+	}
+}
+
+// newSaturationMonitor watches the concurrency limiter, database pool waits,
+// the span export queue and the fullest log sink queue, the buffers that
+// back up first under overload
+func (a *App) newSaturationMonitor(cfg saturation.Config) *saturation.Monitor {
+	m := saturation.New(cfg, a.logger)
+	if a.limiter != nil {
+		m.Add("concurrency", func() float64 {
+			return float64(a.limiter.InFlight()) / float64(a.limiter.Limit())
+		})
+	}
+	// Seconds spent waiting for a pooled connection per second
+	m.Add("db_pool_wait", saturation.Rate(func() float64 {
+		if db := a.currentDB(); db != nil {
+			return db.Stats().WaitDuration.Seconds()
+		}
+		return 0
+	}))
+	m.Add("trace_export_queue", func() float64 {
+		q := a.tracerProvider.QueueStats()
+		if q.Capacity <= 0 {
+			return 0
+		}
+		return float64(q.Size) / float64(q.Capacity)
+	})
+	if len(a.logQueues) > 0 {
+		m.Add("log_buffer", func() float64 {
+			var fill float64
+			for _, queueFill := range a.logQueues {
+				fill = max(fill, queueFill())
+			}
+			return fill
+		})
+	}
+	return m
+}
+
+// saturationReason returns why the replica is saturated, or "" when it is
+// not or saturation readiness is disabled
+func (a *App) saturationReason() string {
+	if a.saturation == nil {
+		return ""
+	}
+	if saturated, reason := a.saturation.Saturated(); saturated {
+		return reason
+	}
+	return ""
+}
+
+// Handler returns the public HTTP handler, for driving the app with httptest
+func (a *App) Handler() http.Handler {
+	return a.handler
+}
+
+// AdminHandler returns the internal HTTP handler serving probes, metrics and
+// debug endpoints
+func (a *App) AdminHandler() http.Handler {
+	return a.adminHandler
+}
+
+// currentDB returns the live database pool, or nil if not connected
+func (a *App) currentDB() *database.DB {
+	return a.db.Load()
+}
+
+// documentStores open the document stores built in, keyed by DOCUMENT_STORE.
+// They register themselves from files behind build tags.
+var documentStores = map[string]func(ctx context.Context, cfg Config) (database.DocumentStore, error){}
+
+// userCaches create the users cache backends built in, keyed by
+// USERS_CACHE. Backends behind build tags register themselves.
+var userCaches = map[string]func(cfg Config) (database.Cache, error){
+	"memory": func(Config) (database.Cache, error) { return database.NewMemoryCache(), nil },
+}
+
+// currentDocuments returns the document store, or nil if not connected
+func (a *App) currentDocuments() database.DocumentStore {
+	docs, _ := a.documents.Load().(database.DocumentStore)
+	return docs
+}
+
+// store adapts currentDB to handlers.StoreFunc. It returns a literal nil
+// so handlers never see a non-nil Store wrapping a nil *DB. With a document
+// store, quotes and weather go there; a configured database that has not
+// connected yet still yields nil. With a users cache, GetUsers is served
+// from it.
+func (a *App) store() database.Store {
+	s := a.backingStore()
+	if s != nil && a.userCache != nil {
+		return a.userCache.Wrap(s)
+	}
+	return s
+}
+
+func (a *App) backingStore() database.Store {
+	db := a.currentDB()
+	if docs := a.currentDocuments(); docs != nil {
+		switch {
+		case db != nil:
+			return database.WithDocumentStore(db, docs)
+		case !a.cfg.DatabaseEnabled:
+			return database.WithDocumentStore(nil, docs)
+		}
+		return nil
+	}
+	if db != nil {
+		return db
+	}
+	return nil
+}
+
+// WaitForDependencies runs the startup wait and wires up the database once
+// it is connected, falling back to a background reconnect
+func (a *App) WaitForDependencies(ctx context.Context) {
+	failed := a.startup.Wait(ctx)
+	for name, err := range failed {
+		logger.Ctx(ctx).Warn().Err(err).Str("dependency", name).Msg("Dependency unavailable after startup wait")
+	}
+
+	if db := a.currentDB(); db != nil {
+		a.dbConnected(db)
+	} else if a.cfg.DatabaseEnabled {
+		logger.Ctx(ctx).Warn().Msg("Failed to connect to database - reconnecting in background")
+		a.goBackground(func(ctx context.Context) {
+			database.Reconnect(ctx, a.cfg.Database, a.cfg.DBReconnectInterval, func(db *database.DB) {
+				a.db.Store(db)
+				a.dbConnected(db)
+			})
+		})
+	}
+
+	logger.Ctx(ctx).Info().
+		Bool("db_available", a.currentDB() != nil).
+		Interface("dependencies", a.startup.Status()).
+		Msg("Startup complete, serving traffic")
+}
+
+// dbConnected checks the schema for drift and starts pool monitoring and
+// the weather cache cleanup once a connection exists, whether it came from
+// the startup wait or the background reconnector
+func (a *App) dbConnected(db *database.DB) {
+	l := a.logger.Named("db").WithContext(context.Background())
+	l.Info().
+		Str("driver", a.cfg.Database.Driver).
+		Str("host", a.cfg.Database.Host).
+		Int("port", a.cfg.Database.Port).
+		Str("application_name", a.cfg.Database.ApplicationName).
+		Msg("Database connected")
+
+	// Warn about missed migrations before they surface as failing queries
+	a.goBackground(func(ctx context.Context) { db.ReportSchemaDrift(ctx, a.schemaMetrics, a.logger.Named("db")) })
+
+	// Export pool saturation metrics and warn on connection waits
+	a.goBackground(func(ctx context.Context) {
+		db.MonitorPool(ctx, a.poolMetrics, a.logger.Named("db"), a.cfg.DBPoolMonitor)
+	})
+	a.goBackground(func(ctx context.Context) {
+		db.RunWeatherCacheCleanup(ctx, a.weatherMetrics, a.logger.Named("db"), a.cfg.WeatherCacheCleanup)
+	})
+}
+
+// goBackground runs fn with the background context in a goroutine that
+// Shutdown waits for
+func (a *App) goBackground(fn func(ctx context.Context)) {
+	a.backgroundWG.Add(1)
+	go func() {
+		defer a.backgroundWG.Done()
+		fn(a.background)
+	}()
+}
+
+// waitBackground waits until the background goroutines and user imports
+// have returned after stopBackground, or ctx expires
+func (a *App) waitBackground(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		a.backgroundWG.Wait()
+		a.importer.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("background goroutines still running: %w", ctx.Err())
+	}
+}
+
+// Run serves HTTP until ctx is cancelled, then shuts down gracefully
+func (a *App) Run(ctx context.Context) error {
+	a.logStartupBanner()
+
+	serverErr := make(chan error, 2)
+	go func() {
+		logger.Ctx(ctx).Info().
+			Str("port", a.cfg.AdminPort).
+			Msg("Starting admin HTTP server")
+
+		if err := a.adminServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- fmt.Errorf("admin server: %w", err)
+		}
+	}()
+	go func() {
+		logger.Ctx(ctx).Info().
+			Str("port", a.cfg.Port).
+			Bool("tracing_enabled", a.cfg.TracingEnabled).
+			Msg("Starting HTTP server")
+
+		if err := a.server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+	}()
+
+	a.goBackground(a.upstreams.Run)
+	a.goBackground(func(ctx context.Context) { a.secrets.Run(ctx, a.cfg.SecretRefreshInterval) })
+	a.goBackground(func(ctx context.Context) {
+		metrics.RunAudit(ctx, a.gatherer, a.cfg.MetricsAudit, a.logger.Named("metrics"))
+	})
+	if a.clock != nil {
+		a.goBackground(a.clock.Run)
+	}
+	if a.saturation != nil {
+		a.goBackground(a.saturation.Run)
+	}
+
+	a.WaitForDependencies(ctx)
+	a.goBackground(a.markDeployment)
+
+	select {
+	case err := <-serverErr:
+		a.Shutdown(context.Background())
+		return fmt.Errorf("failed to start server: %w", err)
+	case <-ctx.Done():
+	}
+
+	logger.Ctx(ctx).Info().Msg("Shutting down server...")
+
+	a.failReadiness()
+
+	// Graceful shutdown with timeout
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), a.cfg.ShutdownTimeout)
+	defer cancel()
+
+	return a.Shutdown(shutdownCtx)
+}
+
+// failReadiness makes /ready fail and keeps serving for ReadinessLag so
+// kube-proxy and the ingress stop routing to this pod before it drains.
+// Without the lag, requests routed during endpoint propagation hit a closed
+// listener and surface as 502s during rollouts.
+func (a *App) failReadiness() {
+	a.terminating.Store(true)
+	if a.cfg.ReadinessLag <= 0 {
+		return
+	}
+
+	l := a.logger.WithContext(context.Background())
+	l.Info().
+		Dur("readiness_lag", a.cfg.ReadinessLag).
+		Msg("Readiness failed, waiting for endpoints to update")
+
+	time.Sleep(a.cfg.ReadinessLag)
+
+	l.Info().
+		Int64("in_flight", a.drainer.InFlight()).
+		Msg("Readiness lag elapsed, starting drain")
+}
+
+// reportDrain logs the shutdown outcome and marks it on Grafana dashboards
+func (a *App) reportDrain(stats middleware.DrainStats) {
+	l := a.logger.WithContext(context.Background())
+	event := l.Info()
+	if stats.Aborted > 0 {
+		event = l.Warn()
+	}
+	event.
+		Int64("in_flight", stats.InFlight).
+		Int64("drained", stats.Drained).
+		Int64("aborted", stats.Aborted).
+		Int64("rejected", stats.Rejected).
+		Dur("drain_duration", stats.Duration).
+		Msg("Request draining finished")
+
+	if a.grafana == nil {
+		return
+	}
+
+	// The shutdown context may already be spent, so annotate on a fresh one
+	ctx, cancel := context.WithTimeout(context.Background(), a.cfg.HTTPClientTimeout)
+	defer cancel()
+
+	instance := a.cfg.AppName
+	if a.cfg.PodName != "" {
+		instance = a.cfg.PodName
+	}
+
+	end := time.Now()
+	annotation := client.Annotation{
+		Time:    end.Add(-stats.Duration).UnixMilli(),
+		TimeEnd: end.UnixMilli(),
+		Tags:    []string{"shutdown", a.cfg.AppName},
+		Text: fmt.Sprintf("%s shut down: drained %d, aborted %d, rejected %d in %s",
+			instance, stats.Drained, stats.Aborted, stats.Rejected,
+			stats.Duration.Round(time.Millisecond)),
+	}
+	if err := a.grafana.Annotate(ctx, annotation); err != nil {
+		l.Warn().Err(err).Msg("Failed to annotate shutdown")
+	}
+}
+
+// Shutdown stops the public server, background workers, database and
+// tracer. The admin server stops last so metrics stay scrapeable while
+// in-flight requests drain.
+func (a *App) Shutdown(ctx context.Context) error {
+	var errs []error
+
+	// Refuse new requests, then wait for in-flight ones until ctx expires
+	a.drainer.Begin()
+	logger.Ctx(ctx).Info().
+		Int64("in_flight", a.drainer.InFlight()).
+		Msg("Draining in-flight requests")
+
+	if err := a.server.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("server forced to shutdown: %w", err))
+	}
+	stats := a.drainer.End()
+	if stats.Aborted > 0 {
+		a.server.Close()
+	}
+	a.reportDrain(stats)
+
+	// Stop background work and let it finish before closing the database
+	// and the log sinks it writes to
+	a.stopBackground()
+	if err := a.waitBackground(ctx); err != nil {
+		errs = append(errs, err)
+	}
+
+	if db := a.currentDB(); db != nil {
+		if err := db.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("error closing database: %w", err))
+		}
+	}
+
+	if docs := a.currentDocuments(); docs != nil {
+		if err := docs.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("error closing document store: %w", err))
+		}
+	}
+
+	if a.downstream != nil {
+		if err := a.downstream.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("error closing gRPC downstream client: %w", err))
+		}
+	}
+
+	if err := a.tracerProvider.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("error shutting down tracer provider: %w", err))
+	}
+
+	if err := a.adminServer.Shutdown(ctx); err != nil {
+		errs = append(errs, fmt.Errorf("admin server forced to shutdown: %w", err))
+	}
+
+	// Last, so the shutdown logs above are shipped too
+	for _, closeSink := range a.logSinks {
+		if err := closeSink(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
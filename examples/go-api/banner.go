@@ -0,0 +1,90 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+// logStartupBanner emits one event with the resolved observability
+// configuration through the app logger, and so every configured sink, so a
+// misconfigured deploy is visible in Loki at a glance:
+//
+//	{app="go-api"} |= "Observability configuration"
+func (a *App) logStartupBanner() {
+	cfg := a.cfg
+
+	rules := make([]string, len(cfg.TraceSampling.Routes))
+	for i, r := range cfg.TraceSampling.Routes {
+		rules[i] = r.Route + "=" + strconv.FormatFloat(r.Ratio, 'g', -1, 64)
+	}
+
+	exporters := []string{}
+	if cfg.TracingEnabled {
+		exporters = append(exporters, "otlp")
+		if cfg.TraceFallback.Enabled {
+			fallback := "stdout"
+			if cfg.TraceFallback.Path != "" {
+				fallback = cfg.TraceFallback.Path
+			}
+			exporters = append(exporters, "fallback:"+fallback)
+		}
+	}
+
+	l := a.logger.WithContext(context.Background())
+	l.Info().
+		Str("event", "startup_banner").
+		Str("environment", cfg.Environment).
+		Dict("logging", zerolog.Dict().
+			Str("level", a.logger.Level().String()).
+			Str("levels", a.logger.Levels()).
+			Str("format", cfg.LogFormat).
+			Bool("loki_probe", cfg.LokiURL != "").
+			Strs("excluded_paths", cfg.TelemetryExcludePaths)).
+		Dict("tracing", zerolog.Dict().
+			Bool("enabled", cfg.TracingEnabled).
+			Str("endpoint", cfg.OTLPEndpoint).
+			Strs("exporters", exporters).
+			Str("sampler", "parent_based").
+			Float64("default_ratio", cfg.TraceSampling.DefaultRatio).
+			Strs("route_rules", rules).
+			Int("queue_size", cfg.TraceQueueSize).
+			Float64("fallback_sample_ratio", cfg.TraceFallback.SampleRatio)).
+		Dict("metrics", zerolog.Dict().
+			Str("endpoint", ":"+cfg.AdminPort+"/metrics").
+			Strs("namespaces", metricNamespaces(a.gatherer)).
+			Bool("openmetrics", true).
+			Bool("span_metrics", cfg.SpanMetrics).
+			Dur("db_pool_interval", cfg.DBPoolMonitor.Interval)).
+		Dict("integrations", zerolog.Dict().
+			Bool("database", cfg.DatabaseEnabled).
+			Str("database_driver", cfg.Database.Driver).
+			Bool("grafana_annotations", cfg.GrafanaURL != "").
+			Bool("vault", cfg.Vault.Addr != "")).
+		Msg("Observability configuration")
+}
+
+// metricNamespaces lists the distinct name prefixes of the metrics exported
+// so far, e.g. "http", "otel" and "traces". Labelled metrics appear once
+// they have their first series.
+func metricNamespaces(g prometheus.Gatherer) []string {
+	families, err := g.Gather()
+	if err != nil {
+		return nil
+	}
+	seen := make(map[string]bool)
+	var namespaces []string
+	for _, mf := range families {
+		ns, _, _ := strings.Cut(mf.GetName(), "_")
+		if !seen[ns] {
+			seen[ns] = true
+			namespaces = append(namespaces, ns)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
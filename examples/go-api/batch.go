@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/example/go-api/pkg/metricspush"
+	"github.com/example/go-api/pkg/secrets"
+)
+
+// metricsPushConfig reads the push settings for the subcommand name from
+// METRICS_PUSH_*. Subcommands exit before Prometheus could scrape them, so
+// their metrics are pushed instead when METRICS_PUSH_MODE is set.
+func metricsPushConfig(ctx context.Context, name string) (metricspush.Config, error) {
+	password, err := secrets.NewLoader(nil, nil).Load(ctx, "METRICS_PUSH_PASSWORD")
+	if err != nil {
+		return metricspush.Config{}, err
+	}
+	cfg := metricspush.Config{
+		Mode:     getEnvOrDefault("METRICS_PUSH_MODE", metricspush.ModeOff),
+		URL:      getEnvOrDefault("METRICS_PUSH_URL", ""),
+		Job:      getEnvOrDefault("METRICS_PUSH_JOB", "go-api-"+name),
+		Labels:   map[string]string{},
+		Username: getEnvOrDefault("METRICS_PUSH_USERNAME", ""),
+		Password: password.Reveal,
+		Timeout:  time.Duration(getEnvAsInt("METRICS_PUSH_TIMEOUT_SECONDS", 10)) * time.Second,
+	}
+	for _, pair := range strings.Split(getEnvOrDefault("METRICS_PUSH_LABELS", ""), ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(pair, "=")
+		if !ok || k == "" {
+			return metricspush.Config{}, fmt.Errorf("METRICS_PUSH_LABELS: %q is not name=value", pair)
+		}
+		cfg.Labels[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return cfg, cfg.Validate()
+}
+
+// runBatch runs the subcommand name and, when a push mode is configured,
+// pushes the default registry together with the run's duration, exit code
+// and completion time. A failed push is reported but does not change the
+// exit code of a successful run.
+func runBatch(name string, run func() int) int {
+	ctx := context.Background()
+	cfg, err := metricsPushConfig(ctx, name)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%s: %v\n", name, err)
+		return 2
+	}
+
+	start := time.Now()
+	code := run()
+	if !cfg.Enabled() {
+		return code
+	}
+
+	reg := prometheus.NewRegistry()
+	duration := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "batch_job_duration_seconds",
+		Help: "Wall time of the last run of the subcommand",
+	})
+	exitCode := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "batch_job_exit_code",
+		Help: "Exit code of the last run of the subcommand; 0 is success",
+	})
+	completed := prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "batch_job_last_completion_timestamp_seconds",
+		Help: "Unix time the last run of the subcommand finished",
+	})
+	reg.MustRegister(duration, exitCode, completed)
+	duration.Set(time.Since(start).Seconds())
+	exitCode.Set(float64(code))
+	completed.SetToCurrentTime()
+
+	if err := metricspush.Push(ctx, cfg, prometheus.Gatherers{reg, prometheus.DefaultGatherer}); err != nil {
+		fmt.Fprintf(os.Stderr, "%s: failed to push metrics: %v\n", name, err)
+	}
+	return code
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const (
+	zerologLogPath = "github.com/rs/zerolog/log"
+	ignoreComment  = "//logcheck:ignore"
+)
+
+// Analyzer reports logging that drops the request context and handlers that
+// bypass obs.Handler
+var Analyzer = &analysis.Analyzer{
+	Name:     "logcheck",
+	Doc:      "check context-first logging and that handlers are served through obs.Handler",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+// handlersPkg is the last element of the import path of packages whose HTTP
+// handlers must use obs.Handler
+var handlersPkg = "handlers"
+
+func init() {
+	Analyzer.Flags.StringVar(&handlersPkg, "handlers", handlersPkg, "last import path element of packages whose HTTP handlers must use obs.Handler")
+}
+
+// globalLogCalls are the github.com/rs/zerolog/log functions that start a
+// log line
+var globalLogCalls = map[string]bool{
+	"Trace": true, "Debug": true, "Info": true, "Warn": true, "Error": true,
+	"Fatal": true, "Panic": true, "Err": true, "Log": true, "WithLevel": true,
+	"Print": true, "Printf": true,
+}
+
+// ctxLogCalls are logger methods taking the context as first argument
+var ctxLogCalls = map[string]bool{
+	"Debug": true, "Info": true, "Warn": true, "Error": true, "ErrorWithStack": true,
+	"Fatal": true, "Panic": true, "WithContext": true, "WithFields": true,
+	"Ctx": true, "Logger": true, "WrapAndRecord": true,
+}
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	isHandlerPkg := path.Base(pass.Pkg.Path()) == handlersPkg
+
+	ignored := make(map[*token.File]map[int]bool)
+	for _, file := range pass.Files {
+		ignored[pass.Fset.File(file.Pos())] = ignoredLines(pass.Fset, file)
+	}
+	report := func(pos token.Pos, format string, args ...interface{}) {
+		f := pass.Fset.File(pos)
+		if line := f.Line(pos); ignored[f][line] || ignored[f][line-1] {
+			return
+		}
+		pass.Reportf(pos, format, args...)
+	}
+
+	ins.Preorder([]ast.Node{(*ast.FuncDecl)(nil)}, func(n ast.Node) {
+		fn := n.(*ast.FuncDecl)
+		if fn.Body == nil {
+			return
+		}
+
+		if isHandlerPkg && fn.Recv != nil && fn.Name.Name == "ServeHTTP" && !strings.HasSuffix(pass.Fset.File(fn.Pos()).Name(), "_test.go") {
+			report(fn.Pos(), "%s.ServeHTTP bypasses obs.Handler: implement Serve(w, r) error and register it with obs.Handler", recvName(fn))
+		}
+
+		ctxName := contextParam(pass.TypesInfo, fn.Type)
+		if ctxName == "" {
+			return
+		}
+
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			sel, ok := call.Fun.(*ast.SelectorExpr)
+			if !ok {
+				return true
+			}
+
+			if pkg := importedPkg(pass.TypesInfo, sel.X); pkg != nil && pkg.Imported().Path() == zerologLogPath && globalLogCalls[sel.Sel.Name] {
+				report(call.Pos(), "global logger drops trace context: use logger.Ctx(%s) instead of %s.%s", ctxName, pkg.Name(), sel.Sel.Name)
+				return true
+			}
+
+			if ctxLogCalls[sel.Sel.Name] && len(call.Args) > 0 && isDetachedContext(pass.TypesInfo, call.Args[0]) {
+				report(call.Args[0].Pos(), "%s called with a detached context while %s is in scope", sel.Sel.Name, ctxName)
+			}
+			return true
+		})
+	})
+	return nil, nil
+}
+
+// contextParam returns how a function's context can be reached: the name of
+// its context.Context parameter, or "<r>.Context()" for an *http.Request
+// parameter. It returns "" when the function has neither.
+func contextParam(info *types.Info, ft *ast.FuncType) string {
+	var fromRequest string
+	for _, field := range ft.Params.List {
+		if len(field.Names) == 0 || field.Names[0].Name == "_" {
+			continue
+		}
+		name := field.Names[0].Name
+		t := info.TypeOf(field.Type)
+		if isNamed(t, "context", "Context") {
+			return name
+		}
+		if ptr, ok := t.(*types.Pointer); ok && isNamed(ptr.Elem(), "net/http", "Request") && fromRequest == "" {
+			fromRequest = name + ".Context()"
+		}
+	}
+	return fromRequest
+}
+
+// isDetachedContext reports whether e is context.Background() or context.TODO()
+func isDetachedContext(info *types.Info, e ast.Expr) bool {
+	call, ok := e.(*ast.CallExpr)
+	if !ok {
+		return false
+	}
+	fn, ok := typeutil.Callee(info, call).(*types.Func)
+	if !ok || fn.Pkg() == nil || fn.Pkg().Path() != "context" {
+		return false
+	}
+	return fn.Name() == "Background" || fn.Name() == "TODO"
+}
+
+// isNamed reports whether t is the named type pkg.name
+func isNamed(t types.Type, pkg, name string) bool {
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == pkg && obj.Name() == name
+}
+
+// importedPkg returns the package e names, or nil when e is not a package
+// name
+func importedPkg(info *types.Info, e ast.Expr) *types.PkgName {
+	id, ok := e.(*ast.Ident)
+	if !ok {
+		return nil
+	}
+	pkg, _ := info.Uses[id].(*types.PkgName)
+	return pkg
+}
+
+func recvName(fn *ast.FuncDecl) string {
+	t := fn.Recv.List[0].Type
+	if star, ok := t.(*ast.StarExpr); ok {
+		t = star.X
+	}
+	if id, ok := t.(*ast.Ident); ok {
+		return id.Name
+	}
+	return "handler"
+}
+
+// ignoredLines returns the lines carrying a //logcheck:ignore comment
+func ignoredLines(fset *token.FileSet, file *ast.File) map[int]bool {
+	lines := make(map[int]bool)
+	for _, group := range file.Comments {
+		for _, c := range group.List {
+			if strings.HasPrefix(c.Text, ignoreComment) {
+				lines[fset.Position(c.Pos()).Line] = true
+			}
+		}
+	}
+	return lines
+}
@@ -0,0 +1,29 @@
+// Command logcheck is a vet-style checker for the context-first logging
+// conventions of this service. It reports:
+//
+//   - calls to the global zerolog logger (github.com/rs/zerolog/log) in a
+//     function that has a context.Context or *http.Request, which drop the
+//     request and trace IDs; use logger.Ctx(ctx) instead
+//   - logger calls passed context.Background() or context.TODO() while a
+//     context is in scope
+//   - ServeHTTP methods in handler packages, which bypass obs.Handler and
+//     with it the handler span, named logger, timing and error envelope
+//
+// Usage:
+//
+//	go run ./cmd/logcheck ./...
+//
+// or, built once, as a vet tool:
+//
+//	go build -o bin/logcheck ./cmd/logcheck
+//	go vet -vettool=$(pwd)/bin/logcheck ./...
+//
+// A finding is suppressed by a "//logcheck:ignore" comment on the same line
+// or the line above. The exit status is non-zero when anything is reported.
+package main
+
+import "golang.org/x/tools/go/analysis/singlechecker"
+
+func main() {
+	singlechecker.Main(Analyzer)
+}
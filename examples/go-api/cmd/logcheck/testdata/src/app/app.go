@@ -0,0 +1,47 @@
+package app
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/rs/zerolog/log"
+	zlog "github.com/rs/zerolog/log"
+)
+
+type logger struct{}
+
+func (logger) Info(ctx context.Context, msg string)                {}
+func (logger) WithFields(ctx context.Context, f map[string]string) {}
+
+var l logger
+
+func withContext(ctx context.Context) {
+	log.Info().Msg("dropped")                // want `global logger drops trace context: use logger.Ctx\(ctx\) instead of log.Info`
+	zlog.Printf("renamed %d", 1)             // want `global logger drops trace context: use logger.Ctx\(ctx\) instead of zlog.Printf`
+	l.Info(context.Background(), "detached") // want `Info called with a detached context while ctx is in scope`
+	l.WithFields(context.TODO(), nil)        // want `WithFields called with a detached context while ctx is in scope`
+
+	l.Info(ctx, "kept")
+	log.Info().Msg("accepted") //logcheck:ignore
+	//logcheck:ignore
+	log.Error().Msg("accepted")
+}
+
+func withRequest(w http.ResponseWriter, r *http.Request) {
+	log.Err(nil).Msg("dropped") // want `use logger.Ctx\(r.Context\(\)\) instead of log.Err`
+	l.Info(r.Context(), "kept")
+}
+
+func withoutContext() {
+	log.Info().Msg("nothing to log through")
+	l.Info(context.Background(), "nothing to detach from")
+}
+
+func ignoresContext(_ context.Context) {
+	log.Info().Msg("unnamed context")
+}
+
+// server is not in a handler package, so its ServeHTTP is allowed
+type server struct{}
+
+func (server) ServeHTTP(w http.ResponseWriter, r *http.Request) {}
@@ -0,0 +1,14 @@
+package handlers
+
+import "net/http"
+
+type Users struct{}
+
+func (h *Users) ServeHTTP(w http.ResponseWriter, r *http.Request) {} // want `Users.ServeHTTP bypasses obs.Handler`
+
+func (h *Users) Serve(w http.ResponseWriter, r *http.Request) error { return nil }
+
+type Legacy struct{}
+
+//logcheck:ignore
+func (Legacy) ServeHTTP(w http.ResponseWriter, r *http.Request) {}
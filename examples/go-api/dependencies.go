@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/example/go-api/pkg/database"
+	"github.com/example/go-api/pkg/upstream"
+)
+
+// dependenciesHandler returns the service's upstreams as a graph with their
+// current health, versions and last error, shaped for Grafana's node graph
+// panel (e.g. through the Infinity data source)
+func (a *App) dependenciesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+
+	service := upstream.Dependency{
+		ID:      a.cfg.AppName,
+		Title:   a.cfg.AppName,
+		Kind:    "service",
+		Version: a.cfg.Version,
+	}
+	graph := upstream.BuildGraph(service, a.dependencies(ctx), a.upstreams.Results())
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"ready": a.startup.Ready(),
+		"nodes": graph.Nodes,
+		"edges": graph.Edges,
+	})
+}
+
+// dependencies lists the configured upstreams. Versions are looked up on
+// demand and left empty when the upstream does not report one.
+func (a *App) dependencies(ctx context.Context) []upstream.Dependency {
+	deps := []upstream.Dependency{
+		{ID: "weather_api", Title: "wttr.in", Kind: "http"},
+		{ID: "quote_api", Title: "Quotable", Kind: "http"},
+	}
+
+	if a.cfg.DatabaseEnabled {
+		dep := upstream.Dependency{ID: "database", Title: "PostgreSQL", Kind: "database"}
+		if a.cfg.Database.Driver == database.DriverSQLite {
+			dep.Title = "SQLite"
+		}
+		if db := a.currentDB(); db != nil {
+			dep.Version, _ = db.ServerVersion(ctx)
+		}
+		deps = append(deps, dep)
+	}
+
+	if a.cfg.TracingEnabled {
+		deps = append(deps, upstream.Dependency{ID: "otlp", Title: "Tempo", Kind: "traces"})
+	}
+
+	if a.cfg.LokiURL != "" {
+		version, _ := lokiVersion(ctx, a.cfg.LokiURL)
+		deps = append(deps, upstream.Dependency{ID: "loki", Title: "Loki", Kind: "logs", Version: version})
+	}
+
+	return deps
+}
+
+// lokiVersion reads the version from Loki's build info endpoint
+func lokiVersion(ctx context.Context, lokiURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, lokiURL+"/loki/api/v1/status/buildinfo", nil)
+	if err != nil {
+		return "", err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("loki returned status %d", resp.StatusCode)
+	}
+
+	var info struct {
+		Version string `json:"version"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&info); err != nil {
+		return "", err
+	}
+	return info.Version, nil
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/example/go-api/pkg/client"
+	"github.com/example/go-api/pkg/database"
+	"github.com/example/go-api/pkg/logger"
+)
+
+// markDeployment compares the running version with the last one recorded
+// in the database, or in DEPLOY_STATE_FILE without one. On a change it logs
+// a "deployment" event and annotates Grafana dashboards with the release.
+func (a *App) markDeployment(ctx context.Context) {
+	version, commit := a.cfg.Build.Version, a.cfg.Build.Commit
+
+	var (
+		prev    *database.Deployment
+		changed bool
+		err     error
+	)
+	switch db := a.currentDB(); {
+	case db != nil:
+		prev, changed, err = db.RecordDeployment(ctx, version, commit)
+	case a.cfg.DeployStateFile != "":
+		prev, changed, err = recordDeploymentFile(a.cfg.DeployStateFile, version, commit)
+	default:
+		logger.Ctx(ctx).Debug().Msg("No database or deploy state file, skipping deployment marker")
+		return
+	}
+	if err != nil {
+		logger.Ctx(ctx).Warn().Err(err).Msg("Failed to record deployment")
+		return
+	}
+	if !changed {
+		return
+	}
+
+	var oldVersion, oldCommit string
+	if prev != nil {
+		oldVersion, oldCommit = prev.Version, prev.Commit
+	}
+	logger.Ctx(ctx).Info().
+		Str("event", "deployment").
+		Str("old_version", oldVersion).
+		Str("old_commit", oldCommit).
+		Str("new_version", version).
+		Str("new_commit", commit).
+		Msg("New deployment detected")
+
+	if a.grafana == nil {
+		return
+	}
+	text := fmt.Sprintf("Deployed %s %s (%s)", a.cfg.AppName, version, shortCommit(commit))
+	if prev != nil {
+		text += fmt.Sprintf(", replacing %s (%s)", oldVersion, shortCommit(oldCommit))
+	}
+	annotateCtx, cancel := context.WithTimeout(ctx, a.cfg.HTTPClientTimeout)
+	defer cancel()
+	annotation := client.Annotation{
+		Time: a.started.UnixMilli(),
+		Tags: []string{"deployment", a.cfg.AppName, version},
+		Text: text,
+	}
+	if err := a.grafana.Annotate(annotateCtx, annotation); err != nil {
+		logger.Ctx(ctx).Warn().Err(err).Msg("Failed to annotate deployment")
+	}
+}
+
+// recordDeploymentFile is the single-instance fallback of
+// database.DB.RecordDeployment, keeping the last deployment in a JSON file
+func recordDeploymentFile(path, version, commit string) (*database.Deployment, bool, error) {
+	var prev *database.Deployment
+	b, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return nil, false, fmt.Errorf("failed to read deploy state: %w", err)
+	default:
+		prev = &database.Deployment{}
+		if err := json.Unmarshal(b, prev); err != nil {
+			return nil, false, fmt.Errorf("failed to parse deploy state: %w", err)
+		}
+		if prev.Version == version && prev.Commit == commit {
+			return prev, false, nil
+		}
+	}
+
+	b, err = json.Marshal(database.Deployment{Version: version, Commit: commit, DeployedAt: time.Now().UTC()})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to encode deploy state: %w", err)
+	}
+	if err := os.WriteFile(path, b, 0o644); err != nil {
+		return nil, false, fmt.Errorf("failed to write deploy state: %w", err)
+	}
+	return prev, true, nil
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 7 {
+		return commit[:7]
+	}
+	return commit
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/maintenance"
+	"github.com/example/go-api/pkg/upstream"
+)
+
+// Diagnostics is a point-in-time snapshot of the service for support
+// escalations
+type Diagnostics struct {
+	GeneratedAt  time.Time                  `json:"generated_at"`
+	Pod          string                     `json:"pod,omitempty"`
+	Uptime       string                     `json:"uptime"`
+	Config       Config                     `json:"config"`
+	Runtime      RuntimeStats               `json:"runtime"`
+	Ready        bool                       `json:"ready"`
+	Startup      map[string]string          `json:"startup"`
+	Upstreams    map[string]upstream.Result `json:"upstreams"`
+	Maintenance  maintenance.State          `json:"maintenance"`
+	DBPool       interface{}                `json:"db_pool,omitempty"`
+	Exporters    map[string]interface{}     `json:"exporters"`
+	RecentErrors []json.RawMessage          `json:"recent_errors"`
+	Goroutines   string                     `json:"goroutines,omitempty"`
+}
+
+// RuntimeStats summarises the Go runtime
+type RuntimeStats struct {
+	GoVersion    string `json:"go_version"`
+	NumGoroutine int    `json:"num_goroutine"`
+	NumCPU       int    `json:"num_cpu"`
+	HeapAlloc    uint64 `json:"heap_alloc_bytes"`
+	HeapObjects  uint64 `json:"heap_objects"`
+	NumGC        uint32 `json:"num_gc"`
+}
+
+// diagnostics collects the bundle. Secrets in the config marshal as
+// REDACTED.
+func (a *App) diagnostics() Diagnostics {
+	cfg := a.cfg
+	cfg.Database.Logger = nil
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+
+	var goroutines bytes.Buffer
+	pprof.Lookup("goroutine").WriteTo(&goroutines, 2)
+
+	d := Diagnostics{
+		GeneratedAt: time.Now().UTC(),
+		Pod:         a.cfg.PodName,
+		Uptime:      time.Since(a.started).Round(time.Second).String(),
+		Config:      cfg,
+		Runtime: RuntimeStats{
+			GoVersion:    runtime.Version(),
+			NumGoroutine: runtime.NumGoroutine(),
+			NumCPU:       runtime.NumCPU(),
+			HeapAlloc:    mem.HeapAlloc,
+			HeapObjects:  mem.HeapObjects,
+			NumGC:        mem.NumGC,
+		},
+		Ready:       a.startup.Ready(),
+		Startup:     a.startup.Status(),
+		Upstreams:   a.upstreams.Results(),
+		Maintenance: a.maintenance.State(),
+		Exporters: map[string]interface{}{
+			"otlp": map[string]interface{}{
+				"enabled":  a.cfg.TracingEnabled,
+				"endpoint": a.cfg.OTLPEndpoint,
+				"queue":    a.tracerProvider.QueueStats(),
+			},
+		},
+		RecentErrors: a.recentErrors.Entries(),
+		Goroutines:   goroutines.String(),
+	}
+	if db := a.currentDB(); db != nil {
+		d.DBPool = db.Stats()
+	}
+	return d
+}
+
+// diagnosticsHandler serves the diagnostics bundle as JSON, or as a gzipped
+// tarball with ?format=tar.gz
+func (a *App) diagnosticsHandler(w http.ResponseWriter, r *http.Request) {
+	d := a.diagnostics()
+
+	if r.URL.Query().Get("format") != "tar.gz" {
+		w.Header().Set("Content-Type", "application/json")
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		enc.Encode(d)
+		return
+	}
+
+	name := fmt.Sprintf("%s-diag-%s", a.cfg.AppName, d.GeneratedAt.Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.tar.gz"`, name))
+	if err := writeDiagnosticsTarball(w, name, d); err != nil {
+		logger.Ctx(r.Context()).Error().Err(err).Msg("Failed to write diagnostics bundle")
+	}
+}
+
+// writeDiagnosticsTarball splits the bundle into files that are easier to
+// read than one large JSON document
+func writeDiagnosticsTarball(w io.Writer, dir string, d Diagnostics) error {
+	goroutines, recentErrors := d.Goroutines, d.RecentErrors
+	d.Goroutines, d.RecentErrors = "", nil
+
+	summary, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode diagnostics: %w", err)
+	}
+
+	var errorLines bytes.Buffer
+	for _, e := range recentErrors {
+		errorLines.Write(bytes.TrimSpace(e))
+		errorLines.WriteByte('\n')
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	files := []struct {
+		name string
+		data []byte
+	}{
+		{"diagnostics.json", summary},
+		{"recent_errors.jsonl", errorLines.Bytes()},
+		{"goroutines.txt", []byte(goroutines)},
+	}
+	for _, f := range files {
+		hdr := &tar.Header{
+			Name:    dir + "/" + f.name,
+			Mode:    0o644,
+			Size:    int64(len(f.data)),
+			ModTime: d.GeneratedAt,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+		if _, err := tw.Write(f.data); err != nil {
+			return fmt.Errorf("failed to write %s: %w", f.name, err)
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// runDiag implements `go-api diag`: it downloads the bundle from a running
+// instance's admin server, e.g. via kubectl exec
+func runDiag(args []string) int {
+	fs := flag.NewFlagSet("diag", flag.ContinueOnError)
+	addr := fs.String("addr", "http://localhost:"+getEnvOrDefault("ADMIN_PORT", "9091"), "admin server base URL")
+	out := fs.String("o", "", `output file (default <app>-diag-<time>.tar.gz, "-" for stdout)`)
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(*addr + "/admin/diagnostics?format=tar.gz")
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "diag: %v\n", err)
+		return 1
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		fmt.Fprintf(os.Stderr, "diag: admin server returned status %d\n", resp.StatusCode)
+		return 1
+	}
+
+	var dst io.Writer = os.Stdout
+	if *out != "-" {
+		path := *out
+		if path == "" {
+			path = fmt.Sprintf("go-api-diag-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+		}
+		f, err := os.Create(path)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "diag: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		dst = f
+		fmt.Fprintf(os.Stderr, "diag: writing %s\n", path)
+	}
+
+	if _, err := io.Copy(dst, resp.Body); err != nil {
+		fmt.Fprintf(os.Stderr, "diag: %v\n", err)
+		return 1
+	}
+	return 0
+}
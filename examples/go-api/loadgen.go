@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/example/go-api/pkg/loadgen"
+	"github.com/example/go-api/pkg/metrics"
+)
+
+// runLoadgen sends a steady rate of requests to a running instance and
+// exits non-zero when more of them failed than -max-error-rate allows
+func runLoadgen(args []string) int {
+	fs := flag.NewFlagSet("loadgen", flag.ContinueOnError)
+	base := fs.String("url", "http://localhost:"+getEnvOrDefault("PORT", "8080"), "public server base URL")
+	paths := fs.String("paths", strings.Join(loadgen.DefaultPaths, ","), "comma-separated GET paths, requested in turn")
+	rate := fs.Float64("rate", 10, "requests per second")
+	duration := fs.Duration("duration", time.Minute, "length of the run")
+	concurrency := fs.Int("concurrency", 10, "requests in flight at most")
+	timeout := fs.Duration("timeout", 10*time.Second, "timeout of each request")
+	maxErrorRate := fs.Float64("max-error-rate", 0.01, "share of failed requests above which the run fails")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	var cfg loadgen.Config
+	for _, p := range strings.Split(*paths, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			cfg.Paths = append(cfg.Paths, p)
+		}
+	}
+	cfg.BaseURL = strings.TrimRight(*base, "/")
+	cfg.Rate, cfg.Duration, cfg.Concurrency, cfg.Timeout = *rate, *duration, *concurrency, *timeout
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// The default registry is what runBatch pushes
+	res := loadgen.Run(ctx, cfg, metrics.New("", nil))
+	fmt.Printf("loadgen: %d requests in %s (%.1f/s), %d failed, %d missed\n",
+		res.Requests, res.Elapsed.Round(time.Millisecond), float64(res.Requests)/res.Elapsed.Seconds(), res.Failed, res.Missed)
+	if res.ErrorRate() > *maxErrorRate {
+		fmt.Fprintf(os.Stderr, "loadgen: error rate %.2f%% is above %.2f%%\n", 100*res.ErrorRate(), 100**maxErrorRate)
+		return 1
+	}
+	return 0
+}
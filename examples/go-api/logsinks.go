@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+// newLogSinks creates the log output and the sinks enabled in cfg, and
+// records how to close them on shutdown and how full their queues are
+func (a *App) newLogSinks(cfg Config) ([]logger.Option, error) {
+	var opts []logger.Option
+
+	switch cfg.LogOutput {
+	case "", "stdout":
+	case "otlp":
+		otlp, err := logger.NewOTLPSink(logger.OTLPConfig{
+			Endpoint: cfg.LogOTLPEndpoint,
+			Insecure: cfg.LogOTLPInsecure,
+			Resource: map[string]string{
+				"service.name":    cfg.AppName,
+				"service.version": cfg.Version,
+			},
+		})
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, logger.WithOutput(otlp))
+		a.logSinks = append(a.logSinks, otlp.Close)
+		a.logQueues = append(a.logQueues, otlp.QueueFill)
+	default:
+		return nil, fmt.Errorf("unsupported LOG_OUTPUT %q", cfg.LogOutput)
+	}
+
+	if cfg.ElasticsearchURL != "" {
+		elastic := logger.NewElasticsearchSink(logger.ElasticsearchConfig{
+			URL:            cfg.ElasticsearchURL,
+			Index:          cfg.ElasticsearchIndex,
+			APIKey:         func() string { return a.elasticKey.Load().Reveal() },
+			DeadLetterPath: cfg.ElasticsearchDeadLetter,
+		})
+		opts = append(opts, logger.WithSink(elastic))
+		a.logSinks = append(a.logSinks, elastic.Close)
+		a.logQueues = append(a.logQueues, elastic.QueueFill)
+	}
+
+	if cfg.LokiPush && cfg.LokiURL != "" {
+		loki, err := logger.NewLokiSink(logger.LokiConfig{
+			URL: cfg.LokiURL,
+			Labels: map[string]string{
+				"app":         cfg.AppName,
+				"environment": cfg.Environment,
+			},
+			LabelFields:    cfg.LokiLabelFields,
+			MetadataFields: cfg.LokiMetadataFields,
+			MaxStreams:     cfg.LokiMaxStreams,
+			Tenant:         cfg.LokiTenant,
+			Tenants:        cfg.Tenants,
+			TimeFormat:     cfg.LogTimeFormat,
+			TimePrecision:  cfg.LogTimePrecision,
+			WALDir:         cfg.LokiWALDir,
+			WALMaxBytes:    int64(cfg.LokiWALMaxMB) << 20,
+		})
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, logger.WithSink(loki))
+		a.logSinks = append(a.logSinks, loki.Close)
+		a.logQueues = append(a.logQueues, loki.QueueFill)
+	}
+
+	if cfg.FluentAddr != "" {
+		fluent := logger.NewFluentSink(logger.FluentConfig{
+			Addr:       cfg.FluentAddr,
+			Tag:        cfg.FluentTag,
+			RequireAck: cfg.FluentRequireAck,
+		})
+		opts = append(opts, logger.WithSink(fluent))
+		a.logSinks = append(a.logSinks, fluent.Close)
+		a.logQueues = append(a.logQueues, fluent.QueueFill)
+	}
+
+	if cfg.SyslogAddr != "" {
+		syslog, err := logger.NewSyslogSink(logger.SyslogConfig{
+			Network: cfg.SyslogNetwork,
+			Addr:    cfg.SyslogAddr,
+			AppName: cfg.AppName,
+		})
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, logger.WithSink(syslog))
+		a.logSinks = append(a.logSinks, func(context.Context) error { return syslog.Close() })
+	}
+
+	if cfg.Journald {
+		journald, err := logger.NewJournaldSink(cfg.AppName)
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, logger.WithSink(journald))
+		a.logSinks = append(a.logSinks, func(context.Context) error { return journald.Close() })
+	}
+
+	if cfg.EventLog {
+		eventLog, err := logger.NewEventLogSink(logger.EventLogConfig{Source: cfg.EventLogSource, Install: true})
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, logger.WithSink(eventLog))
+		a.logSinks = append(a.logSinks, func(context.Context) error { return eventLog.Close() })
+	}
+
+	return opts, nil
+}
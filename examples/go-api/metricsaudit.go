@@ -0,0 +1,30 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/example/go-api/pkg/httperr"
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/metrics"
+)
+
+// metricsAuditHandler serves GET /admin/metrics-audit: series counts per
+// metric family from the local registry, largest first, with the families
+// over their budget. ?top=N limits the families listed.
+func (a *App) metricsAuditHandler(w http.ResponseWriter, r *http.Request) {
+	report, err := metrics.Audit(a.gatherer, a.cfg.MetricsAudit)
+	if err != nil {
+		httperr.Write(w, r, http.StatusInternalServerError, "failed to gather metrics")
+		return
+	}
+	report.Warn(logger.Ctx(r.Context()))
+
+	if top, err := strconv.Atoi(r.URL.Query().Get("top")); err == nil && top >= 0 && top < len(report.Families) {
+		report.Families = report.Families[:top]
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
@@ -0,0 +1,16 @@
+//go:build mongo
+
+package main
+
+import (
+	"context"
+
+	"github.com/example/go-api/pkg/database"
+	"github.com/example/go-api/pkg/mongostore"
+)
+
+func init() {
+	documentStores["mongo"] = func(ctx context.Context, cfg Config) (database.DocumentStore, error) {
+		return mongostore.New(ctx, mongostore.Config{URI: cfg.MongoURI, Database: cfg.MongoDatabase, WeatherTTL: cfg.Database.WeatherCacheTTL})
+	}
+}
@@ -0,0 +1,70 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Injected at build time, e.g.
+//
+//	go build -ldflags "-X github.com/example/go-api/pkg/buildinfo.Version=2.1.0 \
+//	  -X github.com/example/go-api/pkg/buildinfo.Commit=$(git rev-parse HEAD) \
+//	  -X github.com/example/go-api/pkg/buildinfo.BuildDate=$(date -u +%FT%TZ)"
+var (
+	Version   = "dev"
+	Commit    = ""
+	BuildDate = ""
+)
+
+// Info describes the running binary
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	GoVersion string `json:"go_version"`
+	BuildDate string `json:"build_date"`
+}
+
+var buildInfo = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "build_info",
+		Help: "Build information of the running binary, always 1",
+	},
+	[]string{"version", "commit", "go_version", "build_date"},
+)
+
+func init() {
+	prometheus.MustRegister(buildInfo)
+
+	i := Get()
+	buildInfo.WithLabelValues(i.Version, i.Commit, i.GoVersion, i.BuildDate).Set(1)
+}
+
+// Get returns the build information. Without ldflags, the commit and build
+// date fall back to the VCS stamp Go embeds in binaries built from a checkout.
+func Get() Info {
+	i := Info{
+		Version:   Version,
+		Commit:    Commit,
+		GoVersion: runtime.Version(),
+		BuildDate: BuildDate,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range bi.Settings {
+			switch {
+			case s.Key == "vcs.revision" && i.Commit == "":
+				i.Commit = s.Value
+			case s.Key == "vcs.time" && i.BuildDate == "":
+				i.BuildDate = s.Value
+			}
+		}
+	}
+	if i.Commit == "" {
+		i.Commit = "unknown"
+	}
+	if i.BuildDate == "" {
+		i.BuildDate = "unknown"
+	}
+	return i
+}
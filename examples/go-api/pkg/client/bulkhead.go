@@ -0,0 +1,136 @@
+package client
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ErrBulkheadFull is returned when an upstream's concurrency limit is reached
+// and no slot frees up within the queue timeout
+var ErrBulkheadFull = errors.New("bulkhead full")
+
+var (
+	bulkheadWait = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_client_bulkhead_wait_seconds",
+			Help:    "Time outbound requests waited for a bulkhead slot",
+			Buckets: []float64{.0005, .001, .005, .01, .025, .05, .1, .25, .5, 1},
+		},
+		[]string{"host"},
+	)
+	bulkheadRejected = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_client_bulkhead_rejected_total",
+			Help: "Outbound requests rejected because the upstream's concurrency limit was reached",
+		},
+		[]string{"host"},
+	)
+	bulkheadInUse = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "http_client_bulkhead_in_use",
+			Help: "Bulkhead slots currently held by outbound requests",
+		},
+		[]string{"host"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(bulkheadWait)
+	prometheus.MustRegister(bulkheadRejected)
+	prometheus.MustRegister(bulkheadInUse)
+}
+
+// bulkheadTransport caps concurrent requests to one upstream, so a slow
+// upstream ties up at most limit goroutines. A slot is held until the
+// response body is closed.
+type bulkheadTransport struct {
+	base         http.RoundTripper
+	slots        chan struct{}
+	queueTimeout time.Duration
+}
+
+func newBulkheadTransport(base http.RoundTripper, limit int, queueTimeout time.Duration) *bulkheadTransport {
+	return &bulkheadTransport{base: base, slots: make(chan struct{}, limit), queueTimeout: queueTimeout}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *bulkheadTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	span := trace.SpanFromContext(req.Context())
+
+	start := time.Now()
+	if err := t.acquire(req); err != nil {
+		waited := time.Since(start)
+		bulkheadWait.WithLabelValues(host).Observe(waited.Seconds())
+		bulkheadRejected.WithLabelValues(host).Inc()
+		span.AddEvent("bulkhead.rejected", trace.WithAttributes(
+			attribute.Int("bulkhead.limit", cap(t.slots)),
+			attribute.Int64("bulkhead.wait_ms", waited.Milliseconds()),
+		))
+		return nil, err
+	}
+	waited := time.Since(start)
+	bulkheadWait.WithLabelValues(host).Observe(waited.Seconds())
+	span.SetAttributes(attribute.Int64("bulkhead.wait_ms", waited.Milliseconds()))
+
+	inUse := bulkheadInUse.WithLabelValues(host)
+	inUse.Inc()
+	var once sync.Once
+	release := func() {
+		once.Do(func() {
+			inUse.Dec()
+			<-t.slots
+		})
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		release()
+		return nil, err
+	}
+	resp.Body = &releaseOnClose{ReadCloser: resp.Body, release: release}
+	return resp, nil
+}
+
+// acquire takes a slot, waiting at most queueTimeout for one to free up
+func (t *bulkheadTransport) acquire(req *http.Request) error {
+	select {
+	case t.slots <- struct{}{}:
+		return nil
+	default:
+	}
+	if t.queueTimeout <= 0 {
+		return fmt.Errorf("%w: %s has %d requests in flight", ErrBulkheadFull, req.URL.Host, cap(t.slots))
+	}
+
+	timer := time.NewTimer(t.queueTimeout)
+	defer timer.Stop()
+	select {
+	case t.slots <- struct{}{}:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("%w: %s has %d requests in flight", ErrBulkheadFull, req.URL.Host, cap(t.slots))
+	case <-req.Context().Done():
+		return req.Context().Err()
+	}
+}
+
+// releaseOnClose frees a bulkhead slot when the response body is closed
+type releaseOnClose struct {
+	io.ReadCloser
+	release func()
+}
+
+func (b *releaseOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.release()
+	return err
+}
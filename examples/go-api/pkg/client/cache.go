@@ -0,0 +1,371 @@
+package client
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// maxCachedBody bounds the size of a response body kept in the cache
+const maxCachedBody = 1 << 20
+
+// CacheConfig enables an in-memory cache for GET responses. Freshness comes
+// from Cache-Control max-age or Expires, falling back to TTL, and stale
+// entries are revalidated with If-None-Match / If-Modified-Since.
+type CacheConfig struct {
+	MaxEntries           int           // Least recently used entries are evicted beyond this (default 1000)
+	TTL                  time.Duration // Freshness when the response carries none
+	StaleWhileRevalidate time.Duration // How long a stale entry is served while it is refreshed in the background
+	RevalidateTimeout    time.Duration // Timeout for background revalidation (default 10s)
+}
+
+// Cache results, recorded in http_client_cache_requests_total and as the
+// http.cache.result span attribute
+const (
+	cacheHit         = "hit"         // Fresh entry served without a request
+	cacheStale       = "stale"       // Stale entry served, revalidating in the background
+	cacheRevalidated = "revalidated" // Upstream answered 304 to a conditional request
+	cacheMiss        = "miss"        // Full response fetched from upstream
+	cacheBypass      = "bypass"      // Request not eligible for caching
+)
+
+var (
+	cacheRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_client_cache_requests_total",
+			Help: "Outbound requests seen by the response cache, by result (hit, stale, revalidated, miss, bypass)",
+		},
+		[]string{"host", "result"},
+	)
+	cacheRevalidations = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_client_cache_revalidations_total",
+			Help: "Cache revalidations by mode (sync, background) and outcome (not_modified, updated, error)",
+		},
+		[]string{"host", "mode", "outcome"},
+	)
+	cacheEntries = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "http_client_cache_entries",
+			Help: "Number of responses held in the outbound response cache",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(cacheRequests)
+	prometheus.MustRegister(cacheRevalidations)
+	prometheus.MustRegister(cacheEntries)
+}
+
+type cacheEntry struct {
+	key        string
+	status     int
+	header     http.Header
+	body       []byte
+	freshUntil time.Time
+	staleUntil time.Time // Served stale while revalidating until then
+	elem       *list.Element
+}
+
+func (e *cacheEntry) validators() (etag, lastModified string) {
+	return e.header.Get("ETag"), e.header.Get("Last-Modified")
+}
+
+func (e *cacheEntry) response(req *http.Request) *http.Response {
+	return &http.Response{
+		Status:        strconv.Itoa(e.status) + " " + http.StatusText(e.status),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        e.header.Clone(),
+		Body:          io.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
+
+// cacheTransport serves GET responses from memory and refreshes stale
+// entries in the background while stale-while-revalidate allows it
+type cacheTransport struct {
+	base http.RoundTripper
+	cfg  CacheConfig
+
+	mu           sync.Mutex
+	entries      map[string]*cacheEntry
+	lru          *list.List // Front is most recently used
+	revalidating map[string]bool
+}
+
+func newCacheTransport(base http.RoundTripper, cfg CacheConfig) *cacheTransport {
+	if cfg.MaxEntries <= 0 {
+		cfg.MaxEntries = 1000
+	}
+	if cfg.RevalidateTimeout <= 0 {
+		cfg.RevalidateTimeout = 10 * time.Second
+	}
+	return &cacheTransport{
+		base:         base,
+		cfg:          cfg,
+		entries:      make(map[string]*cacheEntry),
+		lru:          list.New(),
+		revalidating: make(map[string]bool),
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	if req.Method != http.MethodGet || req.Header.Get("Range") != "" || hasDirective(req.Header, "no-store") {
+		t.record(req, host, cacheBypass)
+		return t.base.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+	now := time.Now()
+	entry := t.get(key)
+	noCache := hasDirective(req.Header, "no-cache")
+
+	switch {
+	case entry == nil:
+	case !noCache && now.Before(entry.freshUntil):
+		t.record(req, host, cacheHit)
+		return entry.response(req), nil
+	case !noCache && now.Before(entry.staleUntil):
+		t.record(req, host, cacheStale)
+		t.revalidateAsync(req, entry)
+		return entry.response(req), nil
+	}
+
+	resp, err := t.fetch(req, entry)
+	if err != nil {
+		if entry != nil {
+			cacheRevalidations.WithLabelValues(host, "sync", "error").Inc()
+		}
+		return nil, err
+	}
+	if entry != nil && resp.StatusCode == http.StatusNotModified {
+		cacheRevalidations.WithLabelValues(host, "sync", "not_modified").Inc()
+		t.record(req, host, cacheRevalidated)
+		resp.Body.Close()
+		return t.refresh(entry, resp.Header, now).response(req), nil
+	}
+	if entry != nil {
+		cacheRevalidations.WithLabelValues(host, "sync", "updated").Inc()
+	}
+	t.record(req, host, cacheMiss)
+	return t.store(key, resp, now)
+}
+
+// fetch sends req upstream, made conditional when there is a cached entry
+func (t *cacheTransport) fetch(req *http.Request, entry *cacheEntry) (*http.Response, error) {
+	if entry == nil {
+		return t.base.RoundTrip(req)
+	}
+	req = req.Clone(req.Context())
+	etag, lastModified := entry.validators()
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if lastModified != "" {
+		req.Header.Set("If-Modified-Since", lastModified)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// revalidateAsync refreshes entry in the background, at most once at a time
+// per key. The caller's cancellation does not stop it.
+func (t *cacheTransport) revalidateAsync(req *http.Request, entry *cacheEntry) {
+	t.mu.Lock()
+	if t.revalidating[entry.key] {
+		t.mu.Unlock()
+		return
+	}
+	t.revalidating[entry.key] = true
+	t.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(req.Context()), t.cfg.RevalidateTimeout)
+	req = req.Clone(ctx)
+
+	go func() {
+		defer cancel()
+		defer func() {
+			t.mu.Lock()
+			delete(t.revalidating, entry.key)
+			t.mu.Unlock()
+		}()
+
+		host := req.URL.Host
+		now := time.Now()
+		resp, err := t.fetch(req, entry)
+		if err != nil {
+			cacheRevalidations.WithLabelValues(host, "background", "error").Inc()
+			return
+		}
+		if resp.StatusCode == http.StatusNotModified {
+			resp.Body.Close()
+			t.refresh(entry, resp.Header, now)
+			cacheRevalidations.WithLabelValues(host, "background", "not_modified").Inc()
+			return
+		}
+		resp, err = t.store(entry.key, resp, now)
+		if err != nil {
+			cacheRevalidations.WithLabelValues(host, "background", "error").Inc()
+			return
+		}
+		resp.Body.Close()
+		cacheRevalidations.WithLabelValues(host, "background", "updated").Inc()
+	}()
+}
+
+// store caches resp when it is cacheable and returns a response whose body
+// can still be read by the caller
+func (t *cacheTransport) store(key string, resp *http.Response, now time.Time) (*http.Response, error) {
+	if resp.StatusCode != http.StatusOK || hasDirective(resp.Header, "no-store") || resp.Header.Get("Vary") == "*" {
+		return resp, nil
+	}
+	if resp.ContentLength > maxCachedBody {
+		return resp, nil
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedBody+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > maxCachedBody {
+		// Too large to cache: hand back what was read plus the rest
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+
+	entry := &cacheEntry{key: key, status: resp.StatusCode, header: resp.Header.Clone(), body: body}
+	t.setFreshness(entry, now)
+
+	t.mu.Lock()
+	if old, ok := t.entries[key]; ok {
+		t.lru.Remove(old.elem)
+	}
+	entry.elem = t.lru.PushFront(entry)
+	t.entries[key] = entry
+	for t.lru.Len() > t.cfg.MaxEntries {
+		oldest := t.lru.Remove(t.lru.Back()).(*cacheEntry)
+		delete(t.entries, oldest.key)
+	}
+	cacheEntries.Set(float64(t.lru.Len()))
+	t.mu.Unlock()
+
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	resp.ContentLength = int64(len(body))
+	return resp, nil
+}
+
+// refresh applies the headers of a 304 response to entry and restarts its
+// freshness lifetime
+func (t *cacheTransport) refresh(entry *cacheEntry, header http.Header, now time.Time) *cacheEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	updated := *entry
+	updated.header = entry.header.Clone()
+	for k, v := range header {
+		updated.header[k] = v
+	}
+	t.setFreshness(&updated, now)
+
+	// Replace rather than mutate, so responses built from entry stay consistent
+	if cur, ok := t.entries[entry.key]; ok && cur == entry {
+		updated.elem = t.lru.PushFront(&updated)
+		t.lru.Remove(entry.elem)
+		t.entries[entry.key] = &updated
+	}
+	return &updated
+}
+
+func (t *cacheTransport) get(key string) *cacheEntry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	entry, ok := t.entries[key]
+	if !ok {
+		return nil
+	}
+	t.lru.MoveToFront(entry.elem)
+	return entry
+}
+
+// setFreshness computes the fresh and stale-while-revalidate deadlines from
+// the response headers, falling back to the configured defaults
+func (t *cacheTransport) setFreshness(e *cacheEntry, now time.Time) {
+	lifetime := t.cfg.TTL
+	if maxAge, ok := directiveSeconds(e.header, "max-age"); ok {
+		lifetime = maxAge
+	} else if expires, err := http.ParseTime(e.header.Get("Expires")); err == nil {
+		lifetime = expires.Sub(now)
+	}
+	if age, err := strconv.Atoi(e.header.Get("Age")); err == nil {
+		lifetime -= time.Duration(age) * time.Second
+	}
+	if hasDirective(e.header, "no-cache") || lifetime < 0 {
+		lifetime = 0
+	}
+
+	swr := t.cfg.StaleWhileRevalidate
+	if d, ok := directiveSeconds(e.header, "stale-while-revalidate"); ok {
+		swr = d
+	}
+	if hasDirective(e.header, "no-cache") || hasDirective(e.header, "must-revalidate") {
+		swr = 0
+	}
+
+	e.freshUntil = now.Add(lifetime)
+	e.staleUntil = e.freshUntil.Add(swr)
+}
+
+func (t *cacheTransport) record(req *http.Request, host, result string) {
+	cacheRequests.WithLabelValues(host, result).Inc()
+	trace.SpanFromContext(req.Context()).SetAttributes(attribute.String("http.cache.result", result))
+}
+
+// cacheDirectives splits a Cache-Control header into lower-cased directives
+func cacheDirectives(h http.Header) map[string]string {
+	directives := make(map[string]string)
+	for _, part := range strings.Split(h.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), "=")
+		if name != "" {
+			directives[strings.ToLower(name)] = strings.Trim(value, `"`)
+		}
+	}
+	return directives
+}
+
+func hasDirective(h http.Header, name string) bool {
+	_, ok := cacheDirectives(h)[name]
+	return ok
+}
+
+func directiveSeconds(h http.Header, name string) (time.Duration, bool) {
+	v, ok := cacheDirectives(h)[name]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, false
+	}
+	return time.Duration(n) * time.Second, true
+}
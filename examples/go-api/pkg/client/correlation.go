@@ -0,0 +1,85 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/baggage"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+type correlationKey struct{}
+
+// WithCorrelationHeaders returns a context carrying inbound headers to be
+// forwarded on outbound requests made with it
+func WithCorrelationHeaders(ctx context.Context, h http.Header) context.Context {
+	return context.WithValue(ctx, correlationKey{}, h)
+}
+
+// CorrelationHeaders returns the headers stored by WithCorrelationHeaders
+func CorrelationHeaders(ctx context.Context) http.Header {
+	h, _ := ctx.Value(correlationKey{}).(http.Header)
+	return h
+}
+
+// correlationTransport copies allowlisted correlation headers onto outbound
+// requests and trims the W3C baggage header to allowlisted members, so
+// upstreams can log our request ID without seeing internal baggage
+type correlationTransport struct {
+	base    http.RoundTripper
+	headers []string
+	baggage map[string]bool
+}
+
+func newCorrelationTransport(base http.RoundTripper, headers, baggageKeys []string) *correlationTransport {
+	t := &correlationTransport{base: base, baggage: make(map[string]bool, len(baggageKeys))}
+	for _, h := range headers {
+		if h = strings.TrimSpace(h); h != "" {
+			t.headers = append(t.headers, http.CanonicalHeaderKey(h))
+		}
+	}
+	for _, k := range baggageKeys {
+		t.baggage[strings.TrimSpace(k)] = true
+	}
+	return t
+}
+
+func (t *correlationTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// RoundTrippers must not modify the caller's request
+	req = req.Clone(req.Context())
+	ctx := req.Context()
+
+	inbound := CorrelationHeaders(ctx)
+	for _, name := range t.headers {
+		if req.Header.Get(name) != "" {
+			continue
+		}
+		value := inbound.Get(name)
+		if name == "X-Request-Id" {
+			// Generated IDs never appear on the inbound request
+			value = logger.GetRequestID(ctx)
+		}
+		if value != "" {
+			req.Header.Set(name, value)
+		}
+	}
+
+	if h := req.Header.Get("Baggage"); h != "" {
+		req.Header.Del("Baggage")
+		if b, err := baggage.Parse(h); err == nil {
+			var keep []baggage.Member
+			for _, m := range b.Members() {
+				if t.baggage[m.Key()] {
+					keep = append(keep, m)
+				}
+			}
+			if filtered, err := baggage.New(keep...); err == nil && filtered.Len() > 0 {
+				req.Header.Set("Baggage", filtered.String())
+			}
+		}
+	}
+
+	return t.base.RoundTrip(req)
+}
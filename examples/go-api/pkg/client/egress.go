@@ -0,0 +1,136 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"syscall"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+// ErrEgressDenied is returned for outbound requests blocked by an EgressPolicy
+var ErrEgressDenied = errors.New("egress denied")
+
+// blockedPrefixes are never dialled, whatever the host allowlist says:
+// cloud metadata endpoints (169.254.169.254 in the link-local range on AWS,
+// GCP, Azure and Oracle, fd00:ec2::254 on AWS, 100.100.100.200 on Alibaba
+// Cloud), and loopback or unspecified addresses, which reach the pod itself
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("100.100.100.200/32"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("fd00:ec2::254/128"),
+}
+
+var egressDenied = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "http_client_egress_denied_total",
+		Help: "Outbound requests blocked by the egress policy by reason; the host is logged",
+	},
+	[]string{"reason"},
+)
+
+func init() {
+	prometheus.MustRegister(egressDenied)
+}
+
+// EgressPolicy restricts outbound requests to allowlisted hosts and refuses
+// to connect to link-local, metadata and loopback addresses. Addresses are
+// checked at dial time, after DNS resolution, so a hostname that resolves
+// to a blocked address is refused too.
+type EgressPolicy struct {
+	hosts map[string]struct{}
+	log   *logger.Logger // logger.Default() when nil
+}
+
+// NewEgressPolicy creates a policy allowing the given hosts. Entries match
+// the request host exactly, or any subdomain when written as ".example.com".
+// An empty list allows every host but still blocks the reserved ranges.
+func NewEgressPolicy(hosts []string, log *logger.Logger) *EgressPolicy {
+	p := &EgressPolicy{hosts: make(map[string]struct{}, len(hosts)), log: log}
+	for _, h := range hosts {
+		if h = strings.ToLower(strings.TrimSpace(h)); h != "" {
+			p.hosts[h] = struct{}{}
+		}
+	}
+	return p
+}
+
+// AllowHost reports whether requests to host (without port) are allowed
+func (p *EgressPolicy) AllowHost(host string) bool {
+	if len(p.hosts) == 0 {
+		return true
+	}
+	host = strings.ToLower(host)
+	if _, ok := p.hosts[host]; ok {
+		return true
+	}
+	for h := range p.hosts {
+		if strings.HasPrefix(h, ".") && strings.HasSuffix(host, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// AllowAddr reports whether a resolved address may be dialled
+func (p *EgressPolicy) AllowAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *EgressPolicy) deny(ctx context.Context, host, reason string) error {
+	egressDenied.WithLabelValues(reason).Inc()
+	denyLog := logger.OrDefault(p.log).WithFields(ctx, map[string]interface{}{
+		"host":   host,
+		"reason": reason,
+	})
+	denyLog.Warn().Msg("Outbound request blocked by egress policy")
+	return fmt.Errorf("%w: %s (%s)", ErrEgressDenied, host, reason)
+}
+
+// egressTransport enforces the host allowlist before a request is sent
+type egressTransport struct {
+	base   http.RoundTripper
+	policy *EgressPolicy
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *egressTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if host := req.URL.Hostname(); !t.policy.AllowHost(host) {
+		return nil, t.policy.deny(req.Context(), host, "host_not_allowed")
+	}
+	return t.base.RoundTrip(req)
+}
+
+// control is a net.Dialer Control hook rejecting blocked addresses
+func (p *EgressPolicy) control(_, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return err
+	}
+	if !p.AllowAddr(addr) {
+		return p.deny(context.Background(), host, "address_blocked")
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/example/go-api/pkg/secrets"
+)
+
+// Annotation is an event marker shown on Grafana dashboards
+type Annotation struct {
+	Time    int64    `json:"time,omitempty"`    // Epoch milliseconds; Grafana uses now when zero
+	TimeEnd int64    `json:"timeEnd,omitempty"` // Set for region annotations
+	Tags    []string `json:"tags,omitempty"`
+	Text    string   `json:"text"`
+}
+
+// GrafanaClient posts annotations to the Grafana HTTP API
+type GrafanaClient struct {
+	httpClient *TracedHTTPClient
+	baseURL    string
+	token      *secrets.Value
+}
+
+// NewGrafanaClient creates a new Grafana client. token is a service account
+// token with the annotations:write permission.
+func NewGrafanaClient(baseURL string, token *secrets.Value, timeout time.Duration, opts ...Option) *GrafanaClient {
+	return &GrafanaClient{
+		httpClient: NewTracedHTTPClient(timeout, TransportConfig{}, opts...),
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		token:      token,
+	}
+}
+
+// Annotate creates an organisation-wide annotation
+func (c *GrafanaClient) Annotate(ctx context.Context, a Annotation) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return fmt.Errorf("failed to encode annotation: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/api/annotations", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.token.IsSet() {
+		req.Header.Set("Authorization", "Bearer "+c.token.Reveal())
+	}
+
+	resp, err := c.httpClient.Do(ctx, req)
+	if err != nil {
+		return fmt.Errorf("failed to post annotation: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("grafana returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
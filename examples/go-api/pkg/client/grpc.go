@@ -0,0 +1,420 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+// ErrCircuitOpen is returned without calling the upstream while its circuit
+// breaker is open
+var ErrCircuitOpen = errors.New("circuit open")
+
+// grpcTracerName is the instrumentation scope of gRPC client spans
+const grpcTracerName = "github.com/example/go-api/pkg/client/grpc"
+
+var (
+	grpcRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_client_requests_total",
+			Help: "Outbound gRPC calls by target, method and status code; retries count separately",
+		},
+		[]string{"target", "method", "code"},
+	)
+	grpcRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "grpc_client_request_duration_seconds",
+			Help:    "Outbound gRPC call duration in seconds, per attempt; streams until they end",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"target", "method", "code"},
+	)
+	grpcCircuitOpen = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "grpc_client_circuit_open",
+			Help: "Whether the circuit breaker of a gRPC target is open (1) or closed (0)",
+		},
+		[]string{"target"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(grpcRequestsTotal)
+	prometheus.MustRegister(grpcRequestDuration)
+	prometheus.MustRegister(grpcCircuitOpen)
+}
+
+// GRPCConfig configures the gRPC client interceptors. Zero values use the
+// defaults below.
+type GRPCConfig struct {
+	Logger         *logger.Logger // Default logger.Default()
+	ForwardHeaders []string       // Correlation headers sent as metadata (default X-Request-ID)
+
+	MaxAttempts    int           // Unary attempts including the first, 1 disables retries (default 3)
+	InitialBackoff time.Duration // Delay before the first retry (default 50ms)
+	MaxBackoff     time.Duration // Upper bound for the exponential backoff (default 1s)
+
+	BreakerFailures int           // Consecutive failures that open the circuit, 0 disables it (default 0)
+	BreakerOpenFor  time.Duration // How long the circuit stays open before a trial call (default 30s)
+}
+
+// DialGRPC connects to target with the interceptors from GRPCInterceptors.
+// Connections are plaintext unless opts add transport credentials.
+func DialGRPC(ctx context.Context, target string, cfg GRPCConfig, opts ...grpc.DialOption) (*grpc.ClientConn, error) {
+	unary, stream := GRPCInterceptors(cfg)
+	opts = append([]grpc.DialOption{
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithChainUnaryInterceptor(unary),
+		grpc.WithChainStreamInterceptor(stream),
+	}, opts...)
+	conn, err := grpc.DialContext(ctx, target, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s: %w", target, err)
+	}
+	return conn, nil
+}
+
+// GRPCInterceptors returns client interceptors that trace, measure and log
+// each call and forward correlation headers as metadata. Unary calls are
+// retried on Unavailable with exponential backoff; streams are not, as
+// messages may already have been sent. With BreakerFailures set, calls to a
+// target fail fast with ErrCircuitOpen after that many consecutive failures.
+func GRPCInterceptors(cfg GRPCConfig) (grpc.UnaryClientInterceptor, grpc.StreamClientInterceptor) {
+	if cfg.ForwardHeaders == nil {
+		cfg.ForwardHeaders = []string{"X-Request-ID"}
+	}
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 3
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = 50 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Second
+	}
+	if cfg.BreakerOpenFor <= 0 {
+		cfg.BreakerOpenFor = 30 * time.Second
+	}
+	i := &grpcInterceptors{cfg: cfg, tracer: otel.Tracer(grpcTracerName), breakers: make(map[string]*circuitBreaker)}
+	return i.unary, i.stream
+}
+
+type grpcInterceptors struct {
+	cfg    GRPCConfig
+	tracer trace.Tracer
+
+	mu       sync.Mutex
+	breakers map[string]*circuitBreaker
+}
+
+func (i *grpcInterceptors) unary(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	target := cc.Target()
+	ctx, span := i.startSpan(ctx, method, target)
+	defer span.End()
+	ctx = i.outgoingMetadata(ctx)
+
+	breaker := i.breaker(target)
+	backoff := i.cfg.InitialBackoff
+	for attempt := 1; ; attempt++ {
+		if !breaker.allow() {
+			err := fmt.Errorf("%w: %s", ErrCircuitOpen, target)
+			span.AddEvent("circuit.rejected")
+			i.finish(ctx, span, method, target, err)
+			return err
+		}
+
+		start := time.Now()
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		code := status.Code(err)
+		i.observe(method, target, code, time.Since(start))
+		breaker.record(isBreakerFailure(code), i.cfg.Logger, ctx, target)
+
+		if code != codes.Unavailable || attempt >= i.cfg.MaxAttempts || ctx.Err() != nil {
+			i.finish(ctx, span, method, target, err)
+			return err
+		}
+
+		span.AddEvent("rpc.retry", trace.WithAttributes(
+			attribute.Int("rpc.retry.attempt", attempt),
+			attribute.Int64("rpc.retry.backoff_ms", backoff.Milliseconds()),
+			attribute.String("exception.message", err.Error()),
+		))
+		retryLog := logger.OrDefault(i.cfg.Logger).WithFields(ctx, map[string]interface{}{
+			"rpc.method": method,
+			"target":     target,
+			"attempt":    attempt,
+			"backoff_ms": backoff.Milliseconds(),
+		})
+		retryLog.Warn().Err(err).Msg("gRPC call unavailable, retrying")
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			i.finish(ctx, span, method, target, err)
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+		if backoff > i.cfg.MaxBackoff {
+			backoff = i.cfg.MaxBackoff
+		}
+	}
+}
+
+func (i *grpcInterceptors) stream(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+	target := cc.Target()
+	ctx, span := i.startSpan(ctx, method, target)
+	ctx = i.outgoingMetadata(ctx)
+
+	breaker := i.breaker(target)
+	if !breaker.allow() {
+		err := fmt.Errorf("%w: %s", ErrCircuitOpen, target)
+		span.AddEvent("circuit.rejected")
+		i.finish(ctx, span, method, target, err)
+		span.End()
+		return nil, err
+	}
+
+	start := time.Now()
+	cs, err := streamer(ctx, desc, cc, method, opts...)
+	if err != nil {
+		code := status.Code(err)
+		i.observe(method, target, code, time.Since(start))
+		breaker.record(isBreakerFailure(code), i.cfg.Logger, ctx, target)
+		i.finish(ctx, span, method, target, err)
+		span.End()
+		return nil, err
+	}
+
+	return &tracedClientStream{ClientStream: cs, done: func(err error) {
+		code := status.Code(err)
+		i.observe(method, target, code, time.Since(start))
+		breaker.record(isBreakerFailure(code), i.cfg.Logger, ctx, target)
+		i.finish(ctx, span, method, target, err)
+		span.End()
+	}}, nil
+}
+
+func (i *grpcInterceptors) startSpan(ctx context.Context, method, target string) (context.Context, trace.Span) {
+	service, rpcMethod := splitGRPCMethod(method)
+	return i.tracer.Start(ctx, strings.TrimPrefix(method, "/"),
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			semconv.RPCSystemGRPC,
+			semconv.RPCService(service),
+			semconv.RPCMethod(rpcMethod),
+			attribute.String("rpc.grpc.target", target),
+		),
+	)
+}
+
+// outgoingMetadata adds the trace context and correlation headers to the
+// call's metadata
+func (i *grpcInterceptors) outgoingMetadata(ctx context.Context) context.Context {
+	md, _ := metadata.FromOutgoingContext(ctx)
+	md = md.Copy()
+	otel.GetTextMapPropagator().Inject(ctx, metadataCarrier(md))
+
+	inbound := CorrelationHeaders(ctx)
+	for _, name := range i.cfg.ForwardHeaders {
+		key := strings.ToLower(strings.TrimSpace(name))
+		if key == "" || len(md.Get(key)) > 0 {
+			continue
+		}
+		value := inbound.Get(name)
+		if key == "x-request-id" {
+			value = logger.GetRequestID(ctx)
+		}
+		if value != "" {
+			md.Set(key, value)
+		}
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+func (i *grpcInterceptors) observe(method, target string, code codes.Code, d time.Duration) {
+	grpcRequestsTotal.WithLabelValues(target, method, code.String()).Inc()
+	grpcRequestDuration.WithLabelValues(target, method, code.String()).Observe(d.Seconds())
+}
+
+// finish sets the span status and logs a failed call once
+func (i *grpcInterceptors) finish(ctx context.Context, span trace.Span, method, target string, err error) {
+	code := status.Code(err)
+	span.SetAttributes(semconv.RPCGRPCStatusCodeKey.Int(int(code)))
+	if err == nil {
+		return
+	}
+	span.RecordError(err)
+	span.SetStatus(otelcodes.Error, err.Error())
+	failLog := logger.OrDefault(i.cfg.Logger).WithFields(ctx, map[string]interface{}{
+		"rpc.method": method,
+		"target":     target,
+		"code":       code.String(),
+	})
+	failLog.Error().Err(err).Msg("gRPC call failed")
+}
+
+func (i *grpcInterceptors) breaker(target string) *circuitBreaker {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	b, ok := i.breakers[target]
+	if !ok {
+		b = &circuitBreaker{threshold: i.cfg.BreakerFailures, openFor: i.cfg.BreakerOpenFor}
+		i.breakers[target] = b
+	}
+	return b
+}
+
+// isBreakerFailure reports whether a status code means the upstream is
+// unhealthy, as opposed to the caller sending a bad request
+func isBreakerFailure(code codes.Code) bool {
+	switch code {
+	case codes.Unavailable, codes.DeadlineExceeded, codes.Internal, codes.Unknown, codes.ResourceExhausted:
+		return true
+	}
+	return false
+}
+
+// circuitBreaker fails calls fast after threshold consecutive failures. Once
+// openFor has passed a single trial call is let through: success closes the
+// circuit, failure opens it again.
+type circuitBreaker struct {
+	threshold int
+	openFor   time.Duration
+
+	mu       sync.Mutex
+	failures int
+	open     bool
+	openedAt time.Time
+	trial    bool // A trial call is in flight
+}
+
+func (b *circuitBreaker) allow() bool {
+	if b.threshold <= 0 {
+		return true
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if !b.open {
+		return true
+	}
+	if b.trial || time.Since(b.openedAt) < b.openFor {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+func (b *circuitBreaker) record(failed bool, log *logger.Logger, ctx context.Context, target string) {
+	if b.threshold <= 0 {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	wasOpen := b.open
+	b.trial = false
+
+	if !failed {
+		b.failures = 0
+		b.open = false
+	} else {
+		b.failures++
+		if b.open || b.failures >= b.threshold {
+			b.open = true
+			b.openedAt = time.Now()
+		}
+	}
+
+	if b.open == wasOpen {
+		return
+	}
+	state, msg := "closed", "Circuit closed, upstream recovered"
+	if b.open {
+		state, msg = "open", "Circuit opened, failing calls fast"
+		grpcCircuitOpen.WithLabelValues(target).Set(1)
+	} else {
+		grpcCircuitOpen.WithLabelValues(target).Set(0)
+	}
+	stateLog := logger.OrDefault(log).WithFields(ctx, map[string]interface{}{
+		"target":   target,
+		"state":    state,
+		"failures": b.failures,
+	})
+	stateLog.Warn().Msg(msg)
+}
+
+// tracedClientStream reports the end of a stream: the first error from
+// RecvMsg, io.EOF being a clean end, or a failed send
+type tracedClientStream struct {
+	grpc.ClientStream
+	once sync.Once
+	done func(err error)
+}
+
+func (s *tracedClientStream) RecvMsg(m interface{}) error {
+	err := s.ClientStream.RecvMsg(m)
+	if err == io.EOF {
+		s.once.Do(func() { s.done(nil) })
+	} else if err != nil {
+		s.once.Do(func() { s.done(err) })
+	}
+	return err
+}
+
+func (s *tracedClientStream) SendMsg(m interface{}) error {
+	err := s.ClientStream.SendMsg(m)
+	if err != nil && err != io.EOF {
+		s.once.Do(func() { s.done(err) })
+	}
+	return err
+}
+
+// splitGRPCMethod splits "/package.Service/Method"
+func splitGRPCMethod(fullMethod string) (service, method string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "", fullMethod
+}
+
+// metadataCarrier adapts gRPC metadata for trace context propagation
+type metadataCarrier metadata.MD
+
+func (c metadataCarrier) Get(key string) string {
+	if v := metadata.MD(c).Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+func (c metadataCarrier) Set(key, value string) {
+	metadata.MD(c).Set(key, value)
+}
+
+func (c metadataCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
@@ -0,0 +1,41 @@
+package client
+
+import (
+	"context"
+	"fmt"
+
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// GRPCHealthClient checks a downstream gRPC service with the standard
+// grpc.health.v1 protocol
+type GRPCHealthClient struct {
+	conn   *grpc.ClientConn
+	health healthpb.HealthClient
+}
+
+// NewGRPCHealthClient dials target with the traced interceptors. The
+// connection is established lazily, so an unreachable target fails the
+// first Check rather than startup.
+func NewGRPCHealthClient(ctx context.Context, target string, cfg GRPCConfig, opts ...grpc.DialOption) (*GRPCHealthClient, error) {
+	conn, err := DialGRPC(ctx, target, cfg, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &GRPCHealthClient{conn: conn, health: healthpb.NewHealthClient(conn)}, nil
+}
+
+// Check returns the serving status of service, "" for the server as a whole
+func (c *GRPCHealthClient) Check(ctx context.Context, service string) (string, error) {
+	resp, err := c.health.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		return "", fmt.Errorf("failed to check downstream health: %w", err)
+	}
+	return resp.GetStatus().String(), nil
+}
+
+// Close closes the connection
+func (c *GRPCHealthClient) Close() error {
+	return c.conn.Close()
+}
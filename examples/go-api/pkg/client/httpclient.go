@@ -6,24 +6,38 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/go-api/pkg/tracing"
 )
 
+// maxAttributeLength bounds span attributes taken from request input or
+// upstream payloads
+const maxAttributeLength = 256
+
 // TracedHTTPClient wraps an HTTP client with OpenTelemetry instrumentation
 type TracedHTTPClient struct {
 	client *http.Client
 }
 
-// NewTracedHTTPClient creates a new HTTP client with tracing
-func NewTracedHTTPClient(timeout time.Duration) *TracedHTTPClient {
+// NewTracedHTTPClient creates a new HTTP client with tracing, per-host
+// request metrics and connection-phase timing
+func NewTracedHTTPClient(timeout time.Duration, transport TransportConfig, opts ...Option) *TracedHTTPClient {
+	o := newOptions(transport, opts)
+	rt := newRoundTripper(o.transport)
+	for i := len(o.wrappers) - 1; i >= 0; i-- {
+		rt = o.wrappers[i](rt)
+	}
 	return &TracedHTTPClient{
 		client: &http.Client{
 			Timeout: timeout,
-			Transport: otelhttp.NewTransport(http.DefaultTransport,
+			Transport: otelhttp.NewTransport(rt,
 				otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
 					return fmt.Sprintf("HTTP %s %s", r.Method, r.URL.Host)
 				}),
@@ -32,6 +46,23 @@ func NewTracedHTTPClient(timeout time.Duration) *TracedHTTPClient {
 	}
 }
 
+// newRoundTripper layers correlation headers, response caching, the
+// concurrency bulkhead, egress checks, metrics and phase timing over a
+// pooled transport
+func newRoundTripper(cfg TransportConfig) http.RoundTripper {
+	var rt http.RoundTripper = &metricsTransport{base: &phaseTransport{base: NewTransport(cfg)}}
+	if cfg.Egress != nil {
+		rt = &egressTransport{base: rt, policy: cfg.Egress}
+	}
+	if cfg.MaxConcurrent > 0 {
+		rt = newBulkheadTransport(rt, cfg.MaxConcurrent, cfg.QueueTimeout)
+	}
+	if cfg.Cache != nil {
+		rt = newCacheTransport(rt, *cfg.Cache)
+	}
+	return newCorrelationTransport(rt, cfg.ForwardHeaders, cfg.ForwardBaggage)
+}
+
 // Get performs a GET request with tracing
 func (c *TracedHTTPClient) Get(ctx context.Context, url string) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
@@ -54,10 +85,14 @@ type WeatherClient struct {
 }
 
 // NewWeatherClient creates a new weather client
-func NewWeatherClient(timeout time.Duration) *WeatherClient {
+func NewWeatherClient(timeout time.Duration, transport TransportConfig, opts ...Option) *WeatherClient {
+	baseURL := newOptions(transport, opts).baseURL
+	if baseURL == "" {
+		baseURL = "https://wttr.in"
+	}
 	return &WeatherClient{
-		httpClient: NewTracedHTTPClient(timeout),
-		baseURL:    "https://wttr.in",
+		httpClient: NewTracedHTTPClient(timeout, transport, opts...),
+		baseURL:    baseURL,
 	}
 }
 
@@ -71,16 +106,22 @@ type WeatherResponse struct {
 	RawData     string `json:"raw_data,omitempty"`
 }
 
+// ProbeURL returns a lightweight URL for active health probing
+func (c *WeatherClient) ProbeURL() string {
+	return c.baseURL + "/?format=3"
+}
+
 // GetWeather fetches weather for a location
 func (c *WeatherClient) GetWeather(ctx context.Context, location string) (*WeatherResponse, error) {
 	span := trace.SpanFromContext(ctx)
 	span.SetAttributes(
-		attribute.String("weather.location", location),
+		attribute.String("weather.location", tracing.Truncate(location, maxAttributeLength)),
 		attribute.String("weather.provider", "wttr.in"),
 	)
 
-	url := fmt.Sprintf("%s/%s?format=j1", c.baseURL, location)
-	resp, err := c.httpClient.Get(ctx, url)
+	// Escape the location so it cannot change the path, host or query
+	endpoint := fmt.Sprintf("%s/%s?format=j1", c.baseURL, url.PathEscape(location))
+	resp, err := c.httpClient.Get(ctx, endpoint)
 	if err != nil {
 		span.RecordError(err)
 		return nil, fmt.Errorf("failed to fetch weather: %w", err)
@@ -134,27 +175,43 @@ func (c *WeatherClient) GetWeather(ctx context.Context, location string) (*Weath
 	}
 
 	span.SetAttributes(
-		attribute.String("weather.temperature", weather.Temperature),
-		attribute.String("weather.condition", weather.Condition),
+		attribute.String("weather.temperature", tracing.Truncate(weather.Temperature, maxAttributeLength)),
+		attribute.String("weather.condition", tracing.Truncate(weather.Condition, maxAttributeLength)),
 	)
 
 	return weather, nil
 }
 
-// QuoteClient fetches quotes from quotable.io
+// QuoteClient fetches quotes from quotable.io, or the API at WithBaseURL
 type QuoteClient struct {
 	httpClient *TracedHTTPClient
 	baseURL    string
+	provider   string
 }
 
 // NewQuoteClient creates a new quote client
-func NewQuoteClient(timeout time.Duration) *QuoteClient {
+func NewQuoteClient(timeout time.Duration, transport TransportConfig, opts ...Option) *QuoteClient {
+	baseURL := newOptions(transport, opts).baseURL
+	if baseURL == "" {
+		baseURL = "https://api.quotable.io"
+	}
 	return &QuoteClient{
-		httpClient: NewTracedHTTPClient(timeout),
-		baseURL:    "https://api.quotable.io",
+		httpClient: NewTracedHTTPClient(timeout, transport, opts...),
+		baseURL:    baseURL,
+		provider:   quoteProvider(baseURL),
 	}
 }
 
+// quoteProvider names the quote API after the host of baseURL without a
+// leading "api.", e.g. "quotable.io"
+func quoteProvider(baseURL string) string {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Hostname() == "" {
+		return baseURL
+	}
+	return strings.TrimPrefix(u.Hostname(), "api.")
+}
+
 // Quote represents a quote from quotable.io
 type Quote struct {
 	ID           string   `json:"_id"`
@@ -165,17 +222,23 @@ type Quote struct {
 	Length       int      `json:"length"`
 	DateAdded    string   `json:"dateAdded"`
 	DateModified string   `json:"dateModified"`
+	Source       string   `json:"source,omitempty"` // Provider the quote came from, set by the client
+}
+
+// ProbeURL returns a lightweight URL for active health probing
+func (c *QuoteClient) ProbeURL() string {
+	return c.baseURL + "/random"
 }
 
 // GetRandomQuote fetches a random quote
 func (c *QuoteClient) GetRandomQuote(ctx context.Context) (*Quote, error) {
 	span := trace.SpanFromContext(ctx)
 	span.SetAttributes(
-		attribute.String("quote.provider", "quotable.io"),
+		attribute.String("quote.provider", c.provider),
 	)
 
-	url := fmt.Sprintf("%s/random", c.baseURL)
-	resp, err := c.httpClient.Get(ctx, url)
+	endpoint := fmt.Sprintf("%s/random", c.baseURL)
+	resp, err := c.httpClient.Get(ctx, endpoint)
 	if err != nil {
 		span.RecordError(err)
 		return nil, fmt.Errorf("failed to fetch quote: %w", err)
@@ -191,6 +254,7 @@ func (c *QuoteClient) GetRandomQuote(ctx context.Context) (*Quote, error) {
 		span.RecordError(err)
 		return nil, fmt.Errorf("failed to decode quote: %w", err)
 	}
+	quote.Source = c.provider
 
 	span.SetAttributes(
 		attribute.String("quote.author", quote.Author),
@@ -204,13 +268,13 @@ func (c *QuoteClient) GetRandomQuote(ctx context.Context) (*Quote, error) {
 func (c *QuoteClient) GetQuotesByTag(ctx context.Context, tag string, limit int) ([]Quote, error) {
 	span := trace.SpanFromContext(ctx)
 	span.SetAttributes(
-		attribute.String("quote.provider", "quotable.io"),
+		attribute.String("quote.provider", c.provider),
 		attribute.String("quote.tag", tag),
 		attribute.Int("quote.limit", limit),
 	)
 
-	url := fmt.Sprintf("%s/quotes?tags=%s&limit=%d", c.baseURL, tag, limit)
-	resp, err := c.httpClient.Get(ctx, url)
+	endpoint := fmt.Sprintf("%s/quotes?tags=%s&limit=%d", c.baseURL, url.QueryEscape(tag), limit)
+	resp, err := c.httpClient.Get(ctx, endpoint)
 	if err != nil {
 		span.RecordError(err)
 		return nil, fmt.Errorf("failed to fetch quotes: %w", err)
@@ -230,6 +294,9 @@ func (c *QuoteClient) GetQuotesByTag(ctx context.Context, tag string, limit int)
 		span.RecordError(err)
 		return nil, fmt.Errorf("failed to decode quotes: %w", err)
 	}
+	for i := range response.Results {
+		response.Results[i].Source = c.provider
+	}
 
 	span.SetAttributes(
 		attribute.Int("quote.results_count", len(response.Results)),
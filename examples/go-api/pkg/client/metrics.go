@@ -0,0 +1,78 @@
+package client
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Outbound request metrics, labelled by upstream host so weather and quote
+// latency show up next to the server-side RED metrics
+var (
+	clientRequestsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_client_requests_total",
+			Help: "Total number of outbound HTTP requests",
+		},
+		[]string{"host", "method", "status"},
+	)
+	clientRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_client_request_duration_seconds",
+			Help:    "Outbound HTTP request duration in seconds, until response headers",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"host", "method", "status"},
+	)
+	clientRequestsInFlight = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "http_client_requests_in_flight",
+			Help: "Number of outbound HTTP requests currently waiting for a response",
+		},
+		[]string{"host"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(clientRequestsTotal)
+	prometheus.MustRegister(clientRequestDuration)
+	prometheus.MustRegister(clientRequestsInFlight)
+}
+
+// metricsTransport records outbound request metrics. Transport errors such
+// as timeouts and refused connections are counted with status "error".
+type metricsTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *metricsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	inFlight := clientRequestsInFlight.WithLabelValues(host)
+	inFlight.Inc()
+	defer inFlight.Dec()
+
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	duration := time.Since(start)
+
+	status := "error"
+	if err == nil {
+		status = strconv.Itoa(resp.StatusCode)
+	}
+
+	clientRequestsTotal.WithLabelValues(host, req.Method, status).Inc()
+	observer := clientRequestDuration.WithLabelValues(host, req.Method, status)
+	if sc := trace.SpanContextFromContext(req.Context()); sc.IsSampled() {
+		if eo, ok := observer.(prometheus.ExemplarObserver); ok {
+			eo.ObserveWithExemplar(duration.Seconds(), prometheus.Labels{"trace_id": sc.TraceID().String()})
+			return resp, err
+		}
+	}
+	observer.Observe(duration.Seconds())
+
+	return resp, err
+}
@@ -0,0 +1,59 @@
+package client
+
+import (
+	"net/http"
+	"time"
+)
+
+// Option adjusts a client on top of its TransportConfig, so new capabilities
+// can be added without changing every constructor
+type Option func(*options)
+
+type options struct {
+	transport TransportConfig
+	baseURL   string
+	wrappers  []func(http.RoundTripper) http.RoundTripper
+}
+
+func newOptions(transport TransportConfig, opts []Option) options {
+	o := options{transport: transport}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithBaseURL points an API client at another base URL, e.g. a mirror or a
+// local stub
+func WithBaseURL(u string) Option {
+	return func(o *options) { o.baseURL = u }
+}
+
+// WithCache enables the in-memory response cache
+func WithCache(cfg CacheConfig) Option {
+	return func(o *options) { o.transport.Cache = &cfg }
+}
+
+// WithBulkhead caps concurrent requests per upstream
+func WithBulkhead(maxConcurrent int, queueTimeout time.Duration) Option {
+	return func(o *options) {
+		o.transport.MaxConcurrent = maxConcurrent
+		o.transport.QueueTimeout = queueTimeout
+	}
+}
+
+// WithEgress restricts the destinations requests may reach
+func WithEgress(policy *EgressPolicy) Option {
+	return func(o *options) { o.transport.Egress = policy }
+}
+
+// WithForwardHeaders sets the correlation headers copied from the inbound request
+func WithForwardHeaders(headers ...string) Option {
+	return func(o *options) { o.transport.ForwardHeaders = headers }
+}
+
+// WithRoundTripper wraps the transport, e.g. to add retries or auth. Wrappers
+// run inside the client span, the first one outermost.
+func WithRoundTripper(wrap func(http.RoundTripper) http.RoundTripper) Option {
+	return func(o *options) { o.wrappers = append(o.wrappers, wrap) }
+}
@@ -0,0 +1,111 @@
+package client
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Connection phases of an outbound request
+const (
+	phaseDNS     = "dns"
+	phaseConnect = "connect"
+	phaseTLS     = "tls"
+	phaseTTFB    = "ttfb"
+)
+
+var clientPhaseDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "http_client_phase_duration_seconds",
+		Help:    "Outbound HTTP request phase duration in seconds (dns, connect, tls, ttfb)",
+		Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	},
+	[]string{"host", "phase"},
+)
+
+func init() {
+	prometheus.MustRegister(clientPhaseDuration)
+}
+
+// phaseTransport times DNS, connect, TLS handshake and time to first byte
+// with httptrace. Each phase is recorded as a metric and as an event on the
+// client span, so slow upstream calls can be split into network and server
+// time. Reused connections skip the dns, connect and tls phases.
+type phaseTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *phaseTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	pt := &phaseTimer{host: req.URL.Host, span: trace.SpanFromContext(req.Context())}
+	start := time.Now()
+
+	var dnsStart, connectStart, tlsStart time.Time
+	ct := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			clientConnsTotal.WithLabelValues(pt.host, strconv.FormatBool(info.Reused)).Inc()
+			pt.span.AddEvent("http.got_conn", trace.WithAttributes(
+				attribute.Bool("http.conn.reused", info.Reused),
+				attribute.Bool("http.conn.was_idle", info.WasIdle),
+			))
+		},
+		DNSStart: func(httptrace.DNSStartInfo) { dnsStart = time.Now() },
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			pt.record(phaseDNS, dnsStart, info.Err)
+		},
+		ConnectStart: func(_, _ string) {
+			pt.mu.Lock()
+			connectStart = time.Now()
+			pt.mu.Unlock()
+		},
+		ConnectDone: func(_, _ string, err error) {
+			pt.mu.Lock()
+			s := connectStart
+			pt.mu.Unlock()
+			pt.record(phaseConnect, s, err)
+		},
+		TLSHandshakeStart: func() { tlsStart = time.Now() },
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			pt.record(phaseTLS, tlsStart, err)
+		},
+		GotFirstResponseByte: func() {
+			pt.record(phaseTTFB, start, nil)
+		},
+	}
+
+	return t.base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), ct)))
+}
+
+// phaseTimer records phase durations for one request. Hooks can fire from
+// the dialer's goroutines (e.g. parallel IPv4/IPv6 connects), so recording
+// is serialised.
+type phaseTimer struct {
+	mu   sync.Mutex
+	host string
+	span trace.Span
+}
+
+func (p *phaseTimer) record(phase string, start time.Time, err error) {
+	if start.IsZero() {
+		return
+	}
+	d := time.Since(start)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	clientPhaseDuration.WithLabelValues(p.host, phase).Observe(d.Seconds())
+
+	attrs := []attribute.KeyValue{attribute.Float64("duration_ms", float64(d.Microseconds())/1000)}
+	if err != nil {
+		attrs = append(attrs, attribute.String("error", err.Error()))
+	}
+	p.span.AddEvent("http."+phase, trace.WithAttributes(attrs...))
+}
@@ -0,0 +1,113 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// TransportConfig tunes the outbound connection pool. Zero values use the
+// defaults below; Go's own default of 2 idle connections per host causes
+// connection churn under load.
+type TransportConfig struct {
+	MaxIdleConns        int           // Idle connections across all hosts (default 100)
+	MaxIdleConnsPerHost int           // Idle connections kept per host (default 32)
+	MaxConnsPerHost     int           // Total connections per host, 0 for no limit
+	IdleConnTimeout     time.Duration // How long idle connections are kept (default 90s)
+	DisableHTTP2        bool          // Force HTTP/1.1, e.g. for upstreams with broken HTTP/2
+	Egress              *EgressPolicy // Optional destination allowlist and SSRF guard
+	ForwardHeaders      []string      // Correlation headers copied from the inbound request
+	ForwardBaggage      []string      // Baggage members sent upstream; others are dropped
+	Cache               *CacheConfig  // Optional in-memory response cache
+	MaxConcurrent       int           // Concurrent requests per upstream, 0 for no limit
+	QueueTimeout        time.Duration // How long a request waits for a free slot before failing
+}
+
+var (
+	clientConnsOpen = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "http_client_connections_open",
+			Help: "Number of open outbound connections",
+		},
+		[]string{"addr"},
+	)
+	clientConnsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_client_connections_total",
+			Help: "Connections handed to outbound requests, by whether they were reused from the pool",
+		},
+		[]string{"host", "reused"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(clientConnsOpen)
+	prometheus.MustRegister(clientConnsTotal)
+}
+
+// NewTransport creates an http.Transport from cfg whose connections are
+// counted in http_client_connections_open
+func NewTransport(cfg TransportConfig) *http.Transport {
+	if cfg.MaxIdleConns <= 0 {
+		cfg.MaxIdleConns = 100
+	}
+	if cfg.MaxIdleConnsPerHost <= 0 {
+		cfg.MaxIdleConnsPerHost = 32
+	}
+	if cfg.IdleConnTimeout <= 0 {
+		cfg.IdleConnTimeout = 90 * time.Second
+	}
+
+	dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+	if cfg.Egress != nil {
+		dialer.Control = cfg.Egress.control
+	}
+	t := &http.Transport{
+		Proxy:                 http.ProxyFromEnvironment,
+		DialContext:           countingDialer(dialer.DialContext),
+		ForceAttemptHTTP2:     !cfg.DisableHTTP2,
+		MaxIdleConns:          cfg.MaxIdleConns,
+		MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
+		MaxConnsPerHost:       cfg.MaxConnsPerHost,
+		IdleConnTimeout:       cfg.IdleConnTimeout,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+	if cfg.DisableHTTP2 {
+		// A non-nil empty map disables the built-in HTTP/2 upgrade
+		t.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	}
+	return t
+}
+
+type dialFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func countingDialer(dial dialFunc) dialFunc {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		open := clientConnsOpen.WithLabelValues(addr)
+		open.Inc()
+		return &countedConn{Conn: conn, open: open}, nil
+	}
+}
+
+// countedConn decrements the open-connection gauge once on Close
+type countedConn struct {
+	net.Conn
+	open  prometheus.Gauge
+	close sync.Once
+}
+
+// Close implements net.Conn
+func (c *countedConn) Close() error {
+	c.close.Do(c.open.Dec)
+	return c.Conn.Close()
+}
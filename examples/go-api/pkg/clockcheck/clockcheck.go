@@ -0,0 +1,203 @@
+package clockcheck
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+var (
+	clockSkew = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "clock_skew_seconds",
+			Help: "Local clock offset from the reference time source; positive means the local clock is behind",
+		},
+	)
+	clockCheckFailures = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "clock_check_failures_total",
+			Help: "Failed queries to the reference time source",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(clockSkew)
+	prometheus.MustRegister(clockCheckFailures)
+}
+
+// Config controls clock skew checks
+type Config struct {
+	Source    string        // "ntp://host[:port]" or an http(s) URL whose Date header is used
+	Interval  time.Duration // Time between checks (default 10m)
+	Threshold time.Duration // Skew that triggers a warning (default 1s)
+	Timeout   time.Duration // Per-query timeout (default 5s)
+}
+
+// Checker compares the local clock with a reference time source. Skew
+// shifts log and span timestamps against each other, so trace/log
+// correlation windows in Grafana miss matches.
+type Checker struct {
+	cfg Config
+	log *logger.Logger
+
+	mu      sync.Mutex
+	skew    time.Duration
+	checked time.Time
+}
+
+// New creates a Checker
+func New(cfg Config, log *logger.Logger) *Checker {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Minute
+	}
+	if cfg.Threshold <= 0 {
+		cfg.Threshold = time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	return &Checker{cfg: cfg, log: log}
+}
+
+// Run checks once immediately and then every interval until ctx is
+// cancelled
+func (c *Checker) Run(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		c.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Check measures the skew once, records it and warns past the threshold
+func (c *Checker) Check(ctx context.Context) (time.Duration, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	skew, err := c.query(ctx)
+	if err != nil {
+		clockCheckFailures.Inc()
+		l := c.log.WithContext(ctx)
+		l.Warn().Err(err).Str("source", c.cfg.Source).Msg("Clock check failed")
+		return 0, err
+	}
+
+	clockSkew.Set(skew.Seconds())
+	c.mu.Lock()
+	c.skew, c.checked = skew, time.Now()
+	c.mu.Unlock()
+
+	if skew > c.cfg.Threshold || skew < -c.cfg.Threshold {
+		l := c.log.WithContext(ctx)
+		l.Warn().
+			Str("source", c.cfg.Source).
+			Dur("skew", skew).
+			Dur("threshold", c.cfg.Threshold).
+			Msg("Clock skew exceeds threshold")
+	}
+	return skew, nil
+}
+
+// Last returns the most recent skew measurement and when it was taken
+func (c *Checker) Last() (time.Duration, time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.skew, c.checked
+}
+
+func (c *Checker) query(ctx context.Context) (time.Duration, error) {
+	u, err := url.Parse(c.cfg.Source)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time source: %w", err)
+	}
+	switch u.Scheme {
+	case "ntp":
+		return queryNTP(ctx, u.Host)
+	case "http", "https":
+		return queryHTTP(ctx, c.cfg.Source)
+	default:
+		return 0, fmt.Errorf("unsupported time source scheme %q", u.Scheme)
+	}
+}
+
+// ntpEpochOffset is the number of seconds between 1900 and 1970
+const ntpEpochOffset = 2208988800
+
+// queryNTP sends one SNTP request and returns the clock offset
+func queryNTP(ctx context.Context, host string) (time.Duration, error) {
+	if _, _, err := net.SplitHostPort(host); err != nil {
+		host = net.JoinHostPort(host, "123")
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", host)
+	if err != nil {
+		return 0, fmt.Errorf("failed to dial NTP server: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	req := make([]byte, 48)
+	req[0] = 0x1B // LI 0, version 3, client mode
+	t1 := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		return 0, fmt.Errorf("failed to send NTP request: %w", err)
+	}
+	resp := make([]byte, 48)
+	if _, err := conn.Read(resp); err != nil {
+		return 0, fmt.Errorf("failed to read NTP response: %w", err)
+	}
+	t4 := time.Now()
+
+	t2 := ntpTime(resp[32:40]) // Server receive
+	t3 := ntpTime(resp[40:48]) // Server transmit
+	return (t2.Sub(t1) + t3.Sub(t4)) / 2, nil
+}
+
+func ntpTime(b []byte) time.Time {
+	secs := int64(binary.BigEndian.Uint32(b[0:4])) - ntpEpochOffset
+	frac := int64(binary.BigEndian.Uint32(b[4:8]))
+	return time.Unix(secs, frac*1e9>>32)
+}
+
+// queryHTTP estimates the offset from a server's Date header. The header
+// has one-second resolution, so use thresholds of a second or more.
+func queryHTTP(ctx context.Context, source string) (time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, source, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to query time source: %w", err)
+	}
+	resp.Body.Close()
+	rtt := time.Since(start)
+
+	date, err := http.ParseTime(resp.Header.Get("Date"))
+	if err != nil {
+		return 0, fmt.Errorf("time source returned no usable Date header: %w", err)
+	}
+	// The Date header truncates to the second; assume the middle of it
+	remote := date.Add(500 * time.Millisecond)
+	return remote.Sub(start.Add(rtt / 2)), nil
+}
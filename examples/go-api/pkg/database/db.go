@@ -3,55 +3,169 @@ package database
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/XSAM/otelsql"
 	_ "github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
 	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/secrets"
+	"github.com/example/go-api/pkg/tracing"
+)
+
+// Supported database drivers
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite" // Local development only, requires the sqlite build tag
 )
 
 // Config holds database configuration
 type Config struct {
-	Host         string
-	Port         int
-	User         string
-	Password     string
-	Database     string
-	SSLMode      string
-	MaxOpenConns int
-	MaxIdleConns int
-	MaxLifetime  time.Duration
+	Driver             string // DriverPostgres (default) or DriverSQLite
+	Path               string // SQLite database file (DriverSQLite only)
+	Host               string
+	Port               int
+	User               string
+	Password           *secrets.Value // Read per connection, so rotations apply to new connections
+	Database           string
+	SSLMode            string
+	MaxOpenConns       int
+	MaxIdleConns       int
+	MaxLifetime        time.Duration
+	ApplicationName    string          // application_name of every connection, shown in pg_stat_activity (Postgres only)
+	StatementTimeout   time.Duration   // Server-side statement_timeout of every connection; zero keeps the server's (Postgres only)
+	LockTimeout        time.Duration   // Server-side lock_timeout of every connection; zero keeps the server's (Postgres only)
+	Retry              RetryConfig     // Retry policy for transient errors (zero value uses DefaultRetryConfig)
+	MaxStatementLength int             // db.statement span attributes are truncated to this many bytes (default 1024)
+	Logger             *logger.Logger  // Logger for retry and pool events (default logger.Default())
+	WeatherCacheTTL    WeatherCacheTTL // Freshness of weather_cache rows per location class
+	QueryTimeouts      QueryTimeouts   // Statement timeouts per query class
+	QueryMetrics       *QueryMetrics   // Counts statement timeouts; nil leaves them uncounted
+	SlowQuery          SlowQueryConfig // Slow query logging and plan capture
 }
 
 // DB wraps the sql.DB with tracing
 type DB struct {
 	*sql.DB
+	driver    string
+	retry     RetryConfig
+	log       *logger.Logger
+	connector *failoverConnector
+	weather   WeatherCacheTTL
+
+	timeouts     QueryTimeouts
+	queryMetrics *QueryMetrics
+	slow         SlowQueryConfig
+	explainer    *explainer // nil unless slow queries are explained
 }
 
-// New creates a new database connection with OpenTelemetry instrumentation
-func New(ctx context.Context, cfg Config) (*DB, error) {
-	dsn := fmt.Sprintf(
-		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.Database, cfg.SSLMode,
-	)
+// openSQLite is set by sqlite.go when built with the sqlite tag
+var openSQLite func(ctx context.Context, cfg Config) (*sql.DB, error)
+
+// spanOptions controls which database/sql calls otelsql turns into spans
+var spanOptions = otelsql.WithSpanOptions(otelsql.SpanOptions{
+	Ping:         true,
+	RowsNext:     false,
+	DisableQuery: true, // Added truncated by statementAttributes instead
+})
+
+// statementAttributes records the query as db.statement, truncated so large
+// generated statements do not bloat spans. It also hands the statement to
+// the slow query recorder in ctx, if any.
+func statementAttributes(maxLen int) otelsql.Option {
+	if maxLen <= 0 {
+		maxLen = 1024
+	}
+	return otelsql.WithAttributesGetter(func(ctx context.Context, method otelsql.Method, query string, args []driver.NamedValue) []attribute.KeyValue {
+		if query == "" {
+			return nil
+		}
+		recordStatement(ctx, method, query, args)
+		return []attribute.KeyValue{semconv.DBStatement(tracing.Truncate(query, maxLen))}
+	})
+}
 
-	// Register the otelsql wrapper for the postgres driver
-	db, err := otelsql.Open("postgres", dsn,
-		otelsql.WithAttributes(
-			semconv.DBSystemPostgreSQL,
-			semconv.DBName(cfg.Database),
-			semconv.ServerAddress(cfg.Host),
-			semconv.ServerPort(cfg.Port),
-		),
-		otelsql.WithSpanOptions(otelsql.SpanOptions{
-			Ping:         true,
-			RowsNext:     false,
-			DisableQuery: false, // Include query in span attributes
-		}),
+// sessionSettings returns the connection string settings that lib/pq sends
+// to the server at startup, so they apply to every pooled connection,
+// including those dialled after a failover
+func sessionSettings(cfg Config) string {
+	var b strings.Builder
+	if cfg.ApplicationName != "" {
+		fmt.Fprintf(&b, " application_name=%s", quoteSetting(cfg.ApplicationName))
+	}
+	if cfg.StatementTimeout > 0 {
+		fmt.Fprintf(&b, " statement_timeout=%d", cfg.StatementTimeout.Milliseconds())
+	}
+	if cfg.LockTimeout > 0 {
+		fmt.Fprintf(&b, " lock_timeout=%d", cfg.LockTimeout.Milliseconds())
+	}
+	return b.String()
+}
+
+// quoteSetting quotes a connection string value that may contain spaces
+func quoteSetting(v string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `\'`).Replace(v) + "'"
+}
+
+// New creates a new database connection with OpenTelemetry instrumentation.
+// For Postgres, cfg.Host may list several comma-separated hosts; the first
+// writable primary is used and the pool follows it across failovers.
+// Options are applied on top of cfg.
+func New(ctx context.Context, cfg Config, opts ...Option) (*DB, error) {
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var (
+		db        *sql.DB
+		connector *failoverConnector
+		system    = semconv.DBSystemPostgreSQL
+		dbName    = cfg.Database
 	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to open database: %w", err)
+
+	switch cfg.Driver {
+	case "", DriverPostgres:
+		connector = newFailoverConnector(
+			parseHosts(cfg.Host, cfg.Port),
+			func(host, port string) string {
+				return fmt.Sprintf(
+					"host=%s port=%s user=%s password=%s dbname=%s sslmode=%s%s",
+					host, port, cfg.User, cfg.Password.Reveal(), cfg.Database, cfg.SSLMode, sessionSettings(cfg),
+				)
+			},
+			cfg.Logger,
+		)
+
+		// Wrap the failover connector with otelsql for tracing
+		db = otelsql.OpenDB(connector,
+			otelsql.WithAttributes(
+				semconv.DBSystemPostgreSQL,
+				semconv.DBName(cfg.Database),
+				semconv.ServerAddress(cfg.Host),
+				semconv.ServerPort(cfg.Port),
+			),
+			spanOptions,
+			statementAttributes(cfg.MaxStatementLength),
+		)
+	case DriverSQLite:
+		if openSQLite == nil {
+			return nil, fmt.Errorf("sqlite driver not available: rebuild with -tags sqlite")
+		}
+		var err error
+		if db, err = openSQLite(ctx, cfg); err != nil {
+			return nil, fmt.Errorf("failed to open database: %w", err)
+		}
+		// SQLite allows a single writer; more connections only cause SQLITE_BUSY
+		cfg.MaxOpenConns = 1
+		system = semconv.DBSystemSqlite
+		dbName = cfg.Path
+	default:
+		return nil, fmt.Errorf("unsupported database driver %q", cfg.Driver)
 	}
 
 	// Configure connection pool
@@ -67,18 +181,60 @@ func New(ctx context.Context, cfg Config) (*DB, error) {
 
 	// Verify connection
 	if err := db.PingContext(ctx); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
 	// Register stats for metrics
 	if err := otelsql.RegisterDBStatsMetrics(db, otelsql.WithAttributes(
-		semconv.DBSystemPostgreSQL,
-		semconv.DBName(cfg.Database),
+		system,
+		semconv.DBName(dbName),
 	)); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to register DB stats metrics: %w", err)
 	}
 
-	return &DB{DB: db}, nil
+	retry := cfg.Retry
+	if retry.MaxAttempts == 0 {
+		retry = DefaultRetryConfig()
+	}
+
+	driver := cfg.Driver
+	if driver == "" {
+		driver = DriverPostgres
+	}
+
+	var explain *explainer
+	if cfg.SlowQuery.Threshold > 0 && cfg.SlowQuery.Explain {
+		explain = newExplainer(cfg.SlowQuery.ExplainInterval)
+	}
+
+	return &DB{
+		DB:           db,
+		driver:       driver,
+		retry:        retry,
+		log:          cfg.Logger,
+		connector:    connector,
+		weather:      cfg.WeatherCacheTTL,
+		timeouts:     cfg.QueryTimeouts,
+		queryMetrics: cfg.QueryMetrics,
+		slow:         cfg.SlowQuery,
+		explainer:    explain,
+	}, nil
+}
+
+// ServerVersion returns the version reported by the database server
+func (db *DB) ServerVersion(ctx context.Context) (string, error) {
+	query := "SHOW server_version"
+	if db.driver == DriverSQLite {
+		query = "SELECT sqlite_version()"
+	}
+
+	var version string
+	if err := db.QueryRowContext(ctx, query).Scan(&version); err != nil {
+		return "", fmt.Errorf("failed to query server version: %w", err)
+	}
+	return version, nil
 }
 
 // Close closes the database connection
@@ -86,43 +242,67 @@ func (db *DB) Close() error {
 	return db.DB.Close()
 }
 
-// User represents a user record
+// User represents a user record. CreatedBy and UpdatedBy are the actors
+// that wrote it, empty for anonymous writes; see WithActor. Version counts
+// the updates, for UpdateUser's optimistic locking.
 type User struct {
 	ID        int       `json:"id"`
 	Username  string    `json:"username"`
 	Email     string    `json:"email"`
+	Version   int       `json:"version"`
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
+	CreatedBy string    `json:"created_by,omitempty"`
+	UpdatedBy string    `json:"updated_by,omitempty"`
 }
 
-// GetUsers retrieves all users (traced query)
-func (db *DB) GetUsers(ctx context.Context) ([]User, error) {
-	query := `SELECT id, username, email, created_at, updated_at FROM users ORDER BY id`
+// userColumns are the columns scanned by scanUser
+const userColumns = `id, username, email, version, created_at, updated_at, COALESCE(created_by, ''), COALESCE(updated_by, '')`
 
-	rows, err := db.QueryContext(ctx, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to query users: %w", err)
-	}
-	defer rows.Close()
+func scanUser(row interface{ Scan(...interface{}) error }, u *User) error {
+	return row.Scan(&u.ID, &u.Username, &u.Email, &u.Version, &u.CreatedAt, &u.UpdatedAt, &u.CreatedBy, &u.UpdatedBy)
+}
+
+// GetUsers retrieves all users that are not deleted (traced query)
+func (db *DB) GetUsers(ctx context.Context) ([]User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE deleted_at IS NULL ORDER BY id`
 
 	var users []User
-	for rows.Next() {
-		var u User
-		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.CreatedAt, &u.UpdatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan user: %w", err)
+	err := db.withRetry(ctx, "get_users", func(ctx context.Context) error {
+		users = nil
+
+		rows, err := db.QueryContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to query users: %w", err)
 		}
-		users = append(users, u)
+		defer rows.Close()
+
+		for rows.Next() {
+			var u User
+			if err := scanUser(rows, &u); err != nil {
+				return fmt.Errorf("failed to scan user: %w", err)
+			}
+			users = append(users, u)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return users, rows.Err()
+	return users, nil
 }
 
-// GetUserByUsername retrieves a user by username (traced query)
+// GetUserByUsername retrieves a user by username, or nil when there is none
+// or it was deleted (traced query)
 func (db *DB) GetUserByUsername(ctx context.Context, username string) (*User, error) {
-	query := `SELECT id, username, email, created_at, updated_at FROM users WHERE username = $1`
+	query := `SELECT ` + userColumns + ` FROM users WHERE username = $1 AND deleted_at IS NULL`
 
 	var u User
-	err := db.QueryRowContext(ctx, query, username).Scan(&u.ID, &u.Username, &u.Email, &u.CreatedAt, &u.UpdatedAt)
+	err := db.withRetry(ctx, "get_user_by_username", func(ctx context.Context) error {
+		return scanUser(db.QueryRowContext(ctx, query, username), &u)
+	})
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -133,42 +313,123 @@ func (db *DB) GetUserByUsername(ctx context.Context, username string) (*User, er
 	return &u, nil
 }
 
-// Quote represents a quote record
+// Quote represents a quote record. CreatedBy is the actor whose request
+// fetched it, empty when anonymous.
 type Quote struct {
 	ID        int       `json:"id"`
 	Content   string    `json:"content"`
 	Author    string    `json:"author"`
 	FetchedAt time.Time `json:"fetched_at"`
 	Source    string    `json:"source"`
+	CreatedBy string    `json:"created_by,omitempty"`
 }
 
-// SaveQuote stores a quote in the database (traced query)
-func (db *DB) SaveQuote(ctx context.Context, content, author string) error {
-	query := `INSERT INTO quotes (content, author) VALUES ($1, $2)`
-	_, err := db.ExecContext(ctx, query, content, author)
-	return err
+// QuoteStats counts the stored quotes of one author from one source
+type QuoteStats struct {
+	Author string `json:"author"`
+	Source string `json:"source"`
+	Count  int64  `json:"count"`
 }
 
-// GetQuotes retrieves recent quotes (traced query)
-func (db *DB) GetQuotes(ctx context.Context, limit int) ([]Quote, error) {
-	query := `SELECT id, content, author, fetched_at, source FROM quotes ORDER BY fetched_at DESC LIMIT $1`
+// SaveQuote stores a quote unless one with the same content and author is
+// already stored, and reports whether it was new (traced query). The NOT
+// EXISTS check keeps databases created before the unique index free of new
+// duplicates; ON CONFLICT covers two requests saving the same quote at once.
+// Since saving twice is a no-op, the insert is safe to retry. A deleted
+// quote still counts as stored, so it is not saved again.
+func (db *DB) SaveQuote(ctx context.Context, content, author, source string) (bool, error) {
+	query := `
+		INSERT INTO quotes (content, author, source, created_by, updated_by)
+		SELECT $1, $2, $3, $4, $4
+		WHERE NOT EXISTS (SELECT 1 FROM quotes WHERE content = $1 AND author = $2)
+		ON CONFLICT DO NOTHING
+	`
+	var inserted bool
+	err := db.withRetry(ctx, "save_quote", func(ctx context.Context) error {
+		res, err := db.ExecContext(ctx, query, content, author, source, stampedBy(ctx))
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		inserted = n > 0
+		return err
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to save quote: %w", err)
+	}
+	return inserted, nil
+}
 
-	rows, err := db.QueryContext(ctx, query, limit)
+// GetQuoteStats counts stored quotes per author and source, most quoted
+// first (traced query)
+func (db *DB) GetQuoteStats(ctx context.Context) ([]QuoteStats, error) {
+	query := `
+		SELECT COALESCE(author, ''), COALESCE(source, ''), COUNT(*)
+		FROM quotes
+		WHERE deleted_at IS NULL
+		GROUP BY author, source
+		ORDER BY COUNT(*) DESC, author
+	`
+
+	var stats []QuoteStats
+	err := db.withRetry(ctx, "get_quote_stats", func(ctx context.Context) error {
+		stats = nil
+
+		rows, err := db.QueryContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to query quote stats: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var st QuoteStats
+			if err := rows.Scan(&st.Author, &st.Source, &st.Count); err != nil {
+				return fmt.Errorf("failed to scan quote stats: %w", err)
+			}
+			stats = append(stats, st)
+		}
+
+		return rows.Err()
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to query quotes: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
+
+	return stats, nil
+}
+
+// GetQuotes retrieves recent quotes that are not deleted (traced query)
+func (db *DB) GetQuotes(ctx context.Context, limit int) ([]Quote, error) {
+	query := `
+		SELECT id, content, author, fetched_at, source, COALESCE(created_by, '')
+		FROM quotes WHERE deleted_at IS NULL ORDER BY fetched_at DESC LIMIT $1
+	`
 
 	var quotes []Quote
-	for rows.Next() {
-		var q Quote
-		if err := rows.Scan(&q.ID, &q.Content, &q.Author, &q.FetchedAt, &q.Source); err != nil {
-			return nil, fmt.Errorf("failed to scan quote: %w", err)
+	err := db.withRetry(ctx, "get_quotes", func(ctx context.Context) error {
+		quotes = nil
+
+		rows, err := db.QueryContext(ctx, query, limit)
+		if err != nil {
+			return fmt.Errorf("failed to query quotes: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var q Quote
+			if err := rows.Scan(&q.ID, &q.Content, &q.Author, &q.FetchedAt, &q.Source, &q.CreatedBy); err != nil {
+				return fmt.Errorf("failed to scan quote: %w", err)
+			}
+			quotes = append(quotes, q)
 		}
-		quotes = append(quotes, q)
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return quotes, rows.Err()
+	return quotes, nil
 }
 
 // WeatherCache represents cached weather data
@@ -180,24 +441,29 @@ type WeatherCache struct {
 	ExpiresAt time.Time `json:"expires_at"`
 }
 
-// SaveWeatherCache caches weather data (traced query)
+// SaveWeatherCache caches weather data for the TTL of the location's class
+// (traced query)
 func (db *DB) SaveWeatherCache(ctx context.Context, location string, data []byte) error {
 	query := `
 		INSERT INTO weather_cache (location, data, expires_at)
 		VALUES ($1, $2, $3)
 		ON CONFLICT (location) DO UPDATE SET data = $2, cached_at = CURRENT_TIMESTAMP, expires_at = $3
 	`
-	expiresAt := time.Now().Add(30 * time.Minute)
-	_, err := db.ExecContext(ctx, query, location, data, expiresAt)
-	return err
+	expiresAt := time.Now().UTC().Add(db.weather.For(location))
+	return db.withRetry(ctx, "save_weather_cache", func(ctx context.Context) error {
+		_, err := db.ExecContext(ctx, query, location, data, expiresAt)
+		return err
+	})
 }
 
 // GetWeatherCache retrieves cached weather data if not expired
 func (db *DB) GetWeatherCache(ctx context.Context, location string) (*WeatherCache, error) {
-	query := `SELECT id, location, data, cached_at, expires_at FROM weather_cache WHERE location = $1 AND expires_at > NOW()`
+	query := `SELECT id, location, data, cached_at, expires_at FROM weather_cache WHERE location = $1 AND expires_at > CURRENT_TIMESTAMP`
 
 	var wc WeatherCache
-	err := db.QueryRowContext(ctx, query, location).Scan(&wc.ID, &wc.Location, &wc.Data, &wc.CachedAt, &wc.ExpiresAt)
+	err := db.withRetry(ctx, "get_weather_cache", func(ctx context.Context) error {
+		return db.QueryRowContext(ctx, query, location).Scan(&wc.ID, &wc.Location, &wc.Data, &wc.CachedAt, &wc.ExpiresAt)
+	})
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -227,8 +493,10 @@ func (db *DB) LogRequest(ctx context.Context, traceID, spanID, requestID, endpoi
 		INSERT INTO request_logs (trace_id, span_id, request_id, endpoint, method, status_code, duration_ms)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
 	`
-	_, err := db.ExecContext(ctx, query, traceID, spanID, requestID, endpoint, method, statusCode, durationMs)
-	return err
+	return db.withTimeout(ctx, "log_request", func(ctx context.Context) error {
+		_, err := db.ExecContext(ctx, query, traceID, spanID, requestID, endpoint, method, statusCode, durationMs)
+		return err
+	})
 }
 
 // GetRequestLogs retrieves recent request logs (traced query)
@@ -236,20 +504,138 @@ func (db *DB) GetRequestLogs(ctx context.Context, limit int) ([]RequestLog, erro
 	query := `SELECT id, trace_id, span_id, request_id, endpoint, method, status_code, duration_ms, created_at
 		FROM request_logs ORDER BY created_at DESC LIMIT $1`
 
-	rows, err := db.QueryContext(ctx, query, limit)
+	var logs []RequestLog
+	err := db.withRetry(ctx, "get_request_logs", func(ctx context.Context) error {
+		logs = nil
+
+		rows, err := db.QueryContext(ctx, query, limit)
+		if err != nil {
+			return fmt.Errorf("failed to query request logs: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var rl RequestLog
+			if err := rows.Scan(&rl.ID, &rl.TraceID, &rl.SpanID, &rl.RequestID, &rl.Endpoint, &rl.Method, &rl.StatusCode, &rl.DurationMs, &rl.CreatedAt); err != nil {
+				return fmt.Errorf("failed to scan request log: %w", err)
+			}
+			logs = append(logs, rl)
+		}
+
+		return rows.Err()
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to query request logs: %w", err)
+		return nil, err
 	}
-	defer rows.Close()
 
-	var logs []RequestLog
-	for rows.Next() {
-		var rl RequestLog
-		if err := rows.Scan(&rl.ID, &rl.TraceID, &rl.SpanID, &rl.RequestID, &rl.Endpoint, &rl.Method, &rl.StatusCode, &rl.DurationMs, &rl.CreatedAt); err != nil {
-			return nil, fmt.Errorf("failed to scan request log: %w", err)
+	return logs, nil
+}
+
+// AuditEntry records one call to an admin endpoint
+type AuditEntry struct {
+	ID         int       `json:"id"`
+	Actor      string    `json:"actor"`
+	Action     string    `json:"action"`
+	Resource   string    `json:"resource"`
+	RemoteAddr string    `json:"remote_addr"`
+	UserAgent  string    `json:"user_agent"`
+	StatusCode int       `json:"status_code"`
+	TraceID    string    `json:"trace_id"`
+	RequestID  string    `json:"request_id"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// SaveAuditEntry stores an admin audit record (traced query). Like other
+// inserts it is not retried, to avoid duplicate records.
+func (db *DB) SaveAuditEntry(ctx context.Context, e AuditEntry) error {
+	query := `
+		INSERT INTO admin_audit_log (actor, action, resource, remote_addr, user_agent, status_code, trace_id, request_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	`
+	return db.withTimeout(ctx, "save_audit_entry", func(ctx context.Context) error {
+		_, err := db.ExecContext(ctx, query, e.Actor, e.Action, e.Resource, e.RemoteAddr, e.UserAgent, e.StatusCode, e.TraceID, e.RequestID)
+		return err
+	})
+}
+
+// GetAuditEntries retrieves recent admin audit records (traced query)
+func (db *DB) GetAuditEntries(ctx context.Context, limit int) ([]AuditEntry, error) {
+	query := `SELECT id, actor, action, resource, remote_addr, user_agent, status_code, trace_id, request_id, created_at
+		FROM admin_audit_log ORDER BY created_at DESC LIMIT $1`
+
+	var entries []AuditEntry
+	err := db.withRetry(ctx, "get_audit_entries", func(ctx context.Context) error {
+		entries = nil
+
+		rows, err := db.QueryContext(ctx, query, limit)
+		if err != nil {
+			return fmt.Errorf("failed to query audit log: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var e AuditEntry
+			if err := rows.Scan(&e.ID, &e.Actor, &e.Action, &e.Resource, &e.RemoteAddr, &e.UserAgent, &e.StatusCode, &e.TraceID, &e.RequestID, &e.CreatedAt); err != nil {
+				return fmt.Errorf("failed to scan audit entry: %w", err)
+			}
+			entries = append(entries, e)
 		}
-		logs = append(logs, rl)
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// Deployment is a version of the service that has run against this database
+type Deployment struct {
+	Version    string    `json:"version"`
+	Commit     string    `json:"commit"`
+	DeployedAt time.Time `json:"deployed_at"`
+}
+
+// RecordDeployment marks version/commit as the current deployment. It
+// returns the previous deployment and true only for the one caller that
+// made the transition, so replicas of one rollout report it once. Rolling
+// back to an earlier version counts as a new transition.
+func (db *DB) RecordDeployment(ctx context.Context, version, commit string) (*Deployment, bool, error) {
+	query := `SELECT version, commit_sha, deployed_at FROM deployments ORDER BY deployed_at DESC LIMIT 1`
+
+	var prev Deployment
+	err := db.withRetry(ctx, "get_last_deployment", func(ctx context.Context) error {
+		return db.QueryRowContext(ctx, query).Scan(&prev.Version, &prev.Commit, &prev.DeployedAt)
+	})
+	switch {
+	case err == sql.ErrNoRows:
+	case err != nil:
+		return nil, false, fmt.Errorf("failed to query deployments: %w", err)
+	case prev.Version == version && prev.Commit == commit:
+		return &prev, false, nil
 	}
 
-	return logs, rows.Err()
+	// The conditional update makes an existing row current only if another
+	// replica has not already done so
+	var n int64
+	err = db.withTimeout(ctx, "record_deployment", func(ctx context.Context) error {
+		res, err := db.ExecContext(ctx, `
+			INSERT INTO deployments (version, commit_sha) VALUES ($1, $2)
+			ON CONFLICT (version, commit_sha) DO UPDATE SET deployed_at = CURRENT_TIMESTAMP
+			WHERE deployments.deployed_at < (SELECT MAX(deployed_at) FROM deployments)
+		`, version, commit)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to record deployment: %w", err)
+	}
+	if prev.Version == "" {
+		return nil, n > 0, nil
+	}
+	return &prev, n > 0, nil
 }
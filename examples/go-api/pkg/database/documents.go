@@ -0,0 +1,167 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrNotConfigured is returned by repositories with no backing store, e.g.
+// users while only a document store is configured
+var ErrNotConfigured = errors.New("repository not configured")
+
+// DocumentStore holds quotes and cached weather outside the SQL database,
+// e.g. in MongoDB
+type DocumentStore interface {
+	QuoteStore
+	WeatherCacheStore
+	PingContext(ctx context.Context) error
+	Close() error
+}
+
+// WithDocumentStore returns a Store that reads and writes quotes and cached
+// weather through docs and everything else through base. base may be nil,
+// in which case the other repositories return ErrNotConfigured.
+func WithDocumentStore(base Store, docs DocumentStore) Store {
+	return &documentStore{Store: base, docs: docs}
+}
+
+type documentStore struct {
+	Store // nil when only the document store is configured
+	docs  DocumentStore
+}
+
+func (s *documentStore) SaveQuote(ctx context.Context, content, author, source string) (bool, error) {
+	return s.docs.SaveQuote(ctx, content, author, source)
+}
+
+func (s *documentStore) DeleteQuote(ctx context.Context, id int) (bool, error) {
+	return s.docs.DeleteQuote(ctx, id)
+}
+
+func (s *documentStore) GetQuotes(ctx context.Context, limit int) ([]Quote, error) {
+	return s.docs.GetQuotes(ctx, limit)
+}
+
+func (s *documentStore) GetQuoteStats(ctx context.Context) ([]QuoteStats, error) {
+	return s.docs.GetQuoteStats(ctx)
+}
+
+func (s *documentStore) SearchQuotes(ctx context.Context, query string, limit int) ([]QuoteMatch, error) {
+	return s.docs.SearchQuotes(ctx, query, limit)
+}
+
+func (s *documentStore) SaveWeatherCache(ctx context.Context, location string, data []byte) error {
+	return s.docs.SaveWeatherCache(ctx, location, data)
+}
+
+func (s *documentStore) GetWeatherCache(ctx context.Context, location string) (*WeatherCache, error) {
+	return s.docs.GetWeatherCache(ctx, location)
+}
+
+func (s *documentStore) GetUsers(ctx context.Context) ([]User, error) {
+	if s.Store == nil {
+		return nil, ErrNotConfigured
+	}
+	return s.Store.GetUsers(ctx)
+}
+
+func (s *documentStore) GetUserByUsername(ctx context.Context, username string) (*User, error) {
+	if s.Store == nil {
+		return nil, ErrNotConfigured
+	}
+	return s.Store.GetUserByUsername(ctx, username)
+}
+
+func (s *documentStore) InsertUsers(ctx context.Context, users []User) ([]bool, error) {
+	if s.Store == nil {
+		return nil, ErrNotConfigured
+	}
+	return s.Store.InsertUsers(ctx, users)
+}
+
+func (s *documentStore) UpdateUser(ctx context.Context, u User) (*User, error) {
+	if s.Store == nil {
+		return nil, ErrNotConfigured
+	}
+	return s.Store.UpdateUser(ctx, u)
+}
+
+func (s *documentStore) DeleteUser(ctx context.Context, username string) (bool, error) {
+	if s.Store == nil {
+		return false, ErrNotConfigured
+	}
+	return s.Store.DeleteUser(ctx, username)
+}
+
+func (s *documentStore) CreateUserImport(ctx context.Context, total int, traceID string) (*UserImport, error) {
+	if s.Store == nil {
+		return nil, ErrNotConfigured
+	}
+	return s.Store.CreateUserImport(ctx, total, traceID)
+}
+
+func (s *documentStore) UpdateUserImport(ctx context.Context, imp *UserImport) error {
+	if s.Store == nil {
+		return ErrNotConfigured
+	}
+	return s.Store.UpdateUserImport(ctx, imp)
+}
+
+func (s *documentStore) GetUserImport(ctx context.Context, id int) (*UserImport, error) {
+	if s.Store == nil {
+		return nil, ErrNotConfigured
+	}
+	return s.Store.GetUserImport(ctx, id)
+}
+
+func (s *documentStore) LogRequest(ctx context.Context, traceID, spanID, requestID, endpoint, method string, statusCode int, durationMs int64) error {
+	if s.Store == nil {
+		return ErrNotConfigured
+	}
+	return s.Store.LogRequest(ctx, traceID, spanID, requestID, endpoint, method, statusCode, durationMs)
+}
+
+func (s *documentStore) GetRequestLogs(ctx context.Context, limit int) ([]RequestLog, error) {
+	if s.Store == nil {
+		return nil, ErrNotConfigured
+	}
+	return s.Store.GetRequestLogs(ctx, limit)
+}
+
+func (s *documentStore) SaveAuditEntry(ctx context.Context, e AuditEntry) error {
+	if s.Store == nil {
+		return ErrNotConfigured
+	}
+	return s.Store.SaveAuditEntry(ctx, e)
+}
+
+func (s *documentStore) GetAuditEntries(ctx context.Context, limit int) ([]AuditEntry, error) {
+	if s.Store == nil {
+		return nil, ErrNotConfigured
+	}
+	return s.Store.GetAuditEntries(ctx, limit)
+}
+
+func (s *documentStore) RecordDeployment(ctx context.Context, version, commit string) (*Deployment, bool, error) {
+	if s.Store == nil {
+		return nil, false, ErrNotConfigured
+	}
+	return s.Store.RecordDeployment(ctx, version, commit)
+}
+
+// PingContext pings both stores
+func (s *documentStore) PingContext(ctx context.Context) error {
+	if err := s.docs.PingContext(ctx); err != nil {
+		return fmt.Errorf("failed to ping document store: %w", err)
+	}
+	if s.Store == nil {
+		return nil
+	}
+	return s.Store.PingContext(ctx)
+}
+
+// Close leaves both stores open; they are owned and closed by the caller
+func (s *documentStore) Close() error {
+	return nil
+}
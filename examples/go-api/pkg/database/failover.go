@@ -0,0 +1,233 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/lib/pq"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+var failoversTotal = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "db_failovers_total",
+		Help: "Total number of times the database primary changed",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(failoversTotal)
+}
+
+// pqConn is the set of driver interfaces implemented by lib/pq connections
+// that must survive wrapping so database/sql and otelsql keep the fast paths
+type pqConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+// failoverConnector dials a list of hosts in order and only accepts a
+// writable primary. Host names are resolved on every dial, so DNS changes
+// (e.g. a Patroni-managed service record) are picked up by new connections.
+type failoverConnector struct {
+	hosts    []string // host:port pairs
+	buildDSN func(host, port string) string
+	log      *logger.Logger
+
+	mu         sync.RWMutex
+	current    string // host:port of the last accepted primary
+	generation uint64 // bumped whenever pooled connections become stale
+}
+
+func newFailoverConnector(hosts []string, buildDSN func(host, port string) string, log *logger.Logger) *failoverConnector {
+	return &failoverConnector{
+		hosts:    hosts,
+		buildDSN: buildDSN,
+		log:      log,
+	}
+}
+
+// Connect implements driver.Connector
+func (c *failoverConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	var lastErr error
+	for _, addr := range c.dialOrder() {
+		host, port, err := net.SplitHostPort(addr)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+
+		connector, err := pq.NewConnector(c.buildDSN(host, port))
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := connector.Connect(ctx)
+		if err != nil {
+			lastErr = fmt.Errorf("%s: %w", addr, err)
+			continue
+		}
+
+		pc, ok := conn.(pqConn)
+		if !ok {
+			conn.Close()
+			return nil, fmt.Errorf("unexpected driver connection type %T", conn)
+		}
+
+		// A single host is trusted as-is; with several candidates only the
+		// writable primary is accepted
+		if len(c.hosts) > 1 {
+			primary, err := isPrimary(ctx, pc)
+			if err != nil || !primary {
+				pc.Close()
+				if err == nil {
+					err = errors.New("server is in recovery")
+				}
+				lastErr = fmt.Errorf("%s: %w", addr, err)
+				continue
+			}
+		}
+
+		return &failoverConn{pqConn: pc, generation: c.setCurrent(addr), connector: c}, nil
+	}
+
+	if lastErr == nil {
+		lastErr = errors.New("no database hosts configured")
+	}
+	return nil, fmt.Errorf("no reachable primary: %w", lastErr)
+}
+
+// Driver implements driver.Connector
+func (c *failoverConnector) Driver() driver.Driver {
+	return &pq.Driver{}
+}
+
+// dialOrder tries the last known primary first, then the remaining hosts in
+// their configured order
+func (c *failoverConnector) dialOrder() []string {
+	current := c.currentHost()
+	if current == "" {
+		return c.hosts
+	}
+
+	order := make([]string, 0, len(c.hosts))
+	order = append(order, current)
+	for _, h := range c.hosts {
+		if h != current {
+			order = append(order, h)
+		}
+	}
+	return order
+}
+
+func (c *failoverConnector) currentHost() string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.current
+}
+
+func (c *failoverConnector) currentGeneration() uint64 {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.generation
+}
+
+// setCurrent records addr as the primary and returns the pool generation
+// that connections opened against it belong to
+func (c *failoverConnector) setCurrent(addr string) uint64 {
+	c.mu.Lock()
+	previous := c.current
+	c.current = addr
+	if previous != "" && previous != addr {
+		c.generation++
+	}
+	generation := c.generation
+	c.mu.Unlock()
+
+	if previous == "" || previous == addr {
+		return generation
+	}
+
+	failoversTotal.Inc()
+	failoverLog := logger.OrDefault(c.log).WithFields(context.Background(), map[string]interface{}{
+		"previous_primary": previous,
+		"new_primary":      addr,
+	})
+	failoverLog.Warn().Msg("Database failover detected")
+	return generation
+}
+
+// invalidate marks every pooled connection as stale so the next query dials
+// again and re-checks which host is the writable primary
+func (c *failoverConnector) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+}
+
+// failoverConn ties a pooled connection to the primary it was opened against
+type failoverConn struct {
+	pqConn
+	generation uint64
+	connector  *failoverConnector
+}
+
+// IsValid reports false once the connector has moved to another primary,
+// which makes database/sql drop the connection instead of reusing it
+func (fc *failoverConn) IsValid() bool {
+	return fc.pqConn.IsValid() && fc.generation == fc.connector.currentGeneration()
+}
+
+func isPrimary(ctx context.Context, conn driver.QueryerContext) (bool, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT pg_is_in_recovery()", nil)
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	dest := make([]driver.Value, 1)
+	if err := rows.Next(dest); err != nil && err != io.EOF {
+		return false, err
+	}
+	inRecovery, _ := dest[0].(bool)
+	return !inRecovery, nil
+}
+
+// parseHosts splits a comma-separated host list ("pg-0,pg-1:5433") into
+// host:port pairs, applying defaultPort where none is given
+func parseHosts(hosts string, defaultPort int) []string {
+	var out []string
+	for _, h := range strings.Split(hosts, ",") {
+		h = strings.TrimSpace(h)
+		if h == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(h); err != nil {
+			h = net.JoinHostPort(h, strconv.Itoa(defaultPort))
+		}
+		out = append(out, h)
+	}
+	return out
+}
+
+// isReadOnlyError reports whether err signals that the connected server no
+// longer accepts writes, i.e. it was demoted during a switchover
+func isReadOnlyError(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "25006"
+}
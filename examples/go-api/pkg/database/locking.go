@@ -0,0 +1,57 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+)
+
+// ErrVersionConflict is wrapped by the errors of updates based on a version
+// of a row that has since changed
+var ErrVersionConflict = errors.New("version conflict")
+
+// UserConflictError is returned by UpdateUser when the user was changed
+// after the version the update was based on was read
+type UserConflictError struct {
+	Expected int  // Version the update was based on
+	Current  User // The user as stored now
+}
+
+func (e *UserConflictError) Error() string {
+	return fmt.Sprintf("user %s: update based on version %d, stored version is %d", e.Current.Username, e.Expected, e.Current.Version)
+}
+
+func (e *UserConflictError) Unwrap() error { return ErrVersionConflict }
+
+// UpdateUser sets the email of the user with u.Username, provided it is
+// still at u.Version, and returns the updated user, or nil when there is
+// none. The version is incremented on every update, so of two requests that
+// read the same version only the first succeeds; the second gets a
+// *UserConflictError with the user as stored. A retried update could
+// conflict with its own first attempt, so it is not retried.
+func (db *DB) UpdateUser(ctx context.Context, u User) (*User, error) {
+	query := `
+		UPDATE users
+		SET email = $2, version = version + 1, updated_at = CURRENT_TIMESTAMP, updated_by = $4
+		WHERE username = $1 AND version = $3 AND deleted_at IS NULL
+		RETURNING ` + userColumns
+
+	var updated User
+	err := db.withTimeout(ctx, "update_user", func(ctx context.Context) error {
+		return scanUser(db.QueryRowContext(ctx, query, u.Username, u.Email, u.Version, stampedBy(ctx)), &updated)
+	})
+	if err == nil {
+		return &updated, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	// No row matched: the user is gone or at another version
+	current, err := db.GetUserByUsername(ctx, u.Username)
+	if err != nil || current == nil {
+		return nil, err
+	}
+	return nil, &UserConflictError{Expected: u.Version, Current: *current}
+}
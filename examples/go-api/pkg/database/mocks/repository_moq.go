@@ -0,0 +1,2388 @@
+// Code generated by moq; DO NOT EDIT.
+// github.com/matryer/moq
+
+package mocks
+
+import (
+	"context"
+	"sync"
+
+	"github.com/example/go-api/pkg/database"
+)
+
+// Ensure, that UserStoreMock does implement database.UserStore.
+// If this is not the case, regenerate this file with moq.
+var _ database.UserStore = &UserStoreMock{}
+
+// UserStoreMock is a mock implementation of database.UserStore.
+//
+//	func TestSomethingThatUsesUserStore(t *testing.T) {
+//
+//		// make and configure a mocked database.UserStore
+//		mockedUserStore := &UserStoreMock{
+//			DeleteUserFunc: func(ctx context.Context, username string) (bool, error) {
+//				panic("mock out the DeleteUser method")
+//			},
+//			GetUserByUsernameFunc: func(ctx context.Context, username string) (*database.User, error) {
+//				panic("mock out the GetUserByUsername method")
+//			},
+//			GetUsersFunc: func(ctx context.Context) ([]database.User, error) {
+//				panic("mock out the GetUsers method")
+//			},
+//			InsertUsersFunc: func(ctx context.Context, users []database.User) ([]bool, error) {
+//				panic("mock out the InsertUsers method")
+//			},
+//			UpdateUserFunc: func(ctx context.Context, u database.User) (*database.User, error) {
+//				panic("mock out the UpdateUser method")
+//			},
+//		}
+//
+//		// use mockedUserStore in code that requires database.UserStore
+//		// and then make assertions.
+//
+//	}
+type UserStoreMock struct {
+	// DeleteUserFunc mocks the DeleteUser method.
+	DeleteUserFunc func(ctx context.Context, username string) (bool, error)
+
+	// GetUserByUsernameFunc mocks the GetUserByUsername method.
+	GetUserByUsernameFunc func(ctx context.Context, username string) (*database.User, error)
+
+	// GetUsersFunc mocks the GetUsers method.
+	GetUsersFunc func(ctx context.Context) ([]database.User, error)
+
+	// InsertUsersFunc mocks the InsertUsers method.
+	InsertUsersFunc func(ctx context.Context, users []database.User) ([]bool, error)
+
+	// UpdateUserFunc mocks the UpdateUser method.
+	UpdateUserFunc func(ctx context.Context, u database.User) (*database.User, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// DeleteUser holds details about calls to the DeleteUser method.
+		DeleteUser []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Username is the username argument value.
+			Username string
+		}
+		// GetUserByUsername holds details about calls to the GetUserByUsername method.
+		GetUserByUsername []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Username is the username argument value.
+			Username string
+		}
+		// GetUsers holds details about calls to the GetUsers method.
+		GetUsers []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// InsertUsers holds details about calls to the InsertUsers method.
+		InsertUsers []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Users is the users argument value.
+			Users []database.User
+		}
+		// UpdateUser holds details about calls to the UpdateUser method.
+		UpdateUser []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// U is the u argument value.
+			U database.User
+		}
+	}
+	lockDeleteUser        sync.RWMutex
+	lockGetUserByUsername sync.RWMutex
+	lockGetUsers          sync.RWMutex
+	lockInsertUsers       sync.RWMutex
+	lockUpdateUser        sync.RWMutex
+}
+
+// DeleteUser calls DeleteUserFunc.
+func (mock *UserStoreMock) DeleteUser(ctx context.Context, username string) (bool, error) {
+	if mock.DeleteUserFunc == nil {
+		panic("UserStoreMock.DeleteUserFunc: method is nil but UserStore.DeleteUser was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Username string
+	}{
+		Ctx:      ctx,
+		Username: username,
+	}
+	mock.lockDeleteUser.Lock()
+	mock.calls.DeleteUser = append(mock.calls.DeleteUser, callInfo)
+	mock.lockDeleteUser.Unlock()
+	return mock.DeleteUserFunc(ctx, username)
+}
+
+// DeleteUserCalls gets all the calls that were made to DeleteUser.
+// Check the length with:
+//
+//	len(mockedUserStore.DeleteUserCalls())
+func (mock *UserStoreMock) DeleteUserCalls() []struct {
+	Ctx      context.Context
+	Username string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Username string
+	}
+	mock.lockDeleteUser.RLock()
+	calls = mock.calls.DeleteUser
+	mock.lockDeleteUser.RUnlock()
+	return calls
+}
+
+// GetUserByUsername calls GetUserByUsernameFunc.
+func (mock *UserStoreMock) GetUserByUsername(ctx context.Context, username string) (*database.User, error) {
+	if mock.GetUserByUsernameFunc == nil {
+		panic("UserStoreMock.GetUserByUsernameFunc: method is nil but UserStore.GetUserByUsername was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Username string
+	}{
+		Ctx:      ctx,
+		Username: username,
+	}
+	mock.lockGetUserByUsername.Lock()
+	mock.calls.GetUserByUsername = append(mock.calls.GetUserByUsername, callInfo)
+	mock.lockGetUserByUsername.Unlock()
+	return mock.GetUserByUsernameFunc(ctx, username)
+}
+
+// GetUserByUsernameCalls gets all the calls that were made to GetUserByUsername.
+// Check the length with:
+//
+//	len(mockedUserStore.GetUserByUsernameCalls())
+func (mock *UserStoreMock) GetUserByUsernameCalls() []struct {
+	Ctx      context.Context
+	Username string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Username string
+	}
+	mock.lockGetUserByUsername.RLock()
+	calls = mock.calls.GetUserByUsername
+	mock.lockGetUserByUsername.RUnlock()
+	return calls
+}
+
+// GetUsers calls GetUsersFunc.
+func (mock *UserStoreMock) GetUsers(ctx context.Context) ([]database.User, error) {
+	if mock.GetUsersFunc == nil {
+		panic("UserStoreMock.GetUsersFunc: method is nil but UserStore.GetUsers was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetUsers.Lock()
+	mock.calls.GetUsers = append(mock.calls.GetUsers, callInfo)
+	mock.lockGetUsers.Unlock()
+	return mock.GetUsersFunc(ctx)
+}
+
+// GetUsersCalls gets all the calls that were made to GetUsers.
+// Check the length with:
+//
+//	len(mockedUserStore.GetUsersCalls())
+func (mock *UserStoreMock) GetUsersCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetUsers.RLock()
+	calls = mock.calls.GetUsers
+	mock.lockGetUsers.RUnlock()
+	return calls
+}
+
+// InsertUsers calls InsertUsersFunc.
+func (mock *UserStoreMock) InsertUsers(ctx context.Context, users []database.User) ([]bool, error) {
+	if mock.InsertUsersFunc == nil {
+		panic("UserStoreMock.InsertUsersFunc: method is nil but UserStore.InsertUsers was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Users []database.User
+	}{
+		Ctx:   ctx,
+		Users: users,
+	}
+	mock.lockInsertUsers.Lock()
+	mock.calls.InsertUsers = append(mock.calls.InsertUsers, callInfo)
+	mock.lockInsertUsers.Unlock()
+	return mock.InsertUsersFunc(ctx, users)
+}
+
+// InsertUsersCalls gets all the calls that were made to InsertUsers.
+// Check the length with:
+//
+//	len(mockedUserStore.InsertUsersCalls())
+func (mock *UserStoreMock) InsertUsersCalls() []struct {
+	Ctx   context.Context
+	Users []database.User
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Users []database.User
+	}
+	mock.lockInsertUsers.RLock()
+	calls = mock.calls.InsertUsers
+	mock.lockInsertUsers.RUnlock()
+	return calls
+}
+
+// UpdateUser calls UpdateUserFunc.
+func (mock *UserStoreMock) UpdateUser(ctx context.Context, u database.User) (*database.User, error) {
+	if mock.UpdateUserFunc == nil {
+		panic("UserStoreMock.UpdateUserFunc: method is nil but UserStore.UpdateUser was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		U   database.User
+	}{
+		Ctx: ctx,
+		U:   u,
+	}
+	mock.lockUpdateUser.Lock()
+	mock.calls.UpdateUser = append(mock.calls.UpdateUser, callInfo)
+	mock.lockUpdateUser.Unlock()
+	return mock.UpdateUserFunc(ctx, u)
+}
+
+// UpdateUserCalls gets all the calls that were made to UpdateUser.
+// Check the length with:
+//
+//	len(mockedUserStore.UpdateUserCalls())
+func (mock *UserStoreMock) UpdateUserCalls() []struct {
+	Ctx context.Context
+	U   database.User
+} {
+	var calls []struct {
+		Ctx context.Context
+		U   database.User
+	}
+	mock.lockUpdateUser.RLock()
+	calls = mock.calls.UpdateUser
+	mock.lockUpdateUser.RUnlock()
+	return calls
+}
+
+// Ensure, that UserImportStoreMock does implement database.UserImportStore.
+// If this is not the case, regenerate this file with moq.
+var _ database.UserImportStore = &UserImportStoreMock{}
+
+// UserImportStoreMock is a mock implementation of database.UserImportStore.
+//
+//	func TestSomethingThatUsesUserImportStore(t *testing.T) {
+//
+//		// make and configure a mocked database.UserImportStore
+//		mockedUserImportStore := &UserImportStoreMock{
+//			CreateUserImportFunc: func(ctx context.Context, total int, traceID string) (*database.UserImport, error) {
+//				panic("mock out the CreateUserImport method")
+//			},
+//			GetUserImportFunc: func(ctx context.Context, id int) (*database.UserImport, error) {
+//				panic("mock out the GetUserImport method")
+//			},
+//			UpdateUserImportFunc: func(ctx context.Context, imp *database.UserImport) error {
+//				panic("mock out the UpdateUserImport method")
+//			},
+//		}
+//
+//		// use mockedUserImportStore in code that requires database.UserImportStore
+//		// and then make assertions.
+//
+//	}
+type UserImportStoreMock struct {
+	// CreateUserImportFunc mocks the CreateUserImport method.
+	CreateUserImportFunc func(ctx context.Context, total int, traceID string) (*database.UserImport, error)
+
+	// GetUserImportFunc mocks the GetUserImport method.
+	GetUserImportFunc func(ctx context.Context, id int) (*database.UserImport, error)
+
+	// UpdateUserImportFunc mocks the UpdateUserImport method.
+	UpdateUserImportFunc func(ctx context.Context, imp *database.UserImport) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// CreateUserImport holds details about calls to the CreateUserImport method.
+		CreateUserImport []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Total is the total argument value.
+			Total int
+			// TraceID is the traceID argument value.
+			TraceID string
+		}
+		// GetUserImport holds details about calls to the GetUserImport method.
+		GetUserImport []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Id is the id argument value.
+			Id int
+		}
+		// UpdateUserImport holds details about calls to the UpdateUserImport method.
+		UpdateUserImport []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Imp is the imp argument value.
+			Imp *database.UserImport
+		}
+	}
+	lockCreateUserImport sync.RWMutex
+	lockGetUserImport    sync.RWMutex
+	lockUpdateUserImport sync.RWMutex
+}
+
+// CreateUserImport calls CreateUserImportFunc.
+func (mock *UserImportStoreMock) CreateUserImport(ctx context.Context, total int, traceID string) (*database.UserImport, error) {
+	if mock.CreateUserImportFunc == nil {
+		panic("UserImportStoreMock.CreateUserImportFunc: method is nil but UserImportStore.CreateUserImport was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Total   int
+		TraceID string
+	}{
+		Ctx:     ctx,
+		Total:   total,
+		TraceID: traceID,
+	}
+	mock.lockCreateUserImport.Lock()
+	mock.calls.CreateUserImport = append(mock.calls.CreateUserImport, callInfo)
+	mock.lockCreateUserImport.Unlock()
+	return mock.CreateUserImportFunc(ctx, total, traceID)
+}
+
+// CreateUserImportCalls gets all the calls that were made to CreateUserImport.
+// Check the length with:
+//
+//	len(mockedUserImportStore.CreateUserImportCalls())
+func (mock *UserImportStoreMock) CreateUserImportCalls() []struct {
+	Ctx     context.Context
+	Total   int
+	TraceID string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Total   int
+		TraceID string
+	}
+	mock.lockCreateUserImport.RLock()
+	calls = mock.calls.CreateUserImport
+	mock.lockCreateUserImport.RUnlock()
+	return calls
+}
+
+// GetUserImport calls GetUserImportFunc.
+func (mock *UserImportStoreMock) GetUserImport(ctx context.Context, id int) (*database.UserImport, error) {
+	if mock.GetUserImportFunc == nil {
+		panic("UserImportStoreMock.GetUserImportFunc: method is nil but UserImportStore.GetUserImport was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Id  int
+	}{
+		Ctx: ctx,
+		Id:  id,
+	}
+	mock.lockGetUserImport.Lock()
+	mock.calls.GetUserImport = append(mock.calls.GetUserImport, callInfo)
+	mock.lockGetUserImport.Unlock()
+	return mock.GetUserImportFunc(ctx, id)
+}
+
+// GetUserImportCalls gets all the calls that were made to GetUserImport.
+// Check the length with:
+//
+//	len(mockedUserImportStore.GetUserImportCalls())
+func (mock *UserImportStoreMock) GetUserImportCalls() []struct {
+	Ctx context.Context
+	Id  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		Id  int
+	}
+	mock.lockGetUserImport.RLock()
+	calls = mock.calls.GetUserImport
+	mock.lockGetUserImport.RUnlock()
+	return calls
+}
+
+// UpdateUserImport calls UpdateUserImportFunc.
+func (mock *UserImportStoreMock) UpdateUserImport(ctx context.Context, imp *database.UserImport) error {
+	if mock.UpdateUserImportFunc == nil {
+		panic("UserImportStoreMock.UpdateUserImportFunc: method is nil but UserImportStore.UpdateUserImport was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Imp *database.UserImport
+	}{
+		Ctx: ctx,
+		Imp: imp,
+	}
+	mock.lockUpdateUserImport.Lock()
+	mock.calls.UpdateUserImport = append(mock.calls.UpdateUserImport, callInfo)
+	mock.lockUpdateUserImport.Unlock()
+	return mock.UpdateUserImportFunc(ctx, imp)
+}
+
+// UpdateUserImportCalls gets all the calls that were made to UpdateUserImport.
+// Check the length with:
+//
+//	len(mockedUserImportStore.UpdateUserImportCalls())
+func (mock *UserImportStoreMock) UpdateUserImportCalls() []struct {
+	Ctx context.Context
+	Imp *database.UserImport
+} {
+	var calls []struct {
+		Ctx context.Context
+		Imp *database.UserImport
+	}
+	mock.lockUpdateUserImport.RLock()
+	calls = mock.calls.UpdateUserImport
+	mock.lockUpdateUserImport.RUnlock()
+	return calls
+}
+
+// Ensure, that QuoteStoreMock does implement database.QuoteStore.
+// If this is not the case, regenerate this file with moq.
+var _ database.QuoteStore = &QuoteStoreMock{}
+
+// QuoteStoreMock is a mock implementation of database.QuoteStore.
+//
+//	func TestSomethingThatUsesQuoteStore(t *testing.T) {
+//
+//		// make and configure a mocked database.QuoteStore
+//		mockedQuoteStore := &QuoteStoreMock{
+//			DeleteQuoteFunc: func(ctx context.Context, id int) (bool, error) {
+//				panic("mock out the DeleteQuote method")
+//			},
+//			GetQuoteStatsFunc: func(ctx context.Context) ([]database.QuoteStats, error) {
+//				panic("mock out the GetQuoteStats method")
+//			},
+//			GetQuotesFunc: func(ctx context.Context, limit int) ([]database.Quote, error) {
+//				panic("mock out the GetQuotes method")
+//			},
+//			SaveQuoteFunc: func(ctx context.Context, content string, author string, source string) (bool, error) {
+//				panic("mock out the SaveQuote method")
+//			},
+//			SearchQuotesFunc: func(ctx context.Context, query string, limit int) ([]database.QuoteMatch, error) {
+//				panic("mock out the SearchQuotes method")
+//			},
+//		}
+//
+//		// use mockedQuoteStore in code that requires database.QuoteStore
+//		// and then make assertions.
+//
+//	}
+type QuoteStoreMock struct {
+	// DeleteQuoteFunc mocks the DeleteQuote method.
+	DeleteQuoteFunc func(ctx context.Context, id int) (bool, error)
+
+	// GetQuoteStatsFunc mocks the GetQuoteStats method.
+	GetQuoteStatsFunc func(ctx context.Context) ([]database.QuoteStats, error)
+
+	// GetQuotesFunc mocks the GetQuotes method.
+	GetQuotesFunc func(ctx context.Context, limit int) ([]database.Quote, error)
+
+	// SaveQuoteFunc mocks the SaveQuote method.
+	SaveQuoteFunc func(ctx context.Context, content string, author string, source string) (bool, error)
+
+	// SearchQuotesFunc mocks the SearchQuotes method.
+	SearchQuotesFunc func(ctx context.Context, query string, limit int) ([]database.QuoteMatch, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// DeleteQuote holds details about calls to the DeleteQuote method.
+		DeleteQuote []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Id is the id argument value.
+			Id int
+		}
+		// GetQuoteStats holds details about calls to the GetQuoteStats method.
+		GetQuoteStats []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// GetQuotes holds details about calls to the GetQuotes method.
+		GetQuotes []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// SaveQuote holds details about calls to the SaveQuote method.
+		SaveQuote []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Content is the content argument value.
+			Content string
+			// Author is the author argument value.
+			Author string
+			// Source is the source argument value.
+			Source string
+		}
+		// SearchQuotes holds details about calls to the SearchQuotes method.
+		SearchQuotes []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Query is the query argument value.
+			Query string
+			// Limit is the limit argument value.
+			Limit int
+		}
+	}
+	lockDeleteQuote   sync.RWMutex
+	lockGetQuoteStats sync.RWMutex
+	lockGetQuotes     sync.RWMutex
+	lockSaveQuote     sync.RWMutex
+	lockSearchQuotes  sync.RWMutex
+}
+
+// DeleteQuote calls DeleteQuoteFunc.
+func (mock *QuoteStoreMock) DeleteQuote(ctx context.Context, id int) (bool, error) {
+	if mock.DeleteQuoteFunc == nil {
+		panic("QuoteStoreMock.DeleteQuoteFunc: method is nil but QuoteStore.DeleteQuote was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Id  int
+	}{
+		Ctx: ctx,
+		Id:  id,
+	}
+	mock.lockDeleteQuote.Lock()
+	mock.calls.DeleteQuote = append(mock.calls.DeleteQuote, callInfo)
+	mock.lockDeleteQuote.Unlock()
+	return mock.DeleteQuoteFunc(ctx, id)
+}
+
+// DeleteQuoteCalls gets all the calls that were made to DeleteQuote.
+// Check the length with:
+//
+//	len(mockedQuoteStore.DeleteQuoteCalls())
+func (mock *QuoteStoreMock) DeleteQuoteCalls() []struct {
+	Ctx context.Context
+	Id  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		Id  int
+	}
+	mock.lockDeleteQuote.RLock()
+	calls = mock.calls.DeleteQuote
+	mock.lockDeleteQuote.RUnlock()
+	return calls
+}
+
+// GetQuoteStats calls GetQuoteStatsFunc.
+func (mock *QuoteStoreMock) GetQuoteStats(ctx context.Context) ([]database.QuoteStats, error) {
+	if mock.GetQuoteStatsFunc == nil {
+		panic("QuoteStoreMock.GetQuoteStatsFunc: method is nil but QuoteStore.GetQuoteStats was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetQuoteStats.Lock()
+	mock.calls.GetQuoteStats = append(mock.calls.GetQuoteStats, callInfo)
+	mock.lockGetQuoteStats.Unlock()
+	return mock.GetQuoteStatsFunc(ctx)
+}
+
+// GetQuoteStatsCalls gets all the calls that were made to GetQuoteStats.
+// Check the length with:
+//
+//	len(mockedQuoteStore.GetQuoteStatsCalls())
+func (mock *QuoteStoreMock) GetQuoteStatsCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetQuoteStats.RLock()
+	calls = mock.calls.GetQuoteStats
+	mock.lockGetQuoteStats.RUnlock()
+	return calls
+}
+
+// GetQuotes calls GetQuotesFunc.
+func (mock *QuoteStoreMock) GetQuotes(ctx context.Context, limit int) ([]database.Quote, error) {
+	if mock.GetQuotesFunc == nil {
+		panic("QuoteStoreMock.GetQuotesFunc: method is nil but QuoteStore.GetQuotes was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Limit int
+	}{
+		Ctx:   ctx,
+		Limit: limit,
+	}
+	mock.lockGetQuotes.Lock()
+	mock.calls.GetQuotes = append(mock.calls.GetQuotes, callInfo)
+	mock.lockGetQuotes.Unlock()
+	return mock.GetQuotesFunc(ctx, limit)
+}
+
+// GetQuotesCalls gets all the calls that were made to GetQuotes.
+// Check the length with:
+//
+//	len(mockedQuoteStore.GetQuotesCalls())
+func (mock *QuoteStoreMock) GetQuotesCalls() []struct {
+	Ctx   context.Context
+	Limit int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Limit int
+	}
+	mock.lockGetQuotes.RLock()
+	calls = mock.calls.GetQuotes
+	mock.lockGetQuotes.RUnlock()
+	return calls
+}
+
+// SaveQuote calls SaveQuoteFunc.
+func (mock *QuoteStoreMock) SaveQuote(ctx context.Context, content string, author string, source string) (bool, error) {
+	if mock.SaveQuoteFunc == nil {
+		panic("QuoteStoreMock.SaveQuoteFunc: method is nil but QuoteStore.SaveQuote was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Content string
+		Author  string
+		Source  string
+	}{
+		Ctx:     ctx,
+		Content: content,
+		Author:  author,
+		Source:  source,
+	}
+	mock.lockSaveQuote.Lock()
+	mock.calls.SaveQuote = append(mock.calls.SaveQuote, callInfo)
+	mock.lockSaveQuote.Unlock()
+	return mock.SaveQuoteFunc(ctx, content, author, source)
+}
+
+// SaveQuoteCalls gets all the calls that were made to SaveQuote.
+// Check the length with:
+//
+//	len(mockedQuoteStore.SaveQuoteCalls())
+func (mock *QuoteStoreMock) SaveQuoteCalls() []struct {
+	Ctx     context.Context
+	Content string
+	Author  string
+	Source  string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Content string
+		Author  string
+		Source  string
+	}
+	mock.lockSaveQuote.RLock()
+	calls = mock.calls.SaveQuote
+	mock.lockSaveQuote.RUnlock()
+	return calls
+}
+
+// SearchQuotes calls SearchQuotesFunc.
+func (mock *QuoteStoreMock) SearchQuotes(ctx context.Context, query string, limit int) ([]database.QuoteMatch, error) {
+	if mock.SearchQuotesFunc == nil {
+		panic("QuoteStoreMock.SearchQuotesFunc: method is nil but QuoteStore.SearchQuotes was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Query string
+		Limit int
+	}{
+		Ctx:   ctx,
+		Query: query,
+		Limit: limit,
+	}
+	mock.lockSearchQuotes.Lock()
+	mock.calls.SearchQuotes = append(mock.calls.SearchQuotes, callInfo)
+	mock.lockSearchQuotes.Unlock()
+	return mock.SearchQuotesFunc(ctx, query, limit)
+}
+
+// SearchQuotesCalls gets all the calls that were made to SearchQuotes.
+// Check the length with:
+//
+//	len(mockedQuoteStore.SearchQuotesCalls())
+func (mock *QuoteStoreMock) SearchQuotesCalls() []struct {
+	Ctx   context.Context
+	Query string
+	Limit int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Query string
+		Limit int
+	}
+	mock.lockSearchQuotes.RLock()
+	calls = mock.calls.SearchQuotes
+	mock.lockSearchQuotes.RUnlock()
+	return calls
+}
+
+// Ensure, that WeatherCacheStoreMock does implement database.WeatherCacheStore.
+// If this is not the case, regenerate this file with moq.
+var _ database.WeatherCacheStore = &WeatherCacheStoreMock{}
+
+// WeatherCacheStoreMock is a mock implementation of database.WeatherCacheStore.
+//
+//	func TestSomethingThatUsesWeatherCacheStore(t *testing.T) {
+//
+//		// make and configure a mocked database.WeatherCacheStore
+//		mockedWeatherCacheStore := &WeatherCacheStoreMock{
+//			GetWeatherCacheFunc: func(ctx context.Context, location string) (*database.WeatherCache, error) {
+//				panic("mock out the GetWeatherCache method")
+//			},
+//			SaveWeatherCacheFunc: func(ctx context.Context, location string, data []byte) error {
+//				panic("mock out the SaveWeatherCache method")
+//			},
+//		}
+//
+//		// use mockedWeatherCacheStore in code that requires database.WeatherCacheStore
+//		// and then make assertions.
+//
+//	}
+type WeatherCacheStoreMock struct {
+	// GetWeatherCacheFunc mocks the GetWeatherCache method.
+	GetWeatherCacheFunc func(ctx context.Context, location string) (*database.WeatherCache, error)
+
+	// SaveWeatherCacheFunc mocks the SaveWeatherCache method.
+	SaveWeatherCacheFunc func(ctx context.Context, location string, data []byte) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// GetWeatherCache holds details about calls to the GetWeatherCache method.
+		GetWeatherCache []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Location is the location argument value.
+			Location string
+		}
+		// SaveWeatherCache holds details about calls to the SaveWeatherCache method.
+		SaveWeatherCache []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Location is the location argument value.
+			Location string
+			// Data is the data argument value.
+			Data []byte
+		}
+	}
+	lockGetWeatherCache  sync.RWMutex
+	lockSaveWeatherCache sync.RWMutex
+}
+
+// GetWeatherCache calls GetWeatherCacheFunc.
+func (mock *WeatherCacheStoreMock) GetWeatherCache(ctx context.Context, location string) (*database.WeatherCache, error) {
+	if mock.GetWeatherCacheFunc == nil {
+		panic("WeatherCacheStoreMock.GetWeatherCacheFunc: method is nil but WeatherCacheStore.GetWeatherCache was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Location string
+	}{
+		Ctx:      ctx,
+		Location: location,
+	}
+	mock.lockGetWeatherCache.Lock()
+	mock.calls.GetWeatherCache = append(mock.calls.GetWeatherCache, callInfo)
+	mock.lockGetWeatherCache.Unlock()
+	return mock.GetWeatherCacheFunc(ctx, location)
+}
+
+// GetWeatherCacheCalls gets all the calls that were made to GetWeatherCache.
+// Check the length with:
+//
+//	len(mockedWeatherCacheStore.GetWeatherCacheCalls())
+func (mock *WeatherCacheStoreMock) GetWeatherCacheCalls() []struct {
+	Ctx      context.Context
+	Location string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Location string
+	}
+	mock.lockGetWeatherCache.RLock()
+	calls = mock.calls.GetWeatherCache
+	mock.lockGetWeatherCache.RUnlock()
+	return calls
+}
+
+// SaveWeatherCache calls SaveWeatherCacheFunc.
+func (mock *WeatherCacheStoreMock) SaveWeatherCache(ctx context.Context, location string, data []byte) error {
+	if mock.SaveWeatherCacheFunc == nil {
+		panic("WeatherCacheStoreMock.SaveWeatherCacheFunc: method is nil but WeatherCacheStore.SaveWeatherCache was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Location string
+		Data     []byte
+	}{
+		Ctx:      ctx,
+		Location: location,
+		Data:     data,
+	}
+	mock.lockSaveWeatherCache.Lock()
+	mock.calls.SaveWeatherCache = append(mock.calls.SaveWeatherCache, callInfo)
+	mock.lockSaveWeatherCache.Unlock()
+	return mock.SaveWeatherCacheFunc(ctx, location, data)
+}
+
+// SaveWeatherCacheCalls gets all the calls that were made to SaveWeatherCache.
+// Check the length with:
+//
+//	len(mockedWeatherCacheStore.SaveWeatherCacheCalls())
+func (mock *WeatherCacheStoreMock) SaveWeatherCacheCalls() []struct {
+	Ctx      context.Context
+	Location string
+	Data     []byte
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Location string
+		Data     []byte
+	}
+	mock.lockSaveWeatherCache.RLock()
+	calls = mock.calls.SaveWeatherCache
+	mock.lockSaveWeatherCache.RUnlock()
+	return calls
+}
+
+// Ensure, that RequestLogStoreMock does implement database.RequestLogStore.
+// If this is not the case, regenerate this file with moq.
+var _ database.RequestLogStore = &RequestLogStoreMock{}
+
+// RequestLogStoreMock is a mock implementation of database.RequestLogStore.
+//
+//	func TestSomethingThatUsesRequestLogStore(t *testing.T) {
+//
+//		// make and configure a mocked database.RequestLogStore
+//		mockedRequestLogStore := &RequestLogStoreMock{
+//			GetRequestLogsFunc: func(ctx context.Context, limit int) ([]database.RequestLog, error) {
+//				panic("mock out the GetRequestLogs method")
+//			},
+//			LogRequestFunc: func(ctx context.Context, traceID string, spanID string, requestID string, endpoint string, method string, statusCode int, durationMs int64) error {
+//				panic("mock out the LogRequest method")
+//			},
+//		}
+//
+//		// use mockedRequestLogStore in code that requires database.RequestLogStore
+//		// and then make assertions.
+//
+//	}
+type RequestLogStoreMock struct {
+	// GetRequestLogsFunc mocks the GetRequestLogs method.
+	GetRequestLogsFunc func(ctx context.Context, limit int) ([]database.RequestLog, error)
+
+	// LogRequestFunc mocks the LogRequest method.
+	LogRequestFunc func(ctx context.Context, traceID string, spanID string, requestID string, endpoint string, method string, statusCode int, durationMs int64) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// GetRequestLogs holds details about calls to the GetRequestLogs method.
+		GetRequestLogs []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// LogRequest holds details about calls to the LogRequest method.
+		LogRequest []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// TraceID is the traceID argument value.
+			TraceID string
+			// SpanID is the spanID argument value.
+			SpanID string
+			// RequestID is the requestID argument value.
+			RequestID string
+			// Endpoint is the endpoint argument value.
+			Endpoint string
+			// Method is the method argument value.
+			Method string
+			// StatusCode is the statusCode argument value.
+			StatusCode int
+			// DurationMs is the durationMs argument value.
+			DurationMs int64
+		}
+	}
+	lockGetRequestLogs sync.RWMutex
+	lockLogRequest     sync.RWMutex
+}
+
+// GetRequestLogs calls GetRequestLogsFunc.
+func (mock *RequestLogStoreMock) GetRequestLogs(ctx context.Context, limit int) ([]database.RequestLog, error) {
+	if mock.GetRequestLogsFunc == nil {
+		panic("RequestLogStoreMock.GetRequestLogsFunc: method is nil but RequestLogStore.GetRequestLogs was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Limit int
+	}{
+		Ctx:   ctx,
+		Limit: limit,
+	}
+	mock.lockGetRequestLogs.Lock()
+	mock.calls.GetRequestLogs = append(mock.calls.GetRequestLogs, callInfo)
+	mock.lockGetRequestLogs.Unlock()
+	return mock.GetRequestLogsFunc(ctx, limit)
+}
+
+// GetRequestLogsCalls gets all the calls that were made to GetRequestLogs.
+// Check the length with:
+//
+//	len(mockedRequestLogStore.GetRequestLogsCalls())
+func (mock *RequestLogStoreMock) GetRequestLogsCalls() []struct {
+	Ctx   context.Context
+	Limit int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Limit int
+	}
+	mock.lockGetRequestLogs.RLock()
+	calls = mock.calls.GetRequestLogs
+	mock.lockGetRequestLogs.RUnlock()
+	return calls
+}
+
+// LogRequest calls LogRequestFunc.
+func (mock *RequestLogStoreMock) LogRequest(ctx context.Context, traceID string, spanID string, requestID string, endpoint string, method string, statusCode int, durationMs int64) error {
+	if mock.LogRequestFunc == nil {
+		panic("RequestLogStoreMock.LogRequestFunc: method is nil but RequestLogStore.LogRequest was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		TraceID    string
+		SpanID     string
+		RequestID  string
+		Endpoint   string
+		Method     string
+		StatusCode int
+		DurationMs int64
+	}{
+		Ctx:        ctx,
+		TraceID:    traceID,
+		SpanID:     spanID,
+		RequestID:  requestID,
+		Endpoint:   endpoint,
+		Method:     method,
+		StatusCode: statusCode,
+		DurationMs: durationMs,
+	}
+	mock.lockLogRequest.Lock()
+	mock.calls.LogRequest = append(mock.calls.LogRequest, callInfo)
+	mock.lockLogRequest.Unlock()
+	return mock.LogRequestFunc(ctx, traceID, spanID, requestID, endpoint, method, statusCode, durationMs)
+}
+
+// LogRequestCalls gets all the calls that were made to LogRequest.
+// Check the length with:
+//
+//	len(mockedRequestLogStore.LogRequestCalls())
+func (mock *RequestLogStoreMock) LogRequestCalls() []struct {
+	Ctx        context.Context
+	TraceID    string
+	SpanID     string
+	RequestID  string
+	Endpoint   string
+	Method     string
+	StatusCode int
+	DurationMs int64
+} {
+	var calls []struct {
+		Ctx        context.Context
+		TraceID    string
+		SpanID     string
+		RequestID  string
+		Endpoint   string
+		Method     string
+		StatusCode int
+		DurationMs int64
+	}
+	mock.lockLogRequest.RLock()
+	calls = mock.calls.LogRequest
+	mock.lockLogRequest.RUnlock()
+	return calls
+}
+
+// Ensure, that AuditStoreMock does implement database.AuditStore.
+// If this is not the case, regenerate this file with moq.
+var _ database.AuditStore = &AuditStoreMock{}
+
+// AuditStoreMock is a mock implementation of database.AuditStore.
+//
+//	func TestSomethingThatUsesAuditStore(t *testing.T) {
+//
+//		// make and configure a mocked database.AuditStore
+//		mockedAuditStore := &AuditStoreMock{
+//			GetAuditEntriesFunc: func(ctx context.Context, limit int) ([]database.AuditEntry, error) {
+//				panic("mock out the GetAuditEntries method")
+//			},
+//			SaveAuditEntryFunc: func(ctx context.Context, e database.AuditEntry) error {
+//				panic("mock out the SaveAuditEntry method")
+//			},
+//		}
+//
+//		// use mockedAuditStore in code that requires database.AuditStore
+//		// and then make assertions.
+//
+//	}
+type AuditStoreMock struct {
+	// GetAuditEntriesFunc mocks the GetAuditEntries method.
+	GetAuditEntriesFunc func(ctx context.Context, limit int) ([]database.AuditEntry, error)
+
+	// SaveAuditEntryFunc mocks the SaveAuditEntry method.
+	SaveAuditEntryFunc func(ctx context.Context, e database.AuditEntry) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// GetAuditEntries holds details about calls to the GetAuditEntries method.
+		GetAuditEntries []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// SaveAuditEntry holds details about calls to the SaveAuditEntry method.
+		SaveAuditEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// E is the e argument value.
+			E database.AuditEntry
+		}
+	}
+	lockGetAuditEntries sync.RWMutex
+	lockSaveAuditEntry  sync.RWMutex
+}
+
+// GetAuditEntries calls GetAuditEntriesFunc.
+func (mock *AuditStoreMock) GetAuditEntries(ctx context.Context, limit int) ([]database.AuditEntry, error) {
+	if mock.GetAuditEntriesFunc == nil {
+		panic("AuditStoreMock.GetAuditEntriesFunc: method is nil but AuditStore.GetAuditEntries was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Limit int
+	}{
+		Ctx:   ctx,
+		Limit: limit,
+	}
+	mock.lockGetAuditEntries.Lock()
+	mock.calls.GetAuditEntries = append(mock.calls.GetAuditEntries, callInfo)
+	mock.lockGetAuditEntries.Unlock()
+	return mock.GetAuditEntriesFunc(ctx, limit)
+}
+
+// GetAuditEntriesCalls gets all the calls that were made to GetAuditEntries.
+// Check the length with:
+//
+//	len(mockedAuditStore.GetAuditEntriesCalls())
+func (mock *AuditStoreMock) GetAuditEntriesCalls() []struct {
+	Ctx   context.Context
+	Limit int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Limit int
+	}
+	mock.lockGetAuditEntries.RLock()
+	calls = mock.calls.GetAuditEntries
+	mock.lockGetAuditEntries.RUnlock()
+	return calls
+}
+
+// SaveAuditEntry calls SaveAuditEntryFunc.
+func (mock *AuditStoreMock) SaveAuditEntry(ctx context.Context, e database.AuditEntry) error {
+	if mock.SaveAuditEntryFunc == nil {
+		panic("AuditStoreMock.SaveAuditEntryFunc: method is nil but AuditStore.SaveAuditEntry was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		E   database.AuditEntry
+	}{
+		Ctx: ctx,
+		E:   e,
+	}
+	mock.lockSaveAuditEntry.Lock()
+	mock.calls.SaveAuditEntry = append(mock.calls.SaveAuditEntry, callInfo)
+	mock.lockSaveAuditEntry.Unlock()
+	return mock.SaveAuditEntryFunc(ctx, e)
+}
+
+// SaveAuditEntryCalls gets all the calls that were made to SaveAuditEntry.
+// Check the length with:
+//
+//	len(mockedAuditStore.SaveAuditEntryCalls())
+func (mock *AuditStoreMock) SaveAuditEntryCalls() []struct {
+	Ctx context.Context
+	E   database.AuditEntry
+} {
+	var calls []struct {
+		Ctx context.Context
+		E   database.AuditEntry
+	}
+	mock.lockSaveAuditEntry.RLock()
+	calls = mock.calls.SaveAuditEntry
+	mock.lockSaveAuditEntry.RUnlock()
+	return calls
+}
+
+// Ensure, that DeploymentStoreMock does implement database.DeploymentStore.
+// If this is not the case, regenerate this file with moq.
+var _ database.DeploymentStore = &DeploymentStoreMock{}
+
+// DeploymentStoreMock is a mock implementation of database.DeploymentStore.
+//
+//	func TestSomethingThatUsesDeploymentStore(t *testing.T) {
+//
+//		// make and configure a mocked database.DeploymentStore
+//		mockedDeploymentStore := &DeploymentStoreMock{
+//			RecordDeploymentFunc: func(ctx context.Context, version string, commit string) (*database.Deployment, bool, error) {
+//				panic("mock out the RecordDeployment method")
+//			},
+//		}
+//
+//		// use mockedDeploymentStore in code that requires database.DeploymentStore
+//		// and then make assertions.
+//
+//	}
+type DeploymentStoreMock struct {
+	// RecordDeploymentFunc mocks the RecordDeployment method.
+	RecordDeploymentFunc func(ctx context.Context, version string, commit string) (*database.Deployment, bool, error)
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// RecordDeployment holds details about calls to the RecordDeployment method.
+		RecordDeployment []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Version is the version argument value.
+			Version string
+			// Commit is the commit argument value.
+			Commit string
+		}
+	}
+	lockRecordDeployment sync.RWMutex
+}
+
+// RecordDeployment calls RecordDeploymentFunc.
+func (mock *DeploymentStoreMock) RecordDeployment(ctx context.Context, version string, commit string) (*database.Deployment, bool, error) {
+	if mock.RecordDeploymentFunc == nil {
+		panic("DeploymentStoreMock.RecordDeploymentFunc: method is nil but DeploymentStore.RecordDeployment was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Version string
+		Commit  string
+	}{
+		Ctx:     ctx,
+		Version: version,
+		Commit:  commit,
+	}
+	mock.lockRecordDeployment.Lock()
+	mock.calls.RecordDeployment = append(mock.calls.RecordDeployment, callInfo)
+	mock.lockRecordDeployment.Unlock()
+	return mock.RecordDeploymentFunc(ctx, version, commit)
+}
+
+// RecordDeploymentCalls gets all the calls that were made to RecordDeployment.
+// Check the length with:
+//
+//	len(mockedDeploymentStore.RecordDeploymentCalls())
+func (mock *DeploymentStoreMock) RecordDeploymentCalls() []struct {
+	Ctx     context.Context
+	Version string
+	Commit  string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Version string
+		Commit  string
+	}
+	mock.lockRecordDeployment.RLock()
+	calls = mock.calls.RecordDeployment
+	mock.lockRecordDeployment.RUnlock()
+	return calls
+}
+
+// Ensure, that StoreMock does implement database.Store.
+// If this is not the case, regenerate this file with moq.
+var _ database.Store = &StoreMock{}
+
+// StoreMock is a mock implementation of database.Store.
+//
+//	func TestSomethingThatUsesStore(t *testing.T) {
+//
+//		// make and configure a mocked database.Store
+//		mockedStore := &StoreMock{
+//			CloseFunc: func() error {
+//				panic("mock out the Close method")
+//			},
+//			CreateUserImportFunc: func(ctx context.Context, total int, traceID string) (*database.UserImport, error) {
+//				panic("mock out the CreateUserImport method")
+//			},
+//			DeleteQuoteFunc: func(ctx context.Context, id int) (bool, error) {
+//				panic("mock out the DeleteQuote method")
+//			},
+//			DeleteUserFunc: func(ctx context.Context, username string) (bool, error) {
+//				panic("mock out the DeleteUser method")
+//			},
+//			GetAuditEntriesFunc: func(ctx context.Context, limit int) ([]database.AuditEntry, error) {
+//				panic("mock out the GetAuditEntries method")
+//			},
+//			GetQuoteStatsFunc: func(ctx context.Context) ([]database.QuoteStats, error) {
+//				panic("mock out the GetQuoteStats method")
+//			},
+//			GetQuotesFunc: func(ctx context.Context, limit int) ([]database.Quote, error) {
+//				panic("mock out the GetQuotes method")
+//			},
+//			GetRequestLogsFunc: func(ctx context.Context, limit int) ([]database.RequestLog, error) {
+//				panic("mock out the GetRequestLogs method")
+//			},
+//			GetUserByUsernameFunc: func(ctx context.Context, username string) (*database.User, error) {
+//				panic("mock out the GetUserByUsername method")
+//			},
+//			GetUserImportFunc: func(ctx context.Context, id int) (*database.UserImport, error) {
+//				panic("mock out the GetUserImport method")
+//			},
+//			GetUsersFunc: func(ctx context.Context) ([]database.User, error) {
+//				panic("mock out the GetUsers method")
+//			},
+//			GetWeatherCacheFunc: func(ctx context.Context, location string) (*database.WeatherCache, error) {
+//				panic("mock out the GetWeatherCache method")
+//			},
+//			InsertUsersFunc: func(ctx context.Context, users []database.User) ([]bool, error) {
+//				panic("mock out the InsertUsers method")
+//			},
+//			LogRequestFunc: func(ctx context.Context, traceID string, spanID string, requestID string, endpoint string, method string, statusCode int, durationMs int64) error {
+//				panic("mock out the LogRequest method")
+//			},
+//			PingContextFunc: func(ctx context.Context) error {
+//				panic("mock out the PingContext method")
+//			},
+//			RecordDeploymentFunc: func(ctx context.Context, version string, commit string) (*database.Deployment, bool, error) {
+//				panic("mock out the RecordDeployment method")
+//			},
+//			SaveAuditEntryFunc: func(ctx context.Context, e database.AuditEntry) error {
+//				panic("mock out the SaveAuditEntry method")
+//			},
+//			SaveQuoteFunc: func(ctx context.Context, content string, author string, source string) (bool, error) {
+//				panic("mock out the SaveQuote method")
+//			},
+//			SaveWeatherCacheFunc: func(ctx context.Context, location string, data []byte) error {
+//				panic("mock out the SaveWeatherCache method")
+//			},
+//			SearchQuotesFunc: func(ctx context.Context, query string, limit int) ([]database.QuoteMatch, error) {
+//				panic("mock out the SearchQuotes method")
+//			},
+//			UpdateUserFunc: func(ctx context.Context, u database.User) (*database.User, error) {
+//				panic("mock out the UpdateUser method")
+//			},
+//			UpdateUserImportFunc: func(ctx context.Context, imp *database.UserImport) error {
+//				panic("mock out the UpdateUserImport method")
+//			},
+//		}
+//
+//		// use mockedStore in code that requires database.Store
+//		// and then make assertions.
+//
+//	}
+type StoreMock struct {
+	// CloseFunc mocks the Close method.
+	CloseFunc func() error
+
+	// CreateUserImportFunc mocks the CreateUserImport method.
+	CreateUserImportFunc func(ctx context.Context, total int, traceID string) (*database.UserImport, error)
+
+	// DeleteQuoteFunc mocks the DeleteQuote method.
+	DeleteQuoteFunc func(ctx context.Context, id int) (bool, error)
+
+	// DeleteUserFunc mocks the DeleteUser method.
+	DeleteUserFunc func(ctx context.Context, username string) (bool, error)
+
+	// GetAuditEntriesFunc mocks the GetAuditEntries method.
+	GetAuditEntriesFunc func(ctx context.Context, limit int) ([]database.AuditEntry, error)
+
+	// GetQuoteStatsFunc mocks the GetQuoteStats method.
+	GetQuoteStatsFunc func(ctx context.Context) ([]database.QuoteStats, error)
+
+	// GetQuotesFunc mocks the GetQuotes method.
+	GetQuotesFunc func(ctx context.Context, limit int) ([]database.Quote, error)
+
+	// GetRequestLogsFunc mocks the GetRequestLogs method.
+	GetRequestLogsFunc func(ctx context.Context, limit int) ([]database.RequestLog, error)
+
+	// GetUserByUsernameFunc mocks the GetUserByUsername method.
+	GetUserByUsernameFunc func(ctx context.Context, username string) (*database.User, error)
+
+	// GetUserImportFunc mocks the GetUserImport method.
+	GetUserImportFunc func(ctx context.Context, id int) (*database.UserImport, error)
+
+	// GetUsersFunc mocks the GetUsers method.
+	GetUsersFunc func(ctx context.Context) ([]database.User, error)
+
+	// GetWeatherCacheFunc mocks the GetWeatherCache method.
+	GetWeatherCacheFunc func(ctx context.Context, location string) (*database.WeatherCache, error)
+
+	// InsertUsersFunc mocks the InsertUsers method.
+	InsertUsersFunc func(ctx context.Context, users []database.User) ([]bool, error)
+
+	// LogRequestFunc mocks the LogRequest method.
+	LogRequestFunc func(ctx context.Context, traceID string, spanID string, requestID string, endpoint string, method string, statusCode int, durationMs int64) error
+
+	// PingContextFunc mocks the PingContext method.
+	PingContextFunc func(ctx context.Context) error
+
+	// RecordDeploymentFunc mocks the RecordDeployment method.
+	RecordDeploymentFunc func(ctx context.Context, version string, commit string) (*database.Deployment, bool, error)
+
+	// SaveAuditEntryFunc mocks the SaveAuditEntry method.
+	SaveAuditEntryFunc func(ctx context.Context, e database.AuditEntry) error
+
+	// SaveQuoteFunc mocks the SaveQuote method.
+	SaveQuoteFunc func(ctx context.Context, content string, author string, source string) (bool, error)
+
+	// SaveWeatherCacheFunc mocks the SaveWeatherCache method.
+	SaveWeatherCacheFunc func(ctx context.Context, location string, data []byte) error
+
+	// SearchQuotesFunc mocks the SearchQuotes method.
+	SearchQuotesFunc func(ctx context.Context, query string, limit int) ([]database.QuoteMatch, error)
+
+	// UpdateUserFunc mocks the UpdateUser method.
+	UpdateUserFunc func(ctx context.Context, u database.User) (*database.User, error)
+
+	// UpdateUserImportFunc mocks the UpdateUserImport method.
+	UpdateUserImportFunc func(ctx context.Context, imp *database.UserImport) error
+
+	// calls tracks calls to the methods.
+	calls struct {
+		// Close holds details about calls to the Close method.
+		Close []struct {
+		}
+		// CreateUserImport holds details about calls to the CreateUserImport method.
+		CreateUserImport []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Total is the total argument value.
+			Total int
+			// TraceID is the traceID argument value.
+			TraceID string
+		}
+		// DeleteQuote holds details about calls to the DeleteQuote method.
+		DeleteQuote []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Id is the id argument value.
+			Id int
+		}
+		// DeleteUser holds details about calls to the DeleteUser method.
+		DeleteUser []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Username is the username argument value.
+			Username string
+		}
+		// GetAuditEntries holds details about calls to the GetAuditEntries method.
+		GetAuditEntries []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// GetQuoteStats holds details about calls to the GetQuoteStats method.
+		GetQuoteStats []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// GetQuotes holds details about calls to the GetQuotes method.
+		GetQuotes []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// GetRequestLogs holds details about calls to the GetRequestLogs method.
+		GetRequestLogs []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// GetUserByUsername holds details about calls to the GetUserByUsername method.
+		GetUserByUsername []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Username is the username argument value.
+			Username string
+		}
+		// GetUserImport holds details about calls to the GetUserImport method.
+		GetUserImport []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Id is the id argument value.
+			Id int
+		}
+		// GetUsers holds details about calls to the GetUsers method.
+		GetUsers []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// GetWeatherCache holds details about calls to the GetWeatherCache method.
+		GetWeatherCache []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Location is the location argument value.
+			Location string
+		}
+		// InsertUsers holds details about calls to the InsertUsers method.
+		InsertUsers []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Users is the users argument value.
+			Users []database.User
+		}
+		// LogRequest holds details about calls to the LogRequest method.
+		LogRequest []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// TraceID is the traceID argument value.
+			TraceID string
+			// SpanID is the spanID argument value.
+			SpanID string
+			// RequestID is the requestID argument value.
+			RequestID string
+			// Endpoint is the endpoint argument value.
+			Endpoint string
+			// Method is the method argument value.
+			Method string
+			// StatusCode is the statusCode argument value.
+			StatusCode int
+			// DurationMs is the durationMs argument value.
+			DurationMs int64
+		}
+		// PingContext holds details about calls to the PingContext method.
+		PingContext []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+		}
+		// RecordDeployment holds details about calls to the RecordDeployment method.
+		RecordDeployment []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Version is the version argument value.
+			Version string
+			// Commit is the commit argument value.
+			Commit string
+		}
+		// SaveAuditEntry holds details about calls to the SaveAuditEntry method.
+		SaveAuditEntry []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// E is the e argument value.
+			E database.AuditEntry
+		}
+		// SaveQuote holds details about calls to the SaveQuote method.
+		SaveQuote []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Content is the content argument value.
+			Content string
+			// Author is the author argument value.
+			Author string
+			// Source is the source argument value.
+			Source string
+		}
+		// SaveWeatherCache holds details about calls to the SaveWeatherCache method.
+		SaveWeatherCache []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Location is the location argument value.
+			Location string
+			// Data is the data argument value.
+			Data []byte
+		}
+		// SearchQuotes holds details about calls to the SearchQuotes method.
+		SearchQuotes []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Query is the query argument value.
+			Query string
+			// Limit is the limit argument value.
+			Limit int
+		}
+		// UpdateUser holds details about calls to the UpdateUser method.
+		UpdateUser []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// U is the u argument value.
+			U database.User
+		}
+		// UpdateUserImport holds details about calls to the UpdateUserImport method.
+		UpdateUserImport []struct {
+			// Ctx is the ctx argument value.
+			Ctx context.Context
+			// Imp is the imp argument value.
+			Imp *database.UserImport
+		}
+	}
+	lockClose             sync.RWMutex
+	lockCreateUserImport  sync.RWMutex
+	lockDeleteQuote       sync.RWMutex
+	lockDeleteUser        sync.RWMutex
+	lockGetAuditEntries   sync.RWMutex
+	lockGetQuoteStats     sync.RWMutex
+	lockGetQuotes         sync.RWMutex
+	lockGetRequestLogs    sync.RWMutex
+	lockGetUserByUsername sync.RWMutex
+	lockGetUserImport     sync.RWMutex
+	lockGetUsers          sync.RWMutex
+	lockGetWeatherCache   sync.RWMutex
+	lockInsertUsers       sync.RWMutex
+	lockLogRequest        sync.RWMutex
+	lockPingContext       sync.RWMutex
+	lockRecordDeployment  sync.RWMutex
+	lockSaveAuditEntry    sync.RWMutex
+	lockSaveQuote         sync.RWMutex
+	lockSaveWeatherCache  sync.RWMutex
+	lockSearchQuotes      sync.RWMutex
+	lockUpdateUser        sync.RWMutex
+	lockUpdateUserImport  sync.RWMutex
+}
+
+// Close calls CloseFunc.
+func (mock *StoreMock) Close() error {
+	if mock.CloseFunc == nil {
+		panic("StoreMock.CloseFunc: method is nil but Store.Close was just called")
+	}
+	callInfo := struct {
+	}{}
+	mock.lockClose.Lock()
+	mock.calls.Close = append(mock.calls.Close, callInfo)
+	mock.lockClose.Unlock()
+	return mock.CloseFunc()
+}
+
+// CloseCalls gets all the calls that were made to Close.
+// Check the length with:
+//
+//	len(mockedStore.CloseCalls())
+func (mock *StoreMock) CloseCalls() []struct {
+} {
+	var calls []struct {
+	}
+	mock.lockClose.RLock()
+	calls = mock.calls.Close
+	mock.lockClose.RUnlock()
+	return calls
+}
+
+// CreateUserImport calls CreateUserImportFunc.
+func (mock *StoreMock) CreateUserImport(ctx context.Context, total int, traceID string) (*database.UserImport, error) {
+	if mock.CreateUserImportFunc == nil {
+		panic("StoreMock.CreateUserImportFunc: method is nil but Store.CreateUserImport was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Total   int
+		TraceID string
+	}{
+		Ctx:     ctx,
+		Total:   total,
+		TraceID: traceID,
+	}
+	mock.lockCreateUserImport.Lock()
+	mock.calls.CreateUserImport = append(mock.calls.CreateUserImport, callInfo)
+	mock.lockCreateUserImport.Unlock()
+	return mock.CreateUserImportFunc(ctx, total, traceID)
+}
+
+// CreateUserImportCalls gets all the calls that were made to CreateUserImport.
+// Check the length with:
+//
+//	len(mockedStore.CreateUserImportCalls())
+func (mock *StoreMock) CreateUserImportCalls() []struct {
+	Ctx     context.Context
+	Total   int
+	TraceID string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Total   int
+		TraceID string
+	}
+	mock.lockCreateUserImport.RLock()
+	calls = mock.calls.CreateUserImport
+	mock.lockCreateUserImport.RUnlock()
+	return calls
+}
+
+// DeleteQuote calls DeleteQuoteFunc.
+func (mock *StoreMock) DeleteQuote(ctx context.Context, id int) (bool, error) {
+	if mock.DeleteQuoteFunc == nil {
+		panic("StoreMock.DeleteQuoteFunc: method is nil but Store.DeleteQuote was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Id  int
+	}{
+		Ctx: ctx,
+		Id:  id,
+	}
+	mock.lockDeleteQuote.Lock()
+	mock.calls.DeleteQuote = append(mock.calls.DeleteQuote, callInfo)
+	mock.lockDeleteQuote.Unlock()
+	return mock.DeleteQuoteFunc(ctx, id)
+}
+
+// DeleteQuoteCalls gets all the calls that were made to DeleteQuote.
+// Check the length with:
+//
+//	len(mockedStore.DeleteQuoteCalls())
+func (mock *StoreMock) DeleteQuoteCalls() []struct {
+	Ctx context.Context
+	Id  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		Id  int
+	}
+	mock.lockDeleteQuote.RLock()
+	calls = mock.calls.DeleteQuote
+	mock.lockDeleteQuote.RUnlock()
+	return calls
+}
+
+// DeleteUser calls DeleteUserFunc.
+func (mock *StoreMock) DeleteUser(ctx context.Context, username string) (bool, error) {
+	if mock.DeleteUserFunc == nil {
+		panic("StoreMock.DeleteUserFunc: method is nil but Store.DeleteUser was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Username string
+	}{
+		Ctx:      ctx,
+		Username: username,
+	}
+	mock.lockDeleteUser.Lock()
+	mock.calls.DeleteUser = append(mock.calls.DeleteUser, callInfo)
+	mock.lockDeleteUser.Unlock()
+	return mock.DeleteUserFunc(ctx, username)
+}
+
+// DeleteUserCalls gets all the calls that were made to DeleteUser.
+// Check the length with:
+//
+//	len(mockedStore.DeleteUserCalls())
+func (mock *StoreMock) DeleteUserCalls() []struct {
+	Ctx      context.Context
+	Username string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Username string
+	}
+	mock.lockDeleteUser.RLock()
+	calls = mock.calls.DeleteUser
+	mock.lockDeleteUser.RUnlock()
+	return calls
+}
+
+// GetAuditEntries calls GetAuditEntriesFunc.
+func (mock *StoreMock) GetAuditEntries(ctx context.Context, limit int) ([]database.AuditEntry, error) {
+	if mock.GetAuditEntriesFunc == nil {
+		panic("StoreMock.GetAuditEntriesFunc: method is nil but Store.GetAuditEntries was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Limit int
+	}{
+		Ctx:   ctx,
+		Limit: limit,
+	}
+	mock.lockGetAuditEntries.Lock()
+	mock.calls.GetAuditEntries = append(mock.calls.GetAuditEntries, callInfo)
+	mock.lockGetAuditEntries.Unlock()
+	return mock.GetAuditEntriesFunc(ctx, limit)
+}
+
+// GetAuditEntriesCalls gets all the calls that were made to GetAuditEntries.
+// Check the length with:
+//
+//	len(mockedStore.GetAuditEntriesCalls())
+func (mock *StoreMock) GetAuditEntriesCalls() []struct {
+	Ctx   context.Context
+	Limit int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Limit int
+	}
+	mock.lockGetAuditEntries.RLock()
+	calls = mock.calls.GetAuditEntries
+	mock.lockGetAuditEntries.RUnlock()
+	return calls
+}
+
+// GetQuoteStats calls GetQuoteStatsFunc.
+func (mock *StoreMock) GetQuoteStats(ctx context.Context) ([]database.QuoteStats, error) {
+	if mock.GetQuoteStatsFunc == nil {
+		panic("StoreMock.GetQuoteStatsFunc: method is nil but Store.GetQuoteStats was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetQuoteStats.Lock()
+	mock.calls.GetQuoteStats = append(mock.calls.GetQuoteStats, callInfo)
+	mock.lockGetQuoteStats.Unlock()
+	return mock.GetQuoteStatsFunc(ctx)
+}
+
+// GetQuoteStatsCalls gets all the calls that were made to GetQuoteStats.
+// Check the length with:
+//
+//	len(mockedStore.GetQuoteStatsCalls())
+func (mock *StoreMock) GetQuoteStatsCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetQuoteStats.RLock()
+	calls = mock.calls.GetQuoteStats
+	mock.lockGetQuoteStats.RUnlock()
+	return calls
+}
+
+// GetQuotes calls GetQuotesFunc.
+func (mock *StoreMock) GetQuotes(ctx context.Context, limit int) ([]database.Quote, error) {
+	if mock.GetQuotesFunc == nil {
+		panic("StoreMock.GetQuotesFunc: method is nil but Store.GetQuotes was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Limit int
+	}{
+		Ctx:   ctx,
+		Limit: limit,
+	}
+	mock.lockGetQuotes.Lock()
+	mock.calls.GetQuotes = append(mock.calls.GetQuotes, callInfo)
+	mock.lockGetQuotes.Unlock()
+	return mock.GetQuotesFunc(ctx, limit)
+}
+
+// GetQuotesCalls gets all the calls that were made to GetQuotes.
+// Check the length with:
+//
+//	len(mockedStore.GetQuotesCalls())
+func (mock *StoreMock) GetQuotesCalls() []struct {
+	Ctx   context.Context
+	Limit int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Limit int
+	}
+	mock.lockGetQuotes.RLock()
+	calls = mock.calls.GetQuotes
+	mock.lockGetQuotes.RUnlock()
+	return calls
+}
+
+// GetRequestLogs calls GetRequestLogsFunc.
+func (mock *StoreMock) GetRequestLogs(ctx context.Context, limit int) ([]database.RequestLog, error) {
+	if mock.GetRequestLogsFunc == nil {
+		panic("StoreMock.GetRequestLogsFunc: method is nil but Store.GetRequestLogs was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Limit int
+	}{
+		Ctx:   ctx,
+		Limit: limit,
+	}
+	mock.lockGetRequestLogs.Lock()
+	mock.calls.GetRequestLogs = append(mock.calls.GetRequestLogs, callInfo)
+	mock.lockGetRequestLogs.Unlock()
+	return mock.GetRequestLogsFunc(ctx, limit)
+}
+
+// GetRequestLogsCalls gets all the calls that were made to GetRequestLogs.
+// Check the length with:
+//
+//	len(mockedStore.GetRequestLogsCalls())
+func (mock *StoreMock) GetRequestLogsCalls() []struct {
+	Ctx   context.Context
+	Limit int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Limit int
+	}
+	mock.lockGetRequestLogs.RLock()
+	calls = mock.calls.GetRequestLogs
+	mock.lockGetRequestLogs.RUnlock()
+	return calls
+}
+
+// GetUserByUsername calls GetUserByUsernameFunc.
+func (mock *StoreMock) GetUserByUsername(ctx context.Context, username string) (*database.User, error) {
+	if mock.GetUserByUsernameFunc == nil {
+		panic("StoreMock.GetUserByUsernameFunc: method is nil but Store.GetUserByUsername was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Username string
+	}{
+		Ctx:      ctx,
+		Username: username,
+	}
+	mock.lockGetUserByUsername.Lock()
+	mock.calls.GetUserByUsername = append(mock.calls.GetUserByUsername, callInfo)
+	mock.lockGetUserByUsername.Unlock()
+	return mock.GetUserByUsernameFunc(ctx, username)
+}
+
+// GetUserByUsernameCalls gets all the calls that were made to GetUserByUsername.
+// Check the length with:
+//
+//	len(mockedStore.GetUserByUsernameCalls())
+func (mock *StoreMock) GetUserByUsernameCalls() []struct {
+	Ctx      context.Context
+	Username string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Username string
+	}
+	mock.lockGetUserByUsername.RLock()
+	calls = mock.calls.GetUserByUsername
+	mock.lockGetUserByUsername.RUnlock()
+	return calls
+}
+
+// GetUserImport calls GetUserImportFunc.
+func (mock *StoreMock) GetUserImport(ctx context.Context, id int) (*database.UserImport, error) {
+	if mock.GetUserImportFunc == nil {
+		panic("StoreMock.GetUserImportFunc: method is nil but Store.GetUserImport was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Id  int
+	}{
+		Ctx: ctx,
+		Id:  id,
+	}
+	mock.lockGetUserImport.Lock()
+	mock.calls.GetUserImport = append(mock.calls.GetUserImport, callInfo)
+	mock.lockGetUserImport.Unlock()
+	return mock.GetUserImportFunc(ctx, id)
+}
+
+// GetUserImportCalls gets all the calls that were made to GetUserImport.
+// Check the length with:
+//
+//	len(mockedStore.GetUserImportCalls())
+func (mock *StoreMock) GetUserImportCalls() []struct {
+	Ctx context.Context
+	Id  int
+} {
+	var calls []struct {
+		Ctx context.Context
+		Id  int
+	}
+	mock.lockGetUserImport.RLock()
+	calls = mock.calls.GetUserImport
+	mock.lockGetUserImport.RUnlock()
+	return calls
+}
+
+// GetUsers calls GetUsersFunc.
+func (mock *StoreMock) GetUsers(ctx context.Context) ([]database.User, error) {
+	if mock.GetUsersFunc == nil {
+		panic("StoreMock.GetUsersFunc: method is nil but Store.GetUsers was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockGetUsers.Lock()
+	mock.calls.GetUsers = append(mock.calls.GetUsers, callInfo)
+	mock.lockGetUsers.Unlock()
+	return mock.GetUsersFunc(ctx)
+}
+
+// GetUsersCalls gets all the calls that were made to GetUsers.
+// Check the length with:
+//
+//	len(mockedStore.GetUsersCalls())
+func (mock *StoreMock) GetUsersCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockGetUsers.RLock()
+	calls = mock.calls.GetUsers
+	mock.lockGetUsers.RUnlock()
+	return calls
+}
+
+// GetWeatherCache calls GetWeatherCacheFunc.
+func (mock *StoreMock) GetWeatherCache(ctx context.Context, location string) (*database.WeatherCache, error) {
+	if mock.GetWeatherCacheFunc == nil {
+		panic("StoreMock.GetWeatherCacheFunc: method is nil but Store.GetWeatherCache was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Location string
+	}{
+		Ctx:      ctx,
+		Location: location,
+	}
+	mock.lockGetWeatherCache.Lock()
+	mock.calls.GetWeatherCache = append(mock.calls.GetWeatherCache, callInfo)
+	mock.lockGetWeatherCache.Unlock()
+	return mock.GetWeatherCacheFunc(ctx, location)
+}
+
+// GetWeatherCacheCalls gets all the calls that were made to GetWeatherCache.
+// Check the length with:
+//
+//	len(mockedStore.GetWeatherCacheCalls())
+func (mock *StoreMock) GetWeatherCacheCalls() []struct {
+	Ctx      context.Context
+	Location string
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Location string
+	}
+	mock.lockGetWeatherCache.RLock()
+	calls = mock.calls.GetWeatherCache
+	mock.lockGetWeatherCache.RUnlock()
+	return calls
+}
+
+// InsertUsers calls InsertUsersFunc.
+func (mock *StoreMock) InsertUsers(ctx context.Context, users []database.User) ([]bool, error) {
+	if mock.InsertUsersFunc == nil {
+		panic("StoreMock.InsertUsersFunc: method is nil but Store.InsertUsers was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Users []database.User
+	}{
+		Ctx:   ctx,
+		Users: users,
+	}
+	mock.lockInsertUsers.Lock()
+	mock.calls.InsertUsers = append(mock.calls.InsertUsers, callInfo)
+	mock.lockInsertUsers.Unlock()
+	return mock.InsertUsersFunc(ctx, users)
+}
+
+// InsertUsersCalls gets all the calls that were made to InsertUsers.
+// Check the length with:
+//
+//	len(mockedStore.InsertUsersCalls())
+func (mock *StoreMock) InsertUsersCalls() []struct {
+	Ctx   context.Context
+	Users []database.User
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Users []database.User
+	}
+	mock.lockInsertUsers.RLock()
+	calls = mock.calls.InsertUsers
+	mock.lockInsertUsers.RUnlock()
+	return calls
+}
+
+// LogRequest calls LogRequestFunc.
+func (mock *StoreMock) LogRequest(ctx context.Context, traceID string, spanID string, requestID string, endpoint string, method string, statusCode int, durationMs int64) error {
+	if mock.LogRequestFunc == nil {
+		panic("StoreMock.LogRequestFunc: method is nil but Store.LogRequest was just called")
+	}
+	callInfo := struct {
+		Ctx        context.Context
+		TraceID    string
+		SpanID     string
+		RequestID  string
+		Endpoint   string
+		Method     string
+		StatusCode int
+		DurationMs int64
+	}{
+		Ctx:        ctx,
+		TraceID:    traceID,
+		SpanID:     spanID,
+		RequestID:  requestID,
+		Endpoint:   endpoint,
+		Method:     method,
+		StatusCode: statusCode,
+		DurationMs: durationMs,
+	}
+	mock.lockLogRequest.Lock()
+	mock.calls.LogRequest = append(mock.calls.LogRequest, callInfo)
+	mock.lockLogRequest.Unlock()
+	return mock.LogRequestFunc(ctx, traceID, spanID, requestID, endpoint, method, statusCode, durationMs)
+}
+
+// LogRequestCalls gets all the calls that were made to LogRequest.
+// Check the length with:
+//
+//	len(mockedStore.LogRequestCalls())
+func (mock *StoreMock) LogRequestCalls() []struct {
+	Ctx        context.Context
+	TraceID    string
+	SpanID     string
+	RequestID  string
+	Endpoint   string
+	Method     string
+	StatusCode int
+	DurationMs int64
+} {
+	var calls []struct {
+		Ctx        context.Context
+		TraceID    string
+		SpanID     string
+		RequestID  string
+		Endpoint   string
+		Method     string
+		StatusCode int
+		DurationMs int64
+	}
+	mock.lockLogRequest.RLock()
+	calls = mock.calls.LogRequest
+	mock.lockLogRequest.RUnlock()
+	return calls
+}
+
+// PingContext calls PingContextFunc.
+func (mock *StoreMock) PingContext(ctx context.Context) error {
+	if mock.PingContextFunc == nil {
+		panic("StoreMock.PingContextFunc: method is nil but Store.PingContext was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+	}{
+		Ctx: ctx,
+	}
+	mock.lockPingContext.Lock()
+	mock.calls.PingContext = append(mock.calls.PingContext, callInfo)
+	mock.lockPingContext.Unlock()
+	return mock.PingContextFunc(ctx)
+}
+
+// PingContextCalls gets all the calls that were made to PingContext.
+// Check the length with:
+//
+//	len(mockedStore.PingContextCalls())
+func (mock *StoreMock) PingContextCalls() []struct {
+	Ctx context.Context
+} {
+	var calls []struct {
+		Ctx context.Context
+	}
+	mock.lockPingContext.RLock()
+	calls = mock.calls.PingContext
+	mock.lockPingContext.RUnlock()
+	return calls
+}
+
+// RecordDeployment calls RecordDeploymentFunc.
+func (mock *StoreMock) RecordDeployment(ctx context.Context, version string, commit string) (*database.Deployment, bool, error) {
+	if mock.RecordDeploymentFunc == nil {
+		panic("StoreMock.RecordDeploymentFunc: method is nil but Store.RecordDeployment was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Version string
+		Commit  string
+	}{
+		Ctx:     ctx,
+		Version: version,
+		Commit:  commit,
+	}
+	mock.lockRecordDeployment.Lock()
+	mock.calls.RecordDeployment = append(mock.calls.RecordDeployment, callInfo)
+	mock.lockRecordDeployment.Unlock()
+	return mock.RecordDeploymentFunc(ctx, version, commit)
+}
+
+// RecordDeploymentCalls gets all the calls that were made to RecordDeployment.
+// Check the length with:
+//
+//	len(mockedStore.RecordDeploymentCalls())
+func (mock *StoreMock) RecordDeploymentCalls() []struct {
+	Ctx     context.Context
+	Version string
+	Commit  string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Version string
+		Commit  string
+	}
+	mock.lockRecordDeployment.RLock()
+	calls = mock.calls.RecordDeployment
+	mock.lockRecordDeployment.RUnlock()
+	return calls
+}
+
+// SaveAuditEntry calls SaveAuditEntryFunc.
+func (mock *StoreMock) SaveAuditEntry(ctx context.Context, e database.AuditEntry) error {
+	if mock.SaveAuditEntryFunc == nil {
+		panic("StoreMock.SaveAuditEntryFunc: method is nil but Store.SaveAuditEntry was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		E   database.AuditEntry
+	}{
+		Ctx: ctx,
+		E:   e,
+	}
+	mock.lockSaveAuditEntry.Lock()
+	mock.calls.SaveAuditEntry = append(mock.calls.SaveAuditEntry, callInfo)
+	mock.lockSaveAuditEntry.Unlock()
+	return mock.SaveAuditEntryFunc(ctx, e)
+}
+
+// SaveAuditEntryCalls gets all the calls that were made to SaveAuditEntry.
+// Check the length with:
+//
+//	len(mockedStore.SaveAuditEntryCalls())
+func (mock *StoreMock) SaveAuditEntryCalls() []struct {
+	Ctx context.Context
+	E   database.AuditEntry
+} {
+	var calls []struct {
+		Ctx context.Context
+		E   database.AuditEntry
+	}
+	mock.lockSaveAuditEntry.RLock()
+	calls = mock.calls.SaveAuditEntry
+	mock.lockSaveAuditEntry.RUnlock()
+	return calls
+}
+
+// SaveQuote calls SaveQuoteFunc.
+func (mock *StoreMock) SaveQuote(ctx context.Context, content string, author string, source string) (bool, error) {
+	if mock.SaveQuoteFunc == nil {
+		panic("StoreMock.SaveQuoteFunc: method is nil but Store.SaveQuote was just called")
+	}
+	callInfo := struct {
+		Ctx     context.Context
+		Content string
+		Author  string
+		Source  string
+	}{
+		Ctx:     ctx,
+		Content: content,
+		Author:  author,
+		Source:  source,
+	}
+	mock.lockSaveQuote.Lock()
+	mock.calls.SaveQuote = append(mock.calls.SaveQuote, callInfo)
+	mock.lockSaveQuote.Unlock()
+	return mock.SaveQuoteFunc(ctx, content, author, source)
+}
+
+// SaveQuoteCalls gets all the calls that were made to SaveQuote.
+// Check the length with:
+//
+//	len(mockedStore.SaveQuoteCalls())
+func (mock *StoreMock) SaveQuoteCalls() []struct {
+	Ctx     context.Context
+	Content string
+	Author  string
+	Source  string
+} {
+	var calls []struct {
+		Ctx     context.Context
+		Content string
+		Author  string
+		Source  string
+	}
+	mock.lockSaveQuote.RLock()
+	calls = mock.calls.SaveQuote
+	mock.lockSaveQuote.RUnlock()
+	return calls
+}
+
+// SaveWeatherCache calls SaveWeatherCacheFunc.
+func (mock *StoreMock) SaveWeatherCache(ctx context.Context, location string, data []byte) error {
+	if mock.SaveWeatherCacheFunc == nil {
+		panic("StoreMock.SaveWeatherCacheFunc: method is nil but Store.SaveWeatherCache was just called")
+	}
+	callInfo := struct {
+		Ctx      context.Context
+		Location string
+		Data     []byte
+	}{
+		Ctx:      ctx,
+		Location: location,
+		Data:     data,
+	}
+	mock.lockSaveWeatherCache.Lock()
+	mock.calls.SaveWeatherCache = append(mock.calls.SaveWeatherCache, callInfo)
+	mock.lockSaveWeatherCache.Unlock()
+	return mock.SaveWeatherCacheFunc(ctx, location, data)
+}
+
+// SaveWeatherCacheCalls gets all the calls that were made to SaveWeatherCache.
+// Check the length with:
+//
+//	len(mockedStore.SaveWeatherCacheCalls())
+func (mock *StoreMock) SaveWeatherCacheCalls() []struct {
+	Ctx      context.Context
+	Location string
+	Data     []byte
+} {
+	var calls []struct {
+		Ctx      context.Context
+		Location string
+		Data     []byte
+	}
+	mock.lockSaveWeatherCache.RLock()
+	calls = mock.calls.SaveWeatherCache
+	mock.lockSaveWeatherCache.RUnlock()
+	return calls
+}
+
+// SearchQuotes calls SearchQuotesFunc.
+func (mock *StoreMock) SearchQuotes(ctx context.Context, query string, limit int) ([]database.QuoteMatch, error) {
+	if mock.SearchQuotesFunc == nil {
+		panic("StoreMock.SearchQuotesFunc: method is nil but Store.SearchQuotes was just called")
+	}
+	callInfo := struct {
+		Ctx   context.Context
+		Query string
+		Limit int
+	}{
+		Ctx:   ctx,
+		Query: query,
+		Limit: limit,
+	}
+	mock.lockSearchQuotes.Lock()
+	mock.calls.SearchQuotes = append(mock.calls.SearchQuotes, callInfo)
+	mock.lockSearchQuotes.Unlock()
+	return mock.SearchQuotesFunc(ctx, query, limit)
+}
+
+// SearchQuotesCalls gets all the calls that were made to SearchQuotes.
+// Check the length with:
+//
+//	len(mockedStore.SearchQuotesCalls())
+func (mock *StoreMock) SearchQuotesCalls() []struct {
+	Ctx   context.Context
+	Query string
+	Limit int
+} {
+	var calls []struct {
+		Ctx   context.Context
+		Query string
+		Limit int
+	}
+	mock.lockSearchQuotes.RLock()
+	calls = mock.calls.SearchQuotes
+	mock.lockSearchQuotes.RUnlock()
+	return calls
+}
+
+// UpdateUser calls UpdateUserFunc.
+func (mock *StoreMock) UpdateUser(ctx context.Context, u database.User) (*database.User, error) {
+	if mock.UpdateUserFunc == nil {
+		panic("StoreMock.UpdateUserFunc: method is nil but Store.UpdateUser was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		U   database.User
+	}{
+		Ctx: ctx,
+		U:   u,
+	}
+	mock.lockUpdateUser.Lock()
+	mock.calls.UpdateUser = append(mock.calls.UpdateUser, callInfo)
+	mock.lockUpdateUser.Unlock()
+	return mock.UpdateUserFunc(ctx, u)
+}
+
+// UpdateUserCalls gets all the calls that were made to UpdateUser.
+// Check the length with:
+//
+//	len(mockedStore.UpdateUserCalls())
+func (mock *StoreMock) UpdateUserCalls() []struct {
+	Ctx context.Context
+	U   database.User
+} {
+	var calls []struct {
+		Ctx context.Context
+		U   database.User
+	}
+	mock.lockUpdateUser.RLock()
+	calls = mock.calls.UpdateUser
+	mock.lockUpdateUser.RUnlock()
+	return calls
+}
+
+// UpdateUserImport calls UpdateUserImportFunc.
+func (mock *StoreMock) UpdateUserImport(ctx context.Context, imp *database.UserImport) error {
+	if mock.UpdateUserImportFunc == nil {
+		panic("StoreMock.UpdateUserImportFunc: method is nil but Store.UpdateUserImport was just called")
+	}
+	callInfo := struct {
+		Ctx context.Context
+		Imp *database.UserImport
+	}{
+		Ctx: ctx,
+		Imp: imp,
+	}
+	mock.lockUpdateUserImport.Lock()
+	mock.calls.UpdateUserImport = append(mock.calls.UpdateUserImport, callInfo)
+	mock.lockUpdateUserImport.Unlock()
+	return mock.UpdateUserImportFunc(ctx, imp)
+}
+
+// UpdateUserImportCalls gets all the calls that were made to UpdateUserImport.
+// Check the length with:
+//
+//	len(mockedStore.UpdateUserImportCalls())
+func (mock *StoreMock) UpdateUserImportCalls() []struct {
+	Ctx context.Context
+	Imp *database.UserImport
+} {
+	var calls []struct {
+		Ctx context.Context
+		Imp *database.UserImport
+	}
+	mock.lockUpdateUserImport.RLock()
+	calls = mock.calls.UpdateUserImport
+	mock.lockUpdateUserImport.RUnlock()
+	return calls
+}
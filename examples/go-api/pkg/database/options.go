@@ -0,0 +1,67 @@
+package database
+
+import (
+	"time"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+// Option adjusts a Config before New opens the connection pool
+type Option func(*Config)
+
+// WithPoolSize sets the maximum open and idle connections
+func WithPoolSize(maxOpen, maxIdle int) Option {
+	return func(c *Config) {
+		c.MaxOpenConns = maxOpen
+		c.MaxIdleConns = maxIdle
+	}
+}
+
+// WithMaxLifetime sets how long a connection may be reused
+func WithMaxLifetime(d time.Duration) Option {
+	return func(c *Config) { c.MaxLifetime = d }
+}
+
+// WithSessionSettings sets the application_name, statement_timeout and
+// lock_timeout of every Postgres connection
+func WithSessionSettings(applicationName string, statementTimeout, lockTimeout time.Duration) Option {
+	return func(c *Config) {
+		c.ApplicationName = applicationName
+		c.StatementTimeout = statementTimeout
+		c.LockTimeout = lockTimeout
+	}
+}
+
+// WithRetry sets the retry policy for transient errors
+func WithRetry(r RetryConfig) Option {
+	return func(c *Config) { c.Retry = r }
+}
+
+// WithMaxStatementLength sets the truncation length of db.statement attributes
+func WithMaxStatementLength(n int) Option {
+	return func(c *Config) { c.MaxStatementLength = n }
+}
+
+// WithWeatherCacheTTL sets the weather cache freshness per location class
+func WithWeatherCacheTTL(ttl WeatherCacheTTL) Option {
+	return func(c *Config) { c.WeatherCacheTTL = ttl }
+}
+
+// WithQueryTimeouts sets the statement timeouts per query class and the
+// metrics that count them
+func WithQueryTimeouts(t QueryTimeouts, m *QueryMetrics) Option {
+	return func(c *Config) {
+		c.QueryTimeouts = t
+		c.QueryMetrics = m
+	}
+}
+
+// WithSlowQuery sets the slow query threshold and plan capture
+func WithSlowQuery(sq SlowQueryConfig) Option {
+	return func(c *Config) { c.SlowQuery = sq }
+}
+
+// WithLogger sets the logger for retry and pool events
+func WithLogger(l *logger.Logger) Option {
+	return func(c *Config) { c.Logger = l }
+}
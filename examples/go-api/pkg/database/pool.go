@@ -0,0 +1,163 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/metrics"
+)
+
+// PoolMetrics holds Prometheus metrics for connection pool saturation
+type PoolMetrics struct {
+	OpenConnections    prometheus.Gauge
+	InUseConnections   prometheus.Gauge
+	IdleConnections    prometheus.Gauge
+	MaxOpenConnections prometheus.Gauge
+	Saturation         prometheus.Gauge
+	WaitCount          prometheus.Counter
+	WaitDuration       prometheus.Counter
+}
+
+// NewPoolMetrics creates and registers connection pool metrics with reg,
+// or prometheus.DefaultRegisterer when reg is nil. Metrics already
+// registered with reg are reused.
+func NewPoolMetrics(namespace string, reg prometheus.Registerer) *PoolMetrics {
+	m := &PoolMetrics{
+		OpenConnections: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "db_pool_open_connections",
+				Help:      "Number of established connections, both in use and idle",
+			},
+		),
+		InUseConnections: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "db_pool_in_use_connections",
+				Help:      "Number of connections currently in use",
+			},
+		),
+		IdleConnections: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "db_pool_idle_connections",
+				Help:      "Number of idle connections",
+			},
+		),
+		MaxOpenConnections: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "db_pool_max_open_connections",
+				Help:      "Maximum number of open connections allowed (0 means unlimited)",
+			},
+		),
+		Saturation: prometheus.NewGauge(
+			prometheus.GaugeOpts{
+				Namespace: namespace,
+				Name:      "db_pool_saturation_ratio",
+				Help:      "Ratio of in-use connections to the max-open limit",
+			},
+		),
+		WaitCount: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "db_pool_wait_count_total",
+				Help:      "Total number of connections waited for",
+			},
+		),
+		WaitDuration: prometheus.NewCounter(
+			prometheus.CounterOpts{
+				Namespace: namespace,
+				Name:      "db_pool_wait_duration_seconds_total",
+				Help:      "Total time blocked waiting for a new connection",
+			},
+		),
+	}
+
+	m.OpenConnections = metrics.MustGetOrRegister(reg, m.OpenConnections)
+	m.InUseConnections = metrics.MustGetOrRegister(reg, m.InUseConnections)
+	m.IdleConnections = metrics.MustGetOrRegister(reg, m.IdleConnections)
+	m.MaxOpenConnections = metrics.MustGetOrRegister(reg, m.MaxOpenConnections)
+	m.Saturation = metrics.MustGetOrRegister(reg, m.Saturation)
+	m.WaitCount = metrics.MustGetOrRegister(reg, m.WaitCount)
+	m.WaitDuration = metrics.MustGetOrRegister(reg, m.WaitDuration)
+
+	return m
+}
+
+// PoolMonitorConfig holds connection pool monitor configuration
+type PoolMonitorConfig struct {
+	Interval          time.Duration // How often pool stats are sampled
+	WaitWarnThreshold time.Duration // Wait time per interval that triggers a warning
+}
+
+// MonitorPool samples pool statistics until ctx is cancelled, updating metrics
+// and logging a warning when connection waits cross the configured threshold
+func (db *DB) MonitorPool(ctx context.Context, m *PoolMetrics, log *logger.Logger, cfg PoolMonitorConfig) {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 10 * time.Second
+	}
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	// sql.DBStats wait counters are cumulative, so track the previous sample
+	// and feed only the delta into the Prometheus counters
+	prev := db.Stats()
+	observePoolStats(m, prev)
+	m.WaitCount.Add(float64(prev.WaitCount))
+	m.WaitDuration.Add(prev.WaitDuration.Seconds())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		stats := db.Stats()
+		observePoolStats(m, stats)
+
+		waitCount := stats.WaitCount - prev.WaitCount
+		waitDuration := stats.WaitDuration - prev.WaitDuration
+		m.WaitCount.Add(float64(waitCount))
+		m.WaitDuration.Add(waitDuration.Seconds())
+		prev = stats
+
+		log := logger.OrDefault(log)
+		if cfg.WaitWarnThreshold > 0 && waitDuration >= cfg.WaitWarnThreshold {
+			warnLog := log.WithFields(ctx, map[string]interface{}{
+				"wait_count":       waitCount,
+				"wait_duration_ms": waitDuration.Milliseconds(),
+				"threshold_ms":     cfg.WaitWarnThreshold.Milliseconds(),
+				"in_use":           stats.InUse,
+				"max_open":         stats.MaxOpenConnections,
+			})
+			warnLog.Warn().Msg("Database connection pool wait time exceeded threshold")
+		}
+
+		if stats.MaxOpenConnections > 0 && stats.InUse >= stats.MaxOpenConnections {
+			warnLog := log.WithFields(ctx, map[string]interface{}{
+				"in_use":   stats.InUse,
+				"max_open": stats.MaxOpenConnections,
+			})
+			warnLog.Warn().Msg("Database connection pool saturated")
+		}
+	}
+}
+
+func observePoolStats(m *PoolMetrics, stats sql.DBStats) {
+	m.OpenConnections.Set(float64(stats.OpenConnections))
+	m.InUseConnections.Set(float64(stats.InUse))
+	m.IdleConnections.Set(float64(stats.Idle))
+	m.MaxOpenConnections.Set(float64(stats.MaxOpenConnections))
+	if stats.MaxOpenConnections > 0 {
+		m.Saturation.Set(float64(stats.InUse) / float64(stats.MaxOpenConnections))
+	} else {
+		m.Saturation.Set(0)
+	}
+}
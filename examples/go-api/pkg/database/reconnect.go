@@ -0,0 +1,50 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+// Reconnect retries New every interval in the background until a connection
+// succeeds or ctx is cancelled, then hands the live pool to onConnect. It
+// lets the service recover from a database that came up after the app did.
+func Reconnect(ctx context.Context, cfg Config, interval time.Duration, onConnect func(*DB)) {
+	if interval <= 0 {
+		interval = 15 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		attemptCtx, cancel := context.WithTimeout(ctx, interval)
+		db, err := New(attemptCtx, cfg)
+		cancel()
+
+		if err != nil {
+			retryLog := logger.OrDefault(cfg.Logger).WithFields(ctx, map[string]interface{}{
+				"attempt":     attempt,
+				"interval_ms": interval.Milliseconds(),
+			})
+			retryLog.Debug().Err(err).Msg("Database reconnect attempt failed")
+			continue
+		}
+
+		recoveredLog := logger.OrDefault(cfg.Logger).WithFields(ctx, map[string]interface{}{
+			"attempts":    attempt,
+			"downtime_ms": time.Since(start).Milliseconds(),
+		})
+		recoveredLog.Info().Msg("Database connection recovered")
+		onConnect(db)
+		return
+	}
+}
@@ -0,0 +1,73 @@
+package database
+
+import "context"
+
+//go:generate moq -out mocks/repository_moq.go -pkg mocks . UserStore UserImportStore QuoteStore WeatherCacheStore RequestLogStore AuditStore DeploymentStore Store
+
+// UserStore reads, inserts, updates and soft-deletes user records.
+// Deleted users are not read.
+type UserStore interface {
+	GetUsers(ctx context.Context) ([]User, error)
+	GetUserByUsername(ctx context.Context, username string) (*User, error)
+	InsertUsers(ctx context.Context, users []User) ([]bool, error)
+	UpdateUser(ctx context.Context, u User) (*User, error)
+	DeleteUser(ctx context.Context, username string) (bool, error)
+}
+
+// UserImportStore persists the progress of bulk user imports
+type UserImportStore interface {
+	CreateUserImport(ctx context.Context, total int, traceID string) (*UserImport, error)
+	UpdateUserImport(ctx context.Context, imp *UserImport) error
+	GetUserImport(ctx context.Context, id int) (*UserImport, error)
+}
+
+// QuoteStore stores and reads fetched quotes. SaveQuote reports whether
+// the quote was new; a quote already stored with the same content and author
+// is not saved again. Deleted quotes are not read.
+type QuoteStore interface {
+	SaveQuote(ctx context.Context, content, author, source string) (bool, error)
+	DeleteQuote(ctx context.Context, id int) (bool, error)
+	GetQuotes(ctx context.Context, limit int) ([]Quote, error)
+	GetQuoteStats(ctx context.Context) ([]QuoteStats, error)
+	SearchQuotes(ctx context.Context, query string, limit int) ([]QuoteMatch, error)
+}
+
+// WeatherCacheStore caches weather API responses
+type WeatherCacheStore interface {
+	SaveWeatherCache(ctx context.Context, location string, data []byte) error
+	GetWeatherCache(ctx context.Context, location string) (*WeatherCache, error)
+}
+
+// RequestLogStore records requests for analytics
+type RequestLogStore interface {
+	LogRequest(ctx context.Context, traceID, spanID, requestID, endpoint, method string, statusCode int, durationMs int64) error
+	GetRequestLogs(ctx context.Context, limit int) ([]RequestLog, error)
+}
+
+// AuditStore records admin endpoint usage
+type AuditStore interface {
+	SaveAuditEntry(ctx context.Context, e AuditEntry) error
+	GetAuditEntries(ctx context.Context, limit int) ([]AuditEntry, error)
+}
+
+// DeploymentStore tracks which version last ran, to mark releases
+type DeploymentStore interface {
+	RecordDeployment(ctx context.Context, version, commit string) (*Deployment, bool, error)
+}
+
+// Store combines every repository with connection lifecycle methods so
+// handlers can depend on an interface rather than *DB
+type Store interface {
+	UserStore
+	UserImportStore
+	QuoteStore
+	WeatherCacheStore
+	RequestLogStore
+	AuditStore
+	DeploymentStore
+	PingContext(ctx context.Context) error
+	Close() error
+}
+
+// Ensure *DB satisfies every repository interface
+var _ Store = (*DB)(nil)
@@ -0,0 +1,145 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+	"time"
+
+	"github.com/lib/pq"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+// RetryConfig holds retry configuration for transient database errors
+type RetryConfig struct {
+	MaxAttempts    int           // Total attempts including the first one (<= 1 disables retries)
+	InitialBackoff time.Duration // Delay before the first retry
+	MaxBackoff     time.Duration // Upper bound for the exponential backoff
+}
+
+// DefaultRetryConfig returns the retry settings used when none are configured
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxAttempts:    3,
+		InitialBackoff: 50 * time.Millisecond,
+		MaxBackoff:     1 * time.Second,
+	}
+}
+
+// IsRetryable reports whether err is a transient failure worth retrying:
+// serialization failures, deadlocks, server shutdowns during failover and
+// dropped connections. Constraint violations, syntax errors and context
+// cancellation are permanent.
+func IsRetryable(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrQueryTimeout) {
+		return false
+	}
+
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		switch pqErr.Code {
+		case "40001", // serialization_failure
+			"40P01", // deadlock_detected
+			"57P01", // admin_shutdown
+			"57P02", // crash_shutdown
+			"57P03", // cannot_connect_now
+			"25006": // read_only_sql_transaction (primary demoted after failover)
+			return true
+		}
+		// Class 08 - connection exception
+		return pqErr.Code.Class() == "08"
+	}
+
+	if errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// withRetry runs fn until it succeeds, fails permanently or exhausts the
+// configured attempts, each attempt under the timeout of op's class. Each
+// retry is recorded as a span event and logged.
+func (db *DB) withRetry(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	cfg := db.retry
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+
+	span := trace.SpanFromContext(ctx)
+	backoff := cfg.InitialBackoff
+
+	var err error
+	for attempt := 1; ; attempt++ {
+		err = db.withTimeout(ctx, op, fn)
+		if err == nil {
+			return nil
+		}
+
+		// A demoted primary keeps its pooled connections alive; drop them so
+		// the retry dials whichever host was promoted
+		if isReadOnlyError(err) && db.connector != nil {
+			db.connector.invalidate()
+		}
+
+		retryable := IsRetryable(err)
+		if !retryable || attempt >= cfg.MaxAttempts {
+			// Permanent errors on the first attempt are the caller's to report
+			if attempt > 1 || retryable {
+				span.AddEvent("db.retry.exhausted", trace.WithAttributes(
+					attribute.String("db.operation", op),
+					attribute.Int("db.retry.attempt", attempt),
+					attribute.Bool("db.retry.retryable", retryable),
+				))
+				failLog := logger.OrDefault(db.log).WithFields(ctx, map[string]interface{}{
+					"db.operation": op,
+					"attempt":      attempt,
+					"retryable":    retryable,
+				})
+				failLog.Error().Err(err).Msg("Database operation failed after retries")
+			}
+			return err
+		}
+
+		span.AddEvent("db.retry", trace.WithAttributes(
+			attribute.String("db.operation", op),
+			attribute.Int("db.retry.attempt", attempt),
+			attribute.Int64("db.retry.backoff_ms", backoff.Milliseconds()),
+			attribute.String("exception.message", err.Error()),
+		))
+		retryLog := logger.OrDefault(db.log).WithFields(ctx, map[string]interface{}{
+			"db.operation": op,
+			"attempt":      attempt,
+			"backoff_ms":   backoff.Milliseconds(),
+		})
+		retryLog.Warn().Err(err).Msg("Transient database error, retrying")
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if cfg.MaxBackoff > 0 && backoff > cfg.MaxBackoff {
+			backoff = cfg.MaxBackoff
+		}
+	}
+}
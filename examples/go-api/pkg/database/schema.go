@@ -0,0 +1,203 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/metrics"
+)
+
+// Kinds of schema drift
+const (
+	DriftMissingTable  = "missing_table"
+	DriftMissingColumn = "missing_column"
+	DriftMissingIndex  = "missing_index"
+)
+
+// expectedTables are the tables and columns this version reads and writes:
+// the init script in k8s/postgres/configmap.yaml plus every column added
+// since by the migrations in the README. Keep it in step with both, and
+// with sqliteSchema.
+var expectedTables = []struct {
+	name    string
+	columns []string
+}{
+	{"users", []string{"id", "username", "email", "version", "created_at", "updated_at", "created_by", "updated_by", "deleted_at"}},
+	{"quotes", []string{"id", "content", "author", "fetched_at", "source", "updated_at", "created_by", "updated_by", "deleted_at"}},
+	{"weather_cache", []string{"id", "location", "data", "cached_at", "expires_at"}},
+	{"request_logs", []string{"id", "trace_id", "span_id", "request_id", "endpoint", "method", "status_code", "duration_ms", "created_at"}},
+	{"admin_audit_log", []string{"id", "actor", "action", "resource", "remote_addr", "user_agent", "status_code", "trace_id", "request_id", "created_at"}},
+	{"deployments", []string{"id", "version", "commit_sha", "deployed_at"}},
+	{"user_imports", []string{"id", "status", "total", "processed", "imported", "failed", "errors", "error_message", "trace_id", "created_at", "updated_at", "finished_at"}},
+}
+
+// expectedIndexes are the indexes queries rely on for correctness or speed
+var expectedIndexes = []struct {
+	table, name  string
+	postgresOnly bool
+}{
+	{"quotes", "idx_quotes_content_author", false}, // Deduplicates concurrent saves
+	{"quotes", "idx_quotes_search_vector", true},
+	{"weather_cache", "idx_weather_cache_expires_at", false},
+	{"request_logs", "idx_request_logs_created_at", false},
+	{"admin_audit_log", "idx_admin_audit_log_created_at", false},
+}
+
+// SchemaDrift is a difference between the expected and the live schema
+type SchemaDrift struct {
+	Kind   string `json:"kind"` // DriftMissingTable, DriftMissingColumn or DriftMissingIndex
+	Table  string `json:"table"`
+	Object string `json:"object,omitempty"` // Column or index name
+}
+
+// CheckSchema compares the live schema with the one this version expects
+// and returns what is missing. Extra tables, columns and indexes are not
+// drift: a newer version may have added them during a rollout.
+func (db *DB) CheckSchema(ctx context.Context) ([]SchemaDrift, error) {
+	columnsQuery := `SELECT table_name, column_name FROM information_schema.columns WHERE table_schema = current_schema()`
+	indexesQuery := `SELECT indexname FROM pg_indexes WHERE schemaname = current_schema()`
+	if db.driver == DriverSQLite {
+		columnsQuery = `SELECT m.name, p.name FROM sqlite_master m JOIN pragma_table_info(m.name) p WHERE m.type = 'table'`
+		indexesQuery = `SELECT name FROM sqlite_master WHERE type = 'index'`
+	}
+
+	columns := map[string]map[string]bool{}
+	indexes := map[string]bool{}
+	err := db.withRetry(ctx, "check_schema", func(ctx context.Context) error {
+		clear(columns)
+		clear(indexes)
+
+		rows, err := db.QueryContext(ctx, columnsQuery)
+		if err != nil {
+			return fmt.Errorf("failed to query columns: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var table, column string
+			if err := rows.Scan(&table, &column); err != nil {
+				return fmt.Errorf("failed to scan column: %w", err)
+			}
+			if columns[table] == nil {
+				columns[table] = map[string]bool{}
+			}
+			columns[table][column] = true
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		idxRows, err := db.QueryContext(ctx, indexesQuery)
+		if err != nil {
+			return fmt.Errorf("failed to query indexes: %w", err)
+		}
+		defer idxRows.Close()
+		for idxRows.Next() {
+			var name string
+			if err := idxRows.Scan(&name); err != nil {
+				return fmt.Errorf("failed to scan index: %w", err)
+			}
+			indexes[name] = true
+		}
+		return idxRows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var drift []SchemaDrift
+	for _, t := range expectedTables {
+		live, ok := columns[t.name]
+		if !ok {
+			drift = append(drift, SchemaDrift{Kind: DriftMissingTable, Table: t.name})
+			continue
+		}
+		for _, c := range t.columns {
+			if !live[c] {
+				drift = append(drift, SchemaDrift{Kind: DriftMissingColumn, Table: t.name, Object: c})
+			}
+		}
+	}
+	for _, idx := range expectedIndexes {
+		if idx.postgresOnly && db.driver == DriverSQLite {
+			continue
+		}
+		if _, ok := columns[idx.table]; ok && !indexes[idx.name] {
+			drift = append(drift, SchemaDrift{Kind: DriftMissingIndex, Table: idx.table, Object: idx.name})
+		}
+	}
+	return drift, nil
+}
+
+// SchemaMetrics holds Prometheus metrics for schema drift
+type SchemaMetrics struct {
+	InSync prometheus.Gauge
+	Drift  *prometheus.GaugeVec
+}
+
+// NewSchemaMetrics creates and registers schema drift metrics with reg, or
+// prometheus.DefaultRegisterer when reg is nil
+func NewSchemaMetrics(namespace string, reg prometheus.Registerer) *SchemaMetrics {
+	m := &SchemaMetrics{
+		InSync: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "schema_in_sync",
+			Help:      "1 when the last schema check found the database schema as expected, 0 when it drifted or has not passed yet",
+		}),
+		Drift: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "schema_drift_objects",
+			Help:      "Tables, columns and indexes missing from the database schema at the last check, by kind",
+		}, []string{"kind"}),
+	}
+
+	m.InSync = metrics.MustGetOrRegister(reg, m.InSync)
+	m.Drift = metrics.MustGetOrRegister(reg, m.Drift)
+
+	return m
+}
+
+// schemaCheckTimeout bounds the startup schema check
+const schemaCheckTimeout = 10 * time.Second
+
+// ReportSchemaDrift runs CheckSchema, logs a warning per drift and updates
+// m, so a missed migration is noticed at startup rather than as failing
+// queries later. It returns whether the schema is in sync.
+func (db *DB) ReportSchemaDrift(ctx context.Context, m *SchemaMetrics, log *logger.Logger) bool {
+	log = logger.OrDefault(log)
+	ctx, cancel := context.WithTimeout(ctx, schemaCheckTimeout)
+	defer cancel()
+
+	drift, err := db.CheckSchema(ctx)
+	if err != nil {
+		l := log.WithContext(ctx)
+		l.Warn().Err(err).Msg("Database schema check failed")
+		return false
+	}
+
+	counts := map[string]int{DriftMissingTable: 0, DriftMissingColumn: 0, DriftMissingIndex: 0}
+	for _, d := range drift {
+		counts[d.Kind]++
+		l := log.WithFields(ctx, map[string]interface{}{
+			"drift":  d.Kind,
+			"table":  d.Table,
+			"object": d.Object,
+		})
+		l.Warn().Msg("Database schema drift: apply the missing migrations")
+	}
+	for kind, n := range counts {
+		m.Drift.WithLabelValues(kind).Set(float64(n))
+	}
+
+	if len(drift) > 0 {
+		m.InSync.Set(0)
+		return false
+	}
+	m.InSync.Set(1)
+	l := log.WithContext(ctx)
+	l.Info().Msg("Database schema in sync")
+	return true
+}
@@ -0,0 +1,70 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// QuoteMatch is a quote found by SearchQuotes. Rank orders matches by
+// relevance, higher first; it is 0 where the store cannot rank.
+type QuoteMatch struct {
+	Quote
+	Rank float64 `json:"rank"`
+}
+
+// SearchQuotes finds up to limit quotes whose content or author matches
+// query (traced query). Postgres matches words against the search_vector
+// column with its GIN index, so "stars -moon" and "\"to be\"" work as in a
+// web search box, and ranks by ts_rank. SQLite, for local development,
+// matches query as a substring and returns the newest first. Deleted quotes
+// are not searched.
+func (db *DB) SearchQuotes(ctx context.Context, query string, limit int) ([]QuoteMatch, error) {
+	stmt := `
+		SELECT id, content, author, fetched_at, source, COALESCE(created_by, ''), ts_rank(search_vector, q) AS rank
+		FROM quotes, websearch_to_tsquery('english', $1) q
+		WHERE search_vector @@ q AND deleted_at IS NULL
+		ORDER BY rank DESC, fetched_at DESC
+		LIMIT $2
+	`
+	arg := query
+	if db.driver == DriverSQLite {
+		stmt = `
+			SELECT id, content, author, fetched_at, source, COALESCE(created_by, ''), 0
+			FROM quotes
+			WHERE (content LIKE $1 ESCAPE '\' OR author LIKE $1 ESCAPE '\') AND deleted_at IS NULL
+			ORDER BY fetched_at DESC
+			LIMIT $2
+		`
+		arg = "%" + likeEscaper.Replace(query) + "%"
+	}
+
+	var matches []QuoteMatch
+	err := db.withRetry(ctx, "search_quotes", func(ctx context.Context) error {
+		matches = nil
+
+		rows, err := db.QueryContext(ctx, stmt, arg, limit)
+		if err != nil {
+			return fmt.Errorf("failed to search quotes: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var m QuoteMatch
+			if err := rows.Scan(&m.ID, &m.Content, &m.Author, &m.FetchedAt, &m.Source, &m.CreatedBy, &m.Rank); err != nil {
+				return fmt.Errorf("failed to scan quote: %w", err)
+			}
+			matches = append(matches, m)
+		}
+
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return matches, nil
+}
+
+// likeEscaper escapes LIKE wildcards so they match literally
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
@@ -0,0 +1,236 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/XSAM/otelsql"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/tracing"
+)
+
+// tracerName is the instrumentation scope of db.explain spans
+const tracerName = "github.com/example/go-api/pkg/database"
+
+// Limits of plan capture
+const (
+	explainTimeout     = 5 * time.Second
+	maxPlanLength      = 4096 // Bytes of plan kept on logs and span events
+	defaultExplainRate = time.Minute
+)
+
+// SlowQueryConfig controls slow query logging and plan capture
+type SlowQueryConfig struct {
+	Threshold       time.Duration // Operations slower than this are logged; zero disables
+	Explain         bool          // EXPLAIN the slowest statement of slow operations in the background
+	ExplainInterval time.Duration // At most one plan per operation per interval (default 1m)
+}
+
+// recordedStatement is a statement run by an operation, with its arguments
+type recordedStatement struct {
+	query string
+	args  []interface{}
+	start time.Time
+	took  time.Duration
+}
+
+type recorderKey struct{}
+
+// statementRecorder keeps the slowest statement of an operation attempt.
+// A statement is taken to run until the next one starts, so a statement's
+// time includes reading its rows.
+type statementRecorder struct {
+	mu      sync.Mutex
+	current *recordedStatement
+	slowest *recordedStatement
+}
+
+// recordStatement passes a statement run under ctx to its recorder, if any.
+// It is called by otelsql as each statement starts.
+func recordStatement(ctx context.Context, method otelsql.Method, query string, args []driver.NamedValue) {
+	rec, _ := ctx.Value(recorderKey{}).(*statementRecorder)
+	if rec == nil {
+		return
+	}
+	switch method {
+	case otelsql.MethodConnQuery, otelsql.MethodConnExec, otelsql.MethodStmtQuery, otelsql.MethodStmtExec:
+	default:
+		return
+	}
+
+	values := make([]interface{}, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	now := time.Now()
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	rec.finish(now)
+	rec.current = &recordedStatement{query: query, args: values, start: now}
+}
+
+// finish ends the current statement at end
+func (rec *statementRecorder) finish(end time.Time) {
+	if rec.current == nil {
+		return
+	}
+	rec.current.took = end.Sub(rec.current.start)
+	if rec.slowest == nil || rec.current.took > rec.slowest.took {
+		rec.slowest = rec.current
+	}
+	rec.current = nil
+}
+
+// explainer rate-limits plan capture
+type explainer struct {
+	interval time.Duration
+	mu       sync.Mutex
+	last     map[string]time.Time // Last plan captured per operation
+	busy     chan struct{}        // Holds a token while an EXPLAIN runs
+}
+
+func newExplainer(interval time.Duration) *explainer {
+	if interval <= 0 {
+		interval = defaultExplainRate
+	}
+	return &explainer{interval: interval, last: map[string]time.Time{}, busy: make(chan struct{}, 1)}
+}
+
+// acquire reports whether a plan of op may be captured now: no other
+// EXPLAIN is running and op was not explained within the interval. The
+// caller calls release when done.
+func (e *explainer) acquire(op string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if time.Since(e.last[op]) < e.interval {
+		return false
+	}
+	select {
+	case e.busy <- struct{}{}:
+	default:
+		return false
+	}
+	e.last[op] = time.Now()
+	return true
+}
+
+func (e *explainer) release() {
+	<-e.busy
+}
+
+// observe runs fn and logs it when it takes longer than the slow query
+// threshold. With Explain on, the slowest statement of a slow operation is
+// explained in the background, without ANALYZE so it is not run again.
+func (db *DB) observe(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	if db.slow.Threshold <= 0 {
+		return fn(ctx)
+	}
+
+	var rec *statementRecorder
+	if db.explainer != nil {
+		rec = &statementRecorder{}
+		ctx = context.WithValue(ctx, recorderKey{}, rec)
+	}
+	start := time.Now()
+	err := fn(ctx)
+	elapsed := time.Since(start)
+	if elapsed < db.slow.Threshold {
+		return err
+	}
+
+	slowLog := logger.OrDefault(db.log).WithFields(ctx, map[string]interface{}{
+		"db.operation":   op,
+		"db.query.class": string(classOf(op)),
+		"duration_ms":    elapsed.Milliseconds(),
+		"threshold_ms":   db.slow.Threshold.Milliseconds(),
+	})
+	slowLog.Warn().Msg("Slow database query")
+
+	if rec == nil {
+		return err
+	}
+	rec.mu.Lock()
+	rec.finish(start.Add(elapsed))
+	stmt := rec.slowest
+	rec.mu.Unlock()
+	if stmt != nil && db.explainer.acquire(op) {
+		go db.explain(context.WithoutCancel(ctx), op, stmt)
+	}
+	return err
+}
+
+// explain runs EXPLAIN on stmt and attaches the plan to a debug log entry
+// and a db.query.plan event on a db.explain span, a child of the span that
+// ran the statement
+func (db *DB) explain(ctx context.Context, op string, stmt *recordedStatement) {
+	defer db.explainer.release()
+	ctx = context.WithValue(ctx, recorderKey{}, (*statementRecorder)(nil))
+	ctx, cancel := context.WithTimeout(ctx, explainTimeout)
+	defer cancel()
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "db.explain", trace.WithAttributes(
+		attribute.String("db.operation", op),
+	))
+	defer span.End()
+
+	prefix := "EXPLAIN "
+	if db.driver == DriverSQLite {
+		prefix = "EXPLAIN QUERY PLAN "
+	}
+	plan, err := db.queryPlan(ctx, prefix+stmt.query, stmt.args)
+
+	planLog := logger.OrDefault(db.log).WithFields(ctx, map[string]interface{}{
+		"db.operation": op,
+		"duration_ms":  stmt.took.Milliseconds(),
+		"statement":    tracing.Truncate(strings.TrimSpace(stmt.query), maxPlanLength),
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		planLog.Debug().Err(err).Msg("Failed to explain slow database query")
+		return
+	}
+	plan = tracing.Truncate(plan, maxPlanLength)
+	span.AddEvent("db.query.plan", trace.WithAttributes(
+		attribute.String("db.operation", op),
+		attribute.Int64("db.query.duration_ms", stmt.took.Milliseconds()),
+		attribute.String("db.query.plan", plan),
+	))
+	planLog.Debug().Str("plan", plan).Msg("Slow database query plan")
+}
+
+// queryPlan runs an EXPLAIN query and joins its rows into one plan. The
+// plan text is the last column: Postgres returns one, SQLite four.
+func (db *DB) queryPlan(ctx context.Context, query string, args []interface{}) (string, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	dest := make([]interface{}, len(cols))
+	for i := range dest {
+		dest[i] = new(sql.RawBytes)
+	}
+
+	var lines []string
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		lines = append(lines, string(*dest[len(dest)-1].(*sql.RawBytes)))
+	}
+	return strings.Join(lines, "\n"), rows.Err()
+}
@@ -0,0 +1,97 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/tracing"
+)
+
+type actorKey struct{}
+
+// WithActor returns a context whose writes are stamped with actor, the
+// authenticated user making the request: created_by on inserts, updated_by
+// on updates and deletes, and the actor of delete audit entries
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorKey{}, actor)
+}
+
+// ActorFromContext returns the actor set by WithActor, or ""
+func ActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(actorKey{}).(string)
+	return actor
+}
+
+// stampedBy is the created_by or updated_by value of a write: the actor of
+// ctx, or NULL when the request is anonymous
+func stampedBy(ctx context.Context) sql.NullString {
+	actor := ActorFromContext(ctx)
+	return sql.NullString{String: actor, Valid: actor != ""}
+}
+
+// DeleteUser soft-deletes the user with username and reports whether one
+// was deleted. The row is kept with deleted_at set and is no longer read;
+// the delete is recorded in admin_audit_log in the same transaction.
+func (db *DB) DeleteUser(ctx context.Context, username string) (bool, error) {
+	query := `
+		UPDATE users SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, updated_by = $2
+		WHERE username = $1 AND deleted_at IS NULL
+	`
+	return db.softDelete(ctx, "delete_user", "users/"+username, query, username)
+}
+
+// DeleteQuote soft-deletes the quote with id and reports whether one was
+// deleted. A deleted quote is not saved again when fetched anew.
+func (db *DB) DeleteQuote(ctx context.Context, id int) (bool, error) {
+	query := `
+		UPDATE quotes SET deleted_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, updated_by = $2
+		WHERE id = $1 AND deleted_at IS NULL
+	`
+	return db.softDelete(ctx, "delete_quote", "quotes/"+strconv.Itoa(id), query, id)
+}
+
+// softDelete runs query, which sets deleted_at on the row identified by key
+// and updated_by from $2, and audits it. Deleting twice is a no-op, so the
+// transaction is safe to retry.
+func (db *DB) softDelete(ctx context.Context, op, resource, query string, key interface{}) (bool, error) {
+	actor := ActorFromContext(ctx)
+	if actor == "" {
+		actor = "anonymous"
+	}
+	audit := `
+		INSERT INTO admin_audit_log (actor, action, resource, trace_id, request_id)
+		VALUES ($1, 'DELETE', $2, $3, $4)
+	`
+
+	var deleted bool
+	err := db.withRetry(ctx, op, func(ctx context.Context) error {
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		res, err := tx.ExecContext(ctx, query, key, stampedBy(ctx))
+		if err != nil {
+			return err
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return err
+		}
+		if deleted = n > 0; !deleted {
+			return nil
+		}
+		if _, err := tx.ExecContext(ctx, audit, actor, resource, tracing.GetTraceID(ctx), logger.GetRequestID(ctx)); err != nil {
+			return err
+		}
+		return tx.Commit()
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to delete %s: %w", resource, err)
+	}
+	return deleted, nil
+}
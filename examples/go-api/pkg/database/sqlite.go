@@ -0,0 +1,176 @@
+//go:build sqlite
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/XSAM/otelsql"
+	_ "github.com/mattn/go-sqlite3"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// sqliteSchema mirrors the Postgres init script in k8s/postgres/configmap.yaml
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS users (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	username TEXT NOT NULL UNIQUE,
+	email TEXT NOT NULL,
+	version INTEGER NOT NULL DEFAULT 1,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	created_by TEXT,
+	updated_by TEXT,
+	deleted_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS quotes (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	content TEXT NOT NULL,
+	author TEXT,
+	fetched_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	source TEXT DEFAULT 'quotable.io',
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	created_by TEXT,
+	updated_by TEXT,
+	deleted_at TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS weather_cache (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	location TEXT NOT NULL UNIQUE,
+	data BLOB NOT NULL,
+	cached_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	expires_at TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_weather_cache_expires_at ON weather_cache(expires_at);
+
+CREATE TABLE IF NOT EXISTS request_logs (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	trace_id TEXT,
+	span_id TEXT,
+	request_id TEXT,
+	endpoint TEXT,
+	method TEXT,
+	status_code INTEGER,
+	duration_ms INTEGER,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS admin_audit_log (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	actor TEXT NOT NULL,
+	action TEXT NOT NULL,
+	resource TEXT NOT NULL,
+	remote_addr TEXT,
+	user_agent TEXT,
+	status_code INTEGER,
+	trace_id TEXT,
+	request_id TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS deployments (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	version TEXT NOT NULL,
+	commit_sha TEXT NOT NULL,
+	deployed_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (version, commit_sha)
+);
+
+CREATE TABLE IF NOT EXISTS user_imports (
+	id INTEGER PRIMARY KEY AUTOINCREMENT,
+	status TEXT NOT NULL,
+	total INTEGER NOT NULL DEFAULT 0,
+	processed INTEGER NOT NULL DEFAULT 0,
+	imported INTEGER NOT NULL DEFAULT 0,
+	failed INTEGER NOT NULL DEFAULT 0,
+	errors TEXT,
+	error_message TEXT,
+	trace_id TEXT,
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	finished_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_quotes_author ON quotes(author);
+-- Drop duplicates saved before quotes were deduplicated, keeping the oldest
+DELETE FROM quotes WHERE id NOT IN (SELECT MIN(id) FROM quotes GROUP BY content, author);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_quotes_content_author ON quotes(content, author);
+CREATE INDEX IF NOT EXISTS idx_request_logs_trace_id ON request_logs(trace_id);
+CREATE INDEX IF NOT EXISTS idx_request_logs_created_at ON request_logs(created_at);
+CREATE INDEX IF NOT EXISTS idx_admin_audit_log_created_at ON admin_audit_log(created_at);
+
+INSERT OR IGNORE INTO users (username, email) VALUES
+	('alice', 'alice@example.com'),
+	('bob', 'bob@example.com'),
+	('charlie', 'charlie@example.com');
+`
+
+// sqliteColumns were added after the first release of the schema. CREATE
+// TABLE IF NOT EXISTS leaves existing tables alone, so openSQLiteDB adds the
+// missing ones; SQLite has no ADD COLUMN IF NOT EXISTS.
+var sqliteColumns = []struct{ table, column, decl string }{
+	{"users", "created_by", "TEXT"},
+	{"users", "updated_by", "TEXT"},
+	{"users", "deleted_at", "TIMESTAMP"},
+	{"users", "version", "INTEGER NOT NULL DEFAULT 1"},
+	{"quotes", "updated_at", "TIMESTAMP"},
+	{"quotes", "created_by", "TEXT"},
+	{"quotes", "updated_by", "TEXT"},
+	{"quotes", "deleted_at", "TIMESTAMP"},
+}
+
+func init() {
+	openSQLite = openSQLiteDB
+}
+
+// addSQLiteColumns adds the sqliteColumns an older database file lacks
+func addSQLiteColumns(ctx context.Context, db *sql.DB) error {
+	for _, c := range sqliteColumns {
+		var n int
+		if err := db.QueryRowContext(ctx, `SELECT COUNT(*) FROM pragma_table_info(?) WHERE name = ?`, c.table, c.column).Scan(&n); err != nil {
+			return err
+		}
+		if n > 0 {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", c.table, c.column, c.decl)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// openSQLiteDB opens a traced SQLite database and creates the schema
+func openSQLiteDB(ctx context.Context, cfg Config) (*sql.DB, error) {
+	path := cfg.Path
+	if path == "" {
+		path = "go-api.db"
+	}
+
+	db, err := otelsql.Open("sqlite3", fmt.Sprintf("file:%s?_busy_timeout=5000&_foreign_keys=on", path),
+		otelsql.WithAttributes(
+			semconv.DBSystemSqlite,
+			semconv.DBName(path),
+		),
+		spanOptions,
+		statementAttributes(cfg.MaxStatementLength),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := db.ExecContext(ctx, sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to create sqlite schema: %w", err)
+	}
+	if err := addSQLiteColumns(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to migrate sqlite schema: %w", err)
+	}
+
+	return db, nil
+}
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/go-api/pkg/metrics"
+)
+
+// QueryClass groups statements that share a timeout
+type QueryClass string
+
+const (
+	ClassRead      QueryClass = "read"      // Lookups serving a request
+	ClassWrite     QueryClass = "write"     // Inserts, updates and deletes
+	ClassAnalytics QueryClass = "analytics" // Aggregates and scans over whole tables
+)
+
+// queryClasses classifies the store's operations by name. Operations not
+// listed are reads.
+var queryClasses = map[string]QueryClass{
+	"save_quote":                   ClassWrite,
+	"save_weather_cache":           ClassWrite,
+	"delete_user":                  ClassWrite,
+	"delete_quote":                 ClassWrite,
+	"update_user":                  ClassWrite,
+	"insert_users":                 ClassWrite,
+	"create_user_import":           ClassWrite,
+	"update_user_import":           ClassWrite,
+	"log_request":                  ClassWrite,
+	"save_audit_entry":             ClassWrite,
+	"record_deployment":            ClassWrite,
+	"delete_expired_weather_cache": ClassWrite,
+	"get_quote_stats":              ClassAnalytics,
+	"weather_cache_stats":          ClassAnalytics,
+	"get_request_logs":             ClassAnalytics,
+	"check_schema":                 ClassAnalytics,
+}
+
+// classOf returns the class of the operation op
+func classOf(op string) QueryClass {
+	if class, ok := queryClasses[op]; ok {
+		return class
+	}
+	return ClassRead
+}
+
+// QueryTimeouts bounds each statement by its class. A zero timeout leaves
+// the class bounded only by the caller's context.
+type QueryTimeouts struct {
+	Read      time.Duration
+	Write     time.Duration
+	Analytics time.Duration
+}
+
+// For returns the timeout of class
+func (t QueryTimeouts) For(class QueryClass) time.Duration {
+	switch class {
+	case ClassWrite:
+		return t.Write
+	case ClassAnalytics:
+		return t.Analytics
+	default:
+		return t.Read
+	}
+}
+
+// ErrQueryTimeout is wrapped by the errors of statements that ran past the
+// timeout of their class
+var ErrQueryTimeout = errors.New("query timeout")
+
+// QueryMetrics holds Prometheus metrics for statement timeouts
+type QueryMetrics struct {
+	Timeouts *prometheus.CounterVec
+}
+
+// NewQueryMetrics creates and registers query metrics with reg, or
+// prometheus.DefaultRegisterer when reg is nil
+func NewQueryMetrics(namespace string, reg prometheus.Registerer) *QueryMetrics {
+	m := &QueryMetrics{
+		Timeouts: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_query_timeouts_total",
+			Help:      "Statements cancelled for running past the timeout of their class, by class and operation",
+		}, []string{"class", "operation"}),
+	}
+
+	m.Timeouts = metrics.MustGetOrRegister(reg, m.Timeouts)
+
+	return m
+}
+
+// withTimeout runs fn under the timeout of op's class. A statement that
+// times out is counted in db_query_timeouts_total and recorded as a
+// db.query.timeout span event, and its error wraps ErrQueryTimeout.
+// Timeouts are not retried. When the caller's context ends first, the
+// error is left alone.
+func (db *DB) withTimeout(ctx context.Context, op string, fn func(ctx context.Context) error) error {
+	class := classOf(op)
+	timeout := db.timeouts.For(class)
+	if timeout <= 0 {
+		return db.observe(ctx, op, fn)
+	}
+
+	qctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	err := db.observe(qctx, op, fn)
+	if err == nil || qctx.Err() != context.DeadlineExceeded || ctx.Err() != nil {
+		return err
+	}
+
+	if db.queryMetrics != nil {
+		db.queryMetrics.Timeouts.WithLabelValues(string(class), op).Inc()
+	}
+	trace.SpanFromContext(ctx).AddEvent("db.query.timeout", trace.WithAttributes(
+		attribute.String("db.operation", op),
+		attribute.String("db.query.class", string(class)),
+		attribute.Int64("db.query.timeout_ms", timeout.Milliseconds()),
+	))
+	return fmt.Errorf("%w: %s query %s exceeded %s: %w", ErrQueryTimeout, class, op, timeout, err)
+}
@@ -0,0 +1,260 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/go-api/pkg/metrics"
+)
+
+// Cache stores byte values for cached repositories. MemoryCache keeps them
+// in the process; redis.Cache shares them between replicas.
+type Cache interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, val []byte, ttl time.Duration) error
+	Incr(ctx context.Context, key string) (int64, error)
+}
+
+// MemoryCache is a Cache local to one replica
+type MemoryCache struct {
+	mu      sync.Mutex
+	entries map[string]memoryEntry
+}
+
+type memoryEntry struct {
+	val     []byte
+	expires time.Time // Zero for no expiry
+}
+
+// NewMemoryCache creates an empty MemoryCache
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{entries: map[string]memoryEntry{}}
+}
+
+// Get returns the value for key, and false when it is not cached
+func (c *MemoryCache) Get(_ context.Context, key string) ([]byte, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	e, ok := c.entries[key]
+	if !ok || (!e.expires.IsZero() && time.Now().After(e.expires)) {
+		delete(c.entries, key)
+		return nil, false, nil
+	}
+	return e.val, true, nil
+}
+
+// Set caches val for ttl. Expired entries are dropped on the way, so the
+// map holds at most one value per live key.
+func (c *MemoryCache) Set(_ context.Context, key string, val []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for k, e := range c.entries {
+		if !e.expires.IsZero() && now.After(e.expires) {
+			delete(c.entries, k)
+		}
+	}
+	c.entries[key] = memoryEntry{val: val, expires: now.Add(ttl)}
+	return nil
+}
+
+// Incr increments the counter at key, which never expires, and returns its
+// new value
+func (c *MemoryCache) Incr(_ context.Context, key string) (int64, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	n, _ := strconv.ParseInt(string(c.entries[key].val), 10, 64)
+	n++
+	c.entries[key] = memoryEntry{val: []byte(strconv.FormatInt(n, 10))}
+	return n, nil
+}
+
+// UserCacheMetrics holds Prometheus metrics for the users cache
+type UserCacheMetrics struct {
+	Requests      *prometheus.CounterVec
+	Invalidations *prometheus.CounterVec
+}
+
+// NewUserCacheMetrics creates and registers users cache metrics with reg,
+// or prometheus.DefaultRegisterer when reg is nil
+func NewUserCacheMetrics(namespace string, reg prometheus.Registerer) *UserCacheMetrics {
+	m := &UserCacheMetrics{
+		Requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_users_cache_requests_total",
+			Help:      "Users cache lookups by result (hit, miss, error)",
+		}, []string{"result"}),
+		Invalidations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_users_cache_invalidations_total",
+			Help:      "Users cache invalidations by reason",
+		}, []string{"reason"}),
+	}
+
+	m.Requests = metrics.MustGetOrRegister(reg, m.Requests)
+	m.Invalidations = metrics.MustGetOrRegister(reg, m.Invalidations)
+
+	return m
+}
+
+// UserCache caches the user list read by GetUsers. Entries are keyed by a
+// generation that Invalidate increments, instead of being deleted: a read
+// that raced a mutation stores what it read under the old generation, which
+// is never read again, so a stale list cannot outlive the invalidation.
+type UserCache struct {
+	cache   Cache
+	ttl     time.Duration
+	metrics *UserCacheMetrics
+}
+
+// Keys of the users cache
+const (
+	usersCacheKey        = "users:list:"
+	usersGenerationKey   = "users:generation"
+	defaultUsersCacheTTL = time.Minute
+)
+
+// NewUserCache caches user lists in cache for ttl (default 1m)
+func NewUserCache(cache Cache, ttl time.Duration, m *UserCacheMetrics) *UserCache {
+	if ttl <= 0 {
+		ttl = defaultUsersCacheTTL
+	}
+	return &UserCache{cache: cache, ttl: ttl, metrics: m}
+}
+
+// Wrap returns a Store that serves GetUsers from the cache and invalidates
+// it when users are inserted, updated or deleted through the Store
+func (c *UserCache) Wrap(s Store) Store {
+	return &cachedUsersStore{Store: s, cache: c}
+}
+
+// Invalidate drops every cached user list. Call it after changing users;
+// reason labels the invalidation in metrics and on the span.
+func (c *UserCache) Invalidate(ctx context.Context, reason string) error {
+	gen, err := c.cache.Incr(ctx, usersGenerationKey)
+	if err != nil {
+		return fmt.Errorf("failed to invalidate users cache: %w", err)
+	}
+	c.metrics.Invalidations.WithLabelValues(reason).Inc()
+	trace.SpanFromContext(ctx).AddEvent("cache.invalidate", trace.WithAttributes(
+		attribute.String("cache.name", "users"),
+		attribute.String("cache.invalidate.reason", reason),
+		attribute.Int64("cache.generation", gen),
+	))
+	return nil
+}
+
+// key returns the cache key of the current generation
+func (c *UserCache) key(ctx context.Context) (string, error) {
+	gen, ok, err := c.cache.Get(ctx, usersGenerationKey)
+	if err != nil {
+		return "", err
+	}
+	if !ok {
+		return usersCacheKey + "0", nil
+	}
+	return usersCacheKey + string(gen), nil
+}
+
+func (c *UserCache) get(ctx context.Context, key string) ([]User, bool, error) {
+	val, ok, err := c.cache.Get(ctx, key)
+	if err != nil || !ok {
+		return nil, false, err
+	}
+	var users []User
+	if err := json.Unmarshal(val, &users); err != nil {
+		return nil, false, err
+	}
+	return users, true, nil
+}
+
+type cachedUsersStore struct {
+	Store
+	cache *UserCache
+}
+
+// GetUsers returns the cached user list, reading through to the database on
+// a miss. A failing cache is bypassed rather than failing the request, and
+// not filled.
+func (s *cachedUsersStore) GetUsers(ctx context.Context) ([]User, error) {
+	span := trace.SpanFromContext(ctx)
+	c := s.cache
+
+	key, cacheErr := c.key(ctx)
+	if cacheErr == nil {
+		users, hit, err := c.get(ctx, key)
+		if hit {
+			c.metrics.Requests.WithLabelValues("hit").Inc()
+			span.SetAttributes(attribute.String("cache.name", "users"), attribute.Bool("cache.hit", true))
+			return users, nil
+		}
+		cacheErr = err
+	}
+	if cacheErr != nil {
+		c.metrics.Requests.WithLabelValues("error").Inc()
+		span.RecordError(cacheErr)
+	} else {
+		c.metrics.Requests.WithLabelValues("miss").Inc()
+	}
+	span.SetAttributes(attribute.String("cache.name", "users"), attribute.Bool("cache.hit", false))
+
+	users, err := s.Store.GetUsers(ctx)
+	if err != nil || cacheErr != nil {
+		return users, err
+	}
+	if val, err := json.Marshal(users); err == nil {
+		if err := c.cache.Set(ctx, key, val, c.ttl); err != nil {
+			span.RecordError(err)
+		}
+	}
+	return users, nil
+}
+
+// InsertUsers inserts users and invalidates the cache when any was new
+func (s *cachedUsersStore) InsertUsers(ctx context.Context, users []User) ([]bool, error) {
+	inserted, err := s.Store.InsertUsers(ctx, users)
+	if err != nil {
+		return nil, err
+	}
+	for _, ok := range inserted {
+		if ok {
+			if err := s.cache.Invalidate(ctx, "insert"); err != nil {
+				trace.SpanFromContext(ctx).RecordError(err)
+			}
+			break
+		}
+	}
+	return inserted, nil
+}
+
+// UpdateUser updates the user and invalidates the cache when it was updated
+func (s *cachedUsersStore) UpdateUser(ctx context.Context, u User) (*User, error) {
+	updated, err := s.Store.UpdateUser(ctx, u)
+	if err != nil || updated == nil {
+		return updated, err
+	}
+	if err := s.cache.Invalidate(ctx, "update"); err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+	return updated, nil
+}
+
+// DeleteUser deletes the user and invalidates the cache when it existed
+func (s *cachedUsersStore) DeleteUser(ctx context.Context, username string) (bool, error) {
+	deleted, err := s.Store.DeleteUser(ctx, username)
+	if err != nil || !deleted {
+		return deleted, err
+	}
+	if err := s.cache.Invalidate(ctx, "delete"); err != nil {
+		trace.SpanFromContext(ctx).RecordError(err)
+	}
+	return true, nil
+}
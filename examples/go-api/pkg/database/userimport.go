@@ -0,0 +1,167 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Statuses of a user import
+const (
+	ImportRunning = "running"
+	ImportDone    = "done"   // Every row was processed; some may have failed
+	ImportFailed  = "failed" // The job stopped early, see UserImport.Error
+)
+
+// UserImport is the progress of a bulk user import, persisted so that any
+// replica can report it
+type UserImport struct {
+	ID         int           `json:"id"`
+	Status     string        `json:"status"`
+	Total      int           `json:"total"`
+	Processed  int           `json:"processed"`
+	Imported   int           `json:"imported"`
+	Failed     int           `json:"failed"`
+	Errors     []ImportError `json:"errors"`          // First rows that failed, see Failed for the count
+	Error      string        `json:"error,omitempty"` // Why the job stopped when Status is ImportFailed
+	TraceID    string        `json:"trace_id"`        // Trace of the request that started the import
+	CreatedAt  time.Time     `json:"created_at"`
+	UpdatedAt  time.Time     `json:"updated_at"`
+	FinishedAt *time.Time    `json:"finished_at,omitempty"`
+}
+
+// ImportError describes a row that was not imported
+type ImportError struct {
+	Line     int    `json:"line"`
+	Username string `json:"username,omitempty"`
+	Message  string `json:"message"`
+}
+
+// InsertUsers inserts users in one transaction and reports for each whether
+// it was inserted; a user whose username exists is skipped. A deleted user
+// is created again in place of the deleted row. Like other inserts it is
+// not retried; the write timeout bounds the whole transaction.
+func (db *DB) InsertUsers(ctx context.Context, users []User) ([]bool, error) {
+	var inserted []bool
+	err := db.withTimeout(ctx, "insert_users", func(ctx context.Context) error {
+		var err error
+		inserted, err = db.insertUsers(ctx, users)
+		return err
+	})
+	return inserted, err
+}
+
+func (db *DB) insertUsers(ctx context.Context, users []User) ([]bool, error) {
+	query := `
+		INSERT INTO users (username, email, created_by, updated_by) VALUES ($1, $2, $3, $3)
+		ON CONFLICT (username) DO UPDATE SET
+			email = excluded.email, created_by = excluded.created_by, updated_by = excluded.updated_by,
+			created_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP, deleted_at = NULL,
+			version = users.version + 1
+		WHERE users.deleted_at IS NOT NULL
+	`
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare insert: %w", err)
+	}
+	defer stmt.Close()
+
+	by := stampedBy(ctx)
+	inserted := make([]bool, len(users))
+	for i, u := range users {
+		res, err := stmt.ExecContext(ctx, u.Username, u.Email, by)
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert user %q: %w", u.Username, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return nil, fmt.Errorf("failed to insert user %q: %w", u.Username, err)
+		}
+		inserted[i] = n > 0
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit users: %w", err)
+	}
+	return inserted, nil
+}
+
+// CreateUserImport records a running import of total rows
+func (db *DB) CreateUserImport(ctx context.Context, total int, traceID string) (*UserImport, error) {
+	query := `
+		INSERT INTO user_imports (status, total, trace_id) VALUES ($1, $2, $3)
+		RETURNING id, created_at, updated_at
+	`
+	imp := &UserImport{Status: ImportRunning, Total: total, TraceID: traceID, Errors: []ImportError{}}
+	err := db.withTimeout(ctx, "create_user_import", func(ctx context.Context) error {
+		return db.QueryRowContext(ctx, query, imp.Status, total, traceID).Scan(&imp.ID, &imp.CreatedAt, &imp.UpdatedAt)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user import: %w", err)
+	}
+	return imp, nil
+}
+
+// UpdateUserImport saves the progress of imp. Updates are idempotent, so
+// transient errors are retried.
+func (db *DB) UpdateUserImport(ctx context.Context, imp *UserImport) error {
+	errs, err := json.Marshal(imp.Errors)
+	if err != nil {
+		return fmt.Errorf("failed to encode import errors: %w", err)
+	}
+	query := `
+		UPDATE user_imports
+		SET status = $2, processed = $3, imported = $4, failed = $5, errors = $6, error_message = $7,
+			updated_at = CURRENT_TIMESTAMP, finished_at = $8
+		WHERE id = $1
+	`
+	err = db.withRetry(ctx, "update_user_import", func(ctx context.Context) error {
+		_, err := db.ExecContext(ctx, query, imp.ID, imp.Status, imp.Processed, imp.Imported, imp.Failed, string(errs), imp.Error, imp.FinishedAt)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update user import %d: %w", imp.ID, err)
+	}
+	return nil
+}
+
+// GetUserImport retrieves an import by ID, or nil if there is none
+func (db *DB) GetUserImport(ctx context.Context, id int) (*UserImport, error) {
+	query := `
+		SELECT id, status, total, processed, imported, failed, COALESCE(errors, '[]'), COALESCE(error_message, ''),
+			COALESCE(trace_id, ''), created_at, updated_at, finished_at
+		FROM user_imports WHERE id = $1
+	`
+
+	var (
+		imp      UserImport
+		errs     string
+		finished sql.NullTime
+	)
+	err := db.withRetry(ctx, "get_user_import", func(ctx context.Context) error {
+		return db.QueryRowContext(ctx, query, id).Scan(&imp.ID, &imp.Status, &imp.Total, &imp.Processed, &imp.Imported,
+			&imp.Failed, &errs, &imp.Error, &imp.TraceID, &imp.CreatedAt, &imp.UpdatedAt, &finished)
+	})
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query user import: %w", err)
+	}
+
+	if err := json.Unmarshal([]byte(errs), &imp.Errors); err != nil {
+		return nil, fmt.Errorf("failed to decode import errors: %w", err)
+	}
+	if finished.Valid {
+		imp.FinishedAt = &finished.Time
+	}
+	return &imp, nil
+}
@@ -0,0 +1,243 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/metrics"
+)
+
+// Location classes of the weather cache, following wttr.in's location
+// syntax. Coordinates and airports name a precise spot whose weather is
+// worth refreshing sooner than a city's.
+const (
+	LocationCity        = "city"
+	LocationCoordinates = "coordinates" // "51.5,-0.12"
+	LocationAirport     = "airport"     // Three-letter IATA code, e.g. "MUC"
+	LocationLandmark    = "landmark"    // "~Eiffel+Tower"
+	LocationDomain      = "domain"      // "@github.com"
+)
+
+// WeatherCacheTTL decides how long a cached weather response stays fresh
+type WeatherCacheTTL struct {
+	Default time.Duration            // Default 30m
+	Classes map[string]time.Duration // TTL per location class, overriding Default
+}
+
+// For returns the TTL of location
+func (t WeatherCacheTTL) For(location string) time.Duration {
+	if ttl, ok := t.Classes[LocationClass(location)]; ok && ttl > 0 {
+		return ttl
+	}
+	if t.Default > 0 {
+		return t.Default
+	}
+	return 30 * time.Minute
+}
+
+// LocationClass classifies a weather location
+func LocationClass(location string) string {
+	switch {
+	case strings.HasPrefix(location, "~"):
+		return LocationLandmark
+	case strings.HasPrefix(location, "@"):
+		return LocationDomain
+	case isCoordinates(location):
+		return LocationCoordinates
+	case len(location) == 3 && isLetters(location):
+		return LocationAirport
+	default:
+		return LocationCity
+	}
+}
+
+func isCoordinates(location string) bool {
+	lat, lon, ok := strings.Cut(location, ",")
+	if !ok {
+		return false
+	}
+	_, err1 := strconv.ParseFloat(strings.TrimSpace(lat), 64)
+	_, err2 := strconv.ParseFloat(strings.TrimSpace(lon), 64)
+	return err1 == nil && err2 == nil
+}
+
+func isLetters(s string) bool {
+	for _, r := range s {
+		if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseClassTTLs parses "class=seconds" pairs, e.g. "airport=600,coordinates=900"
+func ParseClassTTLs(s string) (map[string]time.Duration, error) {
+	ttls := map[string]time.Duration{}
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		class, secs, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid class TTL %q: want class=seconds", pair)
+		}
+		switch class = strings.TrimSpace(class); class {
+		case LocationCity, LocationCoordinates, LocationAirport, LocationLandmark, LocationDomain:
+		default:
+			return nil, fmt.Errorf("unknown location class %q", class)
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(secs))
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("invalid TTL for %s: %q", class, secs)
+		}
+		ttls[class] = time.Duration(n) * time.Second
+	}
+	return ttls, nil
+}
+
+// WeatherCacheMetrics holds Prometheus metrics for the weather_cache table
+type WeatherCacheMetrics struct {
+	Entries       prometheus.Gauge
+	ExpiredRows   prometheus.Gauge
+	Evicted       prometheus.Counter
+	CleanupErrors prometheus.Counter
+}
+
+// NewWeatherCacheMetrics creates and registers weather cache metrics with
+// reg, or prometheus.DefaultRegisterer when reg is nil
+func NewWeatherCacheMetrics(namespace string, reg prometheus.Registerer) *WeatherCacheMetrics {
+	m := &WeatherCacheMetrics{
+		Entries: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "db_weather_cache_entries",
+			Help:      "Rows in the weather_cache table at the last cleanup",
+		}),
+		ExpiredRows: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "db_weather_cache_expired_entries",
+			Help:      "Expired rows left in the weather_cache table after the last cleanup",
+		}),
+		Evicted: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_weather_cache_evicted_total",
+			Help:      "Expired weather_cache rows deleted by the cleanup job",
+		}),
+		CleanupErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "db_weather_cache_cleanup_errors_total",
+			Help:      "Failed weather_cache cleanup runs",
+		}),
+	}
+
+	m.Entries = metrics.MustGetOrRegister(reg, m.Entries)
+	m.ExpiredRows = metrics.MustGetOrRegister(reg, m.ExpiredRows)
+	m.Evicted = metrics.MustGetOrRegister(reg, m.Evicted)
+	m.CleanupErrors = metrics.MustGetOrRegister(reg, m.CleanupErrors)
+
+	return m
+}
+
+// WeatherCacheCleanupConfig holds weather cache cleanup configuration
+type WeatherCacheCleanupConfig struct {
+	Interval  time.Duration // Time between cleanups (default 5m); negative disables them
+	BatchSize int           // Rows deleted per statement (default 500)
+}
+
+// DeleteExpiredWeatherCache deletes up to limit expired rows and returns how
+// many were deleted. Small batches keep each statement's locks short.
+func (db *DB) DeleteExpiredWeatherCache(ctx context.Context, limit int) (int64, error) {
+	query := `
+		DELETE FROM weather_cache WHERE id IN (
+			SELECT id FROM weather_cache WHERE expires_at <= CURRENT_TIMESTAMP LIMIT $1
+		)
+	`
+	var n int64
+	err := db.withRetry(ctx, "delete_expired_weather_cache", func(ctx context.Context) error {
+		res, err := db.ExecContext(ctx, query, limit)
+		if err != nil {
+			return err
+		}
+		n, err = res.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to delete expired weather cache: %w", err)
+	}
+	return n, nil
+}
+
+// WeatherCacheStats counts the rows of the weather cache and how many of
+// them have expired
+func (db *DB) WeatherCacheStats(ctx context.Context) (entries, expired int64, err error) {
+	query := `
+		SELECT COUNT(*), COALESCE(SUM(CASE WHEN expires_at <= CURRENT_TIMESTAMP THEN 1 ELSE 0 END), 0)
+		FROM weather_cache
+	`
+	err = db.withRetry(ctx, "weather_cache_stats", func(ctx context.Context) error {
+		return db.QueryRowContext(ctx, query).Scan(&entries, &expired)
+	})
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to count weather cache: %w", err)
+	}
+	return entries, expired, nil
+}
+
+// RunWeatherCacheCleanup deletes expired weather_cache rows every
+// cfg.Interval until ctx is cancelled, then updates the size metrics.
+// Reads already skip expired rows; without the cleanup the table would
+// keep one row per location ever requested.
+func (db *DB) RunWeatherCacheCleanup(ctx context.Context, m *WeatherCacheMetrics, log *logger.Logger, cfg WeatherCacheCleanupConfig) {
+	if cfg.Interval < 0 {
+		return
+	}
+	if cfg.Interval == 0 {
+		cfg.Interval = 5 * time.Minute
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	for {
+		if err := db.cleanupWeatherCache(ctx, m, cfg.BatchSize); err != nil && ctx.Err() == nil {
+			m.CleanupErrors.Inc()
+			l := logger.OrDefault(log).WithContext(ctx)
+			l.Warn().Err(err).Msg("Weather cache cleanup failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (db *DB) cleanupWeatherCache(ctx context.Context, m *WeatherCacheMetrics, batch int) error {
+	for {
+		n, err := db.DeleteExpiredWeatherCache(ctx, batch)
+		if err != nil {
+			return err
+		}
+		m.Evicted.Add(float64(n))
+		if n < int64(batch) {
+			break
+		}
+	}
+
+	entries, expired, err := db.WeatherCacheStats(ctx)
+	if err != nil {
+		return err
+	}
+	m.Entries.Set(float64(entries))
+	m.ExpiredRows.Set(float64(expired))
+	return nil
+}
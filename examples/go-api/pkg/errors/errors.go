@@ -0,0 +1,86 @@
+// Package errors wraps errors and reports them to the active span and the
+// log in one call, so each failure is recorded exactly once however many
+// layers wrap it on the way up. It re-exports the standard library helpers
+// and can be imported in place of "errors".
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+// New, Is, As and Unwrap are the standard library functions
+var (
+	New    = stderrors.New
+	Is     = stderrors.Is
+	As     = stderrors.As
+	Unwrap = stderrors.Unwrap
+)
+
+// recordedError marks an error already recorded on a span and logged
+type recordedError struct {
+	msg string
+	err error
+}
+
+func (e *recordedError) Error() string { return e.msg + ": " + e.err.Error() }
+func (e *recordedError) Unwrap() error { return e.err }
+
+// Recorded reports whether err, or an error it wraps, came from WrapAndRecord
+func Recorded(err error) bool {
+	var r *recordedError
+	return As(err, &r)
+}
+
+// WrapAndRecord wraps err with msg. The first time an error passes through
+// it, it is also recorded on the active span, which is marked failed, and
+// logged at error level with the given fields. fields are alternating keys
+// and values, e.g. "location", loc. The log uses the logger stored in ctx by
+// logger.NewContext, or the global logger. Returns nil when err is nil.
+func WrapAndRecord(ctx context.Context, err error, msg string, fields ...interface{}) error {
+	if err == nil {
+		return nil
+	}
+	if Recorded(err) {
+		return fmt.Errorf("%s: %w", msg, err)
+	}
+
+	kv := pairs(fields)
+
+	attrs := make([]attribute.KeyValue, 0, len(kv)+1)
+	attrs = append(attrs, attribute.String("error.context", msg))
+	for k, v := range kv {
+		attrs = append(attrs, attribute.String(k, fmt.Sprint(v)))
+	}
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err, trace.WithAttributes(attrs...))
+	span.SetStatus(codes.Error, msg)
+
+	// Report the caller of WrapAndRecord rather than this line
+	logger.Ctx(ctx).Error().CallerSkipFrame(1).Err(err).Fields(kv).Msg(msg)
+
+	return &recordedError{msg: msg, err: err}
+}
+
+// pairs turns alternating keys and values into a map. A key without a
+// value, or a non-string key, is kept under "!BADKEY" rather than dropped.
+func pairs(fields []interface{}) map[string]interface{} {
+	kv := make(map[string]interface{}, len(fields)/2)
+	for i := 0; i < len(fields); i += 2 {
+		key, ok := fields[i].(string)
+		if !ok || i+1 == len(fields) {
+			kv["!BADKEY"] = fields[i]
+			i--
+			continue
+		}
+		kv[key] = fields[i+1]
+	}
+	return kv
+}
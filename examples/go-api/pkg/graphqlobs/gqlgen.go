@@ -0,0 +1,150 @@
+//go:build gqlgen
+
+package graphqlobs
+
+import (
+	"context"
+	"time"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/99designs/gqlgen/graphql/handler/extension"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/tracing"
+)
+
+// tracerName is the instrumentation scope of GraphQL spans
+const tracerName = "github.com/example/go-api/pkg/graphqlobs"
+
+// maxDocumentLength bounds the graphql.document span attribute
+const maxDocumentLength = 2048
+
+// Tracer is a gqlgen extension adding a span per operation and per resolver,
+// operation, complexity and resolver metrics, and one log line per operation.
+// Register it after the complexity limit so complexity is reported:
+//
+//	srv := handler.NewDefaultServer(generated.NewExecutableSchema(cfg))
+//	srv.Use(extension.FixedComplexityLimit(200))
+//	srv.Use(graphqlobs.Tracer{})
+type Tracer struct {
+	// Variable names whose values are redacted from logs
+	// (default DefaultSensitiveVariables)
+	Sensitive []string
+}
+
+var _ interface {
+	graphql.HandlerExtension
+	graphql.ResponseInterceptor
+	graphql.FieldInterceptor
+} = Tracer{}
+
+// ExtensionName implements graphql.HandlerExtension
+func (Tracer) ExtensionName() string {
+	return "Observability"
+}
+
+// Validate implements graphql.HandlerExtension
+func (Tracer) Validate(graphql.ExecutableSchema) error {
+	return nil
+}
+
+// InterceptResponse runs each operation in a span and records it
+func (t Tracer) InterceptResponse(ctx context.Context, next graphql.ResponseHandler) *graphql.Response {
+	if !graphql.HasOperationContext(ctx) {
+		return next(ctx)
+	}
+	oc := graphql.GetOperationContext(ctx)
+	name := oc.OperationName
+	if name == "" {
+		name = "anonymous"
+	}
+	opType := "query"
+	if oc.Operation != nil {
+		opType = string(oc.Operation.Operation)
+	}
+
+	start := time.Now()
+	ctx, span := otel.Tracer(tracerName).Start(ctx, opType+" "+name, trace.WithAttributes(
+		attribute.String("graphql.operation.name", name),
+		attribute.String("graphql.operation.type", opType),
+		attribute.String("graphql.document", tracing.Truncate(oc.RawQuery, maxDocumentLength)),
+	))
+	defer span.End()
+
+	resp := next(ctx)
+	duration := time.Since(start)
+
+	fields := map[string]interface{}{
+		"operation":   name,
+		"type":        opType,
+		"duration_ms": duration.Milliseconds(),
+		"variables":   RedactVariables(oc.Variables, t.sensitive()),
+	}
+	if stats := extension.GetComplexityStats(ctx); stats != nil {
+		span.SetAttributes(attribute.Int("graphql.complexity", stats.Complexity))
+		queryComplexity.WithLabelValues(name).Observe(float64(stats.Complexity))
+		fields["complexity"] = stats.Complexity
+	}
+
+	outcome := "ok"
+	errs := graphql.GetErrors(ctx)
+	if resp != nil {
+		errs = append(errs, resp.Errors...)
+	}
+	if len(errs) > 0 {
+		outcome = "error"
+		span.SetStatus(codes.Error, errs.Error())
+		fields["errors"] = len(errs)
+	}
+	operationsTotal.WithLabelValues(name, opType, outcome).Inc()
+	operationDuration.WithLabelValues(name, opType).Observe(duration.Seconds())
+
+	opLog := logger.Ctx(ctx).With().Fields(fields).Logger()
+	if outcome == "error" {
+		opLog.Warn().Str("error", errs.Error()).Msg("GraphQL operation failed")
+	} else {
+		opLog.Info().Msg("GraphQL operation completed")
+	}
+	return resp
+}
+
+// InterceptField runs each resolver in a child span. Fields resolved from
+// their parent object, without a resolver, are not traced.
+func (t Tracer) InterceptField(ctx context.Context, next graphql.Resolver) (interface{}, error) {
+	fc := graphql.GetFieldContext(ctx)
+	if fc == nil || !fc.IsResolver {
+		return next(ctx)
+	}
+
+	start := time.Now()
+	ctx, span := otel.Tracer(tracerName).Start(ctx, fc.Object+"."+fc.Field.Name, trace.WithAttributes(
+		attribute.String("graphql.field.object", fc.Object),
+		attribute.String("graphql.field.name", fc.Field.Name),
+		attribute.String("graphql.field.path", fc.Path().String()),
+	))
+	defer span.End()
+
+	res, err := next(ctx)
+	resolverDuration.WithLabelValues(fc.Object, fc.Field.Name).Observe(time.Since(start).Seconds())
+	if errs := graphql.GetFieldErrors(ctx, fc); len(errs) > 0 {
+		for _, e := range errs {
+			span.RecordError(e)
+		}
+		span.SetStatus(codes.Error, errs.Error())
+	} else if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return res, err
+}
+
+func (t Tracer) sensitive() []string {
+	if t.Sensitive != nil {
+		return t.Sensitive
+	}
+	return DefaultSensitiveVariables
+}
@@ -0,0 +1,100 @@
+// Package graphqlobs instruments GraphQL servers: a span per operation and
+// per resolver, operation and complexity metrics, and one log line per
+// operation with its variables redacted. The gqlgen extension is behind the
+// gqlgen build tag; the metrics and redaction here work with any server.
+package graphqlobs
+
+import (
+	"strings"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/example/go-api/pkg/secrets"
+)
+
+var (
+	operationsTotal = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "graphql_operations_total",
+			Help: "GraphQL operations by operation name, type and outcome (ok, error)",
+		},
+		[]string{"operation", "type", "outcome"},
+	)
+	operationDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "graphql_operation_duration_seconds",
+			Help:    "GraphQL operation execution time by operation name and type",
+			Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		},
+		[]string{"operation", "type"},
+	)
+	queryComplexity = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "graphql_query_complexity",
+			Help:    "Calculated complexity of GraphQL operations by operation name",
+			Buckets: []float64{1, 5, 10, 25, 50, 100, 250, 500, 1000},
+		},
+		[]string{"operation"},
+	)
+	resolverDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "graphql_resolver_duration_seconds",
+			Help:    "GraphQL resolver execution time by object and field",
+			Buckets: []float64{.0005, .001, .005, .01, .025, .05, .1, .25, .5, 1},
+		},
+		[]string{"object", "field"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(operationsTotal)
+	prometheus.MustRegister(operationDuration)
+	prometheus.MustRegister(queryComplexity)
+	prometheus.MustRegister(resolverDuration)
+}
+
+// DefaultSensitiveVariables are substrings of variable names whose values
+// are never logged
+var DefaultSensitiveVariables = []string{"password", "secret", "token", "authorization", "apikey", "api_key", "credential"}
+
+// RedactVariables returns a copy of vars for logging. Values of variables,
+// and of nested input fields, whose name contains one of sensitive
+// (case-insensitive) are replaced with REDACTED.
+func RedactVariables(vars map[string]interface{}, sensitive []string) map[string]interface{} {
+	if vars == nil {
+		return nil
+	}
+	out := make(map[string]interface{}, len(vars))
+	for k, v := range vars {
+		if isSensitive(k, sensitive) {
+			out[k] = secrets.Redacted
+			continue
+		}
+		out[k] = redactValue(v, sensitive)
+	}
+	return out
+}
+
+func redactValue(v interface{}, sensitive []string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		return RedactVariables(v, sensitive)
+	case []interface{}:
+		out := make([]interface{}, len(v))
+		for i, item := range v {
+			out[i] = redactValue(item, sensitive)
+		}
+		return out
+	}
+	return v
+}
+
+func isSensitive(name string, sensitive []string) bool {
+	name = strings.ToLower(name)
+	for _, s := range sensitive {
+		if strings.Contains(name, strings.ToLower(s)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/go-api/pkg/tracing"
+)
+
+// DashboardHandler demonstrates nested spans: external APIs + DB queries
+type DashboardHandler struct {
+	weather WeatherFetcher
+	quotes  QuoteFetcher
+	store   StoreFunc
+	tracer  trace.Tracer
+
+	downstream DownstreamChecker // nil when no gRPC downstream is configured
+}
+
+// NewDashboardHandler creates a new DashboardHandler. Serve it with
+// obs.Handler. downstream may be nil.
+func NewDashboardHandler(weather WeatherFetcher, quotes QuoteFetcher, store StoreFunc, downstream DownstreamChecker, tracer trace.Tracer) *DashboardHandler {
+	return &DashboardHandler{weather: weather, quotes: quotes, store: store, downstream: downstream, tracer: tracer}
+}
+
+// Serve implements obs.HandlerFunc
+func (h *DashboardHandler) Serve(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	location := r.URL.Query().Get("location")
+	if location == "" {
+		location = "London"
+	}
+	if msg := ValidateLocation(location); msg != "" {
+		writeValidationError(w, r, &ValidationError{Errors: []FieldError{{Field: "location", Message: msg}}})
+		return nil
+	}
+
+	// Parent span for entire dashboard operation
+	ctx, span := h.tracer.Start(ctx, "build_dashboard",
+		trace.WithAttributes(attribute.String("dashboard.location", tracing.Truncate(location, maxAttributeLength))))
+	defer span.End()
+
+	result := make(map[string]interface{})
+	result["trace_id"] = tracing.GetTraceID(ctx)
+	result["timestamp"] = time.Now().UTC()
+	result["location"] = location
+
+	// Child span 1: Fetch weather
+	weatherCtx, weatherSpan := h.tracer.Start(ctx, "dashboard.fetch_weather")
+	weather, err := h.weather.GetWeather(weatherCtx, location)
+	if err != nil {
+		weatherSpan.RecordError(err)
+		result["weather_error"] = err.Error()
+	} else {
+		result["weather"] = weather
+	}
+	weatherSpan.End()
+
+	// Child span 2: Fetch quote
+	quoteCtx, quoteSpan := h.tracer.Start(ctx, "dashboard.fetch_quote")
+	quote, err := h.quotes.GetRandomQuote(quoteCtx)
+	if err != nil {
+		quoteSpan.RecordError(err)
+		result["quote_error"] = err.Error()
+	} else {
+		result["quote"] = quote
+	}
+	quoteSpan.End()
+
+	// Child span 3: Check the gRPC downstream (if configured)
+	if h.downstream != nil {
+		grpcCtx, grpcSpan := h.tracer.Start(ctx, "dashboard.check_downstream")
+		status, err := h.downstream.Check(grpcCtx, "")
+		if err != nil {
+			grpcSpan.RecordError(err)
+			result["downstream_error"] = err.Error()
+		} else {
+			result["downstream_status"] = status
+		}
+		grpcSpan.End()
+	}
+
+	// Child span 4: Get users from DB (if available)
+	if db := h.store(); db != nil {
+		dbCtx, dbSpan := h.tracer.Start(ctx, "dashboard.get_users")
+		users, err := db.GetUsers(dbCtx)
+		if err != nil {
+			dbSpan.RecordError(err)
+			result["users_error"] = err.Error()
+		} else {
+			result["users"] = users
+			result["users_count"] = len(users)
+		}
+		dbSpan.End()
+
+		// Child span 5: Get recent quotes from DB
+		quotesCtx, quotesSpan := h.tracer.Start(ctx, "dashboard.get_recent_quotes")
+		recentQuotes, err := db.GetQuotes(quotesCtx, 5)
+		if err != nil {
+			quotesSpan.RecordError(err)
+			result["recent_quotes_error"] = err.Error()
+		} else {
+			result["recent_quotes"] = recentQuotes
+		}
+		quotesSpan.End()
+	}
+
+	writeJSON(w, http.StatusOK, result)
+	return nil
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/example/go-api/pkg/client"
+	"github.com/example/go-api/pkg/database"
+	"github.com/example/go-api/pkg/httperr"
+)
+
+// WeatherFetcher fetches current weather for a location
+type WeatherFetcher interface {
+	GetWeather(ctx context.Context, location string) (*client.WeatherResponse, error)
+}
+
+// QuoteFetcher fetches a random quote
+type QuoteFetcher interface {
+	GetRandomQuote(ctx context.Context) (*client.Quote, error)
+}
+
+// DownstreamChecker checks the health of a downstream service
+type DownstreamChecker interface {
+	Check(ctx context.Context, service string) (string, error)
+}
+
+// maxAttributeLength bounds span attributes taken from request input
+const maxAttributeLength = 256
+
+// StoreFunc returns the current database store, or nil while the database
+// is unavailable. A func rather than a Store lets the pool be swapped in
+// after startup without rebuilding handlers.
+type StoreFunc func() database.Store
+
+// writeJSON encodes v as the JSON response body
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError writes an error envelope carrying the trace and request IDs
+func writeError(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	httperr.Write(w, r, status, msg)
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/example/go-api/pkg/upstream"
+)
+
+// UpstreamReporter reports the latest active probe result per upstream
+type UpstreamReporter interface {
+	Results() map[string]upstream.Result
+}
+
+// HealthHandler serves liveness and readiness probes
+type HealthHandler struct {
+	store     StoreFunc
+	dbEnabled bool
+	upstreams UpstreamReporter // Optional
+}
+
+// NewHealthHandler creates a new HealthHandler. dbEnabled marks the database
+// as required for readiness even before a connection exists. upstreams, if
+// not nil, adds a dependency-health section to the readiness output.
+func NewHealthHandler(store StoreFunc, dbEnabled bool, upstreams UpstreamReporter) *HealthHandler {
+	return &HealthHandler{store: store, dbEnabled: dbEnabled, upstreams: upstreams}
+}
+
+// Health reports that the process is alive
+func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte(`{"status":"healthy"}`))
+}
+
+// Ready reports whether the service can take traffic. Upstream health is
+// informational only: a slow third-party API must not take pods out of
+// rotation.
+func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
+	// A configured database that has not connected yet is still reconnecting
+	db := h.store()
+	if h.dbEnabled && db == nil {
+		h.writeReady(w, http.StatusServiceUnavailable, "not ready", "database reconnecting")
+		return
+	}
+
+	// Check database connectivity
+	if db != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+		if err := db.PingContext(ctx); err != nil {
+			h.writeReady(w, http.StatusServiceUnavailable, "not ready", "database unavailable")
+			return
+		}
+	}
+	h.writeReady(w, http.StatusOK, "ready", "")
+}
+
+func (h *HealthHandler) writeReady(w http.ResponseWriter, status int, state, reason string) {
+	body := map[string]interface{}{"status": state}
+	if reason != "" {
+		body["reason"] = reason
+	}
+	if h.upstreams != nil {
+		body["dependencies"] = h.upstreams.Results()
+	}
+	writeJSON(w, status, body)
+}
@@ -0,0 +1,50 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/example/go-api/pkg/errors"
+	"github.com/example/go-api/pkg/metrics"
+	"github.com/example/go-api/pkg/obs"
+	"github.com/example/go-api/pkg/tracing"
+)
+
+// HelloHandler serves the hello endpoint
+type HelloHandler struct{}
+
+// NewHelloHandler creates a new HelloHandler. Serve it with obs.Handler.
+func NewHelloHandler() *HelloHandler {
+	return &HelloHandler{}
+}
+
+// Serve implements obs.HandlerFunc
+func (h *HelloHandler) Serve(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	traceID := tracing.GetTraceID(ctx)
+
+	l := obs.Logger(ctx)
+	l.Info().Msg("Hello endpoint called")
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"message":  "Hello, World!",
+		"trace_id": traceID,
+	})
+	return nil
+}
+
+// ErrorHandler simulates an application error for testing alerting
+type ErrorHandler struct {
+	errors *metrics.Counter
+}
+
+// NewErrorHandler creates a new ErrorHandler that counts errors by type in
+// errors_total. Serve it with obs.Handler, which records and logs the error.
+func NewErrorHandler(reg *metrics.Registry) *ErrorHandler {
+	return &ErrorHandler{errors: reg.Counter("errors_total", "Total number of errors", "type")}
+}
+
+// Serve implements obs.HandlerFunc
+func (h *ErrorHandler) Serve(w http.ResponseWriter, r *http.Request) error {
+	h.errors.Inc("application")
+	return obs.NewError(http.StatusInternalServerError, "Something went wrong", errors.New("simulated error for testing"))
+}
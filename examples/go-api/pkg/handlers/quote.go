@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/go-api/pkg/database"
+	"github.com/example/go-api/pkg/metrics"
+	"github.com/example/go-api/pkg/obs"
+	"github.com/example/go-api/pkg/tracing"
+)
+
+// QuoteHandler fetches a random quote with tracing and stores it
+type QuoteHandler struct {
+	quotes QuoteFetcher
+	store  StoreFunc
+	tracer trace.Tracer
+	saved  *metrics.Counter
+}
+
+// NewQuoteHandler creates a new QuoteHandler that counts stored quotes by
+// source and result (new, duplicate) in quotes_saved_total. Serve it with
+// obs.Handler, which provides its span, logger and error responses.
+func NewQuoteHandler(quotes QuoteFetcher, store StoreFunc, tracer trace.Tracer, reg *metrics.Registry) *QuoteHandler {
+	return &QuoteHandler{
+		quotes: quotes,
+		store:  store,
+		tracer: tracer,
+		saved:  reg.Counter("quotes_saved_total", "Fetched quotes saved to the database by source and result", "source", "result"),
+	}
+}
+
+// Serve implements obs.HandlerFunc
+func (h *QuoteHandler) Serve(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	// Fetch quote from external API
+	quote, err := h.quotes.GetRandomQuote(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Save quote to database (if available); a quote fetched before is
+	// not stored again
+	if db := h.store(); db != nil {
+		ctx, dbSpan := h.tracer.Start(ctx, "save_quote_db")
+		inserted, err := db.SaveQuote(ctx, quote.Content, quote.Author, quote.Source)
+		if err != nil {
+			dbSpan.RecordError(err)
+			l := obs.Logger(ctx)
+			l.Warn().Err(err).Msg("Failed to save quote to database")
+		} else {
+			result := "new"
+			if !inserted {
+				result = "duplicate"
+			}
+			dbSpan.SetAttributes(attribute.Bool("quote.duplicate", !inserted))
+			h.saved.Inc(quote.Source, result)
+		}
+		dbSpan.End()
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"quote":    quote,
+		"trace_id": tracing.GetTraceID(ctx),
+	})
+	return nil
+}
+
+// QuoteStatsHandler reports stored quotes per author and source
+type QuoteStatsHandler struct {
+	store StoreFunc
+}
+
+// NewQuoteStatsHandler creates a new QuoteStatsHandler. Serve it with
+// obs.Handler.
+func NewQuoteStatsHandler(store StoreFunc) *QuoteStatsHandler {
+	return &QuoteStatsHandler{store: store}
+}
+
+// Serve implements obs.HandlerFunc
+func (h *QuoteStatsHandler) Serve(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	db := h.store()
+	if db == nil {
+		return obs.NewError(http.StatusServiceUnavailable, "Database not available", nil)
+	}
+
+	stats, err := db.GetQuoteStats(ctx)
+	if errors.Is(err, database.ErrNotConfigured) {
+		return obs.NewError(http.StatusServiceUnavailable, "Database not available", nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get quote stats: %w", err)
+	}
+
+	var total int64
+	for _, st := range stats {
+		total += st.Count
+	}
+	if stats == nil {
+		stats = []database.QuoteStats{}
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"stats":    stats,
+		"total":    total,
+		"trace_id": tracing.GetTraceID(ctx),
+	})
+	return nil
+}
+
+// QuoteDeleteHandler soft-deletes stored quotes
+type QuoteDeleteHandler struct {
+	store StoreFunc
+}
+
+// NewQuoteDeleteHandler creates a new QuoteDeleteHandler. Serve it with
+// obs.Handler.
+func NewQuoteDeleteHandler(store StoreFunc) *QuoteDeleteHandler {
+	return &QuoteDeleteHandler{store: store}
+}
+
+// Serve implements obs.HandlerFunc for DELETE /api/quotes/{id}
+func (h *QuoteDeleteHandler) Serve(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return obs.NewError(http.StatusBadRequest, "id must be a number", nil)
+	}
+
+	db := h.store()
+	if db == nil {
+		return obs.NewError(http.StatusServiceUnavailable, "Database not available", nil)
+	}
+
+	deleted, err := db.DeleteQuote(ctx, id)
+	if errors.Is(err, database.ErrNotConfigured) {
+		return obs.NewError(http.StatusServiceUnavailable, "Database not available", nil)
+	}
+	if errors.Is(err, errors.ErrUnsupported) {
+		return obs.NewError(http.StatusNotImplemented, "the quote store does not support deletes", err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete quote: %w", err)
+	}
+	if !deleted {
+		return obs.NewError(http.StatusNotFound, "quote not found", nil)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// maxSearchLength bounds the query accepted by the quote search
+const maxSearchLength = 200
+
+// QuoteSearchHandler searches stored quotes by content and author
+type QuoteSearchHandler struct {
+	store    StoreFunc
+	tracer   trace.Tracer
+	duration *metrics.Histogram
+	results  *metrics.Histogram
+}
+
+// NewQuoteSearchHandler creates a new QuoteSearchHandler that times
+// searches in quote_search_duration_seconds by outcome (hits, empty, error)
+// and records their result counts in quote_search_results. Serve it with
+// obs.Handler.
+func NewQuoteSearchHandler(store StoreFunc, tracer trace.Tracer, reg *metrics.Registry) *QuoteSearchHandler {
+	return &QuoteSearchHandler{
+		store:    store,
+		tracer:   tracer,
+		duration: reg.Histogram("quote_search_duration_seconds", "Duration of quote search queries by outcome", []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1}, "outcome"),
+		results:  reg.Histogram("quote_search_results", "Quotes returned per search", []float64{0, 1, 5, 10, 25, 50, 100}),
+	}
+}
+
+// Serve implements obs.HandlerFunc
+func (h *QuoteSearchHandler) Serve(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	q := strings.TrimSpace(r.URL.Query().Get("q"))
+	if n := utf8.RuneCountInString(q); n == 0 || n > maxSearchLength {
+		return obs.NewError(http.StatusBadRequest, fmt.Sprintf("q must be 1 to %d characters", maxSearchLength), nil)
+	}
+	limit := 20
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > 100 {
+			return obs.NewError(http.StatusBadRequest, "limit must be 1 to 100", nil)
+		}
+		limit = n
+	}
+
+	db := h.store()
+	if db == nil {
+		return obs.NewError(http.StatusServiceUnavailable, "Database not available", nil)
+	}
+
+	// The query text stays out of the span: it is user input and high
+	// cardinality; its length is enough to spot pathological searches
+	ctx, span := h.tracer.Start(ctx, "search_quotes_db", trace.WithAttributes(
+		attribute.Int("search.query_length", len(q)),
+		attribute.Int("search.limit", limit),
+	))
+	start := time.Now()
+	matches, err := db.SearchQuotes(ctx, q, limit)
+	if err != nil {
+		h.duration.Since(start, "error")
+		span.RecordError(err)
+		span.End()
+		if errors.Is(err, database.ErrNotConfigured) {
+			return obs.NewError(http.StatusServiceUnavailable, "Database not available", nil)
+		}
+		return fmt.Errorf("failed to search quotes: %w", err)
+	}
+	outcome := "hits"
+	if len(matches) == 0 {
+		outcome = "empty"
+		matches = []database.QuoteMatch{}
+	}
+	h.duration.Since(start, outcome)
+	h.results.Observe(float64(len(matches)))
+	span.SetAttributes(attribute.Int("search.results", len(matches)))
+	span.End()
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"quotes":   matches,
+		"count":    len(matches),
+		"trace_id": tracing.GetTraceID(ctx),
+	})
+	return nil
+}
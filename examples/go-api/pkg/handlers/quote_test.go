@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace/noop"
+
+	"github.com/example/go-api/pkg/client"
+	"github.com/example/go-api/pkg/database"
+	"github.com/example/go-api/pkg/database/mocks"
+	"github.com/example/go-api/pkg/metrics"
+	"github.com/example/go-api/pkg/obs"
+)
+
+// fakeQuotes is a QuoteFetcher returning a fixed quote or error
+type fakeQuotes struct {
+	quote *client.Quote
+	err   error
+}
+
+func (f fakeQuotes) GetRandomQuote(context.Context) (*client.Quote, error) {
+	return f.quote, f.err
+}
+
+func TestQuoteHandler(t *testing.T) {
+	quote := &client.Quote{Content: "Simplicity is prerequisite for reliability.", Author: "Edsger Dijkstra", Source: "quotable"}
+
+	cases := []struct {
+		name   string
+		quotes fakeQuotes
+		store  *mocks.StoreMock
+		status int
+		saves  int
+		result string
+	}{
+		{"saves new quote", fakeQuotes{quote: quote}, &mocks.StoreMock{
+			SaveQuoteFunc: func(context.Context, string, string, string) (bool, error) { return true, nil },
+		}, http.StatusOK, 1, "new"},
+		{"counts duplicate", fakeQuotes{quote: quote}, &mocks.StoreMock{
+			SaveQuoteFunc: func(context.Context, string, string, string) (bool, error) { return false, nil },
+		}, http.StatusOK, 1, "duplicate"},
+		{"answers when the save fails", fakeQuotes{quote: quote}, &mocks.StoreMock{
+			SaveQuoteFunc: func(context.Context, string, string, string) (bool, error) {
+				return false, errors.New("connection reset")
+			},
+		}, http.StatusOK, 1, ""},
+		{"answers without database", fakeQuotes{quote: quote}, nil, http.StatusOK, 0, ""},
+		{"upstream fails", fakeQuotes{err: errors.New("quote API unavailable")}, &mocks.StoreMock{}, http.StatusInternalServerError, 0, ""},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			reg := prometheus.NewRegistry()
+			h := NewQuoteHandler(c.quotes, storeOf(c.store), noop.NewTracerProvider().Tracer("test"), metrics.New("", reg))
+			w := httptest.NewRecorder()
+			obs.Handler("quote", h.Serve).ServeHTTP(w, httptest.NewRequest("GET", "/api/quote", nil))
+
+			if w.Code != c.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, c.status, w.Body)
+			}
+			if c.store != nil {
+				calls := c.store.SaveQuoteCalls()
+				if len(calls) != c.saves {
+					t.Fatalf("SaveQuote called %d times, want %d", len(calls), c.saves)
+				}
+				if c.saves > 0 && (calls[0].Content != quote.Content || calls[0].Author != quote.Author || calls[0].Source != quote.Source) {
+					t.Errorf("SaveQuote called with %+v", calls[0])
+				}
+			}
+			if got := savedResults(t, reg); got != c.result {
+				t.Errorf("quotes_saved_total counted %q, want %q", got, c.result)
+			}
+		})
+	}
+}
+
+// savedResults returns the result label of the one quotes_saved_total
+// series in reg, or "" when nothing was counted
+func savedResults(t *testing.T, reg *prometheus.Registry) string {
+	t.Helper()
+	families, err := reg.Gather()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range families {
+		if f.GetName() != "quotes_saved_total" {
+			continue
+		}
+		if len(f.GetMetric()) != 1 {
+			t.Fatalf("%d quotes_saved_total series, want 1", len(f.GetMetric()))
+		}
+		for _, l := range f.GetMetric()[0].GetLabel() {
+			if l.GetName() == "result" {
+				return l.GetValue()
+			}
+		}
+	}
+	return ""
+}
+
+func TestQuoteDeleteHandler(t *testing.T) {
+	cases := []struct {
+		name   string
+		id     string
+		err    error
+		status int
+	}{
+		{"deletes", "7", nil, http.StatusNoContent},
+		{"invalid id", "seven", nil, http.StatusBadRequest},
+		{"store cannot delete", "7", errors.ErrUnsupported, http.StatusNotImplemented},
+		{"quotes not configured", "7", database.ErrNotConfigured, http.StatusServiceUnavailable},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			store := &mocks.StoreMock{DeleteQuoteFunc: func(context.Context, int) (bool, error) { return c.err == nil, c.err }}
+			r := mux.SetURLVars(httptest.NewRequest("DELETE", "/api/quotes/"+c.id, nil), map[string]string{"id": c.id})
+			w := httptest.NewRecorder()
+			obs.Handler("quote_delete", NewQuoteDeleteHandler(storeOf(store)).Serve).ServeHTTP(w, r)
+
+			if w.Code != c.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, c.status, w.Body)
+			}
+			calls := store.DeleteQuoteCalls()
+			if c.status == http.StatusBadRequest {
+				if len(calls) != 0 {
+					t.Errorf("invalid id reached the store")
+				}
+				return
+			}
+			if len(calls) != 1 || calls[0].Id != 7 {
+				t.Errorf("DeleteQuote calls: %+v", calls)
+			}
+		})
+	}
+}
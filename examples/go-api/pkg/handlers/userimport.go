@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/example/go-api/pkg/database"
+	"github.com/example/go-api/pkg/obs"
+	"github.com/example/go-api/pkg/tracing"
+	"github.com/example/go-api/pkg/userimport"
+)
+
+// maxImportBytes bounds the body of an import request
+const maxImportBytes = 10 << 20
+
+// UserImportHandler starts bulk user imports and reports their progress
+type UserImportHandler struct {
+	importer *userimport.Importer
+	store    StoreFunc
+
+	// PollInterval is how often Events reads the progress (default 500ms)
+	// and StreamFor how long one stream lasts (default 30s)
+	PollInterval time.Duration
+	StreamFor    time.Duration
+}
+
+// NewUserImportHandler creates a new UserImportHandler. Serve its methods
+// with obs.Handler.
+func NewUserImportHandler(importer *userimport.Importer, store StoreFunc) *UserImportHandler {
+	return &UserImportHandler{
+		importer:     importer,
+		store:        store,
+		PollInterval: 500 * time.Millisecond,
+		StreamFor:    30 * time.Second,
+	}
+}
+
+// Start serves POST /api/users/import: it parses a CSV or JSON body and
+// answers 202 with the new import while it runs in the background
+func (h *UserImportHandler) Start(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	rows, err := userimport.Parse(r.Header.Get("Content-Type"), http.MaxBytesReader(w, r.Body, maxImportBytes), h.importer.MaxRows())
+	if errors.Is(err, userimport.ErrUnsupportedFormat) {
+		return obs.NewError(http.StatusUnsupportedMediaType, err.Error(), nil)
+	}
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return obs.NewError(http.StatusRequestEntityTooLarge, fmt.Sprintf("import must be at most %d bytes", maxImportBytes), nil)
+	}
+	if err != nil {
+		return obs.NewError(http.StatusBadRequest, err.Error(), nil)
+	}
+	if len(rows) == 0 {
+		return obs.NewError(http.StatusBadRequest, "import has no rows", nil)
+	}
+
+	imp, err := h.importer.Start(ctx, rows)
+	if errors.Is(err, database.ErrNotConfigured) {
+		return obs.NewError(http.StatusServiceUnavailable, "Database not available", nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start user import: %w", err)
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/api/users/import/%d", imp.ID))
+	writeJSON(w, http.StatusAccepted, map[string]interface{}{
+		"import":     imp,
+		"events_url": fmt.Sprintf("/api/users/import/%d/events", imp.ID),
+		"trace_id":   tracing.GetTraceID(ctx),
+	})
+	return nil
+}
+
+// Status serves GET /api/users/import/{id}
+func (h *UserImportHandler) Status(w http.ResponseWriter, r *http.Request) error {
+	imp, err := h.get(r)
+	if err != nil {
+		return err
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"import":   imp,
+		"trace_id": tracing.GetTraceID(r.Context()),
+	})
+	return nil
+}
+
+// Events serves GET /api/users/import/{id}/events, a server-sent event
+// stream of the import's progress: a "progress" event whenever it changes
+// and a final "done" event. A stream ends after StreamFor; EventSource
+// clients reconnect on their own.
+func (h *UserImportHandler) Events(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	imp, err := h.get(r)
+	if err != nil {
+		return err
+	}
+
+	// The server's write timeout is shorter than a stream
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Now().Add(h.StreamFor + 5*time.Second))
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "retry: %d\n\n", h.PollInterval.Milliseconds())
+
+	ticker := time.NewTicker(h.PollInterval)
+	defer ticker.Stop()
+	deadline := time.After(h.StreamFor)
+
+	var last time.Time
+	for {
+		if !imp.UpdatedAt.Equal(last) {
+			last = imp.UpdatedAt
+			if err := writeEvent(w, "progress", imp); err != nil {
+				return nil // Client went away
+			}
+		}
+		if imp.Status != database.ImportRunning {
+			writeEvent(w, "done", imp)
+			return nil
+		}
+		rc.Flush()
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-deadline:
+			return nil
+		case <-ticker.C:
+		}
+
+		db := h.store()
+		if db == nil {
+			return nil
+		}
+		next, err := db.GetUserImport(ctx, imp.ID)
+		if err != nil || next == nil {
+			return nil
+		}
+		imp = next
+	}
+}
+
+// get loads the import named by the id path variable
+func (h *UserImportHandler) get(r *http.Request) (*database.UserImport, error) {
+	id, err := strconv.Atoi(mux.Vars(r)["id"])
+	if err != nil {
+		return nil, obs.NewError(http.StatusBadRequest, "id must be a number", nil)
+	}
+	db := h.store()
+	if db == nil {
+		return nil, obs.NewError(http.StatusServiceUnavailable, "Database not available", nil)
+	}
+	imp, err := db.GetUserImport(r.Context(), id)
+	if errors.Is(err, database.ErrNotConfigured) {
+		return nil, obs.NewError(http.StatusServiceUnavailable, "Database not available", nil)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user import: %w", err)
+	}
+	if imp == nil {
+		return nil, obs.NewError(http.StatusNotFound, "import not found", nil)
+	}
+	return imp, nil
+}
+
+// writeEvent writes v as a server-sent event named event
+func writeEvent(w http.ResponseWriter, event string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+	return err
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/mail"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/go-api/pkg/database"
+	"github.com/example/go-api/pkg/metrics"
+	"github.com/example/go-api/pkg/obs"
+	"github.com/example/go-api/pkg/tracing"
+)
+
+// UsersHandler retrieves users from the database
+type UsersHandler struct {
+	store StoreFunc
+}
+
+// NewUsersHandler creates a new UsersHandler. Serve it with obs.Handler.
+func NewUsersHandler(store StoreFunc) *UsersHandler {
+	return &UsersHandler{store: store}
+}
+
+// Serve implements obs.HandlerFunc
+func (h *UsersHandler) Serve(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+
+	db := h.store()
+	if db == nil {
+		return obs.NewError(http.StatusServiceUnavailable, "Database not available", nil)
+	}
+
+	users, err := db.GetUsers(ctx)
+	if errors.Is(err, database.ErrNotConfigured) {
+		return obs.NewError(http.StatusServiceUnavailable, "Database not available", nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get users: %w", err)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"users":    users,
+		"count":    len(users),
+		"trace_id": tracing.GetTraceID(ctx),
+	})
+	return nil
+}
+
+// UserDeleteHandler soft-deletes users
+type UserDeleteHandler struct {
+	store StoreFunc
+}
+
+// NewUserDeleteHandler creates a new UserDeleteHandler. Serve it with
+// obs.Handler.
+func NewUserDeleteHandler(store StoreFunc) *UserDeleteHandler {
+	return &UserDeleteHandler{store: store}
+}
+
+// Serve implements obs.HandlerFunc for DELETE /api/users/{username}
+func (h *UserDeleteHandler) Serve(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	username := mux.Vars(r)["username"]
+
+	db := h.store()
+	if db == nil {
+		return obs.NewError(http.StatusServiceUnavailable, "Database not available", nil)
+	}
+
+	deleted, err := db.DeleteUser(ctx, username)
+	if errors.Is(err, database.ErrNotConfigured) {
+		return obs.NewError(http.StatusServiceUnavailable, "Database not available", nil)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if !deleted {
+		return obs.NewError(http.StatusNotFound, "user not found", nil)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// maxEmailLength is the length of the users.email column
+const maxEmailLength = 255
+
+// UserUpdateHandler updates users with optimistic locking
+type UserUpdateHandler struct {
+	store     StoreFunc
+	conflicts *metrics.Counter
+}
+
+// NewUserUpdateHandler creates a new UserUpdateHandler that counts rejected
+// updates in conflicts_total. Serve it with obs.Handler.
+func NewUserUpdateHandler(store StoreFunc, reg *metrics.Registry) *UserUpdateHandler {
+	return &UserUpdateHandler{
+		store:     store,
+		conflicts: reg.Counter("conflicts_total", "Updates rejected because the record changed after it was read, by resource", "resource"),
+	}
+}
+
+// userConflict is the detail of a 409 answered to a stale update
+type userConflict struct {
+	Resource        string        `json:"resource"`
+	ExpectedVersion int           `json:"expected_version"`
+	CurrentVersion  int           `json:"current_version"`
+	Current         database.User `json:"current"`
+}
+
+// Serve implements obs.HandlerFunc for PUT /api/users/{username}. The body
+// carries the new email and the version it was based on, as read from GET
+// /api/users; an update based on an older version is answered with 409 and
+// the user as stored, to be merged and retried.
+func (h *UserUpdateHandler) Serve(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	span := trace.SpanFromContext(ctx)
+
+	var req struct {
+		Email   string `json:"email"`
+		Version int    `json:"version"`
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<20)).Decode(&req); err != nil {
+		return obs.NewError(http.StatusBadRequest, "invalid request body", err)
+	}
+	var fields []FieldError
+	if addr, err := mail.ParseAddress(req.Email); err != nil || addr.Address != req.Email || len(req.Email) > maxEmailLength {
+		fields = append(fields, FieldError{Field: "email", Message: "must be a valid address of at most 255 characters"})
+	}
+	if req.Version < 1 {
+		fields = append(fields, FieldError{Field: "version", Message: "is required: the version the update is based on"})
+	}
+	if len(fields) > 0 {
+		return &obs.Error{Status: http.StatusBadRequest, Message: "invalid request", Errors: fields}
+	}
+
+	db := h.store()
+	if db == nil {
+		return obs.NewError(http.StatusServiceUnavailable, "Database not available", nil)
+	}
+
+	username := mux.Vars(r)["username"]
+	span.SetAttributes(attribute.Int("user.expected_version", req.Version))
+	user, err := db.UpdateUser(ctx, database.User{Username: username, Email: req.Email, Version: req.Version})
+	var conflict *database.UserConflictError
+	switch {
+	case errors.Is(err, database.ErrNotConfigured):
+		return obs.NewError(http.StatusServiceUnavailable, "Database not available", nil)
+	case errors.As(err, &conflict):
+		h.conflicts.Inc("users")
+		span.AddEvent("version_conflict", trace.WithAttributes(
+			attribute.Int("user.expected_version", conflict.Expected),
+			attribute.Int("user.current_version", conflict.Current.Version),
+		))
+		return &obs.Error{
+			Status:  http.StatusConflict,
+			Message: "user was changed by another request",
+			Err:     err,
+			Details: userConflict{
+				Resource:        "users/" + username,
+				ExpectedVersion: conflict.Expected,
+				CurrentVersion:  conflict.Current.Version,
+				Current:         conflict.Current,
+			},
+		}
+	case err != nil:
+		return fmt.Errorf("failed to update user: %w", err)
+	case user == nil:
+		return obs.NewError(http.StatusNotFound, "user not found", nil)
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"user":     user,
+		"trace_id": tracing.GetTraceID(ctx),
+	})
+	return nil
+}
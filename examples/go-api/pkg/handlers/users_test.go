@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/example/go-api/pkg/database"
+	"github.com/example/go-api/pkg/database/mocks"
+	"github.com/example/go-api/pkg/metrics"
+	"github.com/example/go-api/pkg/obs"
+)
+
+// storeOf returns a StoreFunc serving store, or no store when it is nil
+func storeOf(store *mocks.StoreMock) StoreFunc {
+	return func() database.Store {
+		if store == nil {
+			return nil
+		}
+		return store
+	}
+}
+
+func TestUsersHandler(t *testing.T) {
+	users := []database.User{{ID: 1, Username: "alice"}, {ID: 2, Username: "bob"}}
+
+	cases := []struct {
+		name   string
+		store  *mocks.StoreMock
+		status int
+		count  int
+	}{
+		{"lists users", &mocks.StoreMock{
+			GetUsersFunc: func(context.Context) ([]database.User, error) { return users, nil },
+		}, http.StatusOK, 2},
+		{"no database", nil, http.StatusServiceUnavailable, 0},
+		{"users not configured", &mocks.StoreMock{
+			GetUsersFunc: func(context.Context) ([]database.User, error) { return nil, database.ErrNotConfigured },
+		}, http.StatusServiceUnavailable, 0},
+		{"query fails", &mocks.StoreMock{
+			GetUsersFunc: func(context.Context) ([]database.User, error) { return nil, errors.New("connection reset") },
+		}, http.StatusInternalServerError, 0},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			h := obs.Handler("users", NewUsersHandler(storeOf(c.store)).Serve)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, httptest.NewRequest("GET", "/api/users", nil))
+
+			if w.Code != c.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, c.status, w.Body)
+			}
+			if c.store != nil && len(c.store.GetUsersCalls()) != 1 {
+				t.Errorf("GetUsers called %d times, want 1", len(c.store.GetUsersCalls()))
+			}
+			if c.status != http.StatusOK {
+				return
+			}
+			var body struct {
+				Users []database.User `json:"users"`
+				Count int             `json:"count"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&body); err != nil {
+				t.Fatal(err)
+			}
+			if body.Count != c.count || len(body.Users) != c.count {
+				t.Errorf("got %d users, count %d, want %d", len(body.Users), body.Count, c.count)
+			}
+		})
+	}
+}
+
+func TestUserUpdateHandler(t *testing.T) {
+	stored := database.User{ID: 1, Username: "alice", Email: "alice@example.com", Version: 3}
+
+	cases := []struct {
+		name   string
+		body   string
+		update func(context.Context, database.User) (*database.User, error)
+		status int
+	}{
+		{"updates", `{"email":"alice@example.org","version":3}`, func(_ context.Context, u database.User) (*database.User, error) {
+			u.Version++
+			return &u, nil
+		}, http.StatusOK},
+		{"stale version", `{"email":"alice@example.org","version":2}`, func(_ context.Context, u database.User) (*database.User, error) {
+			return nil, &database.UserConflictError{Expected: u.Version, Current: stored}
+		}, http.StatusConflict},
+		{"unknown user", `{"email":"alice@example.org","version":3}`, func(context.Context, database.User) (*database.User, error) {
+			return nil, nil
+		}, http.StatusNotFound},
+		{"invalid email", `{"email":"alice","version":3}`, nil, http.StatusBadRequest},
+		{"missing version", `{"email":"alice@example.org"}`, nil, http.StatusBadRequest},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			store := &mocks.StoreMock{UpdateUserFunc: c.update}
+			h := NewUserUpdateHandler(storeOf(store), metrics.New("test", prometheus.NewRegistry()))
+			r := mux.SetURLVars(httptest.NewRequest("PUT", "/api/users/alice", strings.NewReader(c.body)), map[string]string{"username": "alice"})
+			w := httptest.NewRecorder()
+			obs.Handler("user_update", h.Serve).ServeHTTP(w, r)
+
+			if w.Code != c.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, c.status, w.Body)
+			}
+			if c.update == nil {
+				if n := len(store.UpdateUserCalls()); n != 0 {
+					t.Errorf("invalid request reached the store %d times", n)
+				}
+				return
+			}
+			calls := store.UpdateUserCalls()
+			if len(calls) != 1 || calls[0].U.Username != "alice" || calls[0].U.Email != "alice@example.org" {
+				t.Fatalf("UpdateUser calls: %+v", calls)
+			}
+			if c.status != http.StatusConflict {
+				return
+			}
+			var env struct {
+				Details userConflict `json:"details"`
+			}
+			if err := json.NewDecoder(w.Body).Decode(&env); err != nil {
+				t.Fatal(err)
+			}
+			if env.Details.ExpectedVersion != 2 || env.Details.CurrentVersion != 3 || env.Details.Current.Email != stored.Email {
+				t.Errorf("conflict details %+v", env.Details)
+			}
+		})
+	}
+}
+
+func TestUserDeleteHandler(t *testing.T) {
+	cases := []struct {
+		name    string
+		deleted bool
+		err     error
+		status  int
+	}{
+		{"deletes", true, nil, http.StatusNoContent},
+		{"unknown user", false, nil, http.StatusNotFound},
+		{"users not configured", false, database.ErrNotConfigured, http.StatusServiceUnavailable},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			store := &mocks.StoreMock{DeleteUserFunc: func(context.Context, string) (bool, error) { return c.deleted, c.err }}
+			r := mux.SetURLVars(httptest.NewRequest("DELETE", "/api/users/alice", nil), map[string]string{"username": "alice"})
+			w := httptest.NewRecorder()
+			obs.Handler("user_delete", NewUserDeleteHandler(storeOf(store)).Serve).ServeHTTP(w, r)
+
+			if w.Code != c.status {
+				t.Fatalf("status %d, want %d: %s", w.Code, c.status, w.Body)
+			}
+			if calls := store.DeleteUserCalls(); len(calls) != 1 || calls[0].Username != "alice" {
+				t.Errorf("DeleteUser calls: %+v", calls)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/gorilla/mux"
+
+	"github.com/example/go-api/pkg/httperr"
+)
+
+// maxLocationLength bounds the location accepted by weather endpoints
+const maxLocationLength = 64
+
+// FieldError describes one invalid input field
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationError collects every invalid field of a request
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, fe := range e.Errors {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Validator checks one input value, returning a message describing the
+// problem or "" when the value is valid
+type Validator func(value string) string
+
+// ValidatePathVars rejects requests whose mux path variables fail their
+// validators with a 400 and structured field errors, before the handler
+// sees them
+func ValidatePathVars(validators map[string]Validator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			vars := mux.Vars(r)
+			verr := &ValidationError{}
+			for field, validate := range validators {
+				value, ok := vars[field]
+				if !ok {
+					continue
+				}
+				if msg := validate(value); msg != "" {
+					verr.Errors = append(verr.Errors, FieldError{Field: field, Message: msg})
+				}
+			}
+			if len(verr.Errors) > 0 {
+				writeValidationError(w, r, verr)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ValidateLocation accepts place names, airport codes, coordinates and
+// wttr.in's "~landmark" form. Characters that could change the upstream URL
+// ("/", "?", "#", "%", "@") or break log lines (control characters) are
+// rejected.
+func ValidateLocation(location string) string {
+	if !utf8.ValidString(location) {
+		return "must be valid UTF-8"
+	}
+	if n := utf8.RuneCountInString(location); n == 0 || n > maxLocationLength {
+		return fmt.Sprintf("must be 1 to %d characters", maxLocationLength)
+	}
+	for _, c := range location {
+		switch {
+		case unicode.IsLetter(c), unicode.IsDigit(c):
+		case strings.ContainsRune(" -.,'~+", c):
+		default:
+			return fmt.Sprintf("contains invalid character %q", c)
+		}
+	}
+	return ""
+}
+
+func writeValidationError(w http.ResponseWriter, r *http.Request, verr *ValidationError) {
+	httperr.WriteEnvelope(w, r, http.StatusBadRequest, httperr.Envelope{
+		Error:  "invalid request",
+		Errors: verr.Errors,
+	})
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/go-api/pkg/obs"
+	"github.com/example/go-api/pkg/tracing"
+)
+
+// WeatherHandler fetches weather data with tracing and caches it
+type WeatherHandler struct {
+	weather WeatherFetcher
+	store   StoreFunc
+	tracer  trace.Tracer
+}
+
+// NewWeatherHandler creates a new WeatherHandler. Serve it with
+// obs.Handler, which provides its span, logger and error responses.
+func NewWeatherHandler(weather WeatherFetcher, store StoreFunc, tracer trace.Tracer) *WeatherHandler {
+	return &WeatherHandler{weather: weather, store: store, tracer: tracer}
+}
+
+// Serve implements obs.HandlerFunc
+func (h *WeatherHandler) Serve(w http.ResponseWriter, r *http.Request) error {
+	ctx := r.Context()
+	location := mux.Vars(r)["location"]
+	if location == "" {
+		location = "London"
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("location", tracing.Truncate(location, maxAttributeLength)))
+
+	// Fetch weather from external API
+	weather, err := h.weather.GetWeather(ctx, location)
+	if err != nil {
+		return err
+	}
+
+	// Cache weather in database (if available)
+	if db := h.store(); db != nil {
+		ctx, dbSpan := h.tracer.Start(ctx, "cache_weather_db")
+		data, _ := json.Marshal(weather)
+		if err := db.SaveWeatherCache(ctx, location, data); err != nil {
+			dbSpan.RecordError(err)
+			l := obs.Logger(ctx)
+			l.Warn().Err(err).Msg("Failed to cache weather data")
+		}
+		dbSpan.End()
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"weather":  weather,
+		"trace_id": tracing.GetTraceID(ctx),
+	})
+	return nil
+}
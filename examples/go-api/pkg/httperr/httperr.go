@@ -0,0 +1,137 @@
+// Package httperr writes error responses that carry the trace and request
+// IDs, so users can quote them verbatim when reporting a problem.
+package httperr
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/tracing"
+)
+
+// Envelope is the JSON body of every error response
+type Envelope struct {
+	Error     string      `json:"error"`
+	Errors    interface{} `json:"errors,omitempty"`
+	Details   interface{} `json:"details,omitempty"` // Structured detail, e.g. of a conflict
+	TraceID   string      `json:"trace_id,omitempty"`
+	RequestID string      `json:"request_id,omitempty"`
+}
+
+// PageData is passed to the HTML error page template
+type PageData struct {
+	Status     int
+	StatusText string
+	Message    string
+	TraceID    string
+	RequestID  string
+}
+
+// DefaultPage is the HTML error page used when no template file is configured
+var DefaultPage = template.Must(template.New("error").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head><meta charset="utf-8"><title>{{.Status}} {{.StatusText}}</title></head>
+<body>
+<h1>{{.Status}} {{.StatusText}}</h1>
+<p>{{.Message}}</p>
+<p>If you report this problem, please include:</p>
+<pre>{{if .TraceID}}Trace ID:   {{.TraceID}}
+{{end}}{{if .RequestID}}Request ID: {{.RequestID}}
+{{end}}</pre>
+</body>
+</html>
+`))
+
+// htmlPage is the template for browser clients; nil serves JSON to everyone
+var htmlPage atomic.Pointer[template.Template]
+
+// SetHTMLPage enables HTML error pages for clients that prefer text/html.
+// A nil template disables them.
+func SetHTMLPage(t *template.Template) {
+	htmlPage.Store(t)
+}
+
+// LoadHTMLPage parses the error page template at path, or returns
+// DefaultPage when path is empty
+func LoadHTMLPage(path string) (*template.Template, error) {
+	if path == "" {
+		return DefaultPage, nil
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read error page template: %w", err)
+	}
+	t, err := template.New("error").Parse(string(b))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse error page template: %w", err)
+	}
+	return t, nil
+}
+
+// Write writes an error response with the given status and message
+func Write(w http.ResponseWriter, r *http.Request, status int, msg string) {
+	WriteEnvelope(w, r, status, Envelope{Error: msg})
+}
+
+// WriteEnvelope writes env with the trace and request IDs filled in. Clients
+// that prefer text/html get the HTML error page when one is enabled.
+func WriteEnvelope(w http.ResponseWriter, r *http.Request, status int, env Envelope) {
+	env.TraceID, env.RequestID = ids(r.Context(), w)
+
+	if t := htmlPage.Load(); t != nil && prefersHTML(r) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(status)
+		t.Execute(w, PageData{
+			Status:     status,
+			StatusText: http.StatusText(status),
+			Message:    env.Error,
+			TraceID:    env.TraceID,
+			RequestID:  env.RequestID,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(env)
+}
+
+// ids returns the trace and request IDs for the response. Middleware further
+// in than the caller may not have stored them in ctx yet, so the response
+// headers set by the logging middleware are used as a fallback.
+func ids(ctx context.Context, w http.ResponseWriter) (traceID, requestID string) {
+	traceID = tracing.GetTraceID(ctx)
+	if traceID == "" {
+		traceID = logger.GetTraceID(ctx)
+	}
+	if traceID == "" {
+		traceID = w.Header().Get("X-Trace-ID")
+	}
+	requestID = logger.GetRequestID(ctx)
+	if requestID == "" {
+		requestID = w.Header().Get("X-Request-ID")
+	}
+	return traceID, requestID
+}
+
+// prefersHTML reports whether the Accept header lists text/html before any
+// JSON type, as browsers do
+func prefersHTML(r *http.Request) bool {
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		switch mediaType {
+		case "text/html", "application/xhtml+xml":
+			return true
+		case "application/json", "application/problem+json":
+			return false
+		}
+	}
+	return false
+}
@@ -0,0 +1,160 @@
+// Package loadgen sends a steady rate of requests to a running instance, to
+// exercise dashboards and alerts or to check a change under load. Requests
+// are counted and timed in the registry the run is given, so a pushed run
+// can be compared with what the service recorded over the same window.
+package loadgen
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/example/go-api/pkg/metrics"
+)
+
+// DefaultPaths are the endpoints requested when Config.Paths is empty. They
+// need no database, so a run works against any instance.
+var DefaultPaths = []string{"/api/hello", "/api/weather/London", "/api/quote", "/api/dashboard"}
+
+// Config holds the instance to load and the shape of the load
+type Config struct {
+	BaseURL     string        // Public server, e.g. http://localhost:8080
+	Paths       []string      // GET paths requested in turn (default DefaultPaths)
+	Rate        float64       // Requests per second (default 10)
+	Duration    time.Duration // Length of the run (default 1m)
+	Concurrency int           // Requests in flight at most (default 10)
+	Timeout     time.Duration // Timeout of each request (default 10s)
+}
+
+// Result summarizes a run
+type Result struct {
+	Requests int64         // Requests sent
+	Failed   int64         // Requests with no response or a 5xx
+	Missed   int64         // Requests not sent because Concurrency were in flight
+	Elapsed  time.Duration // Time from the first request until the last returned
+}
+
+// ErrorRate is the share of sent requests that failed
+func (r Result) ErrorRate() float64 {
+	if r.Requests == 0 {
+		return 0
+	}
+	return float64(r.Failed) / float64(r.Requests)
+}
+
+type runner struct {
+	cfg    Config
+	client *http.Client
+
+	requests *metrics.Counter
+	duration *metrics.Histogram
+
+	sent, failed atomic.Int64
+}
+
+// Run sends requests at cfg.Rate for cfg.Duration, or until ctx is
+// cancelled, and waits for those in flight. Requests are counted in
+// loadgen_requests_total by path and code (the status, or "error" when no
+// response came back) and timed in loadgen_request_duration_seconds by
+// path. Requests not sent because Concurrency were in flight, meaning the
+// instance is slower than the rate allows, are counted in
+// loadgen_missed_total.
+func Run(ctx context.Context, cfg Config, reg *metrics.Registry) Result {
+	if len(cfg.Paths) == 0 {
+		cfg.Paths = DefaultPaths
+	}
+	if cfg.Rate <= 0 {
+		cfg.Rate = 10
+	}
+	if cfg.Duration <= 0 {
+		cfg.Duration = time.Minute
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 10
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	r := &runner{
+		cfg:      cfg,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		requests: reg.Counter("loadgen_requests_total", "Requests sent by the load generator by path and code", "path", "code"),
+		duration: reg.Histogram("loadgen_request_duration_seconds", "Latency of load generator requests by path", nil, "path"),
+	}
+	missed := reg.Counter("loadgen_missed_total", "Requests the load generator skipped because too many were in flight")
+
+	// Requests in flight finish under ctx rather than the run window, so
+	// the end of the run does not turn them into errors
+	window, cancel := context.WithTimeout(ctx, cfg.Duration)
+	defer cancel()
+
+	interval := time.Duration(float64(time.Second) / cfg.Rate)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	slots := make(chan struct{}, cfg.Concurrency)
+	var inFlight sync.WaitGroup
+	var res Result
+	start := time.Now()
+	for i := 0; ; i++ {
+		select {
+		case <-window.Done():
+			inFlight.Wait()
+			res.Requests, res.Failed = r.sent.Load(), r.failed.Load()
+			res.Elapsed = time.Since(start)
+			return res
+		case <-ticker.C:
+		}
+
+		select {
+		case slots <- struct{}{}:
+		default:
+			missed.Inc()
+			res.Missed++
+			continue
+		}
+		path := cfg.Paths[i%len(cfg.Paths)]
+		inFlight.Add(1)
+		go func() {
+			defer inFlight.Done()
+			defer func() { <-slots }()
+			r.send(ctx, path)
+		}()
+	}
+}
+
+// send requests path once and records the outcome
+func (r *runner) send(ctx context.Context, path string) {
+	start := time.Now()
+	status, err := r.get(ctx, path)
+	r.duration.Since(start, path)
+
+	code := "error"
+	if err == nil {
+		code = strconv.Itoa(status)
+	}
+	r.requests.Inc(path, code)
+	r.sent.Add(1)
+	if err != nil || status >= http.StatusInternalServerError {
+		r.failed.Add(1)
+	}
+}
+
+// get requests path and returns the response status
+func (r *runner) get(ctx context.Context, path string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.cfg.BaseURL+path, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
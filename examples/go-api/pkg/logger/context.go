@@ -0,0 +1,57 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// LoggerKey stores a *Logger in the context
+const LoggerKey ContextKey = "logger"
+
+// NewContext returns a context carrying l, for code that has a context but
+// no logger of its own
+func NewContext(ctx context.Context, l *Logger) context.Context {
+	return context.WithValue(ctx, LoggerKey, l)
+}
+
+// FromContext returns the logger stored by NewContext, or nil
+func FromContext(ctx context.Context) *Logger {
+	l, _ := ctx.Value(LoggerKey).(*Logger)
+	return l
+}
+
+// Ctx returns the context's logger with trace fields. Without one it falls
+// back to Default(), still adding the trace and span of ctx,
+// so code that has a context never needs to log without it:
+//
+//	logger.Ctx(ctx).Warn().Err(err).Msg("Failed to annotate deployment")
+func Ctx(ctx context.Context) *zerolog.Logger {
+	var l zerolog.Logger
+	if ctxLog := FromContext(ctx); ctxLog != nil {
+		l = ctxLog.WithContext(ctx)
+	} else {
+		l = Default().WithContext(ctx)
+	}
+	return &l
+}
+
+// Named returns a child logger whose lines carry component=name. Its level
+// is the override for name under the parent's component, e.g. "http.hello"
+// for Named("hello") on the "http" logger, falling back to "http" and then
+// to the base level.
+func (l *Logger) Named(name string) *Logger {
+	component := name
+	if l.component != "" {
+		component = l.component + "." + name
+	}
+	named := l.unnamed.With().Str("component", name).Logger()
+	counter := newEntryCounter(component)
+	return &Logger{
+		zlog:      named.With().Caller().Logger().Hook(counter),
+		hot:       named.Hook(counter),
+		unnamed:   l.unnamed,
+		levels:    l.levels,
+		component: component,
+	}
+}
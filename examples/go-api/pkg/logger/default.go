@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+)
+
+var defaultLogger atomic.Pointer[Logger]
+
+// Default returns the process-wide logger. Until SetDefault is called it is
+// a JSON logger on stdout at info level, created on first use, so packages
+// can log before main has wired the configured one.
+func Default() *Logger {
+	if l := defaultLogger.Load(); l != nil {
+		return l
+	}
+	l := New(Config{
+		AppName: strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe"),
+		Level:   "info",
+	})
+	if defaultLogger.CompareAndSwap(nil, l) {
+		return l
+	}
+	return defaultLogger.Load()
+}
+
+// SetDefault replaces the process-wide logger and returns the previous one,
+// e.g. for a test to capture lines and restore it afterwards:
+//
+//	prev := logger.SetDefault(logger.New(logger.Config{Output: &buf}))
+//	defer logger.SetDefault(prev)
+//
+// Passing nil resets it to the lazily created stdout logger.
+func SetDefault(l *Logger) *Logger {
+	return defaultLogger.Swap(l)
+}
+
+// OrDefault returns l, or Default() when l is nil. Packages taking an
+// optional logger call it when logging, so they pick up SetDefault even if
+// they were created before it.
+func OrDefault(l *Logger) *Logger {
+	if l != nil {
+		return l
+	}
+	return Default()
+}
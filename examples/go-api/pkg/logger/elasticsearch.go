@@ -0,0 +1,307 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+// Outcomes of lines handed to a log sink, recorded in log_sink_lines_total
+const (
+	sinkDelivered    = "delivered"     // Accepted by the backend
+	sinkDropped      = "dropped"       // Queue full or sink closed, line discarded
+	sinkDeadLettered = "dead_lettered" // Written to the dead-letter file
+	sinkLost         = "lost"          // Rejected and no dead-letter file could take it
+)
+
+var sinkLines = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "log_sink_lines_total",
+		Help: "Log lines handed to a sink by sink and outcome (delivered, dropped, dead_lettered, lost)",
+	},
+	[]string{"sink", "outcome"},
+)
+
+var sinkRetries = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "log_sink_retries_total",
+		Help: "Retried sink deliveries by sink",
+	},
+	[]string{"sink"},
+)
+
+func init() {
+	prometheus.MustRegister(sinkLines)
+	prometheus.MustRegister(sinkRetries)
+}
+
+// ElasticsearchConfig configures bulk indexing into Elasticsearch or
+// OpenSearch. Zero values use the defaults below.
+type ElasticsearchConfig struct {
+	URL            string        // e.g. "http://elasticsearch:9200"
+	Index          string        // Index or data stream (default "logs-go-api")
+	APIKey         func() string // Optional, read per request so rotations apply
+	BatchSize      int           // Lines per bulk request (default 500)
+	FlushInterval  time.Duration // Maximum time a line waits for its batch (default 2s)
+	QueueSize      int           // Lines buffered before new ones are dropped (default 10000)
+	MaxRetries     int           // Retries of a failed bulk request (default 5)
+	InitialBackoff time.Duration // Delay before the first retry, doubling each time (default 500ms)
+	MaxBackoff     time.Duration // Upper bound for the backoff (default 30s)
+	DeadLetterPath string        // Lines that could not be indexed are appended here; dropped when empty
+	HTTPClient     *http.Client  // Default has a 10s timeout
+}
+
+// ElasticsearchSink is an io.Writer that bulk-indexes log lines in the
+// background. Writes never block logging: when the queue is full, lines are
+// dropped and counted. Register it with WithSink and Close it on shutdown.
+type ElasticsearchSink struct {
+	cfg   ElasticsearchConfig
+	queue *sinkQueue[[]byte]
+	done  chan struct{}
+	errs  zerolog.Logger // Sink failures go to stderr, never back into the sink
+}
+
+// NewElasticsearchSink creates the sink and starts its flush loop
+func NewElasticsearchSink(cfg ElasticsearchConfig) *ElasticsearchSink {
+	if cfg.Index == "" {
+		cfg.Index = "logs-go-api"
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 2 * time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+
+	s := &ElasticsearchSink{
+		cfg:   cfg,
+		queue: newSinkQueue[[]byte](cfg.QueueSize),
+		done:  make(chan struct{}),
+		errs:  zerolog.New(os.Stderr).With().Timestamp().Str("sink", "elasticsearch").Logger(),
+	}
+	go s.run()
+	return s
+}
+
+// Write implements io.Writer. It queues a copy of p and never fails; lines
+// written after Close are dropped.
+func (s *ElasticsearchSink) Write(p []byte) (int, error) {
+	// zerolog reuses p after Write returns
+	line := make([]byte, len(p))
+	copy(line, p)
+	if !s.queue.push(line) {
+		sinkLines.WithLabelValues("elasticsearch", sinkDropped).Inc()
+	}
+	return len(p), nil
+}
+
+// QueueFill is the share of the sink's queue in use, from 0 to 1. Lines are
+// dropped once it reaches 1.
+func (s *ElasticsearchSink) QueueFill() float64 {
+	return s.queue.fill()
+}
+
+// Close flushes queued lines and stops the flush loop, waiting until ctx
+// expires. Call it last on shutdown, so the final log lines are indexed.
+// It is safe to call while other goroutines are still logging.
+func (s *ElasticsearchSink) Close(ctx context.Context) error {
+	s.queue.close()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush elasticsearch sink: %w", ctx.Err())
+	}
+}
+
+func (s *ElasticsearchSink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([][]byte, 0, s.cfg.BatchSize)
+	for {
+		select {
+		case line := <-s.queue.records:
+			batch = append(batch, line)
+			if len(batch) < s.cfg.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+		case <-s.queue.stop:
+			for line, ok := s.queue.drain(); ok; line, ok = s.queue.drain() {
+				if batch = append(batch, line); len(batch) == s.cfg.BatchSize {
+					s.flush(batch)
+					batch = make([][]byte, 0, s.cfg.BatchSize)
+				}
+			}
+			s.flush(batch)
+			return
+		}
+		s.flush(batch)
+		batch = make([][]byte, 0, s.cfg.BatchSize)
+	}
+}
+
+// flush indexes batch, retrying the lines Elasticsearch could not take yet
+// with exponential backoff, and dead-letters what is left
+func (s *ElasticsearchSink) flush(batch [][]byte) {
+	backoff := s.cfg.InitialBackoff
+	for attempt := 0; len(batch) > 0; attempt++ {
+		retry, rejected, err := s.bulk(batch)
+		s.deadLetter(rejected, "rejected")
+		if err == nil {
+			sinkLines.WithLabelValues("elasticsearch", sinkDelivered).Add(float64(len(batch) - len(retry) - len(rejected)))
+		}
+		if len(retry) == 0 {
+			return
+		}
+		if attempt >= s.cfg.MaxRetries {
+			s.errs.Error().Err(err).Int("lines", len(retry)).Msg("Giving up on bulk indexing after retries")
+			s.deadLetter(retry, "retries exhausted")
+			return
+		}
+
+		sinkRetries.WithLabelValues("elasticsearch").Inc()
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > s.cfg.MaxBackoff {
+			backoff = s.cfg.MaxBackoff
+		}
+		batch = retry
+	}
+}
+
+// bulkResponse is the part of the _bulk response needed to find failed items
+type bulkResponse struct {
+	Errors bool `json:"errors"`
+	Items  []map[string]struct {
+		Status int             `json:"status"`
+		Error  json.RawMessage `json:"error"`
+	} `json:"items"`
+}
+
+// bulk sends one _bulk request. It returns the lines worth retrying (the
+// whole batch on network errors, 429 and 5xx, or items rejected with 429)
+// and the lines rejected for good, e.g. mapping conflicts.
+func (s *ElasticsearchSink) bulk(batch [][]byte) (retry, rejected [][]byte, err error) {
+	action := []byte(fmt.Sprintf(`{"create":{"_index":%q}}`+"\n", s.cfg.Index))
+	var body bytes.Buffer
+	for _, line := range batch {
+		body.Write(action)
+		body.Write(bytes.TrimRight(line, "\n"))
+		body.WriteByte('\n')
+	}
+
+	req, err := http.NewRequest(http.MethodPost, s.cfg.URL+"/_bulk", &body)
+	if err != nil {
+		return nil, batch, fmt.Errorf("failed to create bulk request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if s.cfg.APIKey != nil {
+		if key := s.cfg.APIKey(); key != "" {
+			req.Header.Set("Authorization", "ApiKey "+key)
+		}
+	}
+
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return batch, nil, fmt.Errorf("failed to send bulk request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		io.Copy(io.Discard, resp.Body)
+		return batch, nil, fmt.Errorf("bulk request returned status %d", resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("bulk request returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+		s.errs.Error().Err(err).Int("lines", len(batch)).Msg("Bulk request rejected")
+		return nil, batch, err
+	}
+
+	var result bulkResponse
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		// The request went through; without the items there is nothing to retry
+		s.errs.Warn().Err(err).Msg("Failed to decode bulk response")
+		return nil, nil, nil
+	}
+	if !result.Errors {
+		return nil, nil, nil
+	}
+	for i, item := range result.Items {
+		if i >= len(batch) {
+			break
+		}
+		for _, r := range item {
+			switch {
+			case r.Status == http.StatusTooManyRequests:
+				retry = append(retry, batch[i])
+			case r.Status >= 300:
+				s.errs.Warn().Int("status", r.Status).RawJSON("reason", r.Error).Msg("Log line rejected by elasticsearch")
+				rejected = append(rejected, batch[i])
+			}
+		}
+	}
+	return retry, rejected, nil
+}
+
+// deadLetter appends lines to the dead-letter file, so they can be replayed
+// with the _bulk API once the cause is fixed
+func (s *ElasticsearchSink) deadLetter(lines [][]byte, reason string) {
+	if len(lines) == 0 {
+		return
+	}
+	if s.cfg.DeadLetterPath == "" {
+		sinkLines.WithLabelValues("elasticsearch", sinkLost).Add(float64(len(lines)))
+		return
+	}
+
+	f, err := os.OpenFile(s.cfg.DeadLetterPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err == nil {
+		for _, line := range lines {
+			if _, err = f.Write(append(bytes.TrimRight(line, "\n"), '\n')); err != nil {
+				break
+			}
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		s.errs.Error().Err(err).Str("path", s.cfg.DeadLetterPath).Int("lines", len(lines)).Msg("Failed to write dead-letter file")
+		sinkLines.WithLabelValues("elasticsearch", sinkLost).Add(float64(len(lines)))
+		return
+	}
+	s.errs.Warn().Str("reason", reason).Int("lines", len(lines)).Msg("Log lines written to dead-letter file")
+	sinkLines.WithLabelValues("elasticsearch", sinkDeadLettered).Add(float64(len(lines)))
+}
@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// closeWhileWriting closes a sink while goroutines keep writing to it, as
+// request goroutines do during shutdown. It fails when Close returns an
+// error, and panics, failing the test, when a write hits a closed queue.
+func closeWhileWriting(t *testing.T, w io.Writer, closeSink func(context.Context) error) {
+	t.Helper()
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+					w.Write([]byte(`{"level":"info","msg":"during shutdown"}` + "\n"))
+				}
+			}
+		}()
+	}
+
+	time.Sleep(10 * time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := closeSink(ctx); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	// Writes after Close are dropped rather than panicking
+	time.Sleep(10 * time.Millisecond)
+	close(stop)
+	wg.Wait()
+}
+
+func TestElasticsearchSinkCloseWhileWriting(t *testing.T) {
+	var lines atomic.Int64
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		lines.Add(int64(bytes.Count(body, []byte("\n")) / 2))
+		w.Write([]byte(`{"errors":false}`))
+	}))
+	defer srv.Close()
+
+	sink := NewElasticsearchSink(ElasticsearchConfig{URL: srv.URL, BatchSize: 50, FlushInterval: time.Hour, QueueSize: 100})
+	sink.Write([]byte(`{"level":"info","msg":"before shutdown"}` + "\n"))
+	closeWhileWriting(t, sink, sink.Close)
+
+	if lines.Load() == 0 {
+		t.Error("no lines were indexed on Close")
+	}
+}
@@ -0,0 +1,64 @@
+package logger
+
+import (
+	"encoding/json"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// ErrorBuffer keeps the most recent error-level log lines in memory so they
+// can be included in diagnostics bundles. It is a zerolog.LevelWriter.
+type ErrorBuffer struct {
+	mu      sync.Mutex
+	entries []json.RawMessage
+	next    int
+	full    bool
+}
+
+// NewErrorBuffer creates a new ErrorBuffer holding up to size entries
+func NewErrorBuffer(size int) *ErrorBuffer {
+	if size <= 0 {
+		size = 100
+	}
+	return &ErrorBuffer{entries: make([]json.RawMessage, size)}
+}
+
+// Write implements io.Writer. Lines without a level are ignored.
+func (b *ErrorBuffer) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+// WriteLevel implements zerolog.LevelWriter
+func (b *ErrorBuffer) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if level < zerolog.ErrorLevel || level == zerolog.NoLevel {
+		return len(p), nil
+	}
+
+	// zerolog reuses p after Write returns
+	entry := make(json.RawMessage, len(p))
+	copy(entry, p)
+
+	b.mu.Lock()
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+	b.mu.Unlock()
+
+	return len(p), nil
+}
+
+// Entries returns the buffered lines, oldest first
+func (b *ErrorBuffer) Entries() []json.RawMessage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.full {
+		return append([]json.RawMessage(nil), b.entries[:b.next]...)
+	}
+	out := make([]json.RawMessage, 0, len(b.entries))
+	out = append(out, b.entries[b.next:]...)
+	return append(out, b.entries[:b.next]...)
+}
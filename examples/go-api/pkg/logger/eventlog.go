@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"hash/fnv"
+	"strings"
+	"unicode"
+
+	"github.com/rs/zerolog"
+)
+
+// Event IDs of the Windows Event Log sink. Lines without an error use a
+// fixed ID per level; error lines get an ID from their fingerprint in
+// [eventIDErrorBase, eventIDErrorBase+eventIDErrorRange), so collectors can
+// alert on one recurring error. IDs stay at or below 1000, the range the
+// EventCreate message file registered by InstallAsEventCreate covers.
+const (
+	eventIDInfo       = 1
+	eventIDWarn       = 2
+	eventIDError      = 3 // Error-level lines without an error field
+	eventIDDebug      = 4
+	eventIDErrorBase  = 100
+	eventIDErrorRange = 900
+)
+
+// EventLogConfig configures the Windows Event Log sink
+type EventLogConfig struct {
+	Source  string // Event source shown in Event Viewer (default: program name)
+	Install bool   // Register the source when missing; needs administrator rights, skipped without them
+}
+
+// eventID returns the event ID of a line
+func eventID(level zerolog.Level, fields map[string]string) uint32 {
+	switch level {
+	case zerolog.ErrorLevel, zerolog.FatalLevel, zerolog.PanicLevel:
+		if fp := errorFingerprint(fields); fp != "" {
+			h := fnv.New32a()
+			h.Write([]byte(fp))
+			return eventIDErrorBase + h.Sum32()%eventIDErrorRange
+		}
+		return eventIDError
+	case zerolog.WarnLevel:
+		return eventIDWarn
+	case zerolog.DebugLevel, zerolog.TraceLevel:
+		return eventIDDebug
+	}
+	return eventIDInfo
+}
+
+// errorFingerprint identifies an error independently of the values in its
+// message: digits are collapsed, so "timeout after 30s" and "timeout after
+// 5s" share a fingerprint, and the location where it was logged is added
+func errorFingerprint(fields map[string]string) string {
+	msg := fields[DefaultFieldNames.Error]
+	if msg == "" {
+		return ""
+	}
+	var b strings.Builder
+	digits := false
+	for _, r := range msg {
+		if unicode.IsDigit(r) {
+			if !digits {
+				b.WriteByte('#')
+			}
+			digits = true
+			continue
+		}
+		digits = false
+		b.WriteRune(r)
+	}
+	return b.String() + "|" + fields["error_location"]
+}
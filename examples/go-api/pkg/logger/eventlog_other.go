@@ -0,0 +1,27 @@
+//go:build !windows
+
+package logger
+
+import (
+	"errors"
+
+	"github.com/rs/zerolog"
+)
+
+// EventLogSink writes to the Windows Event Log. It is only available on
+// Windows.
+type EventLogSink struct{}
+
+// NewEventLogSink fails outside Windows
+func NewEventLogSink(cfg EventLogConfig) (*EventLogSink, error) {
+	return nil, errors.New("the Windows Event Log is only available on Windows")
+}
+
+// Write implements io.Writer
+func (s *EventLogSink) Write(p []byte) (int, error) { return len(p), nil }
+
+// WriteLevel implements zerolog.LevelWriter
+func (s *EventLogSink) WriteLevel(level zerolog.Level, p []byte) (int, error) { return len(p), nil }
+
+// Close implements io.Closer
+func (s *EventLogSink) Close() error { return nil }
@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"golang.org/x/sys/windows/svc/eventlog"
+)
+
+// EventLogSink writes each line to the Windows Application event log, so
+// existing collectors (Winlogbeat, the OpenTelemetry windowseventlog
+// receiver) pick it up. The event message is the JSON line; the event type
+// follows the level and the event ID the error fingerprint.
+type EventLogSink struct {
+	log *eventlog.Log
+}
+
+// NewEventLogSink opens the event log for cfg.Source
+func NewEventLogSink(cfg EventLogConfig) (*EventLogSink, error) {
+	if cfg.Source == "" {
+		cfg.Source = strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	}
+	if cfg.Install {
+		// Fails when the source exists or without administrator rights.
+		// Events from an unregistered source are still written, Event
+		// Viewer just cannot format their description.
+		_ = eventlog.InstallAsEventCreate(cfg.Source, eventlog.Error|eventlog.Warning|eventlog.Info)
+	}
+	l, err := eventlog.Open(cfg.Source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event log for %s: %w", cfg.Source, err)
+	}
+	return &EventLogSink{log: l}, nil
+}
+
+// Write implements io.Writer for lines without a level
+func (s *EventLogSink) Write(p []byte) (int, error) {
+	return s.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel implements zerolog.LevelWriter
+func (s *EventLogSink) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	fields, _, _ := parseLine(p)
+	id := eventID(level, fields)
+	msg := string(bytes.TrimRight(p, "\n"))
+
+	var err error
+	switch level {
+	case zerolog.ErrorLevel, zerolog.FatalLevel, zerolog.PanicLevel:
+		err = s.log.Error(id, msg)
+	case zerolog.WarnLevel:
+		err = s.log.Warning(id, msg)
+	default:
+		err = s.log.Info(id, msg)
+	}
+	if err != nil {
+		sinkLines.WithLabelValues("eventlog", sinkLost).Inc()
+		return len(p), nil
+	}
+	sinkLines.WithLabelValues("eventlog", sinkDelivered).Inc()
+	return len(p), nil
+}
+
+// Close closes the event log handle
+func (s *EventLogSink) Close() error {
+	return s.log.Close()
+}
@@ -0,0 +1,181 @@
+package logger
+
+import (
+	"io"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// FieldNames names the standard fields of a log line. Empty names use
+// DefaultFieldNames.
+type FieldNames struct {
+	Level   string
+	Message string
+	Time    string
+	Caller  string
+	Error   string
+}
+
+// DefaultFieldNames is the schema the Loki pipeline parses. The log sinks,
+// the logfmt format and the OTLP output recognise these names, so change
+// them only for stdout consumers that expect others.
+var DefaultFieldNames = FieldNames{
+	Level:   "level",
+	Message: "msg",
+	Time:    "time",
+	Caller:  "caller",
+	Error:   "error",
+}
+
+func (n FieldNames) withDefaults() FieldNames {
+	if n.Level == "" {
+		n.Level = DefaultFieldNames.Level
+	}
+	if n.Message == "" {
+		n.Message = DefaultFieldNames.Message
+	}
+	if n.Time == "" {
+		n.Time = DefaultFieldNames.Time
+	}
+	if n.Caller == "" {
+		n.Caller = DefaultFieldNames.Caller
+	}
+	if n.Error == "" {
+		n.Error = DefaultFieldNames.Error
+	}
+	return n
+}
+
+// renameWriter renames the top-level keys zerolog writes under its
+// package-level field names (zerolog.MessageFieldName and so on) to the
+// logger's own names. This keeps New from mutating zerolog's globals, which
+// other libraries in the process may rely on. Lines are passed through
+// untouched when the names already match, the common case.
+type renameWriter struct {
+	out   io.Writer
+	names FieldNames
+}
+
+func renameFields(out io.Writer, names FieldNames) io.Writer {
+	return &renameWriter{out: out, names: names}
+}
+
+// Write implements io.Writer
+func (w *renameWriter) Write(p []byte) (int, error) {
+	return w.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel implements zerolog.LevelWriter, passing the level on to
+// writers that use it
+func (w *renameWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if !w.needsRename() {
+		return w.write(level, p, p)
+	}
+	// Writers must not keep a line after Write returns, so the renamed copy
+	// goes back to the pool for the next line
+	buf := renameBuffers.Get().(*[]byte)
+	line := w.rename((*buf)[:0], p)
+	n, err := w.write(level, p, line)
+	if cap(line) <= maxPooledRenameBuffer && len(line) > 0 && &line[0] != &p[0] {
+		*buf = line[:0]
+		renameBuffers.Put(buf)
+	}
+	return n, err
+}
+
+func (w *renameWriter) write(level zerolog.Level, p, line []byte) (int, error) {
+	var err error
+	if lw, ok := w.out.(zerolog.LevelWriter); ok {
+		_, err = lw.WriteLevel(level, line)
+	} else {
+		_, err = w.out.Write(line)
+	}
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// renameBuffers holds the buffers lines are renamed into. Buffers grown
+// past maxPooledRenameBuffer by a huge line are dropped, as zerolog does
+// with its event buffers.
+var renameBuffers = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 0, 1024)
+		return &b
+	},
+}
+
+const maxPooledRenameBuffer = 64 << 10
+
+// needsRename reads zerolog's globals on every line, since they may change
+// after the logger was created
+func (w *renameWriter) needsRename() bool {
+	return zerolog.LevelFieldName != w.names.Level ||
+		zerolog.MessageFieldName != w.names.Message ||
+		zerolog.TimestampFieldName != w.names.Time ||
+		zerolog.CallerFieldName != w.names.Caller ||
+		zerolog.ErrorFieldName != w.names.Error
+}
+
+// rename appends a copy of the JSON object p with its top-level keys renamed
+// to out. Nested objects and values are copied as they are. An incomplete
+// line is returned as it is.
+func (w *renameWriter) rename(out, p []byte) []byte {
+	depth := 0
+	expectKey := false
+	for i := 0; i < len(p); i++ {
+		c := p[i]
+		if c == '"' {
+			end := i + 1
+			for end < len(p) && p[end] != '"' {
+				if p[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(p) {
+				return p // Not a complete JSON line
+			}
+			if depth == 1 && expectKey {
+				out = append(out, '"')
+				out = append(out, w.lookup(p[i+1:end])...)
+				out = append(out, '"')
+				expectKey = false
+			} else {
+				out = append(out, p[i:end+1]...)
+			}
+			i = end
+			continue
+		}
+
+		switch c {
+		case '{', '[':
+			depth++
+			expectKey = depth == 1 && c == '{'
+		case '}', ']':
+			depth--
+		case ',':
+			expectKey = depth == 1
+		}
+		out = append(out, c)
+	}
+	return out
+}
+
+func (w *renameWriter) lookup(key []byte) []byte {
+	switch {
+	case string(key) == zerolog.LevelFieldName:
+		return []byte(w.names.Level)
+	case string(key) == zerolog.MessageFieldName:
+		return []byte(w.names.Message)
+	case string(key) == zerolog.TimestampFieldName:
+		return []byte(w.names.Time)
+	case string(key) == zerolog.CallerFieldName:
+		return []byte(w.names.Caller)
+	case string(key) == zerolog.ErrorFieldName:
+		return []byte(w.names.Error)
+	}
+	return key
+}
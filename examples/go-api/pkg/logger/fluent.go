@@ -0,0 +1,269 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// FluentConfig configures a Fluentd / Fluent Bit forward protocol sink.
+// Zero values use the defaults below.
+type FluentConfig struct {
+	Addr           string        // host:port of the forward input (default "localhost:24224")
+	Tag            string        // Event tag routed on by Fluent Bit (default "go-api")
+	TLSConfig      *tls.Config   // Connect with TLS when set
+	RequireAck     bool          // Wait for the server to acknowledge each chunk and resend otherwise
+	AckTimeout     time.Duration // How long to wait for an ack (default 5s)
+	BatchSize      int           // Events per chunk (default 500)
+	FlushInterval  time.Duration // Maximum time an event waits for its chunk (default 1s)
+	QueueSize      int           // Events buffered before new ones are dropped (default 10000)
+	MaxRetries     int           // Resends of a chunk before it is dropped (default 5)
+	InitialBackoff time.Duration // Delay before the first resend, doubling each time (default 500ms)
+	MaxBackoff     time.Duration // Upper bound for the backoff (default 30s)
+}
+
+// FluentSink ships log lines with the forward protocol (MessagePack over
+// TCP) in Forward mode: one [tag, [[time, record], ...], options] message per
+// chunk. Like ElasticsearchSink it queues lines so logging never waits on
+// the network; with RequireAck, chunks are resent until acknowledged.
+type FluentSink struct {
+	cfg   FluentConfig
+	queue *sinkQueue[[]byte]
+	done  chan struct{}
+	errs  zerolog.Logger
+
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewFluentSink creates the sink and starts its flush loop. It connects
+// lazily, so Fluent Bit may start after the service.
+func NewFluentSink(cfg FluentConfig) *FluentSink {
+	if cfg.Addr == "" {
+		cfg.Addr = "localhost:24224"
+	}
+	if cfg.Tag == "" {
+		cfg.Tag = "go-api"
+	}
+	if cfg.AckTimeout <= 0 {
+		cfg.AckTimeout = 5 * time.Second
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+
+	s := &FluentSink{
+		cfg:   cfg,
+		queue: newSinkQueue[[]byte](cfg.QueueSize),
+		done:  make(chan struct{}),
+		errs:  zerolog.New(os.Stderr).With().Timestamp().Str("sink", "fluent").Logger(),
+	}
+	go s.run()
+	return s
+}
+
+// Write implements io.Writer. It queues a copy of p and never fails; lines
+// written after Close are dropped.
+func (s *FluentSink) Write(p []byte) (int, error) {
+	line := make([]byte, len(p))
+	copy(line, p)
+	if !s.queue.push(line) {
+		sinkLines.WithLabelValues("fluent", sinkDropped).Inc()
+	}
+	return len(p), nil
+}
+
+// QueueFill is the share of the sink's queue in use, from 0 to 1. Lines are
+// dropped once it reaches 1.
+func (s *FluentSink) QueueFill() float64 {
+	return s.queue.fill()
+}
+
+// Close flushes queued lines and closes the connection, waiting until ctx
+// expires. It is safe to call while other goroutines are still logging.
+func (s *FluentSink) Close(ctx context.Context) error {
+	s.queue.close()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush fluent sink: %w", ctx.Err())
+	}
+}
+
+func (s *FluentSink) run() {
+	defer close(s.done)
+	defer s.disconnect()
+
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([][]byte, 0, s.cfg.BatchSize)
+	for {
+		select {
+		case line := <-s.queue.records:
+			batch = append(batch, line)
+			if len(batch) < s.cfg.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+		case <-s.queue.stop:
+			for line, ok := s.queue.drain(); ok; line, ok = s.queue.drain() {
+				if batch = append(batch, line); len(batch) == s.cfg.BatchSize {
+					s.flush(batch)
+					batch = make([][]byte, 0, s.cfg.BatchSize)
+				}
+			}
+			s.flush(batch)
+			return
+		}
+		s.flush(batch)
+		batch = make([][]byte, 0, s.cfg.BatchSize)
+	}
+}
+
+// flush sends batch as one chunk, reconnecting and resending with backoff
+func (s *FluentSink) flush(batch [][]byte) {
+	if len(batch) == 0 {
+		return
+	}
+	chunk, msg := s.encode(batch)
+
+	backoff := s.cfg.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		err := s.send(chunk, msg)
+		if err == nil {
+			sinkLines.WithLabelValues("fluent", sinkDelivered).Add(float64(len(batch)))
+			return
+		}
+		s.disconnect()
+		if attempt >= s.cfg.MaxRetries {
+			s.errs.Error().Err(err).Int("lines", len(batch)).Msg("Giving up on forwarding chunk after retries")
+			sinkLines.WithLabelValues("fluent", sinkLost).Add(float64(len(batch)))
+			return
+		}
+		sinkRetries.WithLabelValues("fluent").Inc()
+		time.Sleep(backoff)
+		backoff *= 2
+		if backoff > s.cfg.MaxBackoff {
+			backoff = s.cfg.MaxBackoff
+		}
+	}
+}
+
+// encode builds the Forward mode message for batch. Each JSON line becomes
+// a record; its time field becomes the event time.
+func (s *FluentSink) encode(batch [][]byte) (chunk string, msg []byte) {
+	w := &msgpackWriter{}
+	w.arrayHeader(3)
+	w.str(s.cfg.Tag)
+	w.arrayHeader(len(batch))
+	for _, line := range batch {
+		record := map[string]interface{}{}
+		dec := json.NewDecoder(bytes.NewReader(line))
+		dec.UseNumber()
+		if err := dec.Decode(&record); err != nil {
+			record = map[string]interface{}{DefaultFieldNames.Message: string(bytes.TrimRight(line, "\n"))}
+		}
+		ts := time.Now()
+		if v, ok := record[DefaultFieldNames.Time].(string); ok {
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				ts = t
+			}
+		}
+		w.arrayHeader(2)
+		w.eventTime(ts)
+		w.value(record)
+	}
+
+	opts := map[string]interface{}{"size": json.Number(fmt.Sprint(len(batch)))}
+	if s.cfg.RequireAck {
+		id := make([]byte, 16)
+		rand.Read(id)
+		chunk = base64.StdEncoding.EncodeToString(id)
+		opts["chunk"] = chunk
+	}
+	w.value(opts)
+	return chunk, w.buf
+}
+
+// send writes one message and, in ack mode, waits for its ack
+func (s *FluentSink) send(chunk string, msg []byte) error {
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			return err
+		}
+	}
+
+	s.conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if _, err := s.conn.Write(msg); err != nil {
+		return fmt.Errorf("failed to write chunk: %w", err)
+	}
+	if !s.cfg.RequireAck {
+		return nil
+	}
+
+	s.conn.SetReadDeadline(time.Now().Add(s.cfg.AckTimeout))
+	resp, err := readMsgpackStringMap(s.reader)
+	if err != nil {
+		return fmt.Errorf("failed to read ack: %w", err)
+	}
+	if resp["ack"] != chunk {
+		return fmt.Errorf("ack for chunk %q, expected %q", resp["ack"], chunk)
+	}
+	return nil
+}
+
+func (s *FluentSink) connect() error {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	var (
+		conn net.Conn
+		err  error
+	)
+	if s.cfg.TLSConfig != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.cfg.Addr, s.cfg.TLSConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", s.cfg.Addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to fluent at %s: %w", s.cfg.Addr, err)
+	}
+	s.conn = conn
+	s.reader = bufio.NewReader(conn)
+	return nil
+}
+
+func (s *FluentSink) disconnect() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+		s.reader = nil
+	}
+}
@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestFluentSinkCloseWhileWriting(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	var received atomic.Int64
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				n, _ := io.Copy(io.Discard, conn)
+				received.Add(n)
+			}()
+		}
+	}()
+
+	sink := NewFluentSink(FluentConfig{Addr: ln.Addr().String(), BatchSize: 50, FlushInterval: time.Hour, QueueSize: 100})
+	sink.Write([]byte(`{"level":"info","msg":"before shutdown"}` + "\n"))
+	closeWhileWriting(t, sink, sink.Close)
+
+	// The connection is closed once Close returns, so the copy has ended
+	deadline := time.Now().Add(time.Second)
+	for received.Load() == 0 && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	if received.Load() == 0 {
+		t.Error("no chunk was forwarded on Close")
+	}
+}
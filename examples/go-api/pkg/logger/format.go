@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/rs/zerolog"
+)
+
+// Output formats for Config.Format
+const (
+	FormatJSON    = "json"    // One JSON object per line (default), parsed by the Loki pipeline
+	FormatLogfmt  = "logfmt"  // key=value pairs, read by LogQL's logfmt parser
+	FormatConsole = "console" // Colored, human-readable output for development
+)
+
+// formatWriter wraps out so lines are written in format. JSON is returned
+// unchanged; unknown formats fall back to JSON.
+func formatWriter(format string, out io.Writer, names FieldNames) io.Writer {
+	switch format {
+	case FormatLogfmt:
+		return &logfmtWriter{out: out, names: names}
+	case FormatConsole:
+		return newConsoleWriter(out)
+	default:
+		return out
+	}
+}
+
+// logfmtWriter rewrites each JSON line as logfmt: time, level and msg
+// first, then the other fields sorted by name
+type logfmtWriter struct {
+	out   io.Writer
+	names FieldNames
+}
+
+func (w *logfmtWriter) Write(p []byte) (int, error) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(p, &raw); err != nil {
+		if _, err := w.out.Write(p); err != nil {
+			return 0, err
+		}
+		return len(p), nil
+	}
+
+	var b bytes.Buffer
+	for _, k := range []string{w.names.Time, w.names.Level, w.names.Message} {
+		if v, ok := raw[k]; ok {
+			writeLogfmtPair(&b, k, v)
+			delete(raw, k)
+		}
+	}
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		writeLogfmtPair(&b, k, raw[k])
+	}
+	b.WriteByte('\n')
+
+	if _, err := w.out.Write(b.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func writeLogfmtPair(b *bytes.Buffer, k string, v json.RawMessage) {
+	if b.Len() > 0 {
+		b.WriteByte(' ')
+	}
+	b.WriteString(logfmtKey(k))
+	b.WriteByte('=')
+
+	var s string
+	if err := json.Unmarshal(v, &s); err != nil {
+		s = string(v) // Numbers, booleans and objects keep their JSON form
+	}
+	if s == "" || strings.IndexFunc(s, needsQuoting) >= 0 {
+		s = strconv.Quote(s)
+	}
+	b.WriteString(s)
+}
+
+func needsQuoting(r rune) bool {
+	return r == ' ' || r == '=' || r == '"' || r == '\\' || !unicode.IsPrint(r)
+}
+
+// logfmtKey replaces the characters logfmt does not allow in keys
+func logfmtKey(k string) string {
+	return strings.Map(func(r rune) rune {
+		if r <= ' ' || r == '=' || r == '"' {
+			return '_'
+		}
+		return r
+	}, k)
+}
+
+// newConsoleWriter returns the development console format: millisecond
+// times, caller as file:line, colors only on a terminal (and without
+// NO_COLOR), and stack traces printed below the line instead of inline
+func newConsoleWriter(out io.Writer) zerolog.ConsoleWriter {
+	return zerolog.ConsoleWriter{
+		Out:           out,
+		NoColor:       !isTerminal(out) || os.Getenv("NO_COLOR") != "",
+		TimeFormat:    "15:04:05.000",
+		FieldsExclude: []string{"app", "version", "stacktrace"},
+		FormatCaller: func(i interface{}) string {
+			s, _ := i.(string)
+			if s == "" {
+				return ""
+			}
+			return filepath.Base(s) + " >"
+		},
+		FormatExtra: func(fields map[string]interface{}, b *bytes.Buffer) error {
+			stack, _ := fields["stacktrace"].(string)
+			if stack == "" {
+				return nil
+			}
+			for _, line := range strings.Split(strings.TrimRight(stack, "\n"), "\n") {
+				fmt.Fprintf(b, "\n    %s", line)
+			}
+			return nil
+		},
+	}
+}
+
+// isTerminal reports whether w is a character device such as a TTY
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/rs/zerolog"
+)
+
+// Hook enriches every log line written through a Logger, including those
+// from WithContext and WithFields. ctx is the context passed to those
+// methods, or context.Background() for lines logged without one.
+type Hook interface {
+	Enrich(ctx context.Context, e *zerolog.Event, level zerolog.Level)
+}
+
+// HookFunc adapts a function to the Hook interface
+type HookFunc func(ctx context.Context, e *zerolog.Event, level zerolog.Level)
+
+// Enrich implements Hook
+func (f HookFunc) Enrich(ctx context.Context, e *zerolog.Event, level zerolog.Level) {
+	f(ctx, e, level)
+}
+
+// StaticFields returns a Hook adding the same fields to every line, e.g.
+// region, build SHA or deployment ring
+func StaticFields(fields map[string]string) Hook {
+	return HookFunc(func(_ context.Context, e *zerolog.Event, _ zerolog.Level) {
+		for k, v := range fields {
+			e.Str(k, v)
+		}
+	})
+}
+
+// AddHook registers h on the logger. Register hooks during setup, before
+// the logger is shared between goroutines.
+func (l *Logger) AddHook(h Hook) {
+	l.zlog = l.zlog.Hook(zerologHook{h})
+	l.hot = l.hot.Hook(zerologHook{h})
+	l.unnamed = l.unnamed.Hook(zerologHook{h})
+}
+
+// zerologHook runs a Hook from zerolog with the event's context
+type zerologHook struct {
+	hook Hook
+}
+
+// Run implements zerolog.Hook
+func (z zerologHook) Run(e *zerolog.Event, level zerolog.Level, _ string) {
+	z.hook.Enrich(e.GetCtx(), e, level)
+}
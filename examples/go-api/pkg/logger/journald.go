@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/rs/zerolog"
+)
+
+// journaldSocket is where systemd-journald receives native protocol messages
+const journaldSocket = "/run/systemd/journal/socket"
+
+// JournaldSink writes each line to systemd-journald with the native
+// protocol. The JSON fields become journal fields, upper-cased with other
+// characters replaced by '_' (trace_id is TRACE_ID), so they can be matched
+// with journalctl, e.g. "journalctl TRACE_ID=abc123". The level maps to
+// PRIORITY and msg to MESSAGE.
+type JournaldSink struct {
+	identifier string
+
+	mu   sync.Mutex
+	conn *net.UnixConn
+}
+
+// NewJournaldSink connects to the local journal. identifier is the
+// SYSLOG_IDENTIFIER shown by journalctl (default: program name).
+func NewJournaldSink(identifier string) (*JournaldSink, error) {
+	if identifier == "" {
+		identifier = filepath.Base(os.Args[0])
+	}
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: journaldSocket, Net: "unixgram"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to journald: %w", err)
+	}
+	return &JournaldSink{identifier: identifier, conn: conn}, nil
+}
+
+// Write implements io.Writer for lines without a level
+func (s *JournaldSink) Write(p []byte) (int, error) {
+	return s.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel implements zerolog.LevelWriter
+func (s *JournaldSink) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	fields, msg, _ := parseLine(p)
+
+	var b bytes.Buffer
+	journalField(&b, "MESSAGE", msg)
+	journalField(&b, "PRIORITY", strconv.Itoa(syslogSeverity(level)))
+	journalField(&b, "SYSLOG_IDENTIFIER", s.identifier)
+	for _, k := range sortedKeys(fields) {
+		if name := journalName(k); name != "" {
+			journalField(&b, name, fields[k])
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// Messages larger than the socket buffer would need a memfd; they are
+	// dropped and counted instead
+	if _, err := s.conn.Write(b.Bytes()); err != nil {
+		sinkLines.WithLabelValues("journald", sinkLost).Inc()
+		return len(p), nil
+	}
+	sinkLines.WithLabelValues("journald", sinkDelivered).Inc()
+	return len(p), nil
+}
+
+// Close closes the socket
+func (s *JournaldSink) Close() error {
+	return s.conn.Close()
+}
+
+// journalField appends a field, in the binary form when the value spans
+// lines
+func journalField(b *bytes.Buffer, name, value string) {
+	b.WriteString(name)
+	if !strings.Contains(value, "\n") {
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+		return
+	}
+	b.WriteByte('\n')
+	binary.Write(b, binary.LittleEndian, uint64(len(value)))
+	b.WriteString(value)
+	b.WriteByte('\n')
+}
+
+// journalName turns a field name into a journal field name: upper-case
+// letters, digits and '_', not starting with '_' (reserved for trusted
+// fields) or a digit
+func journalName(k string) string {
+	name := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z':
+			return r - 'a' + 'A'
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			return r
+		}
+		return '_'
+	}, k)
+	name = strings.TrimLeft(name, "_0123456789")
+	if len(name) > 64 {
+		name = name[:64]
+	}
+	return name
+}
@@ -0,0 +1,127 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync/atomic"
+
+	"github.com/rs/zerolog"
+)
+
+// levelSet holds the base level and the per-component overrides of a
+// logger and all loggers derived from it with Named. It is swapped as a
+// whole, so SetLevels also applies to loggers created before the change.
+type levelSet struct {
+	v            atomic.Pointer[levels]
+	sampledDebug bool // Config.SampledDebug
+}
+
+type levels struct {
+	base       zerolog.Level
+	components map[string]zerolog.Level
+}
+
+func newLevelSet(base zerolog.Level, sampledDebug bool) *levelSet {
+	s := &levelSet{sampledDebug: sampledDebug}
+	s.v.Store(&levels{base: base})
+	return s
+}
+
+// level returns the minimum level for component. A component "db.pool"
+// falls back to the override for "db", then to the base level.
+func (s *levelSet) level(component string) zerolog.Level {
+	lv := s.v.Load()
+	for component != "" {
+		if level, ok := lv.components[component]; ok {
+			return level
+		}
+		i := strings.LastIndexByte(component, '.')
+		if i < 0 {
+			break
+		}
+		component = component[:i]
+	}
+	return lv.base
+}
+
+// ParseLevels parses a level spec such as "info,db=debug,client=warn": an
+// optional bare base level and component=level overrides, comma-separated.
+// The base level is returned as "" when the spec has none.
+func ParseLevels(spec string) (base string, components map[string]string, err error) {
+	components = map[string]string{}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		name, level, ok := strings.Cut(part, "=")
+		if !ok {
+			name, level = "", name
+		}
+		name, level = strings.TrimSpace(name), strings.ToLower(strings.TrimSpace(level))
+		if _, valid := lookupLevel(level); !valid {
+			return "", nil, fmt.Errorf("invalid log level %q in %q", level, part)
+		}
+		if name == "" {
+			base = level
+		} else {
+			components[name] = level
+		}
+	}
+	return base, components, nil
+}
+
+// SetLevels replaces the component overrides, and the base level when spec
+// has one, for this logger and every logger sharing its root. It is safe to
+// call while logging, e.g. from an admin endpoint.
+func (l *Logger) SetLevels(spec string) error {
+	base, components, err := ParseLevels(spec)
+	if err != nil {
+		return err
+	}
+	next := &levels{base: l.levels.v.Load().base, components: make(map[string]zerolog.Level, len(components))}
+	if base != "" {
+		next.base = parseLevel(base)
+	}
+	for name, level := range components {
+		next.components[name] = parseLevel(level)
+	}
+	l.levels.v.Store(next)
+	return nil
+}
+
+// Levels returns the current spec, base level first, in the form accepted
+// by SetLevels
+func (l *Logger) Levels() string {
+	lv := l.levels.v.Load()
+	names := make([]string, 0, len(lv.components))
+	for name := range lv.components {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	parts := []string{lv.base.String()}
+	for _, name := range names {
+		parts = append(parts, name+"="+lv.components[name].String())
+	}
+	return strings.Join(parts, ",")
+}
+
+func lookupLevel(level string) (zerolog.Level, bool) {
+	switch level {
+	case "trace":
+		return zerolog.TraceLevel, true
+	case "debug":
+		return zerolog.DebugLevel, true
+	case "info":
+		return zerolog.InfoLevel, true
+	case "warn":
+		return zerolog.WarnLevel, true
+	case "error":
+		return zerolog.ErrorLevel, true
+	case "fatal":
+		return zerolog.FatalLevel, true
+	}
+	return zerolog.NoLevel, false
+}
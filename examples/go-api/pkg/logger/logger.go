@@ -3,12 +3,13 @@ package logger
 import (
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"runtime"
-	"time"
 
 	"github.com/google/uuid"
 	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel/trace"
 )
 
 // ContextKey type for context values
@@ -19,90 +20,212 @@ const (
 	TraceIDKey   ContextKey = "trace_id"
 	SpanIDKey    ContextKey = "span_id"
 	UserIDKey    ContextKey = "user_id"
+	TenantIDKey  ContextKey = "tenant_id"
 )
 
 // Logger wraps zerolog with additional functionality
 type Logger struct {
-	zlog zerolog.Logger
+	zlog      zerolog.Logger
+	hot       zerolog.Logger // zlog without the caller, for Event
+	unnamed   zerolog.Logger // zlog without the component field and the caller, so Named replaces them
+	levels    *levelSet      // Shared with the loggers derived by Named
+	component string         // Dotted path of Named calls, for per-component levels
 }
 
 // Config holds logger configuration
 type Config struct {
-	AppName    string
-	Version    string
-	Level      string
-	Pretty     bool // Use console output (for development)
+	AppName     string
+	Version     string
+	Level       string
+	Format      string       // FormatJSON (default), FormatLogfmt or FormatConsole
+	Pretty      bool         // Deprecated: use Format FormatConsole
+	ErrorBuffer *ErrorBuffer // Optional: also capture error-level lines here
+	Hooks       []Hook       // Optional enrichment applied to every line
+	Sinks       []io.Writer  // Optional: extra writers receiving every line
+	Output      io.Writer    // Optional: replaces stdout, e.g. an OTLPSink; Format is ignored
+
+	TimeFormat    string // TimeFormatRFC3339 (default) or TimeFormatEpoch
+	TimePrecision string // "s", "ms", "us" or "ns"; default: RFC 3339 up to ns, epoch in ms
+	TimeUTC       bool   // Use UTC instead of the local time zone
+
+	FieldNames FieldNames // Names of level, msg, time, caller and error; DefaultFieldNames when empty
+
+	Levels string // Optional per-component overrides of Level, e.g. "db=debug,client=warn"
+
+	// SampledDebug logs at debug level for requests whose trace is sampled,
+	// so every stored trace has full logs while log volume follows the
+	// trace sampling ratio. Other requests use the configured levels.
+	SampledDebug bool
 }
 
-// New creates a new Logger instance
-func New(cfg Config) *Logger {
-	zerolog.TimeFieldFormat = time.RFC3339Nano
-	zerolog.LevelFieldName = "level"
-	zerolog.MessageFieldName = "msg"
-	zerolog.TimestampFieldName = "time"
-	zerolog.CallerFieldName = "caller"
+// New creates a new Logger instance. Options are applied on top of cfg.
+func New(cfg Config, opts ...Option) *Logger {
+	for _, opt := range opts {
+		opt(&cfg)
+	}
 
 	level := parseLevel(cfg.Level)
+	names := cfg.FieldNames.withDefaults()
 
-	var output zerolog.Logger
-	if cfg.Pretty {
-		output = zerolog.New(zerolog.ConsoleWriter{Out: os.Stdout, TimeFormat: time.RFC3339}).
-			Level(level).
-			With().
-			Timestamp().
-			Caller().
-			Str("app", cfg.AppName).
-			Str("version", cfg.Version).
-			Logger()
+	if cfg.Format == "" && cfg.Pretty {
+		cfg.Format = FormatConsole
+	}
+	primary := formatWriter(cfg.Format, countBytes(os.Stdout, "stdout"), names)
+	if cfg.Output != nil {
+		primary = countBytes(cfg.Output, outputName(cfg.Output))
+	}
+
+	// zerolog writes the standard fields under its package-level names;
+	// they are renamed once per line for every writer but the console,
+	// which looks them up by those names
+	var out io.Writer
+	var named []io.Writer
+	if cfg.Output == nil && cfg.Format == FormatConsole {
+		out = primary
 	} else {
-		output = zerolog.New(os.Stdout).
-			Level(level).
-			With().
-			Timestamp().
-			Caller().
-			Str("app", cfg.AppName).
-			Str("version", cfg.Version).
-			Logger()
+		named = append(named, primary)
+	}
+	if cfg.ErrorBuffer != nil {
+		named = append(named, cfg.ErrorBuffer)
+	}
+	for _, sink := range cfg.Sinks {
+		named = append(named, countBytes(sink, outputName(sink)))
+	}
+	if len(named) > 0 {
+		renamed := renameFields(zerolog.MultiLevelWriter(named...), names)
+		if out == nil {
+			out = renamed
+		} else {
+			out = zerolog.MultiLevelWriter(out, renamed)
+		}
 	}
 
-	return &Logger{zlog: output}
+	output := zerolog.New(out).
+		Hook(newTimestamper(cfg)).
+		With().
+		Str("app", cfg.AppName).
+		Str("version", cfg.Version).
+		Logger()
+
+	counter := newEntryCounter("")
+	l := &Logger{
+		zlog:    output.With().Caller().Logger().Hook(counter),
+		hot:     output.Hook(counter),
+		unnamed: output,
+		levels:  newLevelSet(level, cfg.SampledDebug),
+	}
+	for _, h := range cfg.Hooks {
+		l.AddHook(h)
+	}
+	if cfg.Levels != "" {
+		if err := l.SetLevels(cfg.Levels); err != nil {
+			l.zlog.Warn().Err(err).Msg("Ignoring invalid per-component log levels")
+		}
+	}
+	return l
 }
 
 func parseLevel(level string) zerolog.Level {
-	switch level {
-	case "debug":
-		return zerolog.DebugLevel
-	case "info":
-		return zerolog.InfoLevel
-	case "warn":
-		return zerolog.WarnLevel
-	case "error":
-		return zerolog.ErrorLevel
-	case "fatal":
-		return zerolog.FatalLevel
-	default:
-		return zerolog.InfoLevel
+	if lv, ok := lookupLevel(level); ok {
+		return lv
 	}
+	return zerolog.InfoLevel
+}
+
+// Level returns the effective minimum level of this logger's component
+func (l *Logger) Level() zerolog.Level {
+	return l.levels.level(l.component)
 }
 
 // WithContext returns a logger with context values
 func (l *Logger) WithContext(ctx context.Context) zerolog.Logger {
-	event := l.zlog.With()
+	// Hooks read ctx back from the event
+	event := l.zlog.With().Ctx(ctx)
 
-	if requestID, ok := ctx.Value(RequestIDKey).(string); ok && requestID != "" {
-		event = event.Str("request_id", requestID)
+	sc := trace.SpanContextFromContext(ctx)
+	f := fieldsFromContext(ctx, sc)
+	if f.requestID != "" {
+		event = event.Str("request_id", f.requestID)
+	}
+	if f.traceID != "" {
+		event = event.Str("trace_id", f.traceID)
 	}
-	if traceID, ok := ctx.Value(TraceIDKey).(string); ok && traceID != "" {
-		event = event.Str("trace_id", traceID)
+	if f.spanID != "" {
+		event = event.Str("span_id", f.spanID)
 	}
-	if spanID, ok := ctx.Value(SpanIDKey).(string); ok && spanID != "" {
-		event = event.Str("span_id", spanID)
+	if f.userID != "" {
+		event = event.Str("user_id", f.userID)
 	}
-	if userID, ok := ctx.Value(UserIDKey).(string); ok && userID != "" {
-		event = event.Str("user_id", userID)
+	if f.tenantID != "" {
+		event = event.Str("tenant_id", f.tenantID)
 	}
+	return event.Logger().Level(l.contextLevel(sc))
+}
 
-	return event.Logger()
+// Event starts a line at level carrying the fields WithContext adds, written
+// straight onto the event instead of into a child logger. It is meant for
+// hot paths such as the request log, and allocates nothing when ctx carries
+// its IDs as strings. The caller is left out, since a hot path always logs
+// from the same place. Event returns nil, on which zerolog's methods are
+// no-ops, when level is disabled for ctx.
+func (l *Logger) Event(ctx context.Context, level zerolog.Level) *zerolog.Event {
+	sc := trace.SpanContextFromContext(ctx)
+	if level < l.contextLevel(sc) {
+		return nil
+	}
+	// Hooks read ctx back from the event
+	e := l.hot.WithLevel(level).Ctx(ctx)
+
+	f := fieldsFromContext(ctx, sc)
+	if f.requestID != "" {
+		e.Str("request_id", f.requestID)
+	}
+	if f.traceID != "" {
+		e.Str("trace_id", f.traceID)
+	}
+	if f.spanID != "" {
+		e.Str("span_id", f.spanID)
+	}
+	if f.userID != "" {
+		e.Str("user_id", f.userID)
+	}
+	if f.tenantID != "" {
+		e.Str("tenant_id", f.tenantID)
+	}
+	return e
+}
+
+// contextLevel is the minimum level of lines logged under the span sc
+func (l *Logger) contextLevel(sc trace.SpanContext) zerolog.Level {
+	level := l.Level()
+	if l.levels.sampledDebug && sc.IsSampled() && level > zerolog.DebugLevel {
+		level = zerolog.DebugLevel
+	}
+	return level
+}
+
+// contextFields are the IDs WithContext and Event add to every line
+type contextFields struct {
+	requestID, traceID, spanID, userID, tenantID string
+}
+
+func fieldsFromContext(ctx context.Context, sc trace.SpanContext) contextFields {
+	var f contextFields
+	f.requestID, _ = ctx.Value(RequestIDKey).(string)
+	f.traceID, _ = ctx.Value(TraceIDKey).(string)
+	f.spanID, _ = ctx.Value(SpanIDKey).(string)
+	f.userID, _ = ctx.Value(UserIDKey).(string)
+	f.tenantID, _ = ctx.Value(TenantIDKey).(string)
+
+	// Outside the logging middleware, e.g. in background work, fall back to
+	// the OTel span carried by ctx
+	if f.traceID == "" && sc.HasTraceID() {
+		f.traceID = sc.TraceID().String()
+	}
+	if f.spanID == "" && sc.HasSpanID() {
+		f.spanID = sc.SpanID().String()
+	}
+	return f
 }
 
 // Info logs an info message
@@ -135,9 +258,7 @@ func (l *Logger) Error(ctx context.Context, err error, msg string) {
 
 // ErrorWithStack logs an error with full stack trace
 func (l *Logger) ErrorWithStack(ctx context.Context, err error, msg string) {
-	stackBuf := make([]byte, 4096)
-	stackSize := runtime.Stack(stackBuf, false)
-	stackTrace := string(stackBuf[:stackSize])
+	stackTrace := Stack()
 
 	logger := l.WithContext(ctx)
 	logger.Error().
@@ -148,9 +269,7 @@ func (l *Logger) ErrorWithStack(ctx context.Context, err error, msg string) {
 
 // Fatal logs a fatal error and exits
 func (l *Logger) Fatal(ctx context.Context, err error, msg string) {
-	stackBuf := make([]byte, 4096)
-	stackSize := runtime.Stack(stackBuf, false)
-	stackTrace := string(stackBuf[:stackSize])
+	stackTrace := Stack()
 
 	logger := l.WithContext(ctx)
 	logger.Fatal().
@@ -161,9 +280,7 @@ func (l *Logger) Fatal(ctx context.Context, err error, msg string) {
 
 // Panic logs a panic with stack trace
 func (l *Logger) Panic(ctx context.Context, recovered interface{}, msg string) {
-	stackBuf := make([]byte, 4096)
-	stackSize := runtime.Stack(stackBuf, false)
-	stackTrace := string(stackBuf[:stackSize])
+	stackTrace := Stack()
 
 	logger := l.WithContext(ctx)
 	logger.Error().
@@ -240,6 +357,20 @@ func WithSpanID(ctx context.Context, spanID string) context.Context {
 	return context.WithValue(ctx, SpanIDKey, spanID)
 }
 
+// WithTenantID adds the tenant of a request to an existing context. Loggers
+// add it as tenant_id, which the Loki sink pushes as X-Scope-OrgID.
+func WithTenantID(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, TenantIDKey, tenantID)
+}
+
+// GetTenantID extracts tenant ID from context
+func GetTenantID(ctx context.Context) string {
+	if id, ok := ctx.Value(TenantIDKey).(string); ok {
+		return id
+	}
+	return ""
+}
+
 // WithTraceID adds a trace ID to an existing context
 func WithTraceID(ctx context.Context, traceID string) context.Context {
 	return context.WithValue(ctx, TraceIDKey, traceID)
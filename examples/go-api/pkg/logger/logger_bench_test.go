@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/rs/zerolog"
+)
+
+// BenchmarkLogger measures the logging paths of a request: plain messages,
+// the context fields every request line carries, field maps, and lines
+// dropped by the level
+func BenchmarkLogger(b *testing.B) {
+	ctx := ExtractTraceContext(context.Background(), "3f2b8c1e-bench", "4bf92f3577b34da6a3ce929d0e0e4736")
+	ctx = WithSpanID(ctx, "00f067aa0ba902b7")
+	err := errors.New("upstream timeout")
+
+	cases := []struct {
+		name string
+		cfg  Config
+		log  func(l *Logger)
+	}{
+		{"info", Config{}, func(l *Logger) { l.Info(ctx, "bench") }},
+		{"with_context", Config{}, func(l *Logger) {
+			zl := l.WithContext(ctx)
+			zl.Info().Str("path", "/api/users/42").Int("status", 200).Msg("bench")
+		}},
+		{"event", Config{}, func(l *Logger) {
+			l.Event(ctx, zerolog.InfoLevel).Str("path", "/api/users/42").Int("status", 200).Msg("bench")
+		}},
+		{"with_fields", Config{}, func(l *Logger) {
+			zl := l.WithFields(ctx, map[string]interface{}{"path": "/api/users/42", "status": 200})
+			zl.Info().Msg("bench")
+		}},
+		{"error", Config{}, func(l *Logger) { l.Error(ctx, err, "bench") }},
+		{"filtered", Config{Level: "warn"}, func(l *Logger) { l.Debug(ctx, "bench") }},
+		{"renamed_fields", Config{FieldNames: FieldNames{Message: "message"}}, func(l *Logger) { l.Info(ctx, "bench") }},
+	}
+	for _, c := range cases {
+		b.Run(c.name, func(b *testing.B) {
+			cfg := c.cfg
+			cfg.AppName = "bench"
+			cfg.Output = io.Discard
+			l := New(cfg)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				c.log(l)
+			}
+		})
+	}
+}
@@ -0,0 +1,767 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+var lokiStreams = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "loki_sink_streams",
+		Help: "Distinct Loki label sets pushed in the current cardinality window",
+	},
+)
+
+var lokiFallbacks = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "loki_sink_label_fallbacks_total",
+		Help: "Log lines pushed with the fallback label set because the stream budget was exhausted",
+	},
+)
+
+var lokiTenantLines = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "loki_sink_tenant_lines_total",
+		Help: "Log lines handled by the Loki sink by tenant and outcome (delivered, dropped, rejected, lost)",
+	},
+	[]string{"tenant", "outcome"},
+)
+
+var lokiTenantRetries = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "loki_sink_tenant_retries_total",
+		Help: "Retried Loki push requests by tenant",
+	},
+	[]string{"tenant"},
+)
+
+var lokiRateLimited = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "loki_sink_rate_limited_total",
+		Help: "Loki push requests answered with 429 Too Many Requests by tenant",
+	},
+	[]string{"tenant"},
+)
+
+func init() {
+	prometheus.MustRegister(lokiStreams)
+	prometheus.MustRegister(lokiFallbacks)
+	prometheus.MustRegister(lokiTenantLines)
+	prometheus.MustRegister(lokiTenantRetries)
+	prometheus.MustRegister(lokiRateLimited)
+}
+
+// lokiRejected counts, per tenant, lines Loki refused with a 4xx other than
+// 429, e.g. too old or too long; they are recorded as lost in
+// log_sink_lines_total
+const lokiRejected = "rejected"
+
+// LokiConfig configures pushing logs straight to Loki's push API, for
+// environments without Promtail or Alloy. Zero values use the defaults below.
+type LokiConfig struct {
+	URL    string            // Loki base URL, e.g. "http://loki:3100"
+	Labels map[string]string // Static labels on every stream, e.g. app and environment
+
+	// LabelFields are the JSON fields promoted to labels (default level and
+	// log_type, matching the Promtail pipeline). Only string values are used.
+	LabelFields []string
+
+	// MetadataFields are the JSON fields also sent as Loki 3 structured
+	// metadata, e.g. trace_id, so they can be filtered on without parsing
+	// the line and without becoming labels. Only string values are used.
+	// Leave empty for Loki 2.x, which rejects structured metadata unless
+	// allow_structured_metadata is on.
+	MetadataFields []string
+
+	// MaxStreams is the budget of distinct label sets per CardinalityWindow
+	// (default 100). Once it is spent, new label sets are replaced by the
+	// static labels plus FallbackFields, and a warning is logged.
+	MaxStreams        int
+	CardinalityWindow time.Duration // default 1h
+	FallbackFields    []string      // Label fields kept in the fallback set (default level)
+
+	// Lines are pushed with X-Scope-OrgID set to their TenantField (default
+	// tenant_id, set by WithTenantID) when it is one of Tenants, and to
+	// Tenant otherwise, so a tenant ID that reached a log line from a client
+	// cannot write to another tenant or start a batch of its own. An empty
+	// Tenant sends those lines without the header, for a single-tenant Loki.
+	// Each tenant is batched and retried on its own, so a tenant being rate
+	// limited does not hold back the others.
+	TenantField string
+	Tenant      string
+	Tenants     []string
+
+	// Lines are stamped with their TimeField (default time), read in the
+	// logger's TimeFormat and TimePrecision, and with the time they are
+	// batched when it is missing or unreadable
+	TimeField     string
+	TimeFormat    string
+	TimePrecision string
+	MaxTenants    int // Tenants with their own batch; lines for further tenants are dropped (default 50)
+
+	BatchSize     int           // Lines per push (default 1000)
+	FlushInterval time.Duration // Maximum time a line waits for its batch (default 1s)
+	QueueSize     int           // Lines buffered, per tenant, before new ones are dropped (default 10000)
+
+	// Pushes failing with a network error, 429 or 5xx are retried with
+	// exponential backoff and jitter. A Retry-After header on 429 or 503
+	// replaces the backoff, up to MaxBackoff. Other 4xx responses mean Loki
+	// will never take the batch, so it is dropped without retrying.
+	MaxRetries     int           // Retries of a failed push (default 5, negative disables)
+	InitialBackoff time.Duration // Delay before the first retry, doubling each time (default 500ms)
+	MaxBackoff     time.Duration // Upper bound for the backoff (default 30s)
+	HTTPClient     *http.Client  // Default has a 10s timeout
+
+	// WALDir, when set, keeps batches on disk until they are pushed, so lines
+	// survive a restart or a Loki outage and are replayed on startup. Lines
+	// are written to disk at each flush, so a crash loses at most
+	// FlushInterval. Beyond WALMaxBytes (default 256MiB) new lines are only
+	// kept in memory.
+	WALDir      string
+	WALMaxBytes int64
+}
+
+// LokiSink batches log lines into Loki push requests. Like the other
+// sinks it never blocks logging: lines are queued and dropped when the
+// queue is full or the sink is closed.
+type LokiSink struct {
+	cfg   LokiConfig
+	queue *sinkQueue[[]byte]
+	done  chan struct{}
+	errs  zerolog.Logger
+
+	wal *lokiWAL // nil without WALDir
+
+	// Used by the dispatch loop only
+	guard     labelGuard
+	times     timestamper            // Parses cfg.TimeField
+	allowed   map[string]bool        // cfg.Tenants
+	tenants   map[string]*lokiTenant // Written under tenantsMu, for QueueFill
+	tenantsMu sync.Mutex
+	overflown bool
+	workers   sync.WaitGroup
+}
+
+// lokiTenant batches and pushes the lines of one tenant
+type lokiTenant struct {
+	id    string // X-Scope-OrgID, "" for none
+	label string // id in metrics, "none" for none
+	queue chan lokiEntry
+
+	// WAL segment of the batch being built, used by the tenant's worker only
+	seq uint64
+	seg *walSegment
+}
+
+// lokiEntry is one parsed line
+type lokiEntry struct {
+	tenant   string
+	labels   map[string]string
+	metadata map[string]string // nil without MetadataFields
+	ts       time.Time
+	line     string
+}
+
+// NewLokiSink creates the sink and starts its dispatch loop. With WALDir
+// set, tenants with segments left by a previous run replay them first.
+func NewLokiSink(cfg LokiConfig) (*LokiSink, error) {
+	if len(cfg.LabelFields) == 0 {
+		cfg.LabelFields = []string{"level", "log_type"}
+	}
+	// A field that is already a label would only repeat it
+	var metadata []string
+	for _, field := range cfg.MetadataFields {
+		if field != "" && !slices.Contains(cfg.LabelFields, field) {
+			metadata = append(metadata, field)
+		}
+	}
+	cfg.MetadataFields = metadata
+	if cfg.MaxStreams <= 0 {
+		cfg.MaxStreams = 100
+	}
+	if cfg.CardinalityWindow <= 0 {
+		cfg.CardinalityWindow = time.Hour
+	}
+	if len(cfg.FallbackFields) == 0 {
+		cfg.FallbackFields = []string{"level"}
+	}
+	if cfg.TenantField == "" {
+		cfg.TenantField = string(TenantIDKey)
+	}
+	if cfg.MaxTenants <= 0 {
+		cfg.MaxTenants = 50
+	}
+	if cfg.TimeField == "" {
+		cfg.TimeField = DefaultFieldNames.Time
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1000
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	if cfg.MaxRetries < 0 {
+		cfg.MaxRetries = 0
+	} else if cfg.MaxRetries == 0 {
+		cfg.MaxRetries = 5
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	if cfg.WALMaxBytes <= 0 {
+		cfg.WALMaxBytes = 256 << 20
+	}
+	cfg.URL = strings.TrimRight(cfg.URL, "/")
+
+	s := &LokiSink{
+		cfg:     cfg,
+		queue:   newSinkQueue[[]byte](cfg.QueueSize),
+		done:    make(chan struct{}),
+		errs:    zerolog.New(os.Stderr).With().Timestamp().Str("sink", "loki").Logger(),
+		times:   newTimestamper(Config{TimeFormat: cfg.TimeFormat, TimePrecision: cfg.TimePrecision}),
+		allowed: map[string]bool{},
+		tenants: map[string]*lokiTenant{},
+	}
+	for _, tenant := range cfg.Tenants {
+		if tenant != "" {
+			s.allowed[tenant] = true
+		}
+	}
+	s.guard = labelGuard{
+		max:      cfg.MaxStreams,
+		window:   cfg.CardinalityWindow,
+		fallback: cfg.FallbackFields,
+		static:   cfg.Labels,
+		errs:     &s.errs,
+	}
+	if cfg.WALDir != "" {
+		wal, replay, err := openLokiWAL(cfg.WALDir, cfg.WALMaxBytes, &s.errs)
+		if err != nil {
+			return nil, err
+		}
+		s.wal = wal
+		for _, tenant := range replay {
+			s.tenant(tenant)
+		}
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write implements io.Writer. It queues a copy of p and never fails.
+func (s *LokiSink) Write(p []byte) (int, error) {
+	line := make([]byte, len(p))
+	copy(line, p)
+	if !s.queue.push(line) {
+		sinkLines.WithLabelValues("loki", sinkDropped).Inc()
+	}
+	return len(p), nil
+}
+
+// Close pushes queued lines of every tenant and stops the sink, waiting
+// until ctx expires. It is safe to call while other goroutines are still
+// logging.
+func (s *LokiSink) Close(ctx context.Context) error {
+	s.queue.close()
+	select {
+	case <-s.done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush loki sink: %w", ctx.Err())
+	}
+}
+
+// run hands queued lines to their tenant's worker until the sink is
+// closed, then drains the queue and waits for the workers to push. The
+// tenant queues are closed here, by their only sender.
+func (s *LokiSink) run() {
+	defer close(s.done)
+
+	for {
+		select {
+		case line := <-s.queue.records:
+			s.dispatch(line)
+		case <-s.queue.stop:
+			for line, ok := s.queue.drain(); ok; line, ok = s.queue.drain() {
+				s.dispatch(line)
+			}
+			for _, t := range s.tenants {
+				close(t.queue)
+			}
+			s.workers.Wait()
+			return
+		}
+	}
+}
+
+// dispatch parses line and hands it to its tenant's worker
+func (s *LokiSink) dispatch(line []byte) {
+	e := s.parse(line)
+	t := s.tenant(e.tenant)
+	if t == nil {
+		sinkLines.WithLabelValues("loki", sinkDropped).Inc()
+		return
+	}
+	select {
+	case t.queue <- e:
+	default:
+		sinkLines.WithLabelValues("loki", sinkDropped).Inc()
+		lokiTenantLines.WithLabelValues(t.label, sinkDropped).Inc()
+	}
+}
+
+// QueueFill is the share in use, from 0 to 1, of the fullest of the sink's
+// queues: the one lines are written to and those of each tenant. Lines are
+// dropped once it reaches 1.
+func (s *LokiSink) QueueFill() float64 {
+	fill := s.queue.fill()
+	s.tenantsMu.Lock()
+	defer s.tenantsMu.Unlock()
+	for _, t := range s.tenants {
+		fill = max(fill, float64(len(t.queue))/float64(cap(t.queue)))
+	}
+	return fill
+}
+
+// tenant returns the worker of id, starting it on first use, or nil once
+// MaxTenants workers exist
+func (s *LokiSink) tenant(id string) *lokiTenant {
+	if t, ok := s.tenants[id]; ok {
+		return t
+	}
+	if len(s.tenants) >= s.cfg.MaxTenants {
+		if !s.overflown {
+			s.overflown = true
+			s.errs.Error().Str("tenant", id).Int("max_tenants", s.cfg.MaxTenants).Msg("Too many Loki tenants, dropping lines of new tenants")
+		}
+		return nil
+	}
+
+	label := id
+	if label == "" {
+		label = "none"
+	}
+	t := &lokiTenant{
+		id:    id,
+		label: label,
+		queue: make(chan lokiEntry, s.cfg.QueueSize),
+	}
+	s.tenantsMu.Lock()
+	s.tenants[id] = t
+	s.tenantsMu.Unlock()
+	s.workers.Add(1)
+	go s.work(t)
+	return t
+}
+
+// work batches the lines of t until its queue is closed
+func (s *LokiSink) work(t *lokiTenant) {
+	defer s.workers.Done()
+	s.replay(t)
+
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]lokiEntry, 0, s.cfg.BatchSize)
+	for {
+		select {
+		case e, ok := <-t.queue:
+			if !ok {
+				s.commit(t, batch)
+				return
+			}
+			s.persist(t, e)
+			batch = append(batch, e)
+			if len(batch) < s.cfg.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+		}
+		s.commit(t, batch)
+		batch = make([]lokiEntry, 0, s.cfg.BatchSize)
+	}
+}
+
+// replay pushes the segments t left in the WAL before the restart
+func (s *LokiSink) replay(t *lokiTenant) {
+	if s.wal == nil {
+		return
+	}
+	paths, err := s.wal.segments(t.id)
+	if err != nil {
+		s.errs.Error().Err(err).Str("tenant", t.id).Msg("Failed to replay loki WAL")
+		return
+	}
+	t.seq = nextSeq(paths)
+
+	for _, path := range paths {
+		batch, size, corrupt, err := s.wal.read(t.id, path)
+		if err != nil {
+			s.errs.Error().Err(err).Str("path", path).Msg("Failed to replay loki WAL segment")
+			continue
+		}
+		if corrupt {
+			lokiWALCorrupt.Inc()
+			s.errs.Warn().Str("path", path).Int("lines", len(batch)).Msg("Loki WAL segment is corrupt, replaying the lines before the damage")
+		}
+		lokiWALReplayed.WithLabelValues(t.label).Add(float64(len(batch)))
+		s.flush(t, batch)
+		s.wal.remove(path, size)
+	}
+}
+
+// persist writes e to the WAL segment of t's current batch
+func (s *LokiSink) persist(t *lokiTenant, e lokiEntry) {
+	if s.wal == nil {
+		return
+	}
+	if t.seg == nil {
+		seg, err := s.wal.create(t.id, t.seq)
+		if err != nil {
+			s.errs.Error().Err(err).Str("tenant", t.id).Msg("Failed to write loki WAL")
+			lokiWALSkipped.Inc()
+			return
+		}
+		t.seq++
+		t.seg = seg
+	}
+	if !s.wal.append(t.seg, e) {
+		lokiWALSkipped.Inc()
+	}
+}
+
+// commit syncs the WAL segment of batch, pushes batch and then drops the
+// segment, whether the push succeeded or was given up
+func (s *LokiSink) commit(t *lokiTenant, batch []lokiEntry) {
+	seg := t.seg
+	t.seg = nil
+	if seg != nil {
+		if err := s.wal.commit(seg); err != nil {
+			s.errs.Error().Err(err).Str("path", seg.path).Msg("Failed to write loki WAL")
+		}
+	}
+	s.flush(t, batch)
+	if seg != nil {
+		s.wal.remove(seg.path, seg.bytes)
+	}
+}
+
+// parse extracts the tenant, label fields and timestamp of a line and
+// applies the cardinality guard
+func (s *LokiSink) parse(line []byte) lokiEntry {
+	e := lokiEntry{tenant: s.cfg.Tenant, line: string(bytes.TrimRight(line, "\n"))}
+
+	var raw map[string]json.RawMessage
+	labels := make(map[string]string, len(s.cfg.Labels)+len(s.cfg.LabelFields))
+	if err := json.Unmarshal(line, &raw); err == nil {
+		for _, field := range s.cfg.LabelFields {
+			var v string
+			if json.Unmarshal(raw[field], &v) == nil && v != "" {
+				labels[lokiLabelName(field)] = v
+			}
+		}
+		for _, field := range s.cfg.MetadataFields {
+			var v string
+			if json.Unmarshal(raw[field], &v) == nil && v != "" {
+				if e.metadata == nil {
+					e.metadata = map[string]string{}
+				}
+				e.metadata[lokiLabelName(field)] = v
+			}
+		}
+		var tenant string
+		if json.Unmarshal(raw[s.cfg.TenantField], &tenant) == nil && s.allowed[tenant] {
+			e.tenant = tenant
+		}
+		if ts, ok := s.times.parse(raw[s.cfg.TimeField]); ok {
+			e.ts = ts
+		}
+	}
+	if e.ts.IsZero() {
+		e.ts = time.Now()
+	}
+	for k, v := range s.cfg.Labels {
+		labels[lokiLabelName(k)] = v
+	}
+	e.labels = s.guard.admit(labels)
+	return e
+}
+
+// lokiPush is the body of POST /loki/api/v1/push
+type lokiPush struct {
+	Streams []lokiStream `json:"streams"`
+}
+
+type lokiStream struct {
+	Stream map[string]string `json:"stream"`
+	Values []lokiValue       `json:"values"`
+}
+
+// lokiValue is a line of a stream: [ts, line] or, with structured
+// metadata, [ts, line, {metadata}]
+type lokiValue struct {
+	ts       string
+	line     string
+	metadata map[string]string
+}
+
+// MarshalJSON implements json.Marshaler
+func (v lokiValue) MarshalJSON() ([]byte, error) {
+	if len(v.metadata) == 0 {
+		return json.Marshal([2]string{v.ts, v.line})
+	}
+	return json.Marshal([3]any{v.ts, v.line, v.metadata})
+}
+
+// flush pushes batch to the tenant, one stream per label set, retrying
+// with exponential backoff. Only t's worker waits during the backoff.
+func (s *LokiSink) flush(t *lokiTenant, batch []lokiEntry) {
+	if len(batch) == 0 {
+		return
+	}
+
+	streams := map[string]*lokiStream{}
+	var keys []string
+	for _, e := range batch {
+		key := labelKey(e.labels)
+		st, ok := streams[key]
+		if !ok {
+			st = &lokiStream{Stream: e.labels}
+			streams[key] = st
+			keys = append(keys, key)
+		}
+		st.Values = append(st.Values, lokiValue{ts: strconv.FormatInt(e.ts.UnixNano(), 10), line: e.line, metadata: e.metadata})
+	}
+	body := lokiPush{Streams: make([]lokiStream, 0, len(keys))}
+	for _, key := range keys {
+		body.Streams = append(body.Streams, *streams[key])
+	}
+
+	backoff := s.cfg.InitialBackoff
+	for attempt := 0; ; attempt++ {
+		res := s.push(t.id, body)
+		if res.err == nil {
+			sinkLines.WithLabelValues("loki", sinkDelivered).Add(float64(len(batch)))
+			lokiTenantLines.WithLabelValues(t.label, sinkDelivered).Add(float64(len(batch)))
+			return
+		}
+		err := res.err
+		if res.status == http.StatusTooManyRequests {
+			lokiRateLimited.WithLabelValues(t.label).Inc()
+		}
+		if !res.retry {
+			s.errs.Error().Err(err).Str("tenant", t.id).Int("lines", len(batch)).Msg("Loki rejected pushed logs, dropping them")
+			sinkLines.WithLabelValues("loki", sinkLost).Add(float64(len(batch)))
+			lokiTenantLines.WithLabelValues(t.label, lokiRejected).Add(float64(len(batch)))
+			return
+		}
+		if attempt >= s.cfg.MaxRetries {
+			s.errs.Error().Err(err).Str("tenant", t.id).Int("lines", len(batch)).Msg("Giving up on pushing logs to loki after retries")
+			sinkLines.WithLabelValues("loki", sinkLost).Add(float64(len(batch)))
+			lokiTenantLines.WithLabelValues(t.label, sinkLost).Add(float64(len(batch)))
+			return
+		}
+
+		sinkRetries.WithLabelValues("loki").Inc()
+		lokiTenantRetries.WithLabelValues(t.label).Inc()
+		wait := jitter(backoff)
+		if res.retryAfter > 0 {
+			wait = min(res.retryAfter, s.cfg.MaxBackoff)
+		}
+		time.Sleep(wait)
+		backoff *= 2
+		if backoff > s.cfg.MaxBackoff {
+			backoff = s.cfg.MaxBackoff
+		}
+	}
+}
+
+// jitter spreads d over [d/2, d), so tenants and pods that were rate
+// limited together do not retry in lockstep
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// lokiPushResult is the outcome of one push request
+type lokiPushResult struct {
+	err        error
+	status     int           // 0 when no response was received
+	retry      bool          // Network errors, 429 and 5xx
+	retryAfter time.Duration // From Retry-After on 429 and 503, 0 when absent
+}
+
+func (s *LokiSink) push(tenant string, body lokiPush) lokiPushResult {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return lokiPushResult{err: fmt.Errorf("failed to encode push request: %w", err)}
+	}
+	req, err := http.NewRequest(http.MethodPost, s.cfg.URL+"/loki/api/v1/push", bytes.NewReader(payload))
+	if err != nil {
+		return lokiPushResult{err: fmt.Errorf("failed to create push request: %w", err)}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if tenant != "" {
+		req.Header.Set("X-Scope-OrgID", tenant)
+	}
+
+	resp, err := s.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return lokiPushResult{err: fmt.Errorf("failed to send push request: %w", err), retry: true}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 == 2 {
+		io.Copy(io.Discard, resp.Body)
+		return lokiPushResult{status: resp.StatusCode}
+	}
+
+	msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	res := lokiPushResult{
+		err:    fmt.Errorf("push request returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg))),
+		status: resp.StatusCode,
+		retry:  resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500,
+	}
+	if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable {
+		res.retryAfter = parseRetryAfter(resp.Header.Get("Retry-After"), time.Now())
+	}
+	return res
+}
+
+// parseRetryAfter reads a Retry-After value in seconds or as an HTTP date,
+// returning 0 when it is absent, invalid or in the past
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(strings.TrimSpace(v)); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	if at, err := http.ParseTime(v); err == nil && at.After(now) {
+		return at.Sub(now)
+	}
+	return 0
+}
+
+// labelGuard keeps the number of distinct label sets within budget. Label
+// sets seen in the current window are admitted as they are; once max sets
+// have been seen, new ones are reduced to the static labels plus the
+// fallback fields until the window ends.
+type labelGuard struct {
+	max      int
+	window   time.Duration
+	fallback []string
+	static   map[string]string
+	errs     *zerolog.Logger
+
+	seen    map[string]struct{}
+	started time.Time
+	warned  bool
+}
+
+func (g *labelGuard) admit(labels map[string]string) map[string]string {
+	if now := time.Now(); g.seen == nil || now.Sub(g.started) >= g.window {
+		g.seen = map[string]struct{}{}
+		g.started = now
+		g.warned = false
+	}
+
+	key := labelKey(labels)
+	if _, ok := g.seen[key]; ok {
+		return labels
+	}
+	if len(g.seen) < g.max {
+		g.seen[key] = struct{}{}
+		lokiStreams.Set(float64(len(g.seen)))
+		return labels
+	}
+
+	reduced := make(map[string]string, len(g.static)+len(g.fallback))
+	for k, v := range g.static {
+		reduced[lokiLabelName(k)] = v
+	}
+	for _, field := range g.fallback {
+		name := lokiLabelName(field)
+		if v, ok := labels[name]; ok {
+			reduced[name] = v
+		}
+	}
+	lokiFallbacks.Inc()
+	if !g.warned {
+		g.warned = true
+		// Once per window, at error level, so it is not missed: the fallback
+		// hides label values until the window ends
+		g.errs.Error().
+			Int("max_streams", g.max).
+			Dur("window", g.window).
+			Str("rejected_labels", key).
+			Strs("fallback_fields", g.fallback).
+			Msg("Loki label cardinality budget exceeded, pushing new label sets with the fallback labels; check the label fields for high-cardinality values")
+	}
+	return reduced
+}
+
+// labelKey renders labels in Loki's selector syntax, sorted by name
+func labelKey(labels map[string]string) string {
+	names := make([]string, 0, len(labels))
+	for k := range labels {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, k := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(k + "=" + strconv.Quote(labels[k]))
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+// lokiLabelName turns a field name into a valid label name:
+// [a-zA-Z_][a-zA-Z0-9_]*
+func lokiLabelName(field string) string {
+	var b strings.Builder
+	for i, r := range field {
+		switch {
+		case r == '_', r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z':
+			b.WriteRune(r)
+		case r >= '0' && r <= '9' && i > 0:
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
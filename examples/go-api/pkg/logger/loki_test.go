@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLokiSinkCloseWhileWriting(t *testing.T) {
+	var mu sync.Mutex
+	pushes := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		pushes[r.Header.Get("X-Scope-OrgID")]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sink, err := NewLokiSink(LokiConfig{URL: srv.URL, BatchSize: 50, FlushInterval: time.Hour, QueueSize: 100, WALDir: t.TempDir(), Tenants: []string{"acme", "globex"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tenant := range []string{"acme", "globex"} {
+		sink.Write([]byte(`{"level":"info","tenant_id":"` + tenant + `","msg":"before shutdown"}` + "\n"))
+	}
+	closeWhileWriting(t, sink, sink.Close)
+
+	mu.Lock()
+	defer mu.Unlock()
+	for _, tenant := range []string{"acme", "globex"} {
+		if pushes[tenant] == 0 {
+			t.Errorf("tenant %s: nothing pushed on Close", tenant)
+		}
+	}
+}
+
+func TestLokiSinkUnknownTenant(t *testing.T) {
+	var mu sync.Mutex
+	pushes := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		pushes[r.Header.Get("X-Scope-OrgID")]++
+		mu.Unlock()
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sink, err := NewLokiSink(LokiConfig{URL: srv.URL, FlushInterval: time.Hour, Tenant: "ops", Tenants: []string{"acme"}, MaxTenants: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Write([]byte(`{"level":"info","tenant_id":"acme","msg":"known"}` + "\n"))
+	// More junk tenants than MaxTenants, which must neither get workers nor
+	// use up the budget the configured tenants need
+	for i := 0; i < 10; i++ {
+		sink.Write([]byte(`{"level":"info","tenant_id":"junk-` + strconv.Itoa(i) + `","msg":"unknown"}` + "\n"))
+	}
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(sink.tenants) != 2 {
+		t.Errorf("%d tenant workers, want 2 (acme and ops)", len(sink.tenants))
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if pushes["acme"] != 1 || pushes["ops"] != 1 || len(pushes) != 2 {
+		t.Errorf("pushes by tenant: %v, want one to acme and one to ops", pushes)
+	}
+}
+
+func TestLokiSinkQueueFill(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sink, err := NewLokiSink(LokiConfig{URL: srv.URL, BatchSize: 1, QueueSize: 4, MaxRetries: -1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fill := sink.QueueFill(); fill != 0 {
+		t.Errorf("QueueFill of an idle sink: %v, want 0", fill)
+	}
+	// The first line holds the worker in a push, so the rest back up in
+	// the tenant queue
+	deadline := time.Now().Add(5 * time.Second)
+	for sink.QueueFill() < 1 && time.Now().Before(deadline) {
+		sink.Write([]byte(`{"level":"info","msg":"backlog"}` + "\n"))
+		time.Sleep(time.Millisecond)
+	}
+	if fill := sink.QueueFill(); fill != 1 {
+		t.Errorf("QueueFill with a stalled push: %v, want 1", fill)
+	}
+
+	close(release)
+	if err := sink.Close(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLokiSinkLineTime(t *testing.T) {
+	logged := time.Date(2024, 1, 15, 10, 30, 0, 123_000_000, time.UTC)
+	cases := []struct {
+		name string
+		cfg  LokiConfig
+		line string
+	}{
+		{"rfc3339", LokiConfig{}, `{"time":"2024-01-15T10:30:00.123Z"}`},
+		{"epoch ms", LokiConfig{TimeFormat: TimeFormatEpoch}, `{"time":1705314600123}`},
+		{"epoch ns", LokiConfig{TimeFormat: TimeFormatEpoch, TimePrecision: "ns"}, `{"time":1705314600123000000}`},
+		{"renamed field", LokiConfig{TimeField: "ts", TimeFormat: TimeFormatEpoch}, `{"ts":1705314600123,"time":"not the time"}`},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			c.cfg.URL = "http://loki.invalid"
+			sink, err := NewLokiSink(c.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer sink.Close(context.Background())
+			if got := sink.parse([]byte(c.line + "\n")).ts; !got.Equal(logged) {
+				t.Errorf("line time %v, want %v", got, logged)
+			}
+		})
+	}
+}
@@ -0,0 +1,265 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+var lokiWALBytes = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "loki_wal_bytes",
+		Help: "Bytes of Loki WAL segments waiting to be pushed",
+	},
+)
+
+var lokiWALReplayed = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "loki_wal_replayed_lines_total",
+		Help: "Log lines read back from the Loki WAL at startup, by tenant",
+	},
+	[]string{"tenant"},
+)
+
+var lokiWALCorrupt = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "loki_wal_corrupt_segments_total",
+		Help: "Loki WAL segments that were truncated at a corrupt record during replay",
+	},
+)
+
+var lokiWALSkipped = prometheus.NewCounter(
+	prometheus.CounterOpts{
+		Name: "loki_wal_unpersisted_lines_total",
+		Help: "Log lines pushed without being written to the Loki WAL because it was full or failing",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(lokiWALBytes)
+	prometheus.MustRegister(lokiWALReplayed)
+	prometheus.MustRegister(lokiWALCorrupt)
+	prometheus.MustRegister(lokiWALSkipped)
+}
+
+// lokiWAL keeps each tenant's unpushed batches on disk, one segment file per
+// batch in a directory per tenant. A segment is synced before its push and
+// removed once the push succeeded or was given up, so what is left after a
+// crash is exactly what needs replaying; no positions file is needed.
+//
+// Each record is a 4-byte big-endian length, the CRC-32 of the payload and
+// the payload, a JSON walRecord.
+type lokiWAL struct {
+	dir      string
+	maxBytes int64
+	size     atomic.Int64
+	full     atomic.Bool // Warned about maxBytes until the WAL shrinks
+	errs     *zerolog.Logger
+}
+
+type walRecord struct {
+	Labels   map[string]string `json:"labels"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+	TS       int64             `json:"ts"`
+	Line     string            `json:"line"`
+}
+
+// walSegment is the segment of the batch a tenant is building
+type walSegment struct {
+	path  string
+	f     *os.File
+	w     *bufio.Writer
+	bytes int64
+}
+
+// openLokiWAL creates dir if needed and returns the WAL with the tenants
+// that have segments to replay
+func openLokiWAL(dir string, maxBytes int64, errs *zerolog.Logger) (*lokiWAL, []string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, nil, fmt.Errorf("failed to create loki WAL directory: %w", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read loki WAL directory: %w", err)
+	}
+
+	w := &lokiWAL{dir: dir, maxBytes: maxBytes, errs: errs}
+	var tenants []string
+	for _, e := range entries {
+		tenant, ok := walTenant(e.Name())
+		if !e.IsDir() || !ok {
+			continue
+		}
+		segments, err := w.segments(tenant)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(segments) == 0 {
+			continue
+		}
+		for _, path := range segments {
+			if info, err := os.Stat(path); err == nil {
+				w.size.Add(info.Size())
+			}
+		}
+		tenants = append(tenants, tenant)
+	}
+	lokiWALBytes.Set(float64(w.size.Load()))
+	return w, tenants, nil
+}
+
+// Tenant directories are "t-" and the hex tenant ID, so any ID, including
+// none, is a safe file name
+func (w *lokiWAL) tenantDir(tenant string) string {
+	return filepath.Join(w.dir, "t-"+hex.EncodeToString([]byte(tenant)))
+}
+
+func walTenant(name string) (string, bool) {
+	id, ok := strings.CutPrefix(name, "t-")
+	if !ok {
+		return "", false
+	}
+	b, err := hex.DecodeString(id)
+	return string(b), err == nil
+}
+
+// segments returns the tenant's segment files, oldest first
+func (w *lokiWAL) segments(tenant string) ([]string, error) {
+	paths, err := filepath.Glob(filepath.Join(w.tenantDir(tenant), "*.wal"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list loki WAL segments: %w", err)
+	}
+	sort.Strings(paths)
+	return paths, nil
+}
+
+// create opens segment seq of tenant
+func (w *lokiWAL) create(tenant string, seq uint64) (*walSegment, error) {
+	dir := w.tenantDir(tenant)
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create loki WAL directory: %w", err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%020d.wal", seq))
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create loki WAL segment: %w", err)
+	}
+	return &walSegment{path: path, f: f, w: bufio.NewWriter(f)}, nil
+}
+
+// nextSeq returns the sequence number after the newest segment in paths
+func nextSeq(paths []string) uint64 {
+	if len(paths) == 0 {
+		return 0
+	}
+	seq, _ := strconv.ParseUint(strings.TrimSuffix(filepath.Base(paths[len(paths)-1]), ".wal"), 10, 64)
+	return seq + 1
+}
+
+// append writes e to seg unless that would grow the WAL beyond maxBytes.
+// It reports whether e was persisted.
+func (w *lokiWAL) append(seg *walSegment, e lokiEntry) bool {
+	payload, err := json.Marshal(walRecord{Labels: e.labels, Metadata: e.metadata, TS: e.ts.UnixNano(), Line: e.line})
+	if err != nil {
+		return false
+	}
+	n := int64(8 + len(payload))
+	if w.size.Load()+n > w.maxBytes {
+		if !w.full.Swap(true) {
+			w.errs.Error().Int64("max_bytes", w.maxBytes).Msg("Loki WAL is full, new lines are pushed without surviving a restart")
+		}
+		return false
+	}
+
+	var header [8]byte
+	binary.BigEndian.PutUint32(header[:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(header[4:], crc32.ChecksumIEEE(payload))
+	if _, err := seg.w.Write(header[:]); err != nil {
+		return false
+	}
+	if _, err := seg.w.Write(payload); err != nil {
+		return false
+	}
+	seg.bytes += n
+	lokiWALBytes.Set(float64(w.size.Add(n)))
+	return true
+}
+
+// commit syncs seg to disk before its batch is pushed
+func (w *lokiWAL) commit(seg *walSegment) error {
+	err := seg.w.Flush()
+	if err == nil {
+		err = seg.f.Sync()
+	}
+	if cerr := seg.f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to sync loki WAL segment: %w", err)
+	}
+	return nil
+}
+
+// remove deletes a segment whose batch has been handled
+func (w *lokiWAL) remove(path string, bytes int64) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		w.errs.Error().Err(err).Str("path", path).Msg("Failed to remove loki WAL segment")
+		return
+	}
+	size := w.size.Add(-bytes)
+	lokiWALBytes.Set(float64(size))
+	if size < w.maxBytes {
+		w.full.Store(false)
+	}
+}
+
+// read returns the entries of a segment written before a restart. A torn
+// or corrupt record ends the segment: the records before it are returned
+// and corrupt is set.
+func (w *lokiWAL) read(tenant, path string) (entries []lokiEntry, size int64, corrupt bool, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, 0, false, fmt.Errorf("failed to open loki WAL segment: %w", err)
+	}
+	defer f.Close()
+	if info, err := f.Stat(); err == nil {
+		size = info.Size()
+	}
+
+	r := bufio.NewReader(f)
+	var header [8]byte
+	for {
+		if _, err := io.ReadFull(r, header[:]); err != nil {
+			// A clean end of file, or a record torn by the crash
+			return entries, size, err != io.EOF, nil
+		}
+		n := binary.BigEndian.Uint32(header[:4])
+		if int64(n) > size {
+			return entries, size, true, nil
+		}
+		payload := make([]byte, n)
+		if _, err := io.ReadFull(r, payload); err != nil {
+			return entries, size, true, nil
+		}
+		var rec walRecord
+		if crc32.ChecksumIEEE(payload) != binary.BigEndian.Uint32(header[4:]) || json.Unmarshal(payload, &rec) != nil {
+			return entries, size, true, nil
+		}
+		entries = append(entries, lokiEntry{tenant: tenant, labels: rec.Labels, metadata: rec.Metadata, ts: time.Unix(0, rec.TS), line: rec.Line})
+	}
+}
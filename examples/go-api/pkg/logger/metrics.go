@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"sync/atomic"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+)
+
+var logEntries = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "log_entries_total",
+		Help: "Log entries emitted by level and component",
+	},
+	[]string{"level", "component"},
+)
+
+var logBytes = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "log_bytes_total",
+		Help: "Bytes of log lines handed to each output (stdout, otlp or a sink)",
+	},
+	[]string{"output"},
+)
+
+func init() {
+	prometheus.MustRegister(logEntries)
+	prometheus.MustRegister(logBytes)
+}
+
+// entryCounter counts the entries a logger emits. zerolog runs hooks only
+// for enabled levels, so lines filtered out are not counted.
+type entryCounter struct {
+	component string // Named path, "none" for the root logger
+
+	// The counter of each level from trace to panic, and NoLevel, looked up
+	// on first use, since a lookup by label values allocates
+	levels *[8]atomic.Pointer[prometheus.Counter]
+}
+
+// Run implements zerolog.Hook
+func (c entryCounter) Run(_ *zerolog.Event, level zerolog.Level, _ string) {
+	i := int(level) - int(zerolog.TraceLevel)
+	if i < 0 || i >= len(c.levels) {
+		logEntries.WithLabelValues(level.String(), c.component).Inc()
+		return
+	}
+	counter := c.levels[i].Load()
+	if counter == nil {
+		cnt := logEntries.WithLabelValues(level.String(), c.component)
+		counter = &cnt
+		c.levels[i].Store(counter)
+	}
+	(*counter).Inc()
+}
+
+func newEntryCounter(component string) entryCounter {
+	if component == "" {
+		component = "none"
+	}
+	return entryCounter{component: component, levels: new([8]atomic.Pointer[prometheus.Counter])}
+}
+
+// countingWriter counts the bytes written to out in log_bytes_total
+type countingWriter struct {
+	out   io.Writer
+	bytes prometheus.Counter
+}
+
+func countBytes(out io.Writer, output string) io.Writer {
+	return &countingWriter{out: out, bytes: logBytes.WithLabelValues(output)}
+}
+
+// Write implements io.Writer
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.bytes.Add(float64(len(p)))
+	return w.out.Write(p)
+}
+
+// WriteLevel implements zerolog.LevelWriter, passing the level on to
+// writers that use it
+func (w *countingWriter) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	w.bytes.Add(float64(len(p)))
+	if lw, ok := w.out.(zerolog.LevelWriter); ok {
+		return lw.WriteLevel(level, p)
+	}
+	return w.out.Write(p)
+}
+
+// outputName labels w in log_bytes_total
+func outputName(w io.Writer) string {
+	switch w.(type) {
+	case *ElasticsearchSink:
+		return "elasticsearch"
+	case *FluentSink:
+		return "fluent"
+	case *SyslogSink:
+		return "syslog"
+	case *JournaldSink:
+		return "journald"
+	case *EventLogSink:
+		return "eventlog"
+	case *OTLPSink:
+		return "otlp"
+	case *LokiSink:
+		return "loki"
+	}
+	return fmt.Sprintf("%T", w)
+}
@@ -0,0 +1,53 @@
+package logger
+
+import "io"
+
+// Option adjusts a Config before New builds the Logger, so new capabilities
+// can be added without changing every caller
+type Option func(*Config)
+
+// WithLevel sets the minimum level ("debug", "info", "warn", "error")
+func WithLevel(level string) Option {
+	return func(c *Config) { c.Level = level }
+}
+
+// WithPretty switches to human-readable console output
+func WithPretty(pretty bool) Option {
+	return func(c *Config) { c.Pretty = pretty }
+}
+
+// WithFormat selects the stdout format: FormatJSON, FormatLogfmt or
+// FormatConsole
+func WithFormat(format string) Option {
+	return func(c *Config) { c.Format = format }
+}
+
+// WithSink also writes every line to w, e.g. a file or a log shipper
+func WithSink(w io.Writer) Option {
+	return func(c *Config) { c.Sinks = append(c.Sinks, w) }
+}
+
+// WithOutput writes lines to w instead of stdout
+func WithOutput(w io.Writer) Option {
+	return func(c *Config) { c.Output = w }
+}
+
+// WithTimestamp sets the time field's format ("rfc3339" or "epoch"),
+// precision ("s", "ms", "us" or "ns") and whether it is in UTC
+func WithTimestamp(format, precision string, utc bool) Option {
+	return func(c *Config) {
+		c.TimeFormat = format
+		c.TimePrecision = precision
+		c.TimeUTC = utc
+	}
+}
+
+// WithErrorBuffer also captures error-level lines in b
+func WithErrorBuffer(b *ErrorBuffer) Option {
+	return func(c *Config) { c.ErrorBuffer = b }
+}
+
+// WithHooks registers hooks enriching every line
+func WithHooks(hooks ...Hook) Option {
+	return func(c *Config) { c.Hooks = append(c.Hooks, hooks...) }
+}
@@ -0,0 +1,330 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	collogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	commonpb "go.opentelemetry.io/proto/otlp/common/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	resourcepb "go.opentelemetry.io/proto/otlp/resource/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/rs/zerolog"
+)
+
+// otlpScope names the instrumentation scope of exported log records
+const otlpScope = "github.com/example/go-api/pkg/logger"
+
+// OTLPConfig configures export of logs over OTLP/gRPC to a collector such
+// as Alloy, Vector or otelcol. Zero values use the defaults below.
+type OTLPConfig struct {
+	Endpoint      string            // host:port of the collector's OTLP gRPC receiver (default "localhost:4317")
+	Insecure      bool              // Plaintext instead of TLS, for a local collector
+	Resource      map[string]string // Resource attributes, e.g. service.name
+	BatchSize     int               // Records per export (default 512)
+	FlushInterval time.Duration     // Maximum time a record waits for its batch (default 1s)
+	QueueSize     int               // Records buffered before new ones are dropped (default 10000)
+	Timeout       time.Duration     // Per export (default 10s)
+}
+
+// OTLPSink converts each JSON line to an OTel log record and exports them
+// in batches. level becomes SeverityNumber/SeverityText, msg the body, time
+// the timestamp and trace_id/span_id the record's trace context; all other
+// fields are attributes. It can replace stdout (Config.Output) or be added
+// as a sink.
+type OTLPSink struct {
+	cfg      OTLPConfig
+	conn     *grpc.ClientConn
+	client   collogs.LogsServiceClient
+	resource *resourcepb.Resource
+	queue    *sinkQueue[*logspb.LogRecord]
+	done     chan struct{}
+	errs     zerolog.Logger
+}
+
+// NewOTLPSink creates the sink and starts its export loop. The connection
+// is established lazily, so the collector may start after the service.
+func NewOTLPSink(cfg OTLPConfig) (*OTLPSink, error) {
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "localhost:4317"
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 512
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 10000
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	creds := credentials.NewClientTLSFromCert(nil, "")
+	if cfg.Insecure {
+		creds = insecure.NewCredentials()
+	}
+	conn, err := grpc.Dial(cfg.Endpoint, grpc.WithTransportCredentials(creds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create OTLP log exporter: %w", err)
+	}
+
+	resource := &resourcepb.Resource{}
+	for _, k := range sortedKeys(cfg.Resource) {
+		resource.Attributes = append(resource.Attributes, &commonpb.KeyValue{
+			Key:   k,
+			Value: &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: cfg.Resource[k]}},
+		})
+	}
+
+	s := &OTLPSink{
+		cfg:      cfg,
+		conn:     conn,
+		client:   collogs.NewLogsServiceClient(conn),
+		resource: resource,
+		queue:    newSinkQueue[*logspb.LogRecord](cfg.QueueSize),
+		done:     make(chan struct{}),
+		errs:     zerolog.New(os.Stderr).With().Timestamp().Str("sink", "otlp").Logger(),
+	}
+	go s.run()
+	return s, nil
+}
+
+// Write implements io.Writer for lines without a level
+func (s *OTLPSink) Write(p []byte) (int, error) {
+	return s.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel implements zerolog.LevelWriter. It converts p and queues the
+// record, dropping it when the queue is full or the sink is closed.
+func (s *OTLPSink) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	if !s.queue.push(toLogRecord(level, p, time.Now())) {
+		sinkLines.WithLabelValues("otlp", sinkDropped).Inc()
+	}
+	return len(p), nil
+}
+
+// QueueFill is the share of the sink's queue in use, from 0 to 1. Lines are
+// dropped once it reaches 1.
+func (s *OTLPSink) QueueFill() float64 {
+	return s.queue.fill()
+}
+
+// Close exports queued records and closes the connection, waiting until
+// ctx expires. It is safe to call while other goroutines are still logging.
+func (s *OTLPSink) Close(ctx context.Context) error {
+	s.queue.close()
+	select {
+	case <-s.done:
+		return s.conn.Close()
+	case <-ctx.Done():
+		return fmt.Errorf("failed to flush OTLP log sink: %w", ctx.Err())
+	}
+}
+
+func (s *OTLPSink) run() {
+	defer close(s.done)
+
+	ticker := time.NewTicker(s.cfg.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]*logspb.LogRecord, 0, s.cfg.BatchSize)
+	for {
+		select {
+		case rec := <-s.queue.records:
+			batch = append(batch, rec)
+			if len(batch) < s.cfg.BatchSize {
+				continue
+			}
+		case <-ticker.C:
+		case <-s.queue.stop:
+			for rec, ok := s.queue.drain(); ok; rec, ok = s.queue.drain() {
+				if batch = append(batch, rec); len(batch) == s.cfg.BatchSize {
+					s.export(batch)
+					batch = make([]*logspb.LogRecord, 0, s.cfg.BatchSize)
+				}
+			}
+			s.export(batch)
+			return
+		}
+		s.export(batch)
+		batch = make([]*logspb.LogRecord, 0, s.cfg.BatchSize)
+	}
+}
+
+func (s *OTLPSink) export(batch []*logspb.LogRecord) {
+	if len(batch) == 0 {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+
+	resp, err := s.client.Export(ctx, &collogs.ExportLogsServiceRequest{
+		ResourceLogs: []*logspb.ResourceLogs{{
+			Resource: s.resource,
+			ScopeLogs: []*logspb.ScopeLogs{{
+				Scope:      &commonpb.InstrumentationScope{Name: otlpScope},
+				LogRecords: batch,
+			}},
+		}},
+	})
+	if err != nil {
+		s.errs.Error().Err(err).Int("records", len(batch)).Msg("Failed to export logs")
+		sinkLines.WithLabelValues("otlp", sinkLost).Add(float64(len(batch)))
+		return
+	}
+
+	rejected := resp.GetPartialSuccess().GetRejectedLogRecords()
+	if rejected > 0 {
+		s.errs.Warn().Int64("records", rejected).Str("reason", resp.GetPartialSuccess().GetErrorMessage()).Msg("Collector rejected logs")
+		sinkLines.WithLabelValues("otlp", sinkLost).Add(float64(rejected))
+	}
+	sinkLines.WithLabelValues("otlp", sinkDelivered).Add(float64(int64(len(batch)) - rejected))
+}
+
+// toLogRecord maps a JSON log line to the OTel log data model. Lines
+// written without a level, through Write, take it from their level field.
+func toLogRecord(level zerolog.Level, p []byte, observed time.Time) *logspb.LogRecord {
+	rec := &logspb.LogRecord{ObservedTimeUnixNano: uint64(observed.UnixNano())}
+
+	var fields map[string]interface{}
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.UseNumber()
+	if err := dec.Decode(&fields); err != nil {
+		rec.SeverityNumber, rec.SeverityText = otelSeverity(level), severityText(level, "")
+		rec.Body = anyValue(string(bytes.TrimRight(p, "\n")))
+		return rec
+	}
+
+	text, _ := fields[DefaultFieldNames.Level].(string)
+	if level == zerolog.NoLevel && text != "" {
+		if parsed, err := zerolog.ParseLevel(text); err == nil {
+			level = parsed
+		}
+	}
+	rec.SeverityNumber, rec.SeverityText = otelSeverity(level), severityText(level, text)
+
+	keys := make([]string, 0, len(fields))
+	for k := range fields {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v := fields[k]
+		s, _ := v.(string)
+		switch k {
+		case DefaultFieldNames.Level:
+		case DefaultFieldNames.Message:
+			rec.Body = anyValue(v)
+		case DefaultFieldNames.Time:
+			if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+				rec.TimeUnixNano = uint64(t.UnixNano())
+			}
+		case "trace_id":
+			if id, err := hex.DecodeString(s); err == nil && len(id) == 16 {
+				rec.TraceId = id
+				continue
+			}
+			rec.Attributes = append(rec.Attributes, &commonpb.KeyValue{Key: k, Value: anyValue(v)})
+		case "span_id":
+			if id, err := hex.DecodeString(s); err == nil && len(id) == 8 {
+				rec.SpanId = id
+				continue
+			}
+			rec.Attributes = append(rec.Attributes, &commonpb.KeyValue{Key: k, Value: anyValue(v)})
+		default:
+			rec.Attributes = append(rec.Attributes, &commonpb.KeyValue{Key: k, Value: anyValue(v)})
+		}
+	}
+	return rec
+}
+
+// otelSeverity maps a zerolog level to an OTel severity number. Each
+// standard level opens its OTel range; panic, above fatal, is FATAL2.
+// Custom levels keep their order: below trace they are TRACE, above panic
+// FATAL4. NoLevel and Disabled carry no severity.
+func otelSeverity(level zerolog.Level) logspb.SeverityNumber {
+	switch level {
+	case zerolog.NoLevel, zerolog.Disabled:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED
+	case zerolog.TraceLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_TRACE
+	case zerolog.DebugLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG
+	case zerolog.InfoLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_INFO
+	case zerolog.WarnLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_WARN
+	case zerolog.ErrorLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_ERROR
+	case zerolog.FatalLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL
+	case zerolog.PanicLevel:
+		return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL2
+	}
+	if level < zerolog.TraceLevel {
+		return logspb.SeverityNumber_SEVERITY_NUMBER_TRACE
+	}
+	return logspb.SeverityNumber_SEVERITY_NUMBER_FATAL4
+}
+
+// severityText is the level as the source wrote it, which the OTel data
+// model asks for: the line's level field when it has one, otherwise
+// zerolog's name for the level, the number for custom levels
+func severityText(level zerolog.Level, field string) string {
+	if field != "" {
+		return field
+	}
+	if level == zerolog.NoLevel || level == zerolog.Disabled {
+		return ""
+	}
+	return level.String()
+}
+
+// anyValue converts a value decoded by encoding/json with UseNumber
+func anyValue(v interface{}) *commonpb.AnyValue {
+	switch v := v.(type) {
+	case string:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_StringValue{StringValue: v}}
+	case bool:
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_BoolValue{BoolValue: v}}
+	case json.Number:
+		if i, err := v.Int64(); err == nil {
+			return &commonpb.AnyValue{Value: &commonpb.AnyValue_IntValue{IntValue: i}}
+		}
+		f, _ := v.Float64()
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_DoubleValue{DoubleValue: f}}
+	case []interface{}:
+		arr := &commonpb.ArrayValue{}
+		for _, e := range v {
+			arr.Values = append(arr.Values, anyValue(e))
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_ArrayValue{ArrayValue: arr}}
+	case map[string]interface{}:
+		kv := &commonpb.KeyValueList{}
+		for _, k := range sortedAnyKeys(v) {
+			kv.Values = append(kv.Values, &commonpb.KeyValue{Key: k, Value: anyValue(v[k])})
+		}
+		return &commonpb.AnyValue{Value: &commonpb.AnyValue_KvlistValue{KvlistValue: kv}}
+	}
+	return &commonpb.AnyValue{}
+}
+
+func sortedAnyKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	collogs "go.opentelemetry.io/proto/otlp/collector/logs/v1"
+	logspb "go.opentelemetry.io/proto/otlp/logs/v1"
+	"google.golang.org/grpc"
+)
+
+// logsCollector counts the records exported to it
+type logsCollector struct {
+	collogs.UnimplementedLogsServiceServer
+	records atomic.Int64
+}
+
+func (c *logsCollector) Export(_ context.Context, req *collogs.ExportLogsServiceRequest) (*collogs.ExportLogsServiceResponse, error) {
+	for _, rl := range req.ResourceLogs {
+		for _, sl := range rl.ScopeLogs {
+			c.records.Add(int64(len(sl.LogRecords)))
+		}
+	}
+	return &collogs.ExportLogsServiceResponse{}, nil
+}
+
+func TestOTLPSinkCloseWhileWriting(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	collector := &logsCollector{}
+	srv := grpc.NewServer()
+	collogs.RegisterLogsServiceServer(srv, collector)
+	go srv.Serve(ln)
+	defer srv.Stop()
+
+	sink, err := NewOTLPSink(OTLPConfig{Endpoint: ln.Addr().String(), Insecure: true, BatchSize: 50, FlushInterval: time.Hour, QueueSize: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sink.Write([]byte(`{"level":"info","msg":"before shutdown"}` + "\n"))
+	closeWhileWriting(t, sink, sink.Close)
+
+	if collector.records.Load() == 0 {
+		t.Error("no records were exported on Close")
+	}
+}
+
+func TestSeverity(t *testing.T) {
+	cases := []struct {
+		level  zerolog.Level
+		field  string // The line's level field
+		number logspb.SeverityNumber
+		text   string
+	}{
+		{zerolog.TraceLevel, "", logspb.SeverityNumber_SEVERITY_NUMBER_TRACE, "trace"},
+		{zerolog.DebugLevel, "", logspb.SeverityNumber_SEVERITY_NUMBER_DEBUG, "debug"},
+		{zerolog.InfoLevel, "", logspb.SeverityNumber_SEVERITY_NUMBER_INFO, "info"},
+		{zerolog.WarnLevel, "", logspb.SeverityNumber_SEVERITY_NUMBER_WARN, "warn"},
+		{zerolog.ErrorLevel, "", logspb.SeverityNumber_SEVERITY_NUMBER_ERROR, "error"},
+		{zerolog.FatalLevel, "", logspb.SeverityNumber_SEVERITY_NUMBER_FATAL, "fatal"},
+		{zerolog.PanicLevel, "", logspb.SeverityNumber_SEVERITY_NUMBER_FATAL2, "panic"},
+		{zerolog.NoLevel, "", logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, ""},
+		{zerolog.Disabled, "", logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, ""},
+		// Custom levels below trace and above disabled
+		{zerolog.Level(-2), "", logspb.SeverityNumber_SEVERITY_NUMBER_TRACE, "-2"},
+		{zerolog.Level(-8), "", logspb.SeverityNumber_SEVERITY_NUMBER_TRACE, "-8"},
+		{zerolog.Level(8), "", logspb.SeverityNumber_SEVERITY_NUMBER_FATAL4, "8"},
+		{zerolog.Level(100), "", logspb.SeverityNumber_SEVERITY_NUMBER_FATAL4, "100"},
+		// The level field is kept as written
+		{zerolog.WarnLevel, "WARNING", logspb.SeverityNumber_SEVERITY_NUMBER_WARN, "WARNING"},
+		{zerolog.NoLevel, "notice", logspb.SeverityNumber_SEVERITY_NUMBER_UNSPECIFIED, "notice"},
+	}
+	for _, c := range cases {
+		t.Run(fmt.Sprintf("%d/%s", c.level, c.field), func(t *testing.T) {
+			if got := otelSeverity(c.level); got != c.number {
+				t.Errorf("otelSeverity(%d) = %v, want %v", c.level, got, c.number)
+			}
+			if got := severityText(c.level, c.field); got != c.text {
+				t.Errorf("severityText(%d, %q) = %q, want %q", c.level, c.field, got, c.text)
+			}
+		})
+	}
+}
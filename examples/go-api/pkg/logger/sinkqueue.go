@@ -0,0 +1,67 @@
+package logger
+
+import "sync"
+
+// sinkQueue buffers records between the loggers writing to a sink and the
+// sink's flush loop. The channel is never closed, since writers may still be
+// sending when the sink is closed: close marks the queue closed under a lock
+// instead, and the flush loop drains what is left once stop is closed.
+type sinkQueue[T any] struct {
+	records chan T
+	stop    chan struct{} // Closed by close, once no push can add more
+	once    sync.Once
+
+	mu     sync.RWMutex // Held for reading by push, so close waits for sends in progress
+	closed bool
+}
+
+func newSinkQueue[T any](size int) *sinkQueue[T] {
+	return &sinkQueue[T]{
+		records: make(chan T, size),
+		stop:    make(chan struct{}),
+	}
+}
+
+// push queues v without blocking. It reports false when the queue is full
+// or closed, and v was dropped.
+func (q *sinkQueue[T]) push(v T) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return false
+	}
+	select {
+	case q.records <- v:
+		return true
+	default:
+		return false
+	}
+}
+
+// close rejects further pushes and closes stop. Everything pushed before
+// is in the channel for the flush loop to drain.
+func (q *sinkQueue[T]) close() {
+	q.once.Do(func() {
+		q.mu.Lock()
+		q.closed = true
+		q.mu.Unlock()
+		close(q.stop)
+	})
+}
+
+// fill is the share of the queue in use, from 0 (empty) to 1 (full)
+func (q *sinkQueue[T]) fill() float64 {
+	return float64(len(q.records)) / float64(cap(q.records))
+}
+
+// drain returns the next queued record without waiting, and false once the
+// queue is empty. After stop is closed, nothing is added behind it.
+func (q *sinkQueue[T]) drain() (T, bool) {
+	select {
+	case v := <-q.records:
+		return v, true
+	default:
+		var zero T
+		return zero, false
+	}
+}
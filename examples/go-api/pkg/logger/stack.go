@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"runtime"
+	"sync"
+)
+
+// stackBuffers pools the buffers stacks are captured into. A buffer grown
+// by a deep stack is kept up to maxPooledStackBuffer, so the next deep
+// stack does not grow one again.
+var stackBuffers = sync.Pool{
+	New: func() interface{} {
+		b := make([]byte, 4096)
+		return &b
+	},
+}
+
+const maxPooledStackBuffer = 64 << 10
+
+// Stack returns the stack trace of the calling goroutine. The buffer is
+// doubled until the whole stack fits, so deep stacks are not cut off.
+func Stack() string {
+	bufp := stackBuffers.Get().(*[]byte)
+	buf := *bufp
+	for {
+		n := runtime.Stack(buf, false)
+		if n < len(buf) {
+			stack := string(buf[:n])
+			if len(buf) <= maxPooledStackBuffer {
+				*bufp = buf
+				stackBuffers.Put(bufp)
+			}
+			return stack
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
@@ -0,0 +1,30 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStackIsNotCutOff(t *testing.T) {
+	var stack string
+	var recurse func(depth int)
+	recurse = func(depth int) {
+		if depth == 0 {
+			stack = Stack()
+			return
+		}
+		recurse(depth - 1)
+	}
+
+	for _, depth := range []int{0, 500} {
+		recurse(depth)
+		if len(stack) <= 4096 && depth > 0 {
+			t.Errorf("depth %d: stack of %d bytes, want more than the initial buffer", depth, len(stack))
+		}
+		// The outermost frame is the test runner; a cut-off stack ends
+		// before it
+		if !strings.Contains(stack, "testing.tRunner") {
+			t.Errorf("depth %d: stack of %d bytes does not reach testing.tRunner", depth, len(stack))
+		}
+	}
+}
@@ -0,0 +1,266 @@
+package logger
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// syslogEnterpriseID qualifies the structured-data element carrying the log
+// fields. 32473 is the private enterprise number reserved for examples
+// (RFC 5612); set SyslogConfig.SDID to use your own.
+const syslogEnterpriseID = "32473"
+
+// SyslogConfig configures an RFC 5424 syslog sink
+type SyslogConfig struct {
+	Network   string      // "udp" (default), "tcp" or "tls"
+	Addr      string      // host:port of the syslog server
+	AppName   string      // APP-NAME header field (default: program name)
+	Facility  int         // Syslog facility (default 16, local0)
+	SDID      string      // Structured-data ID of the fields element (default "fields@32473")
+	TLSConfig *tls.Config // Used with Network "tls"
+}
+
+// SyslogSink writes each line as an RFC 5424 message. The JSON fields other
+// than level, time and msg become structured-data parameters, so receivers
+// such as rsyslog can index them without parsing the message. Over TCP and
+// TLS messages are octet-counted (RFC 6587). A failed write drops the line
+// and reconnects on the next one, so a syslog outage never blocks logging
+// for long.
+type SyslogSink struct {
+	cfg      SyslogConfig
+	hostname string
+	procID   string
+
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogSink creates the sink and connects to the server
+func NewSyslogSink(cfg SyslogConfig) (*SyslogSink, error) {
+	if cfg.Network == "" {
+		cfg.Network = "udp"
+	}
+	if cfg.AppName == "" {
+		cfg.AppName = strings.TrimSuffix(filepath.Base(os.Args[0]), ".exe")
+	}
+	if cfg.Facility == 0 {
+		cfg.Facility = 16
+	}
+	if cfg.SDID == "" {
+		cfg.SDID = "fields@" + syslogEnterpriseID
+	}
+	hostname, err := os.Hostname()
+	if err != nil || hostname == "" {
+		hostname = "-"
+	}
+
+	s := &SyslogSink{cfg: cfg, hostname: hostname, procID: strconv.Itoa(os.Getpid())}
+	if err := s.connect(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *SyslogSink) connect() error {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	var (
+		conn net.Conn
+		err  error
+	)
+	switch s.cfg.Network {
+	case "udp", "tcp":
+		conn, err = dialer.Dial(s.cfg.Network, s.cfg.Addr)
+	case "tls":
+		conn, err = tls.DialWithDialer(dialer, "tcp", s.cfg.Addr, s.cfg.TLSConfig)
+	default:
+		return fmt.Errorf("unsupported syslog network %q", s.cfg.Network)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to syslog at %s: %w", s.cfg.Addr, err)
+	}
+	s.conn = conn
+	return nil
+}
+
+// Write implements io.Writer for lines without a level
+func (s *SyslogSink) Write(p []byte) (int, error) {
+	return s.WriteLevel(zerolog.NoLevel, p)
+}
+
+// WriteLevel implements zerolog.LevelWriter
+func (s *SyslogSink) WriteLevel(level zerolog.Level, p []byte) (int, error) {
+	msg := s.format(level, p)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		if err := s.connect(); err != nil {
+			sinkLines.WithLabelValues("syslog", sinkLost).Inc()
+			return len(p), nil
+		}
+	}
+	s.conn.SetWriteDeadline(time.Now().Add(time.Second))
+	if _, err := s.conn.Write(msg); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		sinkLines.WithLabelValues("syslog", sinkLost).Inc()
+		return len(p), nil
+	}
+	sinkLines.WithLabelValues("syslog", sinkDelivered).Inc()
+	return len(p), nil
+}
+
+// Close closes the connection
+func (s *SyslogSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return nil
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	return err
+}
+
+// format builds the RFC 5424 message, framed for stream transports
+func (s *SyslogSink) format(level zerolog.Level, p []byte) []byte {
+	fields, msg, ts := parseLine(p)
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "<%d>1 %s %s %s %s - ",
+		s.cfg.Facility*8+syslogSeverity(level),
+		ts.UTC().Format("2006-01-02T15:04:05.000000Z07:00"),
+		headerField(s.hostname, 255), headerField(s.cfg.AppName, 48), s.procID)
+
+	if len(fields) == 0 {
+		b.WriteString("-")
+	} else {
+		b.WriteString("[" + s.cfg.SDID)
+		for _, k := range sortedKeys(fields) {
+			name := sdName(k)
+			if name == "" {
+				continue
+			}
+			b.WriteString(" " + name + `="` + sdEscape(fields[k]) + `"`)
+		}
+		b.WriteString("]")
+	}
+	if msg != "" {
+		// The BOM marks MSG as UTF-8
+		b.WriteString(" \ufeff" + msg)
+	}
+
+	out := b.String()
+	if s.cfg.Network == "udp" {
+		return []byte(out)
+	}
+	return []byte(strconv.Itoa(len(out)) + " " + out)
+}
+
+// syslogSeverity maps zerolog levels to syslog severities
+func syslogSeverity(level zerolog.Level) int {
+	switch level {
+	case zerolog.PanicLevel:
+		return 0 // Emergency
+	case zerolog.FatalLevel:
+		return 2 // Critical
+	case zerolog.ErrorLevel:
+		return 3 // Error
+	case zerolog.WarnLevel:
+		return 4 // Warning
+	case zerolog.InfoLevel, zerolog.NoLevel:
+		return 6 // Informational
+	default:
+		return 7 // Debug
+	}
+}
+
+// parseLine splits a JSON log line into its message, timestamp and the
+// remaining fields as strings. Lines that are not JSON become the message.
+func parseLine(p []byte) (fields map[string]string, msg string, ts time.Time) {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(p, &raw); err != nil {
+		return nil, strings.TrimRight(string(p), "\n"), time.Time{}
+	}
+
+	fields = make(map[string]string, len(raw))
+	for k, v := range raw {
+		var s string
+		if err := json.Unmarshal(v, &s); err != nil {
+			s = string(v) // Numbers, booleans and objects keep their JSON form
+		}
+		switch k {
+		case DefaultFieldNames.Level:
+		case DefaultFieldNames.Message:
+			msg = s
+		case DefaultFieldNames.Time:
+			ts, _ = time.Parse(time.RFC3339Nano, s)
+		default:
+			fields[k] = s
+		}
+	}
+	return fields, msg, ts
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// sdName turns a field name into an SD-NAME: at most 32 printable ASCII
+// characters other than '=', ' ', ']' and '"'
+func sdName(k string) string {
+	var b strings.Builder
+	for _, r := range k {
+		if b.Len() == 32 {
+			break
+		}
+		if r > 32 && r < 127 && r != '=' && r != ']' && r != '"' {
+			b.WriteRune(r)
+		} else {
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// sdEscape escapes a PARAM-VALUE
+func sdEscape(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(v)
+}
+
+// headerField returns v as a header field: printable ASCII without spaces,
+// truncated to maxLen, or "-" when empty
+func headerField(v string, maxLen int) string {
+	v = strings.Map(func(r rune) rune {
+		if r > 32 && r < 127 {
+			return r
+		}
+		return -1
+	}, v)
+	if v == "" {
+		return "-"
+	}
+	if len(v) > maxLen {
+		v = v[:maxLen]
+	}
+	return v
+}
@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/rs/zerolog"
+)
+
+// Timestamp formats for Config.TimeFormat
+const (
+	TimeFormatRFC3339 = "rfc3339" // e.g. "2024-01-15T10:30:00.123Z" (default)
+	TimeFormatEpoch   = "epoch"   // Integer Unix time in units of TimePrecision
+)
+
+// timestamper adds the time field to every line according to the logger's
+// own settings, instead of zerolog's package-level TimeFieldFormat and
+// TimestampFunc, so loggers with different settings can share a process
+type timestamper struct {
+	layout string        // RFC 3339 layout; empty for epoch
+	unit   time.Duration // Epoch unit
+	utc    bool
+}
+
+// newTimestamper builds the timestamper for cfg. precision is "s", "ms",
+// "us" or "ns"; when empty, RFC 3339 times keep up to nanoseconds with
+// trailing zeros trimmed and epoch times are in milliseconds.
+func newTimestamper(cfg Config) timestamper {
+	t := timestamper{utc: cfg.TimeUTC}
+
+	t.unit = time.Millisecond
+	fraction := ".999999999"
+	switch cfg.TimePrecision {
+	case "s":
+		t.unit, fraction = time.Second, ""
+	case "ms":
+		t.unit, fraction = time.Millisecond, ".000"
+	case "us", "µs":
+		t.unit, fraction = time.Microsecond, ".000000"
+	case "ns":
+		t.unit, fraction = time.Nanosecond, ".000000000"
+	}
+	if cfg.TimeFormat != TimeFormatEpoch {
+		t.layout = "2006-01-02T15:04:05" + fraction + "Z07:00"
+	}
+	return t
+}
+
+// Run implements zerolog.Hook
+func (t timestamper) Run(e *zerolog.Event, _ zerolog.Level, _ string) {
+	now := time.Now()
+	if t.utc {
+		now = now.UTC()
+	}
+	if t.layout == "" {
+		e.Int64(zerolog.TimestampFieldName, now.UnixNano()/int64(t.unit))
+		return
+	}
+	// Formatting into a stack buffer keeps the time off the heap
+	var buf [64]byte
+	e.Bytes(zerolog.TimestampFieldName, now.AppendFormat(buf[:0], t.layout))
+}
+
+// parse reads back a time field written by t from its JSON value, for
+// sinks that stamp lines with the time they were logged
+func (t timestamper) parse(raw json.RawMessage) (time.Time, bool) {
+	if t.layout == "" {
+		var n int64
+		if json.Unmarshal(raw, &n) != nil {
+			return time.Time{}, false
+		}
+		return time.Unix(0, n*int64(t.unit)), true
+	}
+	var s string
+	if json.Unmarshal(raw, &s) != nil {
+		return time.Time{}, false
+	}
+	ts, err := time.Parse(time.RFC3339Nano, s)
+	return ts, err == nil
+}
@@ -0,0 +1,161 @@
+package maintenance
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/example/go-api/pkg/client"
+	"github.com/example/go-api/pkg/httperr"
+	"github.com/example/go-api/pkg/logger"
+)
+
+var modeEnabled = prometheus.NewGauge(
+	prometheus.GaugeOpts{
+		Name: "maintenance_mode_enabled",
+		Help: "Whether the API is in maintenance mode (1) or serving traffic (0)",
+	},
+)
+
+func init() {
+	prometheus.MustRegister(modeEnabled)
+}
+
+// Annotator records maintenance transitions on dashboards
+type Annotator interface {
+	Annotate(ctx context.Context, a client.Annotation) error
+}
+
+// State is a snapshot of the maintenance mode
+type State struct {
+	Enabled bool      `json:"enabled"`
+	Reason  string    `json:"reason,omitempty"`
+	Since   time.Time `json:"since,omitempty"`
+}
+
+// Mode is a runtime toggle that takes the API out of service without a
+// restart, e.g. for a dependency maintenance window
+type Mode struct {
+	log        *logger.Logger
+	annotator  Annotator // Optional
+	retryAfter time.Duration
+
+	mu    sync.RWMutex
+	state State
+}
+
+// New creates a new Mode, initially disabled. retryAfter is advertised to
+// clients in the Retry-After header while maintenance is on.
+func New(log *logger.Logger, annotator Annotator, retryAfter time.Duration) *Mode {
+	if retryAfter <= 0 {
+		retryAfter = 5 * time.Minute
+	}
+	return &Mode{log: log, annotator: annotator, retryAfter: retryAfter}
+}
+
+// Enabled reports whether maintenance mode is on
+func (m *Mode) Enabled() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state.Enabled
+}
+
+// State returns the current maintenance state
+func (m *Mode) State() State {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.state
+}
+
+// Set switches maintenance mode on or off. Transitions are logged and
+// annotated; setting the current state again is a no-op. It reports whether
+// the state changed.
+func (m *Mode) Set(ctx context.Context, enabled bool, reason string) bool {
+	m.mu.Lock()
+	if m.state.Enabled == enabled {
+		m.mu.Unlock()
+		return false
+	}
+	previous := m.state
+	m.state = State{Enabled: enabled, Reason: reason, Since: time.Now().UTC()}
+	m.mu.Unlock()
+
+	text := "Maintenance mode disabled"
+	if enabled {
+		modeEnabled.Set(1)
+		text = "Maintenance mode enabled"
+	} else {
+		modeEnabled.Set(0)
+	}
+
+	fields := map[string]interface{}{
+		"maintenance": enabled,
+		"reason":      reason,
+	}
+	if !enabled {
+		fields["duration_ms"] = time.Since(previous.Since).Milliseconds()
+	}
+	transitionLog := m.log.WithFields(ctx, fields)
+	transitionLog.Warn().Msg(text)
+
+	if m.annotator != nil {
+		if reason != "" {
+			text += ": " + reason
+		}
+		annotation := client.Annotation{
+			Time: time.Now().UnixMilli(),
+			Tags: []string{"maintenance", "go-api"},
+			Text: text,
+		}
+		if err := m.annotator.Annotate(ctx, annotation); err != nil {
+			annotateLog := m.log.WithContext(ctx)
+			annotateLog.Warn().Err(err).Msg("Failed to annotate maintenance transition")
+		}
+	}
+	return true
+}
+
+// Middleware creates a middleware that answers 503 with Retry-After while
+// maintenance mode is on
+func (m *Mode) Middleware() func(http.Handler) http.Handler {
+	retryAfter := strconv.Itoa(int(m.retryAfter.Seconds()))
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !m.Enabled() {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusServiceUnavailable)
+			w.Write([]byte(`{"status":"maintenance","reason":"service is under maintenance"}`))
+		})
+	}
+}
+
+// Handler serves the admin toggle. GET returns the current state; PUT with
+// {"enabled":bool,"reason":string} switches it.
+func (m *Mode) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			var req struct {
+				Enabled bool   `json:"enabled"`
+				Reason  string `json:"reason"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				httperr.Write(w, r, http.StatusBadRequest, "invalid request body")
+				return
+			}
+			m.Set(r.Context(), req.Enabled, req.Reason)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(m.State())
+	})
+}
@@ -0,0 +1,169 @@
+package metrics
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/rs/zerolog"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+var (
+	auditSeries = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "metrics_audit_series",
+			Help: "Series exported by this process at the last metrics audit",
+		},
+	)
+	auditOverBudget = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "metrics_audit_families_over_budget",
+			Help: "Metric families over their series budget at the last metrics audit",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(auditSeries)
+	prometheus.MustRegister(auditOverBudget)
+}
+
+// AuditConfig controls the metrics self-audit
+type AuditConfig struct {
+	Budget   int            // Series allowed per family (default 1000)
+	Budgets  map[string]int // Per-family overrides of Budget
+	Interval time.Duration  // Time between background audits; 0 disables them
+}
+
+func (c AuditConfig) budget(family string) int {
+	if b, ok := c.Budgets[family]; ok {
+		return b
+	}
+	if c.Budget > 0 {
+		return c.Budget
+	}
+	return 1000
+}
+
+// AuditReport is the result of an audit, families sorted by series count
+type AuditReport struct {
+	Series     int           `json:"series"`
+	Families   []FamilyAudit `json:"families"`
+	OverBudget []string      `json:"over_budget"`
+}
+
+// FamilyAudit is the series count of one metric family. Labels holds the
+// number of distinct values of each label, which points at the label that
+// exploded.
+type FamilyAudit struct {
+	Name       string         `json:"name"`
+	Type       string         `json:"type"`
+	Series     int            `json:"series"`
+	Budget     int            `json:"budget"`
+	OverBudget bool           `json:"over_budget"`
+	Labels     map[string]int `json:"labels,omitempty"`
+}
+
+// Audit counts the series g exports per family, as Prometheus would store
+// them: a histogram has one series per bucket plus _sum and _count, a
+// summary one per quantile plus _sum and _count.
+func Audit(g prometheus.Gatherer, cfg AuditConfig) (AuditReport, error) {
+	families, err := g.Gather()
+	if err != nil {
+		return AuditReport{}, err
+	}
+
+	report := AuditReport{Families: make([]FamilyAudit, 0, len(families)), OverBudget: []string{}}
+	for _, mf := range families {
+		fa := FamilyAudit{
+			Name:   mf.GetName(),
+			Type:   mf.GetType().String(),
+			Budget: cfg.budget(mf.GetName()),
+		}
+		values := map[string]map[string]bool{}
+		for _, m := range mf.GetMetric() {
+			fa.Series += seriesOf(mf.GetType(), m)
+			for _, lp := range m.GetLabel() {
+				if values[lp.GetName()] == nil {
+					values[lp.GetName()] = map[string]bool{}
+				}
+				values[lp.GetName()][lp.GetValue()] = true
+			}
+		}
+		if len(values) > 0 {
+			fa.Labels = make(map[string]int, len(values))
+			for name, vs := range values {
+				fa.Labels[name] = len(vs)
+			}
+		}
+		if fa.Series > fa.Budget {
+			fa.OverBudget = true
+			report.OverBudget = append(report.OverBudget, fa.Name)
+		}
+		report.Series += fa.Series
+		report.Families = append(report.Families, fa)
+	}
+
+	sort.SliceStable(report.Families, func(i, j int) bool {
+		return report.Families[i].Series > report.Families[j].Series
+	})
+	sort.Strings(report.OverBudget)
+	auditSeries.Set(float64(report.Series))
+	auditOverBudget.Set(float64(len(report.OverBudget)))
+	return report, nil
+}
+
+func seriesOf(t dto.MetricType, m *dto.Metric) int {
+	switch t {
+	case dto.MetricType_HISTOGRAM, dto.MetricType_GAUGE_HISTOGRAM:
+		// Buckets plus +Inf, _sum and _count
+		return len(m.GetHistogram().GetBucket()) + 3
+	case dto.MetricType_SUMMARY:
+		return len(m.GetSummary().GetQuantile()) + 2
+	default:
+		return 1
+	}
+}
+
+// Warn logs each family over its budget at warn level
+func (r AuditReport) Warn(l *zerolog.Logger) {
+	for _, fa := range r.Families {
+		if !fa.OverBudget {
+			continue
+		}
+		l.Warn().
+			Str("metric", fa.Name).
+			Int("series", fa.Series).
+			Int("budget", fa.Budget).
+			Interface("label_values", fa.Labels).
+			Msg("Metric family over its series budget")
+	}
+}
+
+// RunAudit audits g every cfg.Interval until ctx is cancelled, logging
+// families over budget. A non-positive interval disables it.
+func RunAudit(ctx context.Context, g prometheus.Gatherer, cfg AuditConfig, log *logger.Logger) {
+	if cfg.Interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			l := log.WithContext(ctx)
+			report, err := Audit(g, cfg)
+			if err != nil {
+				l.Warn().Err(err).Msg("Metrics audit failed")
+				continue
+			}
+			report.Warn(&l)
+		}
+	}
+}
@@ -0,0 +1,256 @@
+// Package metrics is the facade for application (business) metrics.
+// Handlers declare counters, gauges and histograms on a Registry instead of
+// creating raw Prometheus vectors at package scope. Names and labels are
+// validated when a metric is declared, a metric is registered the first
+// time it is used, and observations with the wrong label values are counted
+// and dropped instead of panicking in a request.
+//
+//	orders := reg.Counter("orders_total", "Orders placed", "channel")
+//	orders.Inc("web")
+package metrics
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var facadeErrors = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "app_metrics_errors_total",
+		Help: "Observations dropped by the metrics facade by metric and reason (labels, negative, register)",
+	},
+	[]string{"metric", "reason"},
+)
+
+func init() {
+	prometheus.MustRegister(facadeErrors)
+}
+
+var (
+	metricNameRE = regexp.MustCompile(`^[a-zA-Z_:][a-zA-Z0-9_:]*$`)
+	labelNameRE  = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+)
+
+// Registry declares namespaced application metrics. Declaring the same
+// name again returns the existing metric, so a handler constructed more
+// than once shares its metrics. Registries made with Sub share the
+// declarations of their parent.
+type Registry struct {
+	namespace string
+	subsystem string
+	reg       prometheus.Registerer
+	declared  *declarations
+}
+
+type declarations struct {
+	mu      sync.Mutex
+	metrics map[string]declared
+}
+
+type declared struct {
+	kind   string
+	labels []string
+	metric any
+}
+
+// New creates a Registry whose metric names are prefixed with namespace
+// (none when empty). reg defaults to prometheus.DefaultRegisterer.
+func New(namespace string, reg prometheus.Registerer) *Registry {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	return &Registry{
+		namespace: namespace,
+		reg:       reg,
+		declared:  &declarations{metrics: map[string]declared{}},
+	}
+}
+
+// Sub returns a Registry that also prefixes names with subsystem, e.g.
+// New("shop", nil).Sub("checkout") declares shop_checkout_* metrics
+func (r *Registry) Sub(subsystem string) *Registry {
+	sub := *r
+	if sub.subsystem != "" {
+		subsystem = sub.subsystem + "_" + subsystem
+	}
+	sub.subsystem = subsystem
+	return &sub
+}
+
+// Counter declares a counter. Counter names must end in _total.
+func (r *Registry) Counter(name, help string, labels ...string) *Counter {
+	fq := r.validate("counter", name, labels)
+	if !strings.HasSuffix(fq, "_total") {
+		panic(fmt.Sprintf("metrics: counter %s must end in _total", fq))
+	}
+	return declare(r, "counter", fq, labels, func() *Counter {
+		return &Counter{metric[prometheus.Counter]{r: r, name: fq, labels: labels, vec: prometheus.NewCounterVec(
+			prometheus.CounterOpts{Name: fq, Help: help}, labels)}}
+	})
+}
+
+// Gauge declares a gauge
+func (r *Registry) Gauge(name, help string, labels ...string) *Gauge {
+	fq := r.validate("gauge", name, labels)
+	return declare(r, "gauge", fq, labels, func() *Gauge {
+		return &Gauge{metric[prometheus.Gauge]{r: r, name: fq, labels: labels, vec: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{Name: fq, Help: help}, labels)}}
+	})
+}
+
+// Histogram declares a histogram. buckets defaults to
+// prometheus.DefBuckets; "le" is reserved as a label name.
+func (r *Registry) Histogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	fq := r.validate("histogram", name, labels)
+	if slices.Contains(labels, "le") {
+		panic(fmt.Sprintf("metrics: histogram %s cannot have an le label", fq))
+	}
+	return declare(r, "histogram", fq, labels, func() *Histogram {
+		return &Histogram{metric[prometheus.Observer]{r: r, name: fq, labels: labels, vec: prometheus.NewHistogramVec(
+			prometheus.HistogramOpts{Name: fq, Help: help, Buckets: buckets}, labels)}}
+	})
+}
+
+// validate returns the fully qualified name and panics on invalid names or
+// labels; declarations run at construction, so this fails at startup
+func (r *Registry) validate(kind, name string, labels []string) string {
+	fq := prometheus.BuildFQName(r.namespace, r.subsystem, name)
+	if name == "" || !metricNameRE.MatchString(fq) {
+		panic(fmt.Sprintf("metrics: invalid %s name %q", kind, fq))
+	}
+	for i, l := range labels {
+		if !labelNameRE.MatchString(l) || strings.HasPrefix(l, "__") {
+			panic(fmt.Sprintf("metrics: %s %s has invalid label name %q", kind, fq, l))
+		}
+		if slices.Contains(labels[:i], l) {
+			panic(fmt.Sprintf("metrics: %s %s has duplicate label %q", kind, fq, l))
+		}
+	}
+	return fq
+}
+
+func declare[M any](r *Registry, kind, fq string, labels []string, create func() M) M {
+	d := r.declared
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if prev, ok := d.metrics[fq]; ok {
+		m, same := prev.metric.(M)
+		if !same || prev.kind != kind || !slices.Equal(prev.labels, labels) {
+			panic(fmt.Sprintf("metrics: %s redeclared as %s%v, was %s%v", fq, kind, labels, prev.kind, prev.labels))
+		}
+		return m
+	}
+	m := create()
+	d.metrics[fq] = declared{kind: kind, labels: slices.Clone(labels), metric: m}
+	return m
+}
+
+// vec is the part of the Prometheus vectors a metric uses
+type vec[T any] interface {
+	prometheus.Collector
+	GetMetricWithLabelValues(lvs ...string) (T, error)
+}
+
+// metric registers its vector on first use and resolves label values
+type metric[T any] struct {
+	r      *Registry
+	name   string
+	labels []string
+	once   sync.Once
+	vec    vec[T]
+}
+
+func (m *metric[T]) with(values []string) (T, bool) {
+	m.once.Do(m.register)
+	var zero T
+	if len(values) != len(m.labels) {
+		facadeErrors.WithLabelValues(m.name, "labels").Inc()
+		return zero, false
+	}
+	o, err := m.vec.GetMetricWithLabelValues(values...)
+	if err != nil {
+		facadeErrors.WithLabelValues(m.name, "labels").Inc()
+		return zero, false
+	}
+	return o, true
+}
+
+// register adds the vector to the Registerer with GetOrRegister, so an
+// identical collector registered elsewhere shares its series; any other
+// failure leaves the metric working but unexported.
+func (m *metric[T]) register() {
+	v, err := GetOrRegister[vec[T]](m.r.reg, m.vec)
+	if err != nil {
+		facadeErrors.WithLabelValues(m.name, "register").Inc()
+		return
+	}
+	m.vec = v
+}
+
+// Counter is a counter declared on a Registry
+type Counter struct{ metric[prometheus.Counter] }
+
+// Inc adds 1 to the series with the given label values
+func (c *Counter) Inc(values ...string) {
+	if o, ok := c.with(values); ok {
+		o.Inc()
+	}
+}
+
+// Add adds v, which must not be negative, to the series with the given
+// label values
+func (c *Counter) Add(v float64, values ...string) {
+	if v < 0 {
+		facadeErrors.WithLabelValues(c.name, "negative").Inc()
+		return
+	}
+	if o, ok := c.with(values); ok {
+		o.Add(v)
+	}
+}
+
+// Gauge is a gauge declared on a Registry
+type Gauge struct{ metric[prometheus.Gauge] }
+
+// Set sets the series with the given label values to v
+func (g *Gauge) Set(v float64, values ...string) {
+	if o, ok := g.with(values); ok {
+		o.Set(v)
+	}
+}
+
+// Add adds v, which may be negative, to the series with the given label
+// values
+func (g *Gauge) Add(v float64, values ...string) {
+	if o, ok := g.with(values); ok {
+		o.Add(v)
+	}
+}
+
+// Inc adds 1 to the series with the given label values
+func (g *Gauge) Inc(values ...string) { g.Add(1, values...) }
+
+// Dec subtracts 1 from the series with the given label values
+func (g *Gauge) Dec(values ...string) { g.Add(-1, values...) }
+
+// Histogram is a histogram declared on a Registry
+type Histogram struct{ metric[prometheus.Observer] }
+
+// Observe records v in the series with the given label values
+func (h *Histogram) Observe(v float64, values ...string) {
+	if o, ok := h.with(values); ok {
+		o.Observe(v)
+	}
+}
+
+// Since records the seconds elapsed since start, e.g.
+// defer h.Since(time.Now(), "web")
+func (h *Histogram) Since(start time.Time, values ...string) {
+	h.Observe(time.Since(start).Seconds(), values...)
+}
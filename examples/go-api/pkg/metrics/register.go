@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// GetOrRegister registers c with reg (prometheus.DefaultRegisterer when
+// nil). When an identical collector, one with the same name, help and
+// labels, is already registered, that collector is returned instead, so a
+// library and the app embedding it can both define the same metric. A
+// collector of another type, or a conflicting definition, is an error.
+func GetOrRegister[C prometheus.Collector](reg prometheus.Registerer, c C) (C, error) {
+	if reg == nil {
+		reg = prometheus.DefaultRegisterer
+	}
+	err := reg.Register(c)
+	if err == nil {
+		return c, nil
+	}
+	var are prometheus.AlreadyRegisteredError
+	if errors.As(err, &are) {
+		if existing, ok := are.ExistingCollector.(C); ok {
+			return existing, nil
+		}
+		return c, fmt.Errorf("metric already registered as %T, not %T", are.ExistingCollector, c)
+	}
+	return c, err
+}
+
+// MustGetOrRegister is GetOrRegister for package and constructor
+// initialization; it panics on conflicting definitions
+func MustGetOrRegister[C prometheus.Collector](reg prometheus.Registerer, c C) C {
+	c, err := GetOrRegister(reg, c)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
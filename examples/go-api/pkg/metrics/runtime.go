@@ -0,0 +1,17 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+// RegisterRuntime adds the Go runtime (go_*) and process (process_*)
+// collectors to reg. prometheus.DefaultRegisterer has them already; a
+// custom registry needs them for the usual runtime dashboards.
+func RegisterRuntime(reg prometheus.Registerer) error {
+	if _, err := GetOrRegister(reg, collectors.NewGoCollector()); err != nil {
+		return err
+	}
+	_, err := GetOrRegister(reg, collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	return err
+}
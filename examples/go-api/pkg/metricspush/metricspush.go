@@ -0,0 +1,97 @@
+// Package metricspush sends metrics from short-lived invocations, such as CLI
+// subcommands, that exit before Prometheus could scrape them. Metrics go to
+// a Prometheus Pushgateway or to any Prometheus remote-write endpoint (Mimir,
+// Thanos Receive, Prometheus with --web.enable-remote-write-receiver).
+package metricspush
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/push"
+)
+
+// Push modes
+const (
+	ModeOff         = ""
+	ModePushgateway = "pushgateway"
+	ModeRemoteWrite = "remote-write"
+)
+
+// Config controls where metrics are pushed
+type Config struct {
+	Mode string // ModePushgateway, ModeRemoteWrite or ModeOff
+	URL  string // Pushgateway base URL, or the full remote-write URL
+	Job  string // job label (Pushgateway grouping key)
+
+	// Labels are added to the Pushgateway grouping key, or to every series
+	// sent by remote write, e.g. the instance or environment
+	Labels map[string]string
+
+	Username string        // Basic auth, used when set
+	Password func() string // Read per push so rotated secrets apply
+	Timeout  time.Duration // Per-push timeout (default 10s)
+}
+
+// Enabled reports whether a push mode is configured
+func (c Config) Enabled() bool {
+	return c.Mode != ModeOff
+}
+
+// Validate checks the mode and that a URL and job are set when pushing
+func (c Config) Validate() error {
+	switch c.Mode {
+	case ModeOff:
+		return nil
+	case ModePushgateway, ModeRemoteWrite:
+	default:
+		return fmt.Errorf("unknown metrics push mode %q (want %q or %q)", c.Mode, ModePushgateway, ModeRemoteWrite)
+	}
+	if c.URL == "" {
+		return fmt.Errorf("metrics push mode %q needs a URL", c.Mode)
+	}
+	if c.Job == "" {
+		return fmt.Errorf("metrics push mode %q needs a job name", c.Mode)
+	}
+	return nil
+}
+
+// Push sends everything g gathers. With a Pushgateway the job's group is
+// replaced, so series from an earlier run that this run did not produce are
+// removed. Push is a no-op when no mode is configured.
+func Push(ctx context.Context, cfg Config, g prometheus.Gatherer) error {
+	if err := cfg.Validate(); err != nil || !cfg.Enabled() {
+		return err
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = 10 * time.Second
+	}
+	client := &http.Client{Timeout: timeout}
+
+	if cfg.Mode == ModeRemoteWrite {
+		return remoteWrite(ctx, client, cfg, g)
+	}
+
+	p := push.New(cfg.URL, cfg.Job).Gatherer(g).Client(client)
+	for name, value := range cfg.Labels {
+		p.Grouping(name, value)
+	}
+	if cfg.Username != "" {
+		p.BasicAuth(cfg.Username, cfg.password())
+	}
+	if err := p.PushContext(ctx); err != nil {
+		return fmt.Errorf("push to pushgateway: %w", err)
+	}
+	return nil
+}
+
+func (c Config) password() string {
+	if c.Password == nil {
+		return ""
+	}
+	return c.Password()
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+// budgetsFile holds what each benchmark case may cost per request on top
+// of the bare router
+var budgetsFile = filepath.Join("testdata", "budgets.json")
+
+// budget is the allowed per-request overhead of a middleware
+type budget struct {
+	Allocs  float64 `json:"allocs_per_op"`
+	NsPerOp int64   `json:"ns_per_op"`
+}
+
+// TestPerformanceBudgets fails when a middleware allocates more per request
+// than its budget. Allocation counts do not depend on the machine, but the
+// race detector adds its own, so they are checked in every build but -race.
+// Timings are only checked with PERF_BUDGETS=1, on a machine the ns budgets
+// were set for.
+func TestPerformanceBudgets(t *testing.T) {
+	data, err := os.ReadFile(budgetsFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var budgets map[string]budget
+	if err := json.Unmarshal(data, &budgets); err != nil {
+		t.Fatalf("invalid %s: %v", budgetsFile, err)
+	}
+	timed := os.Getenv("PERF_BUDGETS") == "1"
+
+	var baseAllocs float64
+	var baseNs int64
+	for _, c := range benchCases() {
+		h, w, r := benchHandler(c.mw), newDiscardWriter(), benchRequest()
+		allocs := testing.AllocsPerRun(1000, func() { serve(h, w, r) })
+		var ns int64
+		if timed {
+			ns = testing.Benchmark(func(b *testing.B) {
+				for i := 0; i < b.N; i++ {
+					serve(h, w, r)
+				}
+			}).NsPerOp()
+		}
+		if c.name == baselineCase {
+			baseAllocs, baseNs = allocs, ns
+			continue
+		}
+
+		limit, ok := budgets[c.name]
+		if !ok {
+			t.Errorf("%s: no budget in %s", c.name, budgetsFile)
+			continue
+		}
+		if over := allocs - baseAllocs; !raceEnabled && over > limit.Allocs {
+			t.Errorf("%s: %.0f allocs/op over the bare router, budget %.0f", c.name, over, limit.Allocs)
+		}
+		if over := ns - baseNs; timed && over > limit.NsPerOp {
+			t.Errorf("%s: %d ns/op over the bare router, budget %d", c.name, over, limit.NsPerOp)
+		}
+	}
+}
+
+// TestAccessLogAllocations holds the request log line to zero heap
+// allocations. Like the budgets, it is not checked under the race detector.
+func TestAccessLogAllocations(t *testing.T) {
+	if raceEnabled {
+		t.Skip("the race detector adds allocations")
+	}
+	log := logger.New(logger.Config{AppName: "bench", Output: io.Discard})
+	r := accessLogRequest()
+	rw := &responseWriter{ResponseWriter: newDiscardWriter(), statusCode: http.StatusOK}
+	allocs := testing.AllocsPerRun(1000, func() { logRequest(log, r, rw, 1500*time.Microsecond) })
+	if allocs > 0 {
+		t.Errorf("request log line: %.0f allocs/op, want 0", allocs)
+	}
+}
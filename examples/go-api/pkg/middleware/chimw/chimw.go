@@ -0,0 +1,31 @@
+//go:build chi
+
+// Package chimw adapts the middleware stack to chi routers. It is behind the
+// chi build tag so the service does not depend on chi; add the module with
+// "go get github.com/go-chi/chi/v5" and build with -tags chi.
+package chimw
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+
+	"github.com/example/go-api/pkg/middleware"
+)
+
+// Route returns the chi route pattern that matched r, e.g. "/users/{id}"
+func Route(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil {
+		return rctx.RoutePattern()
+	}
+	return ""
+}
+
+// Middleware adapts a middleware stack to chi, so metrics, logs and span
+// names use chi's route patterns:
+//
+//	r := chi.NewRouter()
+//	r.Use(chimw.Middleware(o.Middleware))
+func Middleware(stack func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	return middleware.Chain(middleware.Routes(Route), stack)
+}
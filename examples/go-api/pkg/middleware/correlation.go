@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/example/go-api/pkg/client"
+)
+
+// Correlation creates a middleware that stores the named inbound headers in
+// the request context, where the traced HTTP client forwards them upstream
+func Correlation(headers []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			captured := make(http.Header)
+			for _, name := range headers {
+				name = strings.TrimSpace(name)
+				if v := r.Header.Get(name); v != "" {
+					captured.Set(name, v)
+				}
+			}
+			if len(captured) > 0 {
+				r = r.WithContext(client.WithCorrelationHeaders(r.Context(), captured))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
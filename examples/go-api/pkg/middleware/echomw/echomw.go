@@ -0,0 +1,49 @@
+//go:build echo
+
+// Package echomw adapts the middleware stack to Echo. It is behind the echo
+// build tag so the service does not depend on Echo; add the module with
+// "go get github.com/labstack/echo/v4" and build with -tags echo.
+package echomw
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+
+	"github.com/example/go-api/pkg/middleware"
+)
+
+// Wrap adapts a net/http middleware, such as the stack from
+// observability.Init, to Echo. Metrics, logs and span names use Echo's route
+// templates, e.g. "/users/:id":
+//
+//	e := echo.New()
+//	e.Use(echomw.Wrap(o.Middleware))
+//
+// Echo handlers return errors that Echo normally turns into a response only
+// after every middleware has returned, so the stack would log and count a
+// failed request as 200. Wrap writes the error response inside the stack
+// instead, through Echo's HTTPErrorHandler.
+func Wrap(mw func(http.Handler) http.Handler) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			res := c.Response()
+			echoWriter := res.Writer
+
+			h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				c.SetRequest(r)
+				// The stack's writer wraps echoWriter, so writes still reach
+				// the client and Echo still tracks status and size
+				res.Writer = w
+				defer func() { res.Writer = echoWriter }()
+				if err := next(c); err != nil {
+					c.Error(err)
+				}
+			}))
+
+			r := middleware.WithRouteFunc(c.Request(), func(*http.Request) string { return c.Path() })
+			h.ServeHTTP(echoWriter, r)
+			return nil
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// DefaultExcludedPaths are probe and scrape endpoints kept out of traces,
+// request logs and request metrics
+var DefaultExcludedPaths = []string{"/health", "/ready", "/metrics"}
+
+// PathFilter is a set of request paths that bypass telemetry middleware
+type PathFilter map[string]struct{}
+
+// NewPathFilter creates a PathFilter from exact request paths. Empty entries
+// are ignored.
+func NewPathFilter(paths ...string) PathFilter {
+	f := make(PathFilter, len(paths))
+	for _, p := range paths {
+		if p = strings.TrimSpace(p); p != "" {
+			f[p] = struct{}{}
+		}
+	}
+	return f
+}
+
+// Excluded reports whether r bypasses telemetry middleware
+func (f PathFilter) Excluded(r *http.Request) bool {
+	_, ok := f[r.URL.Path]
+	return ok
+}
+
+// Skip wraps mw so that excluded requests go straight to the next handler
+func (f PathFilter) Skip(mw func(http.Handler) http.Handler) func(http.Handler) http.Handler {
+	if len(f) == 0 {
+		return mw
+	}
+	return func(next http.Handler) http.Handler {
+		wrapped := mw(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if f.Excluded(r) {
+				next.ServeHTTP(w, r)
+				return
+			}
+			wrapped.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,210 @@
+//go:build fasthttp
+
+// Package fasthttpmw gives fasthttp services the same tracing, request
+// logging, metrics and panic recovery as the net/http middleware stack. It
+// is behind the fasthttp build tag so the service does not depend on
+// fasthttp; add the module with "go get github.com/valyala/fasthttp" and
+// build with -tags fasthttp.
+//
+// fasthttp has no http.ResponseWriter, so nothing is wrapped: the status is
+// read from the response once the handler returns.
+package fasthttpmw
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/valyala/fasthttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/go-api/pkg/httperr"
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/middleware"
+	"github.com/example/go-api/pkg/tracing"
+)
+
+// tracerName is the instrumentation scope of server spans
+const tracerName = "github.com/example/go-api/pkg/middleware/fasthttpmw"
+
+// contextKey is the user value holding the request's context.Context
+const contextKey = "fasthttpmw.context"
+
+// Config holds fasthttp middleware configuration
+type Config struct {
+	Logger       *logger.Logger
+	Metrics      *middleware.Metrics
+	ExcludePaths []string // Served without telemetry, but still recovered (default middleware.DefaultExcludedPaths)
+
+	// Route returns the route template that matched, e.g. the
+	// router.MatchedRoutePathParam user value of fasthttp/router with
+	// SaveMatchedRoutePath enabled. The raw path is used when it is nil or
+	// returns "".
+	Route func(ctx *fasthttp.RequestCtx) string
+}
+
+// Context returns the request's context.Context, carrying the span, the
+// request and trace IDs and the logger, for handlers and the packages they
+// call, e.g. logger.Ctx(fasthttpmw.Context(ctx))
+func Context(ctx *fasthttp.RequestCtx) context.Context {
+	if c, ok := ctx.UserValue(contextKey).(context.Context); ok {
+		return c
+	}
+	return ctx
+}
+
+// Middleware returns a fasthttp middleware equivalent to the standard
+// net/http stack: a server span continued from the incoming trace context,
+// X-Request-ID and X-Trace-ID headers, one log line per request, request
+// metrics and panic recovery with the standard error envelope.
+func Middleware(cfg Config) func(fasthttp.RequestHandler) fasthttp.RequestHandler {
+	if cfg.ExcludePaths == nil {
+		cfg.ExcludePaths = middleware.DefaultExcludedPaths
+	}
+	exclude := middleware.NewPathFilter(cfg.ExcludePaths...)
+	tracer := otel.Tracer(tracerName)
+
+	return func(next fasthttp.RequestHandler) fasthttp.RequestHandler {
+		return func(ctx *fasthttp.RequestCtx) {
+			path := string(ctx.Path())
+			if _, ok := exclude[path]; ok {
+				defer recoverPanic(ctx, cfg)
+				next(ctx)
+				return
+			}
+
+			start := time.Now()
+			method := string(ctx.Method())
+
+			parent := otel.GetTextMapPropagator().Extract(context.Background(), headerCarrier{&ctx.Request.Header})
+			spanCtx, span := tracer.Start(parent, "HTTP "+method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(semconv.HTTPMethod(method), semconv.URLPath(path)),
+			)
+			defer span.End()
+
+			traceID := span.SpanContext().TraceID().String()
+			if !span.SpanContext().HasTraceID() {
+				traceID = string(ctx.Request.Header.Peek("X-Trace-ID"))
+			}
+			reqCtx := logger.ExtractTraceContext(spanCtx, string(ctx.Request.Header.Peek("X-Request-ID")), traceID)
+			if span.SpanContext().HasSpanID() {
+				reqCtx = logger.WithSpanID(reqCtx, span.SpanContext().SpanID().String())
+			}
+			if cfg.Logger != nil {
+				reqCtx = logger.NewContext(reqCtx, cfg.Logger)
+			}
+			ctx.SetUserValue(contextKey, reqCtx)
+
+			ctx.Response.Header.Set("X-Request-ID", logger.GetRequestID(reqCtx))
+			ctx.Response.Header.Set("X-Trace-ID", logger.GetTraceID(reqCtx))
+
+			if cfg.Metrics != nil {
+				cfg.Metrics.RequestsInFlight.Inc()
+				defer cfg.Metrics.RequestsInFlight.Dec()
+			}
+
+			// Runs after recoverPanic below, so a recovered request is
+			// still logged and counted as 500
+			defer func() {
+				status := ctx.Response.StatusCode()
+				duration := time.Since(start)
+
+				route := path
+				if cfg.Route != nil {
+					if r := cfg.Route(ctx); r != "" {
+						route = r
+						span.SetName(method + " " + r)
+						span.SetAttributes(semconv.HTTPRoute(r))
+					}
+				}
+				span.SetAttributes(
+					attribute.Int("http.status_code", status),
+					attribute.Int64("http.duration_ms", duration.Milliseconds()),
+				)
+				tracing.SetHTTPSpanStatus(span, status, trace.SpanKindServer)
+
+				if cfg.Metrics != nil {
+					cfg.Metrics.RequestsTotal.WithLabelValues(method, route, fmt.Sprintf("%d", status)).Inc()
+					cfg.Metrics.RequestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+				}
+
+				if cfg.Logger != nil {
+					reqLog := cfg.Logger.WithFields(reqCtx, map[string]interface{}{
+						"method":      method,
+						"path":        path,
+						"route":       route,
+						"status":      status,
+						"duration_ms": duration.Milliseconds(),
+						"remote_addr": ctx.RemoteAddr().String(),
+						"user_agent":  string(ctx.UserAgent()),
+					})
+					middleware.LogCompletion(&reqLog, status)
+				}
+			}()
+			defer recoverPanic(ctx, cfg)
+
+			next(ctx)
+		}
+	}
+}
+
+// recoverPanic answers a panicking request with 500 and the standard error
+// envelope
+func recoverPanic(ctx *fasthttp.RequestCtx, cfg Config) {
+	p := recover()
+	if p == nil {
+		return
+	}
+
+	stackTrace := logger.Stack()
+	reqCtx := Context(ctx)
+
+	if cfg.Logger != nil {
+		panicLog := cfg.Logger.WithFields(reqCtx, map[string]interface{}{
+			"method":     string(ctx.Method()),
+			"path":       string(ctx.Path()),
+			"panic":      p,
+			"stacktrace": stackTrace,
+		})
+		panicLog.Error().Msg("Panic recovered")
+	}
+	if cfg.Metrics != nil {
+		cfg.Metrics.PanicRecoveries.Inc()
+	}
+
+	body, _ := json.Marshal(httperr.Envelope{
+		Error:     "Internal Server Error",
+		TraceID:   logger.GetTraceID(reqCtx),
+		RequestID: logger.GetRequestID(reqCtx),
+	})
+	ctx.Response.ResetBody()
+	ctx.SetStatusCode(fasthttp.StatusInternalServerError)
+	ctx.SetContentType("application/json")
+	ctx.SetBody(body)
+}
+
+// headerCarrier adapts fasthttp request headers for trace context propagation
+type headerCarrier struct {
+	h *fasthttp.RequestHeader
+}
+
+func (c headerCarrier) Get(key string) string {
+	return string(c.h.Peek(key))
+}
+
+func (c headerCarrier) Set(key, value string) {
+	c.h.Set(key, value)
+}
+
+func (c headerCarrier) Keys() []string {
+	var keys []string
+	c.h.VisitAll(func(k, _ []byte) {
+		keys = append(keys, string(k))
+	})
+	return keys
+}
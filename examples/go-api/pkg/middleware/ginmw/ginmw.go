@@ -0,0 +1,72 @@
+//go:build gin
+
+// Package ginmw adapts the middleware stack to gin. It is behind the gin
+// build tag so the service does not depend on gin; add the module with
+// "go get github.com/gin-gonic/gin" and build with -tags gin.
+package ginmw
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/example/go-api/pkg/middleware"
+)
+
+// Route returns the gin route that matched c, e.g. "/users/:id"
+func Route(c *gin.Context) string {
+	return c.FullPath()
+}
+
+// Wrap adapts a net/http middleware, such as the stack from
+// observability.Init, to a gin middleware. Metrics, logs and span names use
+// gin's route templates:
+//
+//	r := gin.New()
+//	r.Use(ginmw.Wrap(o.Middleware))
+//
+// When the middleware answers without calling the next handler, e.g. a
+// recovered panic or a shed request, the rest of the gin chain is aborted.
+func Wrap(mw func(http.Handler) http.Handler) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		called := false
+		h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			called = true
+			ginWriter := c.Writer
+			c.Request = r
+			c.Writer = &responseWriter{ResponseWriter: ginWriter, w: w}
+			defer func() { c.Writer = ginWriter }()
+			c.Next()
+		}))
+
+		r := middleware.WithRouteFunc(c.Request, func(*http.Request) string { return Route(c) })
+		h.ServeHTTP(c.Writer, r)
+		if !called {
+			c.Abort()
+		}
+	}
+}
+
+// responseWriter sends the body and status through the writer the net/http
+// middleware passed down, so it sees what gin handlers write. Everything
+// else, such as Status, Size and Hijack, is gin's own writer.
+type responseWriter struct {
+	gin.ResponseWriter
+	w http.ResponseWriter
+}
+
+func (rw *responseWriter) Header() http.Header {
+	return rw.w.Header()
+}
+
+func (rw *responseWriter) WriteHeader(code int) {
+	rw.w.WriteHeader(code)
+}
+
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	return rw.w.Write(b)
+}
+
+func (rw *responseWriter) WriteString(s string) (int, error) {
+	return rw.w.Write([]byte(s))
+}
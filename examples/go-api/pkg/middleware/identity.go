@@ -0,0 +1,22 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/example/go-api/pkg/database"
+)
+
+// Identity creates a middleware that stores the authenticated user in the
+// request context with database.WithActor, so rows the request writes are
+// stamped with created_by and updated_by. The user is read like the audit
+// actor; anonymous requests leave the context alone.
+func Identity() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if user := requestUser(r); user != "" {
+				r = r.WithContext(database.WithActor(r.Context(), user))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/example/go-api/pkg/httperr"
+	"github.com/example/go-api/pkg/logger"
+)
+
+var (
+	concurrencyLimit = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "http_concurrency_limit",
+			Help: "Current adaptive concurrency limit for API requests",
+		},
+	)
+	concurrencyInFlight = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "http_concurrency_in_flight",
+			Help: "API requests currently holding a concurrency slot",
+		},
+	)
+	requestsShed = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "http_requests_shed_total",
+			Help: "API requests rejected with 503 by the adaptive concurrency limiter, by priority class",
+		},
+		[]string{"priority"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(concurrencyLimit)
+	prometheus.MustRegister(concurrencyInFlight)
+	prometheus.MustRegister(requestsShed)
+}
+
+// LimiterConfig tunes the adaptive concurrency limiter. Zero values use the
+// defaults below.
+type LimiterConfig struct {
+	InitialLimit  int           // Starting limit (default 100)
+	MinLimit      int           // The limit never drops below this (default 10)
+	MaxLimit      int           // The limit never grows above this (default 1000)
+	LatencyTarget time.Duration // Requests slower than this shrink the limit (default 1s)
+	Backoff       float64       // Multiplier applied on a slow request (default 0.9)
+	Classify      Classifier    // Load shedding priority (default: NewClassifier(nil))
+}
+
+// AdaptiveLimiter sheds load once more requests are in flight than the
+// current limit. The limit follows AIMD: every request completing within
+// LatencyTarget raises it by 1/limit (about one per limit requests), and a
+// slow request multiplies it by Backoff, at most once per LatencyTarget so a
+// burst of slow requests does not collapse it.
+//
+// Lower priority classes may only fill part of the limit, so bots and then
+// anonymous callers are shed before authenticated ones. Critical requests
+// are always admitted.
+type AdaptiveLimiter struct {
+	cfg LimiterConfig
+	log *logger.Logger
+
+	mu           sync.Mutex
+	limit        float64
+	inFlight     int
+	lastDecrease time.Time
+}
+
+// NewAdaptiveLimiter creates a new AdaptiveLimiter
+func NewAdaptiveLimiter(cfg LimiterConfig, log *logger.Logger) *AdaptiveLimiter {
+	if cfg.MinLimit <= 0 {
+		cfg.MinLimit = 10
+	}
+	if cfg.MaxLimit <= 0 {
+		cfg.MaxLimit = 1000
+	}
+	if cfg.InitialLimit <= 0 {
+		cfg.InitialLimit = 100
+	}
+	if cfg.LatencyTarget <= 0 {
+		cfg.LatencyTarget = time.Second
+	}
+	if cfg.Backoff <= 0 || cfg.Backoff >= 1 {
+		cfg.Backoff = 0.9
+	}
+	if cfg.Classify == nil {
+		cfg.Classify = NewClassifier(nil)
+	}
+	l := &AdaptiveLimiter{cfg: cfg, log: log}
+	l.limit = math.Min(math.Max(float64(cfg.InitialLimit), float64(cfg.MinLimit)), float64(cfg.MaxLimit))
+	concurrencyLimit.Set(math.Floor(l.limit))
+	return l
+}
+
+// Limit returns the current concurrency limit
+func (l *AdaptiveLimiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return int(l.limit)
+}
+
+// InFlight returns the number of requests holding a slot
+func (l *AdaptiveLimiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// acquire takes a slot, or reports false when requests of priority p have
+// filled their share of the limit
+func (l *AdaptiveLimiter) acquire(p Priority) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if p != PriorityCritical && l.inFlight >= int(l.limit*p.share()) {
+		return false
+	}
+	l.inFlight++
+	concurrencyInFlight.Set(float64(l.inFlight))
+	return true
+}
+
+// release frees a slot and adjusts the limit from the request's latency
+func (l *AdaptiveLimiter) release(latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.inFlight--
+	concurrencyInFlight.Set(float64(l.inFlight))
+
+	now := time.Now()
+	if latency > l.cfg.LatencyTarget {
+		if now.Sub(l.lastDecrease) < l.cfg.LatencyTarget {
+			return
+		}
+		l.lastDecrease = now
+		l.limit = math.Max(l.limit*l.cfg.Backoff, float64(l.cfg.MinLimit))
+	} else {
+		l.limit = math.Min(l.limit+1/l.limit, float64(l.cfg.MaxLimit))
+	}
+	concurrencyLimit.Set(math.Floor(l.limit))
+}
+
+// Middleware creates a middleware that answers 503 with Retry-After once the
+// concurrency limit is reached
+func (l *AdaptiveLimiter) Middleware() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			priority := l.cfg.Classify(r)
+			if !l.acquire(priority) {
+				requestsShed.WithLabelValues(priority.String()).Inc()
+				shedLog := l.log.WithFields(r.Context(), map[string]interface{}{
+					"path":       r.URL.Path,
+					"priority":   priority.String(),
+					"limit":      l.Limit(),
+					"in_flight":  l.InFlight(),
+					"user_agent": r.UserAgent(),
+				})
+				shedLog.Warn().Msg("Request shed by concurrency limiter")
+				w.Header().Set("Retry-After", "1")
+				httperr.Write(w, r, http.StatusServiceUnavailable, "server overloaded")
+				return
+			}
+
+			start := time.Now()
+			defer func() { l.release(time.Since(start)) }()
+			next.ServeHTTP(w, r)
+		})
+	}
+}
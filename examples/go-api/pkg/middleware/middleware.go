@@ -1,14 +1,20 @@
 package middleware
 
 import (
+	"bufio"
 	"fmt"
+	"net"
 	"net/http"
-	"runtime"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/example/go-api/pkg/httperr"
 	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/metrics"
 	"github.com/example/go-api/pkg/tracing"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -20,10 +26,36 @@ type Metrics struct {
 	RequestDuration  *prometheus.HistogramVec
 	RequestsInFlight prometheus.Gauge
 	PanicRecoveries  prometheus.Counter
+
+	// Optional: time to the first response byte, and bytes of streamed
+	// (flushed) responses counted as they are flushed. Request duration
+	// alone misrepresents SSE and other long-lived responses.
+	FirstByteDuration *prometheus.HistogramVec
+	StreamedBytes     *prometheus.CounterVec
+}
+
+// MetricsOption adjusts how NewMetrics registers its collectors
+type MetricsOption func(*metricsOptions)
+
+type metricsOptions struct {
+	reg prometheus.Registerer
+}
+
+// WithRegisterer registers the metrics with reg instead of
+// prometheus.DefaultRegisterer
+func WithRegisterer(reg prometheus.Registerer) MetricsOption {
+	return func(o *metricsOptions) { o.reg = reg }
 }
 
-// NewMetrics creates a new Metrics instance
-func NewMetrics(namespace string) *Metrics {
+// NewMetrics creates a new Metrics instance. Collectors that are already
+// registered with the same definition, e.g. by main.go or an earlier
+// NewMetrics call, are reused rather than registered twice.
+func NewMetrics(namespace string, opts ...MetricsOption) *Metrics {
+	o := metricsOptions{reg: prometheus.DefaultRegisterer}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	m := &Metrics{
 		RequestsTotal: prometheus.NewCounterVec(
 			prometheus.CounterOpts{
@@ -56,19 +88,73 @@ func NewMetrics(namespace string) *Metrics {
 				Help:      "Total number of panic recoveries",
 			},
 		),
+		FirstByteDuration: NewFirstByteDuration(namespace),
+		StreamedBytes:     NewStreamedBytes(namespace),
 	}
 
-	prometheus.MustRegister(m.RequestsTotal)
-	prometheus.MustRegister(m.RequestDuration)
-	prometheus.MustRegister(m.RequestsInFlight)
-	prometheus.MustRegister(m.PanicRecoveries)
+	m.RequestsTotal = metrics.MustGetOrRegister(o.reg, m.RequestsTotal)
+	m.RequestDuration = metrics.MustGetOrRegister(o.reg, m.RequestDuration)
+	m.RequestsInFlight = metrics.MustGetOrRegister(o.reg, m.RequestsInFlight)
+	m.PanicRecoveries = metrics.MustGetOrRegister(o.reg, m.PanicRecoveries)
+	m.FirstByteDuration = metrics.MustGetOrRegister(o.reg, m.FirstByteDuration)
+	m.StreamedBytes = metrics.MustGetOrRegister(o.reg, m.StreamedBytes)
 
 	return m
 }
 
+// NewFirstByteDuration creates the unregistered time-to-first-byte
+// histogram of Metrics
+func NewFirstByteDuration(namespace string) *prometheus.HistogramVec {
+	return prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_response_first_byte_seconds",
+			Help:      "Time from the start of an HTTP request to the first byte of its response",
+			Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		},
+		[]string{"method", "path"},
+	)
+}
+
+// NewStreamedBytes creates the unregistered streamed bytes counter of
+// Metrics
+func NewStreamedBytes(namespace string) *prometheus.CounterVec {
+	return prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_streamed_response_bytes_total",
+			Help:      "Body bytes of streamed (flushed) HTTP responses, counted at each flush",
+		},
+		[]string{"method", "path"},
+	)
+}
+
+// streamEventInterval is the minimum time between stream.progress events
+// on the span of a long-lived streamed response
+const streamEventInterval = 15 * time.Second
+
+// responseWriter records the status for logs and metrics. It passes
+// Flush, Hijack and Push through to the writer it wraps, so SSE, WebSockets
+// and server push work behind the middleware, and unwraps for
+// http.ResponseController.
+//
+// It also times the first byte from start and counts the body bytes. A
+// response flushed before it ends is streamed: onStream, when set, gets
+// the bytes of each flush, and span, when set, gets a progress event at
+// most every streamEventInterval.
 type responseWriter struct {
 	http.ResponseWriter
 	statusCode int
+
+	start     time.Time
+	firstByte time.Duration // Zero until the first Write or Flush
+	wrote     bool
+	bytes     int64
+	unflushed int64 // Bytes written since the last flush
+	flushes   int
+	onStream  func(bytes int64)
+	span      trace.Span
+	lastEvent time.Time
 }
 
 func (rw *responseWriter) WriteHeader(code int) {
@@ -76,6 +162,129 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Write implements io.Writer, counting the bytes written
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.markFirstByte()
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytes += int64(n)
+	rw.unflushed += int64(n)
+	return n, err
+}
+
+// Flush implements http.Flusher. It does nothing when the wrapped writer
+// cannot flush.
+func (rw *responseWriter) Flush() {
+	f, ok := rw.ResponseWriter.(http.Flusher)
+	if !ok {
+		return
+	}
+	rw.markFirstByte()
+	f.Flush()
+	rw.flushes++
+	rw.streamed(false)
+}
+
+// markFirstByte records the time to the first byte on first use
+func (rw *responseWriter) markFirstByte() {
+	if !rw.wrote {
+		rw.wrote = true
+		if !rw.start.IsZero() {
+			rw.firstByte = time.Since(rw.start)
+		}
+	}
+}
+
+// isStream reports whether the response was flushed before it ended
+func (rw *responseWriter) isStream() bool {
+	return rw.flushes > 0
+}
+
+// streamed reports the bytes flushed since the last call to onStream and,
+// when an interval has passed or the response is done, adds a progress
+// event to span
+func (rw *responseWriter) streamed(done bool) {
+	if !rw.isStream() {
+		return
+	}
+	if rw.onStream != nil && rw.unflushed > 0 {
+		rw.onStream(rw.unflushed)
+	}
+	rw.unflushed = 0
+
+	if rw.span == nil {
+		return
+	}
+	now := time.Now()
+	if rw.lastEvent.IsZero() {
+		rw.lastEvent = rw.start
+	}
+	if !done && now.Sub(rw.lastEvent) < streamEventInterval {
+		return
+	}
+	rw.lastEvent = now
+	name := "stream.progress"
+	if done {
+		name = "stream.end"
+	}
+	rw.span.AddEvent(name, trace.WithAttributes(
+		attribute.Int64("http.response.body.size", rw.bytes),
+		attribute.Int("stream.flushes", rw.flushes),
+		attribute.Int64("stream.elapsed_ms", now.Sub(rw.start).Milliseconds()),
+	))
+}
+
+// Hijack implements http.Hijacker. A hijacked connection, e.g. a WebSocket
+// upgrade, is recorded as 101 Switching Protocols unless the handler wrote
+// a status first.
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%T does not support hijacking: %w", rw.ResponseWriter, http.ErrNotSupported)
+	}
+	conn, buf, err := h.Hijack()
+	if err == nil && rw.statusCode == http.StatusOK {
+		rw.statusCode = http.StatusSwitchingProtocols
+	}
+	return conn, buf, err
+}
+
+// Push implements http.Pusher
+func (rw *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := rw.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// Unwrap returns the wrapped writer, for http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// responseWriters pools the wrappers, since every request gets one from
+// each of the logging, metrics and audit middleware
+var responseWriters = sync.Pool{
+	New: func() interface{} { return new(responseWriter) },
+}
+
+// getResponseWriter returns a pooled wrapper of w, reset to a 200 response
+// started at start. Release it with putResponseWriter once the request is
+// logged and counted; the handler has returned by then, so nothing else
+// holds it.
+func getResponseWriter(w http.ResponseWriter, start time.Time) *responseWriter {
+	rw := responseWriters.Get().(*responseWriter)
+	*rw = responseWriter{ResponseWriter: w, statusCode: http.StatusOK, start: start}
+	return rw
+}
+
+// putResponseWriter returns rw to the pool, dropping its references to the
+// request. A wrapper is not returned when its handler panics, which only
+// costs the pool a reuse.
+func putResponseWriter(rw *responseWriter) {
+	*rw = responseWriter{}
+	responseWriters.Put(rw)
+}
+
 // Logging creates a logging middleware
 func Logging(log *logger.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -86,6 +295,7 @@ func Logging(log *logger.Logger) func(http.Handler) http.Handler {
 			requestID := r.Header.Get("X-Request-ID")
 			traceID := r.Header.Get("X-Trace-ID")
 			ctx := logger.ExtractTraceContext(r.Context(), requestID, traceID)
+			ctx = logger.NewContext(ctx, log)
 			r = r.WithContext(ctx)
 
 			// Set response headers for tracing
@@ -93,23 +303,13 @@ func Logging(log *logger.Logger) func(http.Handler) http.Handler {
 			w.Header().Set("X-Trace-ID", logger.GetTraceID(ctx))
 
 			// Wrap response writer
-			rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			rw := getResponseWriter(w, start)
 
 			// Process request
 			next.ServeHTTP(rw, r)
 
-			duration := time.Since(start)
-
-			// Log request
-			logCtx := log.WithFields(ctx, map[string]interface{}{
-				"method":      r.Method,
-				"path":        r.URL.Path,
-				"status":      rw.statusCode,
-				"duration_ms": duration.Milliseconds(),
-				"remote_addr": r.RemoteAddr,
-				"user_agent":  r.UserAgent(),
-			})
-			logCtx.Info().Msg("HTTP request completed")
+			logRequest(log, r, rw, time.Since(start))
+			putResponseWriter(rw)
 		})
 	}
 }
@@ -121,7 +321,13 @@ func MetricsMiddleware(m *Metrics) func(http.Handler) http.Handler {
 			start := time.Now()
 
 			// Wrap response writer
-			rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			rw := getResponseWriter(w, start)
+			if m.StreamedBytes != nil {
+				// The route is matched by the time the handler flushes
+				rw.onStream = func(bytes int64) {
+					m.StreamedBytes.WithLabelValues(r.Method, routeLabel(r)).Add(float64(bytes))
+				}
+			}
 
 			// Track in-flight requests
 			m.RequestsInFlight.Inc()
@@ -129,26 +335,80 @@ func MetricsMiddleware(m *Metrics) func(http.Handler) http.Handler {
 
 			// Process request
 			next.ServeHTTP(rw, r)
+			rw.streamed(true)
 
 			duration := time.Since(start)
 
 			// Record metrics
-			m.RequestsTotal.WithLabelValues(r.Method, r.URL.Path, fmt.Sprintf("%d", rw.statusCode)).Inc()
-			m.RequestDuration.WithLabelValues(r.Method, r.URL.Path).Observe(duration.Seconds())
+			// Label by route template so path parameters do not explode
+			// the series count
+			path := routeLabel(r)
+			m.RequestsTotal.WithLabelValues(r.Method, path, fmt.Sprintf("%d", rw.statusCode)).Inc()
+			m.RequestDuration.WithLabelValues(r.Method, path).Observe(duration.Seconds())
+			if m.FirstByteDuration != nil && rw.wrote {
+				m.FirstByteDuration.WithLabelValues(r.Method, path).Observe(rw.firstByte.Seconds())
+			}
+			putResponseWriter(rw)
 		})
 	}
 }
 
+// completionMessage is the message of the request log line
+const completionMessage = "HTTP request completed"
+
+// LogCompletion writes the request log line, at error level for 5xx
+// responses so error panels and level="error" alerts see failed requests,
+// and at info level otherwise
+func LogCompletion(l *zerolog.Logger, status int) {
+	l.WithLevel(completionLevel(status)).Msg(completionMessage)
+}
+
+func completionLevel(status int) zerolog.Level {
+	if status >= http.StatusInternalServerError {
+		return zerolog.ErrorLevel
+	}
+	return zerolog.InfoLevel
+}
+
+// logRequest writes the request log line of r, whose context carries the
+// request and trace IDs. The fields are written straight onto the event,
+// so on the happy path the line is encoded without heap allocations.
+func logRequest(log *logger.Logger, r *http.Request, rw *responseWriter, duration time.Duration) {
+	event := log.Event(r.Context(), completionLevel(rw.statusCode))
+	if event == nil {
+		return
+	}
+	event.
+		Str("method", r.Method).
+		Str("path", r.URL.Path).
+		Str("route", Route(r)).
+		Int("status", rw.statusCode).
+		Int64("duration_ms", duration.Milliseconds()).
+		Str("remote_addr", r.RemoteAddr).
+		Str("user_agent", r.UserAgent())
+	if rw.isStream() {
+		event.
+			Bool("streamed", true).
+			Int64("first_byte_ms", rw.firstByte.Milliseconds()).
+			Int64("bytes", rw.bytes)
+	}
+	event.Msg(completionMessage)
+}
+
 // Recovery creates a panic recovery middleware
 func Recovery(log *logger.Logger, m *Metrics) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if err := recover(); err != nil {
+					// net/http aborts the response quietly for this one,
+					// so leave it to the server
+					if err == http.ErrAbortHandler {
+						panic(err)
+					}
+
 					// Capture stack trace
-					stackBuf := make([]byte, 4096)
-					stackSize := runtime.Stack(stackBuf, false)
-					stackTrace := string(stackBuf[:stackSize])
+					stackTrace := logger.Stack()
 
 					// Log panic
 					panicLog := log.WithFields(r.Context(), map[string]interface{}{
@@ -165,7 +425,7 @@ func Recovery(log *logger.Logger, m *Metrics) func(http.Handler) http.Handler {
 					}
 
 					// Return 500 error
-					http.Error(w, "Internal Server Error", http.StatusInternalServerError)
+					httperr.Write(w, r, http.StatusInternalServerError, "Internal Server Error")
 				}
 			}()
 
@@ -174,6 +434,18 @@ func Recovery(log *logger.Logger, m *Metrics) func(http.Handler) http.Handler {
 	}
 }
 
+// acceptsTrailers reports whether the TE header lists "trailers"
+func acceptsTrailers(r *http.Request) bool {
+	for _, v := range r.Header.Values("TE") {
+		for _, part := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(part), "trailers") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // Chain chains multiple middleware functions
 func Chain(middlewares ...func(http.Handler) http.Handler) func(http.Handler) http.Handler {
 	return func(final http.Handler) http.Handler {
@@ -216,6 +488,7 @@ func TracedLogging(log *logger.Logger) func(http.Handler) http.Handler {
 			if otelSpanID != "" {
 				ctx = logger.WithSpanID(ctx, otelSpanID)
 			}
+			ctx = logger.NewContext(ctx, log)
 
 			r = r.WithContext(ctx)
 
@@ -225,34 +498,42 @@ func TracedLogging(log *logger.Logger) func(http.Handler) http.Handler {
 			if otelSpanID != "" {
 				w.Header().Set("X-Span-ID", otelSpanID)
 			}
+			// Clients sending "TE: trailers" also get the trace ID as a
+			// trailer, which survives proxies that rewrite headers
+			if otelTraceID != "" && acceptsTrailers(r) {
+				w.Header().Add("Trailer", "X-Trace-ID")
+			}
 
-			// Wrap response writer
-			rw := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			// Wrap response writer; long streams add progress events to the span
+			span := trace.SpanFromContext(r.Context())
+			rw := getResponseWriter(w, start)
+			rw.span = span
 
 			// Process request
 			next.ServeHTTP(rw, r)
+			rw.streamed(true)
 
 			duration := time.Since(start)
 
 			// Add span attributes for the response
-			span := trace.SpanFromContext(r.Context())
 			span.SetAttributes(
 				attribute.Int("http.status_code", rw.statusCode),
 				attribute.Int64("http.duration_ms", duration.Milliseconds()),
 			)
+			if rw.isStream() {
+				span.SetAttributes(
+					attribute.Bool("http.streamed", true),
+					attribute.Int64("http.first_byte_ms", rw.firstByte.Milliseconds()),
+					attribute.Int64("http.response.body.size", rw.bytes),
+				)
+			}
+			tracing.SetHTTPSpanStatus(span, rw.statusCode, trace.SpanKindServer)
+			nameSpan(span, r)
 
-			// Log with trace correlation
-			tracedLog := log.WithFields(ctx, map[string]interface{}{
-				"method":      r.Method,
-				"path":        r.URL.Path,
-				"status":      rw.statusCode,
-				"duration_ms": duration.Milliseconds(),
-				"remote_addr": r.RemoteAddr,
-				"user_agent":  r.UserAgent(),
-				"trace_id":    otelTraceID,
-				"span_id":     otelSpanID,
-			})
-			tracedLog.Info().Msg("HTTP request completed")
+			// Log with trace correlation; ctx carries the OTel trace and
+			// span IDs
+			logRequest(log, r, rw, duration)
+			putResponseWriter(rw)
 		})
 	}
 }
@@ -0,0 +1,176 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+// benchCase is a middleware measured by the benchmarks and held to a budget
+// by TestPerformanceBudgets
+type benchCase struct {
+	name string
+	mw   func(http.Handler) http.Handler
+}
+
+// baselineCase is the bare router, whose cost the budgets leave out
+const baselineCase = "none"
+
+// benchCases returns each middleware set up as in production, logging to
+// io.Discard, plus the whole public stack
+func benchCases() []benchCase {
+	log := logger.New(logger.Config{AppName: "bench", Output: io.Discard})
+	m := NewMetrics("bench", WithRegisterer(prometheus.NewRegistry()))
+	limiter := NewAdaptiveLimiter(LimiterConfig{}, log)
+	forward := []string{"X-Correlation-ID"}
+
+	return []benchCase{
+		{baselineCase, func(next http.Handler) http.Handler { return next }},
+		{"logging", Logging(log)},
+		{"traced_logging", TracedLogging(log)},
+		{"metrics", MetricsMiddleware(m)},
+		{"recovery", Recovery(log, m)},
+		{"correlation", Correlation(forward)},
+		{"tenancy", Tenancy(DefaultTenantHeader, []string{"bench"})},
+		{"identity", Identity()},
+		{"limiter", limiter.Middleware()},
+		{"public_stack", Public(PresetConfig{
+			ServiceName:    "bench",
+			Logger:         log,
+			Metrics:        m,
+			Exclude:        NewPathFilter(DefaultExcludedPaths...),
+			ForwardHeaders: forward,
+			TenantHeader:   DefaultTenantHeader,
+			Tenants:        []string{"bench"},
+			Limiter:        limiter,
+			AnyRouter:      true,
+		}).Then},
+	}
+}
+
+var benchBody = []byte(`{"id":42,"username":"bench"}`)
+
+// benchHandler serves a route template through gorilla/mux with mw
+// installed, as the API does, so route labels resolve
+func benchHandler(mw func(http.Handler) http.Handler) http.Handler {
+	router := mux.NewRouter()
+	router.Use(mw)
+	router.HandleFunc("/api/users/{id:[0-9]+}", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(benchBody)
+	}).Methods("GET")
+	return router
+}
+
+// benchRequest is a request arriving with a request ID, correlation and
+// tenant headers, under a sampled span of the tracing middleware
+func benchRequest() *http.Request {
+	r := httptest.NewRequest("GET", "/api/users/42", nil)
+	r.Header.Set("User-Agent", "bench/1.0")
+	r.Header.Set("X-Request-ID", "3f2b8c1e-bench")
+	r.Header.Set("X-Correlation-ID", "bench-correlation")
+	r.Header.Set(DefaultTenantHeader, "bench")
+	sc := trace.NewSpanContext(trace.SpanContextConfig{
+		TraceID:    trace.TraceID{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36},
+		SpanID:     trace.SpanID{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7},
+		TraceFlags: trace.FlagsSampled,
+	})
+	return r.WithContext(trace.ContextWithSpanContext(r.Context(), sc))
+}
+
+// discardWriter is a ResponseWriter that keeps nothing but headers, so the
+// benchmarks measure the middleware rather than a recorder
+type discardWriter struct {
+	header http.Header
+}
+
+func newDiscardWriter() *discardWriter {
+	return &discardWriter{header: make(http.Header)}
+}
+
+func (w *discardWriter) Header() http.Header         { return w.header }
+func (w *discardWriter) Write(p []byte) (int, error) { return len(p), nil }
+func (w *discardWriter) WriteHeader(int)             {}
+
+// serve runs one request through h, reusing w and r
+func serve(h http.Handler, w *discardWriter, r *http.Request) {
+	clear(w.header)
+	h.ServeHTTP(w, r)
+}
+
+// BenchmarkMiddleware measures the per-request cost of each middleware on
+// one goroutine. Subtract the "none" case to get what the middleware adds.
+func BenchmarkMiddleware(b *testing.B) {
+	for _, c := range benchCases() {
+		b.Run(c.name, func(b *testing.B) {
+			h, w, r := benchHandler(c.mw), newDiscardWriter(), benchRequest()
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				serve(h, w, r)
+			}
+		})
+	}
+}
+
+// BenchmarkMiddlewareParallel measures the same under concurrent requests,
+// where shared metrics, the limiter and log writers contend
+func BenchmarkMiddlewareParallel(b *testing.B) {
+	for _, c := range benchCases() {
+		b.Run(c.name, func(b *testing.B) {
+			h := benchHandler(c.mw)
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				w, r := newDiscardWriter(), benchRequest()
+				for pb.Next() {
+					serve(h, w, r)
+				}
+			})
+		})
+	}
+}
+
+// accessLogRequest is benchRequest as the logging middleware passes it on,
+// matched to its route and carrying the trace IDs
+func accessLogRequest() *http.Request {
+	r := mux.SetURLVars(benchRequest(), map[string]string{"id": "42"})
+	ctx := logger.ExtractTraceContext(r.Context(), r.Header.Get("X-Request-ID"), "4bf92f3577b34da6a3ce929d0e0e4736")
+	ctx = logger.WithSpanID(ctx, "00f067aa0ba902b7")
+	return r.WithContext(ctx)
+}
+
+// BenchmarkAccessLog measures writing the request log line alone
+func BenchmarkAccessLog(b *testing.B) {
+	log := logger.New(logger.Config{AppName: "bench", Output: io.Discard})
+	r := accessLogRequest()
+	rw := &responseWriter{ResponseWriter: newDiscardWriter(), statusCode: http.StatusOK}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		logRequest(log, r, rw, 1500*time.Microsecond)
+	}
+}
+
+// BenchmarkRecoveryPanic measures recovering a panic, including capturing
+// its stack
+func BenchmarkRecoveryPanic(b *testing.B) {
+	log := logger.New(logger.Config{AppName: "bench", Output: io.Discard})
+	h := Recovery(log, nil)(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {
+		panic("bench")
+	}))
+	w, r := newDiscardWriter(), benchRequest()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		serve(h, w, r)
+	}
+}
@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+// TestPooledResponseWriterIsReset checks that a wrapper reused from the
+// pool does not carry the status or stream state of an earlier request
+func TestPooledResponseWriterIsReset(t *testing.T) {
+	var buf bytes.Buffer
+	log := logger.New(logger.Config{AppName: "test", Output: &buf})
+	h := TracedLogging(log)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+			io.WriteString(w, "partial")
+			w.(http.Flusher).Flush()
+		}
+	}))
+
+	for _, path := range []string{"/fail", "/ok", "/fail", "/ok"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("got %d log lines, want 4:\n%s", len(lines), buf.String())
+	}
+	for i, line := range lines {
+		var entry struct {
+			Path     string `json:"path"`
+			Status   int    `json:"status"`
+			Streamed bool   `json:"streamed"`
+			Bytes    int64  `json:"bytes"`
+		}
+		if err := json.Unmarshal([]byte(line), &entry); err != nil {
+			t.Fatalf("line %d: %v", i, err)
+		}
+		fail := entry.Path == "/fail"
+		if want := map[bool]int{true: 500, false: 200}[fail]; entry.Status != want {
+			t.Errorf("%s: status %d, want %d", entry.Path, entry.Status, want)
+		}
+		if entry.Streamed != fail || (entry.Bytes > 0) != fail {
+			t.Errorf("%s: streamed=%v bytes=%d, want stream state only on /fail", entry.Path, entry.Streamed, entry.Bytes)
+		}
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Priority orders requests for load shedding: under overload the lowest
+// classes are shed first
+type Priority int
+
+const (
+	PriorityBot           Priority = iota // Crawlers and other self-identified bots
+	PriorityAnonymous                     // Unauthenticated callers
+	PriorityAuthenticated                 // Callers identified by a proxy header, basic auth or bearer token
+	PriorityCritical                      // Health checks; never shed
+)
+
+// String returns the priority class used in logs and metric labels
+func (p Priority) String() string {
+	switch p {
+	case PriorityBot:
+		return "bot"
+	case PriorityAnonymous:
+		return "anonymous"
+	case PriorityAuthenticated:
+		return "authenticated"
+	case PriorityCritical:
+		return "critical"
+	}
+	return "unknown"
+}
+
+// share is the fraction of the concurrency limit a class may fill. Lower
+// classes are turned away while headroom remains for higher ones.
+func (p Priority) share() float64 {
+	switch p {
+	case PriorityBot:
+		return 0.5
+	case PriorityAnonymous:
+		return 0.8
+	}
+	return 1
+}
+
+// botMarkers are User-Agent substrings identifying crawlers
+var botMarkers = []string{"bot", "crawler", "spider", "slurp", "scraper"}
+
+// Classifier assigns a load shedding priority to a request
+type Classifier func(r *http.Request) Priority
+
+// NewClassifier returns a Classifier treating criticalPaths as health checks
+// and prioritising authenticated callers over anonymous ones and bots
+func NewClassifier(criticalPaths []string) Classifier {
+	critical := make(map[string]bool, len(criticalPaths))
+	for _, p := range criticalPaths {
+		if p = strings.TrimSpace(p); p != "" {
+			critical[p] = true
+		}
+	}
+
+	return func(r *http.Request) Priority {
+		switch {
+		case critical[r.URL.Path]:
+			return PriorityCritical
+		case r.Header.Get("Authorization") != "" || auditActor(r) != "anonymous":
+			return PriorityAuthenticated
+		case isBot(r.UserAgent()):
+			return PriorityBot
+		}
+		return PriorityAnonymous
+	}
+}
+
+func isBot(userAgent string) bool {
+	ua := strings.ToLower(userAgent)
+	for _, m := range botMarkers {
+		if strings.Contains(ua, m) {
+			return true
+		}
+	}
+	return false
+}
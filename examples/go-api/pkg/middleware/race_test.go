@@ -0,0 +1,7 @@
+//go:build race
+
+package middleware
+
+// raceEnabled reports whether tests run under the race detector, whose
+// instrumentation adds allocations of its own
+const raceEnabled = true
@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RouteFunc returns the route template that matched r, e.g.
+// "/weather/{location}", or "" when none did. It is called after the handler
+// returns, once the router has finished matching.
+type RouteFunc func(r *http.Request) string
+
+type routeFuncKey struct{}
+
+// WithRouteFunc returns r carrying fn as its route resolver. Router adapters
+// use it to tell the middleware below how to find the matched route.
+func WithRouteFunc(r *http.Request, fn RouteFunc) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), routeFuncKey{}, fn))
+}
+
+// Routes creates a middleware installing fn as the route resolver for the
+// middleware after it. Put it first when the router is not gorilla/mux.
+func Routes(fn RouteFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, WithRouteFunc(r, fn))
+		})
+	}
+}
+
+// Route returns the route template that matched r, from the resolver
+// installed by Routes or WithRouteFunc, or else from gorilla/mux. It returns
+// "" when neither knows the route.
+func Route(r *http.Request) string {
+	if fn, ok := r.Context().Value(routeFuncKey{}).(RouteFunc); ok {
+		if route := fn(r); route != "" {
+			return route
+		}
+	}
+	return MuxRoute(r)
+}
+
+// MuxRoute returns the gorilla/mux path template that matched r
+func MuxRoute(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if tmpl, err := route.GetPathTemplate(); err == nil {
+			return tmpl
+		}
+	}
+	return ""
+}
+
+// ServeMuxRoute returns a RouteFunc for a net/http ServeMux, including Go
+// 1.22 patterns such as "GET /users/{id}". The method and host are dropped
+// from the pattern, since they are reported separately:
+//
+//	mux := http.NewServeMux()
+//	mux.Handle("GET /users/{id}", users)
+//	handler := middleware.Routes(middleware.ServeMuxRoute(mux))(o.Middleware(mux))
+func ServeMuxRoute(serveMux *http.ServeMux) RouteFunc {
+	return func(r *http.Request) string {
+		// Handler matches without serving, so it works on the request the
+		// middleware holds rather than the copy the mux was given
+		_, pattern := serveMux.Handler(r)
+		return patternPath(pattern)
+	}
+}
+
+// patternPath strips the method and host from a ServeMux pattern
+func patternPath(pattern string) string {
+	if i := strings.IndexAny(pattern, " \t"); i >= 0 {
+		pattern = strings.TrimLeft(pattern[i:], " \t")
+	}
+	if i := strings.IndexByte(pattern, '/'); i > 0 {
+		pattern = pattern[i:]
+	}
+	return pattern
+}
+
+// unmatchedRoute is the route label of requests no router matched. The raw
+// path would make a series per path a scanner tries; it stays in the
+// request log line instead.
+const unmatchedRoute = "unmatched"
+
+// routeLabel returns the route for metric labels, or unmatchedRoute for
+// requests no router matched
+func routeLabel(r *http.Request) string {
+	if route := Route(r); route != "" {
+		return route
+	}
+	return unmatchedRoute
+}
+
+// OTelHTTPMiddleware returns OpenTelemetry middleware for any router. Spans
+// start as "HTTP GET" and are renamed to "GET /route" by TracedLogging once
+// the router has matched; use OTelMiddleware with gorilla/mux instead.
+func OTelHTTPMiddleware(serviceName string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return otelhttp.NewHandler(next, serviceName,
+			otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+				return "HTTP " + r.Method
+			}),
+		)
+	}
+}
+
+// nameSpan names the server span after the matched route
+func nameSpan(span trace.Span, r *http.Request) {
+	if route := Route(r); route != "" {
+		span.SetName(r.Method + " " + route)
+		span.SetAttributes(semconv.HTTPRoute(route))
+	}
+}
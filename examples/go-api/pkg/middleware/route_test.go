@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+// TestServeMuxRoute checks that Go 1.22 ServeMux patterns resolve to their
+// path, which needs go 1.22 or later in go.mod: with httpmuxgo121=1, the
+// default for older modules, the method patterns below never match.
+func TestServeMuxRoute(t *testing.T) {
+	noop := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	serveMux := http.NewServeMux()
+	serveMux.Handle("GET /users/{id}", noop)
+	serveMux.Handle("POST /users/{id}/roles/{role...}", noop)
+	serveMux.Handle("api.example.com/health", noop)
+	serveMux.Handle("/static/", noop)
+
+	cases := []struct {
+		method, target string
+		want           string
+	}{
+		{"GET", "/users/42", "/users/{id}"},
+		{"HEAD", "/users/42", "/users/{id}"},
+		{"POST", "/users/42/roles/admin/read", "/users/{id}/roles/{role...}"},
+		{"GET", "http://api.example.com/health", "/health"},
+		{"GET", "/static/app.css", "/static/"},
+		{"GET", "/unknown", ""},
+		{"DELETE", "/users/42", ""},
+	}
+	route := ServeMuxRoute(serveMux)
+	for _, c := range cases {
+		t.Run(c.method+" "+c.target, func(t *testing.T) {
+			var got string
+			h := Routes(route)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				serveMux.ServeHTTP(w, r)
+				got = Route(r)
+			}))
+			h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(c.method, c.target, nil))
+			if got != c.want {
+				t.Errorf("route %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestRouteLabelUnmatched(t *testing.T) {
+	router := mux.NewRouter()
+	router.HandleFunc("/api/users/{id}", func(http.ResponseWriter, *http.Request) {})
+	var labels []string
+	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		labels = append(labels, routeLabel(r))
+	})
+	router.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			next.ServeHTTP(w, r)
+			labels = append(labels, routeLabel(r))
+		})
+	})
+
+	for _, target := range []string{"/api/users/42", "/wp-login.php", "/.env"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", target, nil))
+	}
+	want := []string{"/api/users/{id}", unmatchedRoute, unmatchedRoute}
+	if !slices.Equal(labels, want) {
+		t.Errorf("route labels %q, want %q", labels, want)
+	}
+}
@@ -0,0 +1,207 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/example/go-api/pkg/database"
+	"github.com/example/go-api/pkg/logger"
+)
+
+// Layer names the role of a middleware in a Stack. Validate orders layers
+// by role; LayerCustom is left wherever it is put.
+type Layer string
+
+const (
+	LayerTracing     Layer = "tracing"
+	LayerRecovery    Layer = "recovery"
+	LayerCorrelation Layer = "correlation"
+	LayerTenancy     Layer = "tenancy"
+	LayerIdentity    Layer = "identity"
+	LayerLogging     Layer = "logging"
+	LayerMetrics     Layer = "metrics"
+	LayerAudit       Layer = "audit"
+	LayerLimiter     Layer = "limiter"
+	LayerCustom      Layer = "custom"
+)
+
+// orderRule requires outer to wrap inner when both are in a stack
+type orderRule struct {
+	outer, inner Layer
+	why          string
+}
+
+var orderRules = []orderRule{
+	{LayerTracing, LayerRecovery, "the server span records the 500 of a recovered panic"},
+	{LayerTracing, LayerLogging, "request logs carry the trace ID of the server span"},
+	{LayerTracing, LayerAudit, "audit records carry the trace ID of the server span"},
+	{LayerTenancy, LayerLogging, "request logs carry the tenant"},
+	{LayerLogging, LayerLimiter, "shed requests are logged"},
+	{LayerMetrics, LayerLimiter, "shed requests are counted"},
+}
+
+// Stack is an ordered list of middleware, each tagged with its Layer. The
+// first layer added is the outermost, as with Chain. Validate checks the
+// order before the stack serves traffic, so a misordered stack fails at
+// startup instead of silently producing logs without trace IDs or panics
+// that escape recovery.
+type Stack struct {
+	name   string
+	layers []stackLayer
+}
+
+type stackLayer struct {
+	kind Layer
+	mw   func(http.Handler) http.Handler
+}
+
+// NewStack creates an empty stack. name appears in validation errors.
+func NewStack(name string) *Stack {
+	return &Stack{name: name}
+}
+
+// Use appends mw as the innermost layer so far
+func (s *Stack) Use(kind Layer, mw func(http.Handler) http.Handler) *Stack {
+	s.layers = append(s.layers, stackLayer{kind: kind, mw: mw})
+	return s
+}
+
+// Layers returns the layer kinds, outermost first
+func (s *Stack) Layers() []Layer {
+	kinds := make([]Layer, len(s.layers))
+	for i, l := range s.layers {
+		kinds[i] = l.kind
+	}
+	return kinds
+}
+
+// Validate checks the order of the layers: tracing wraps recovery, logging
+// and audit; recovery wraps everything but tracing; tenancy wraps logging;
+// logging and metrics wrap the limiter. Apart from LayerCustom, each layer
+// may appear once. All violations are returned together.
+func (s *Stack) Validate() error {
+	pos := map[Layer]int{}
+	var errs []error
+	for i, l := range s.layers {
+		if l.kind == LayerCustom {
+			continue
+		}
+		if _, ok := pos[l.kind]; ok {
+			errs = append(errs, fmt.Errorf("%s appears more than once", l.kind))
+			continue
+		}
+		pos[l.kind] = i
+	}
+
+	for _, rule := range orderRules {
+		outer, ok1 := pos[rule.outer]
+		inner, ok2 := pos[rule.inner]
+		if ok1 && ok2 && outer > inner {
+			errs = append(errs, fmt.Errorf("%s must come before %s so %s", rule.outer, rule.inner, rule.why))
+		}
+	}
+	if recovery, ok := pos[LayerRecovery]; ok {
+		for i, l := range s.layers[:recovery] {
+			if l.kind != LayerTracing && l.kind != LayerCustom {
+				errs = append(errs, fmt.Errorf("%s at position %d must come after recovery so its panics are recovered", l.kind, i+1))
+			}
+		}
+	}
+
+	if len(errs) == 0 {
+		return nil
+	}
+	order := make([]string, len(s.layers))
+	for i, l := range s.layers {
+		order[i] = string(l.kind)
+	}
+	return fmt.Errorf("invalid middleware stack %q (%s): %w", s.name, strings.Join(order, " -> "), errors.Join(errs...))
+}
+
+// Then wraps h in the stack. It has the signature of mux.MiddlewareFunc,
+// so a validated stack can be installed with router.Use(stack.Then).
+func (s *Stack) Then(h http.Handler) http.Handler {
+	for i := len(s.layers) - 1; i >= 0; i-- {
+		h = s.layers[i].mw(h)
+	}
+	return h
+}
+
+// PresetConfig holds what the preset stacks are built from. Layers whose
+// parts are nil or empty are left out.
+type PresetConfig struct {
+	ServiceName    string
+	Logger         *logger.Logger
+	Metrics        *Metrics
+	Exclude        PathFilter // Paths kept out of tracing, logging and metrics
+	ForwardHeaders []string   // Correlation headers forwarded to upstreams
+	TenantHeader   string
+	Tenants        []string // Tenants accepted from TenantHeader; tenancy is left out when empty
+	Limiter        *AdaptiveLimiter
+	AuditStore     func() database.Store // Admin only; nil logs audit records without saving them
+
+	// AnyRouter selects OTelHTTPMiddleware, which works with any router,
+	// instead of the gorilla/mux OTelMiddleware
+	AnyRouter bool
+}
+
+func (c PresetConfig) tracing() func(http.Handler) http.Handler {
+	if c.AnyRouter {
+		return c.Exclude.Skip(OTelHTTPMiddleware(c.ServiceName))
+	}
+	return c.Exclude.Skip(OTelMiddleware(c.ServiceName))
+}
+
+// Public is the stack for externally reachable routes: tracing, recovery,
+// correlation headers, tenancy, the user identity, request logging, metrics
+// and, innermost so shed requests are still traced, logged and counted, the
+// limiter
+func Public(cfg PresetConfig) *Stack {
+	s := NewStack("public").
+		Use(LayerTracing, cfg.tracing()).
+		Use(LayerRecovery, Recovery(cfg.Logger, cfg.Metrics)).
+		Use(LayerCorrelation, Correlation(cfg.ForwardHeaders))
+	if cfg.TenantHeader != "" && len(cfg.Tenants) > 0 {
+		s.Use(LayerTenancy, Tenancy(cfg.TenantHeader, cfg.Tenants))
+	}
+	s.Use(LayerIdentity, Identity())
+	s.Use(LayerLogging, cfg.Exclude.Skip(TracedLogging(cfg.Logger)))
+	if cfg.Metrics != nil {
+		s.Use(LayerMetrics, cfg.Exclude.Skip(MetricsMiddleware(cfg.Metrics)))
+	}
+	if cfg.Limiter != nil {
+		s.Use(LayerLimiter, cfg.Limiter.Middleware())
+	}
+	return s
+}
+
+// Internal is the stack for service-to-service routes behind the cluster
+// boundary: tracing, recovery, request logging and metrics
+func Internal(cfg PresetConfig) *Stack {
+	s := NewStack("internal").
+		Use(LayerTracing, cfg.tracing()).
+		Use(LayerRecovery, Recovery(cfg.Logger, cfg.Metrics)).
+		Use(LayerLogging, cfg.Exclude.Skip(TracedLogging(cfg.Logger)))
+	if cfg.Metrics != nil {
+		s.Use(LayerMetrics, cfg.Exclude.Skip(MetricsMiddleware(cfg.Metrics)))
+	}
+	return s
+}
+
+// Admin is the stack for operational endpoints: tracing, recovery and the
+// audit trail
+func Admin(cfg PresetConfig) *Stack {
+	return NewStack("admin").
+		Use(LayerTracing, cfg.tracing()).
+		Use(LayerRecovery, Recovery(cfg.Logger, cfg.Metrics)).
+		Use(LayerAudit, Audit(cfg.Logger, auditStore(cfg.AuditStore)))
+}
+
+func auditStore(store func() database.Store) func() database.Store {
+	if store == nil {
+		return func() database.Store { return nil }
+	}
+	return store
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+// DefaultTenantHeader is the header Loki and Mimir read the tenant from
+const DefaultTenantHeader = "X-Scope-OrgID"
+
+// Tenancy creates a middleware that stores the tenant named by header in
+// the request context, so log lines carry tenant_id and the Loki sink pushes
+// them to that tenant. The header comes from the client, so only the
+// configured tenants are taken from it; any other value, and IDs Loki would
+// reject, are ignored and the request has no tenant.
+func Tenancy(header string, tenants []string) func(http.Handler) http.Handler {
+	if header == "" {
+		header = DefaultTenantHeader
+	}
+	allowed := make(map[string]bool, len(tenants))
+	for _, tenant := range tenants {
+		if validTenantID(tenant) {
+			allowed[tenant] = true
+		}
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if tenant := r.Header.Get(header); allowed[tenant] {
+				r = r.WithContext(logger.WithTenantID(r.Context(), tenant))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// validTenantID applies Loki's tenant ID rules: at most 150 characters from
+// [a-zA-Z0-9!-_.*'()], and not "." or ".."
+func validTenantID(id string) bool {
+	if id == "" || len(id) > 150 || id == "." || id == ".." {
+		return false
+	}
+	for _, r := range id {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case r == '!', r == '-', r == '_', r == '.', r == '*', r == '\'', r == '(', r == ')':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,39 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+func TestTenancy(t *testing.T) {
+	cases := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{"configured tenant", "acme", "acme"},
+		{"unknown tenant", "globex", ""},
+		{"no header", "", ""},
+		{"configured id Loki would reject", "bad/id", ""},
+	}
+	mw := Tenancy(DefaultTenantHeader, []string{"acme", "bad/id"})
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var got string
+			h := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = logger.GetTenantID(r.Context())
+			}))
+			r := httptest.NewRequest("GET", "/api/hello", nil)
+			if c.header != "" {
+				r.Header.Set(DefaultTenantHeader, c.header)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+			if got != c.want {
+				t.Errorf("tenant %q, want %q", got, c.want)
+			}
+		})
+	}
+}
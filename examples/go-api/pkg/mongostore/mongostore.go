@@ -0,0 +1,267 @@
+//go:build mongo
+
+// Package mongostore keeps quotes and cached weather in MongoDB, for teams
+// whose stack is Mongo rather than Postgres. Commands are traced with
+// otelmongo. It needs the mongo build tag and the MongoDB driver:
+//
+//	go get go.mongodb.org/mongo-driver/mongo \
+//		go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo
+//	go build -tags mongo ./...
+package mongostore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+
+	"github.com/example/go-api/pkg/database"
+	"github.com/example/go-api/pkg/secrets"
+)
+
+// Config holds MongoDB configuration
+type Config struct {
+	URI      *secrets.Value // mongodb:// connection string, may carry credentials
+	Database string         // Default "goapi"
+
+	// WeatherTTL matches the expiry of the SQL weather cache
+	WeatherTTL database.WeatherCacheTTL
+}
+
+// Store implements database.DocumentStore on MongoDB
+type Store struct {
+	client     *mongo.Client
+	quotes     *mongo.Collection
+	weather    *mongo.Collection
+	weatherTTL database.WeatherCacheTTL
+}
+
+// Ensure *Store satisfies database.DocumentStore
+var _ database.DocumentStore = (*Store)(nil)
+
+type quoteDoc struct {
+	Content   string    `bson:"content"`
+	Author    string    `bson:"author"`
+	FetchedAt time.Time `bson:"fetched_at"`
+	Source    string    `bson:"source"`
+	CreatedBy string    `bson:"created_by,omitempty"`
+}
+
+type weatherDoc struct {
+	Location  string    `bson:"_id"`
+	Data      []byte    `bson:"data"`
+	CachedAt  time.Time `bson:"cached_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+// New connects to MongoDB with otelmongo instrumentation and creates the
+// indexes the store relies on
+func New(ctx context.Context, cfg Config) (*Store, error) {
+	if cfg.Database == "" {
+		cfg.Database = "goapi"
+	}
+
+	client, err := mongo.Connect(ctx, options.Client().
+		ApplyURI(cfg.URI.Reveal()).
+		SetMonitor(otelmongo.NewMonitor()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to mongodb: %w", err)
+	}
+
+	db := client.Database(cfg.Database)
+	s := &Store{
+		client:     client,
+		quotes:     db.Collection("quotes"),
+		weather:    db.Collection("weather_cache"),
+		weatherTTL: cfg.WeatherTTL,
+	}
+
+	if err := s.PingContext(ctx); err != nil {
+		client.Disconnect(context.WithoutCancel(ctx))
+		return nil, err
+	}
+
+	// Expired weather is removed by MongoDB's TTL monitor; GetWeatherCache
+	// also filters on expires_at since the monitor only runs every minute
+	indexes := []struct {
+		coll  *mongo.Collection
+		model mongo.IndexModel
+	}{
+		{s.quotes, mongo.IndexModel{Keys: bson.D{{Key: "fetched_at", Value: -1}}}},
+		{s.quotes, mongo.IndexModel{Keys: bson.D{{Key: "content", Value: 1}, {Key: "author", Value: 1}}, Options: options.Index().SetUnique(true)}},
+		{s.quotes, mongo.IndexModel{Keys: bson.D{{Key: "content", Value: "text"}, {Key: "author", Value: "text"}}}},
+		{s.weather, mongo.IndexModel{Keys: bson.D{{Key: "expires_at", Value: 1}}, Options: options.Index().SetExpireAfterSeconds(0)}},
+	}
+	for _, idx := range indexes {
+		if _, err := idx.coll.Indexes().CreateOne(ctx, idx.model); err != nil {
+			client.Disconnect(context.WithoutCancel(ctx))
+			return nil, fmt.Errorf("failed to create index on %s: %w", idx.coll.Name(), err)
+		}
+	}
+	return s, nil
+}
+
+// SaveQuote stores a quote unless one with the same content and author is
+// already stored, and reports whether it was new. The quote is stamped with
+// the actor of ctx.
+func (s *Store) SaveQuote(ctx context.Context, content, author, source string) (bool, error) {
+	res, err := s.quotes.UpdateOne(ctx,
+		bson.D{{Key: "content", Value: content}, {Key: "author", Value: author}},
+		bson.D{{Key: "$setOnInsert", Value: quoteDoc{
+			Content:   content,
+			Author:    author,
+			FetchedAt: time.Now().UTC(),
+			Source:    source,
+			CreatedBy: database.ActorFromContext(ctx),
+		}}},
+		options.Update().SetUpsert(true))
+	if mongo.IsDuplicateKeyError(err) {
+		// Saved by a concurrent request between the match and the insert
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to save quote: %w", err)
+	}
+	return res.UpsertedCount > 0, nil
+}
+
+// DeleteQuote is not supported: quotes in MongoDB have no numeric ID
+func (s *Store) DeleteQuote(ctx context.Context, id int) (bool, error) {
+	return false, fmt.Errorf("quotes in mongodb are not deleted by id: %w", errors.ErrUnsupported)
+}
+
+// GetQuotes retrieves recent quotes. Quote.ID is zero, as documents are
+// keyed by ObjectID.
+func (s *Store) GetQuotes(ctx context.Context, limit int) ([]database.Quote, error) {
+	cur, err := s.quotes.Find(ctx, bson.D{}, options.Find().
+		SetSort(bson.D{{Key: "fetched_at", Value: -1}}).
+		SetLimit(int64(limit)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quotes: %w", err)
+	}
+
+	var docs []quoteDoc
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode quotes: %w", err)
+	}
+
+	quotes := make([]database.Quote, 0, len(docs))
+	for _, d := range docs {
+		quotes = append(quotes, database.Quote{Content: d.Content, Author: d.Author, FetchedAt: d.FetchedAt, Source: d.Source, CreatedBy: d.CreatedBy})
+	}
+	return quotes, nil
+}
+
+// GetQuoteStats counts stored quotes per author and source, most quoted
+// first
+func (s *Store) GetQuoteStats(ctx context.Context) ([]database.QuoteStats, error) {
+	cur, err := s.quotes.Aggregate(ctx, mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "author", Value: "$author"}, {Key: "source", Value: "$source"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "count", Value: -1}, {Key: "_id.author", Value: 1}}}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query quote stats: %w", err)
+	}
+
+	var rows []struct {
+		ID struct {
+			Author string `bson:"author"`
+			Source string `bson:"source"`
+		} `bson:"_id"`
+		Count int64 `bson:"count"`
+	}
+	if err := cur.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("failed to decode quote stats: %w", err)
+	}
+
+	stats := make([]database.QuoteStats, 0, len(rows))
+	for _, r := range rows {
+		stats = append(stats, database.QuoteStats{Author: r.ID.Author, Source: r.ID.Source, Count: r.Count})
+	}
+	return stats, nil
+}
+
+// SearchQuotes finds up to limit quotes whose content or author matches
+// query through the text index, ranked by text score
+func (s *Store) SearchQuotes(ctx context.Context, query string, limit int) ([]database.QuoteMatch, error) {
+	score := bson.D{{Key: "score", Value: bson.D{{Key: "$meta", Value: "textScore"}}}}
+	cur, err := s.quotes.Find(ctx,
+		bson.D{{Key: "$text", Value: bson.D{{Key: "$search", Value: query}}}},
+		options.Find().SetProjection(score).SetSort(score).SetLimit(int64(limit)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to search quotes: %w", err)
+	}
+
+	var docs []struct {
+		Content   string    `bson:"content"`
+		Author    string    `bson:"author"`
+		FetchedAt time.Time `bson:"fetched_at"`
+		Source    string    `bson:"source"`
+		Score     float64   `bson:"score"`
+	}
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("failed to decode quotes: %w", err)
+	}
+
+	matches := make([]database.QuoteMatch, 0, len(docs))
+	for _, d := range docs {
+		matches = append(matches, database.QuoteMatch{
+			Quote: database.Quote{Content: d.Content, Author: d.Author, FetchedAt: d.FetchedAt, Source: d.Source},
+			Rank:  d.Score,
+		})
+	}
+	return matches, nil
+}
+
+// SaveWeatherCache caches weather data for a location
+func (s *Store) SaveWeatherCache(ctx context.Context, location string, data []byte) error {
+	now := time.Now().UTC()
+	_, err := s.weather.ReplaceOne(ctx,
+		bson.D{{Key: "_id", Value: location}},
+		weatherDoc{Location: location, Data: data, CachedAt: now, ExpiresAt: now.Add(s.weatherTTL.For(location))},
+		options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("failed to save weather cache: %w", err)
+	}
+	return nil
+}
+
+// GetWeatherCache retrieves cached weather data if not expired, or nil
+func (s *Store) GetWeatherCache(ctx context.Context, location string) (*database.WeatherCache, error) {
+	var d weatherDoc
+	err := s.weather.FindOne(ctx, bson.D{
+		{Key: "_id", Value: location},
+		{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: time.Now().UTC()}}},
+	}).Decode(&d)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to query weather cache: %w", err)
+	}
+	return &database.WeatherCache{Location: d.Location, Data: d.Data, CachedAt: d.CachedAt, ExpiresAt: d.ExpiresAt}, nil
+}
+
+// PingContext checks the primary is reachable
+func (s *Store) PingContext(ctx context.Context) error {
+	if err := s.client.Ping(ctx, readpref.Primary()); err != nil {
+		return fmt.Errorf("failed to ping mongodb: %w", err)
+	}
+	return nil
+}
+
+// Close disconnects from MongoDB
+func (s *Store) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.client.Disconnect(ctx)
+}
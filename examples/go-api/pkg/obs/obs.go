@@ -0,0 +1,110 @@
+// Package obs wraps HTTP handlers with the tracing, logging, timing and
+// error handling every handler otherwise repeats
+package obs
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+
+	apperrors "github.com/example/go-api/pkg/errors"
+	"github.com/example/go-api/pkg/httperr"
+	"github.com/example/go-api/pkg/logger"
+)
+
+// tracerName is the instrumentation scope of handler spans
+const tracerName = "github.com/example/go-api/pkg/obs"
+
+var handlerDuration = prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "handler_duration_seconds",
+		Help:    "Handler execution time by handler name and outcome (ok, client_error, error)",
+		Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	},
+	[]string{"handler", "outcome"},
+)
+
+func init() {
+	prometheus.MustRegister(handlerDuration)
+}
+
+// HandlerFunc is an HTTP handler that returns its error instead of writing
+// an error response itself
+type HandlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+// Error is an error with the HTTP status and client-facing message to
+// answer with. Errors of other types are answered with 500 and their text.
+type Error struct {
+	Status  int
+	Message string
+	Err     error       // Optional cause, logged but not sent to the client
+	Errors  interface{} // Optional field errors sent as the envelope's errors
+	Details interface{} // Optional detail sent as the envelope's details
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return e.Message + ": " + e.Err.Error()
+	}
+	return e.Message
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// NewError returns an *Error answering with status and msg
+func NewError(status int, msg string, cause error) *Error {
+	return &Error{Status: status, Message: msg, Err: cause}
+}
+
+// Handler adapts fn to http.Handler. Each call runs in a span named name,
+// with a logger named name in its context (see Logger), and is timed in
+// handler_duration_seconds. A returned error is written as the standard
+// error envelope; server errors are also recorded on the span and logged
+// once via errors.WrapAndRecord.
+func Handler(name string, fn HandlerFunc) http.Handler {
+	tracer := otel.Tracer(tracerName)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx, span := tracer.Start(r.Context(), name)
+		defer span.End()
+
+		if l := logger.FromContext(ctx); l != nil {
+			ctx = logger.NewContext(ctx, l.Named(name))
+		}
+		r = r.WithContext(ctx)
+
+		err := fn(w, r)
+
+		outcome := "ok"
+		if err != nil {
+			status, env := http.StatusInternalServerError, httperr.Envelope{Error: err.Error()}
+			var herr *Error
+			if errors.As(err, &herr) {
+				status, env = herr.Status, httperr.Envelope{Error: herr.Message, Errors: herr.Errors, Details: herr.Details}
+			}
+			span.SetAttributes(attribute.Int("handler.status_code", status))
+
+			if status >= http.StatusInternalServerError {
+				outcome = "error"
+				apperrors.WrapAndRecord(ctx, err, name+" failed")
+			} else {
+				outcome = "client_error"
+			}
+			httperr.WriteEnvelope(w, r, status, env)
+		}
+		handlerDuration.WithLabelValues(name, outcome).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Logger returns the request's logger with trace fields, named after the
+// handler when called under Handler. It falls back to the global logger.
+func Logger(ctx context.Context) *zerolog.Logger {
+	return logger.Ctx(ctx)
+}
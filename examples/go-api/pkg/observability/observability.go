@@ -0,0 +1,153 @@
+// Package observability wires the logger, tracer, metrics and HTTP
+// middleware of a service together in one call, so a new service gets the
+// same conventions as this one without re-assembling main.go:
+//
+//	o, err := observability.Init(ctx, observability.Config{
+//		ServiceName:    "billing",
+//		ServiceVersion: version,
+//		OTLPEndpoint:   "tempo:4317",
+//		TracingEnabled: true,
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer o.Shutdown(context.Background())
+//
+//	r := mux.NewRouter()
+//	r.Use(o.Middleware)
+//	r.Handle("/invoices", obs.Handler("list_invoices", invoices.Serve))
+package observability
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/middleware"
+	"github.com/example/go-api/pkg/tracing"
+)
+
+// Config holds observability configuration. Only ServiceName is required.
+type Config struct {
+	ServiceName    string
+	ServiceVersion string
+	Environment    string
+
+	LogLevel  string // "debug", "info" (default), "warn" or "error"
+	LogPretty bool   // Deprecated: use LogFormat "console"
+	LogFormat string // "json" (default), "logfmt" or "console"
+
+	TracingEnabled bool
+	OTLPEndpoint   string // e.g., "tempo:4317"
+	SpanMetrics    bool   // Derive RED metrics from server spans in-process
+
+	MetricsNamespace string                // Prefix for the HTTP metrics, empty for none
+	MetricsRegistry  prometheus.Registerer // Where the HTTP metrics are registered (default prometheus.DefaultRegisterer)
+	ExcludePaths     []string              // Kept out of traces, logs and metrics (default middleware.DefaultExcludedPaths)
+	ForwardHeaders   []string              // Correlation headers forwarded by outbound clients (default X-Request-ID)
+
+	// Attributes describing this deployment, e.g. region or build commit.
+	// They are added to the trace resource and to every log line.
+	Attributes map[string]string
+
+	LoggerOptions  []logger.Option  // Applied after the settings above
+	TracingOptions []tracing.Option // Applied after the settings above
+}
+
+// Observability holds the pre-wired components of a service
+type Observability struct {
+	Logger  *logger.Logger
+	Tracer  trace.Tracer
+	Metrics *middleware.Metrics // HTTP request metrics, registered with Prometheus
+
+	// Middleware is the standard stack: tracing, panic recovery,
+	// correlation headers, request logging and metrics, in that order
+	Middleware func(http.Handler) http.Handler
+
+	provider *tracing.Provider
+}
+
+// Init creates the logger, tracer provider, HTTP metrics and middleware
+// stack. The metrics are registered with the default Prometheus registry,
+// so call it once per process.
+func Init(ctx context.Context, cfg Config) (*Observability, error) {
+	if cfg.ServiceName == "" {
+		return nil, fmt.Errorf("observability: ServiceName is required")
+	}
+	if cfg.ExcludePaths == nil {
+		cfg.ExcludePaths = middleware.DefaultExcludedPaths
+	}
+	if cfg.ForwardHeaders == nil {
+		cfg.ForwardHeaders = []string{"X-Request-ID"}
+	}
+
+	logOpts := cfg.LoggerOptions
+	resourceAttrs := make([]attribute.KeyValue, 0, len(cfg.Attributes))
+	if len(cfg.Attributes) > 0 {
+		logOpts = append([]logger.Option{logger.WithHooks(logger.StaticFields(cfg.Attributes))}, logOpts...)
+		for k, v := range cfg.Attributes {
+			resourceAttrs = append(resourceAttrs, attribute.String(k, v))
+		}
+	}
+	log := logger.New(logger.Config{
+		AppName: cfg.ServiceName,
+		Version: cfg.ServiceVersion,
+		Level:   cfg.LogLevel,
+		Pretty:  cfg.LogPretty,
+		Format:  cfg.LogFormat,
+	}, logOpts...)
+
+	provider, err := tracing.InitTracer(ctx, tracing.Config{
+		ServiceName:        cfg.ServiceName,
+		ServiceVersion:     cfg.ServiceVersion,
+		Environment:        cfg.Environment,
+		OTLPEndpoint:       cfg.OTLPEndpoint,
+		Enabled:            cfg.TracingEnabled,
+		Logger:             log,
+		SpanMetrics:        cfg.SpanMetrics,
+		Sampling:           tracing.SamplingConfig{DefaultRatio: 1},
+		ResourceAttributes: resourceAttrs,
+	}, cfg.TracingOptions...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize tracer: %w", err)
+	}
+
+	var metricsOpts []middleware.MetricsOption
+	if cfg.MetricsRegistry != nil {
+		metricsOpts = append(metricsOpts, middleware.WithRegisterer(cfg.MetricsRegistry))
+	}
+	metrics := middleware.NewMetrics(cfg.MetricsNamespace, metricsOpts...)
+	stack := middleware.Public(middleware.PresetConfig{
+		ServiceName:    cfg.ServiceName,
+		Logger:         log,
+		Metrics:        metrics,
+		Exclude:        middleware.NewPathFilter(cfg.ExcludePaths...),
+		ForwardHeaders: cfg.ForwardHeaders,
+		AnyRouter:      true,
+	})
+	if err := stack.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &Observability{
+		Logger:     log,
+		Tracer:     provider.Tracer(),
+		Metrics:    metrics,
+		Middleware: stack.Then,
+		provider:   provider,
+	}, nil
+}
+
+// Shutdown flushes buffered spans. Call it after the HTTP server has
+// stopped, so spans of the last requests are exported.
+func (o *Observability) Shutdown(ctx context.Context) error {
+	if err := o.provider.Shutdown(ctx); err != nil {
+		return fmt.Errorf("failed to shut down tracer: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+//go:build redis
+
+// Package redis wraps go-redis with the telemetry conventions of this
+// service: a client span per command or pipeline, pool metrics, slow command
+// logs and a health probe. It needs the redis build tag and go-redis v9:
+//
+//	go get github.com/redis/go-redis/v9
+//	go build -tags redis ./...
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	goredis "github.com/redis/go-redis/v9"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/secrets"
+	"github.com/example/go-api/pkg/startup"
+	"github.com/example/go-api/pkg/tracing"
+)
+
+// tracerName is the instrumentation scope of Redis spans
+const tracerName = "github.com/example/go-api/pkg/redis"
+
+var (
+	commandDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "redis_command_duration_seconds",
+			Help:    "Redis command duration in seconds by command and outcome (ok, nil, error)",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		},
+		[]string{"command", "outcome"},
+	)
+	poolConnections = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "redis_pool_connections",
+			Help: "Redis pool connections by state (total, idle, stale)",
+		},
+		[]string{"state"},
+	)
+	poolEvents = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "redis_pool_events_total",
+			Help: "Redis pool connection requests by result (hit, miss, timeout)",
+		},
+		[]string{"result"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(commandDuration)
+	prometheus.MustRegister(poolConnections)
+	prometheus.MustRegister(poolEvents)
+}
+
+// Config holds Redis configuration
+type Config struct {
+	Addr               string // host:port
+	Password           *secrets.Value
+	DB                 int
+	PoolSize           int            // Connections per CPU are used when zero (go-redis default)
+	SlowThreshold      time.Duration  // Commands slower than this are logged (default 100ms, negative disables)
+	MaxStatementLength int            // db.statement span attributes are truncated to this many bytes (default 256)
+	PoolInterval       time.Duration  // How often pool stats are sampled by MonitorPool (default 10s)
+	Logger             *logger.Logger // Optional logger for slow commands
+}
+
+// Client wraps the go-redis client with tracing, metrics and slow command
+// logging
+type Client struct {
+	*goredis.Client
+	cfg Config
+}
+
+// New creates a Redis client. It does not connect; call Ping or add Probe
+// to the startup waiter to wait for the server.
+func New(cfg Config) *Client {
+	if cfg.SlowThreshold == 0 {
+		cfg.SlowThreshold = 100 * time.Millisecond
+	}
+	if cfg.MaxStatementLength <= 0 {
+		cfg.MaxStatementLength = 256
+	}
+	if cfg.PoolInterval <= 0 {
+		cfg.PoolInterval = 10 * time.Second
+	}
+
+	rdb := goredis.NewClient(&goredis.Options{
+		Addr: cfg.Addr,
+		// Read per connection, so rotations apply to new connections
+		CredentialsProvider: func() (string, string) {
+			return "", cfg.Password.Reveal()
+		},
+		DB:       cfg.DB,
+		PoolSize: cfg.PoolSize,
+	})
+	rdb.AddHook(newHook(cfg))
+	return &Client{Client: rdb, cfg: cfg}
+}
+
+// Ping checks the server is reachable
+func (c *Client) Ping(ctx context.Context) error {
+	if err := c.Client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("failed to ping redis: %w", err)
+	}
+	return nil
+}
+
+// Probe returns a startup and upstream probe for the server
+func (c *Client) Probe() startup.Probe {
+	return c.Ping
+}
+
+// MonitorPool samples pool statistics until ctx is cancelled
+func (c *Client) MonitorPool(ctx context.Context) {
+	ticker := time.NewTicker(c.cfg.PoolInterval)
+	defer ticker.Stop()
+
+	// PoolStats counters are cumulative, so feed only the delta into the
+	// Prometheus counters
+	var prev goredis.PoolStats
+	for {
+		stats := *c.PoolStats()
+		poolConnections.WithLabelValues("total").Set(float64(stats.TotalConns))
+		poolConnections.WithLabelValues("idle").Set(float64(stats.IdleConns))
+		poolConnections.WithLabelValues("stale").Set(float64(stats.StaleConns))
+		poolEvents.WithLabelValues("hit").Add(float64(stats.Hits - prev.Hits))
+		poolEvents.WithLabelValues("miss").Add(float64(stats.Misses - prev.Misses))
+		poolEvents.WithLabelValues("timeout").Add(float64(stats.Timeouts - prev.Timeouts))
+		prev = stats
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// hook creates a client span per command or pipeline, records the command
+// duration and logs slow commands
+type hook struct {
+	cfg    Config
+	tracer trace.Tracer
+	attrs  []attribute.KeyValue
+}
+
+func newHook(cfg Config) *hook {
+	attrs := []attribute.KeyValue{semconv.DBSystemRedis, semconv.DBRedisDBIndex(cfg.DB)}
+	if host, port, err := net.SplitHostPort(cfg.Addr); err == nil {
+		attrs = append(attrs, semconv.ServerAddress(host))
+		if p, err := strconv.Atoi(port); err == nil {
+			attrs = append(attrs, semconv.ServerPort(p))
+		}
+	}
+	return &hook{cfg: cfg, tracer: otel.Tracer(tracerName), attrs: attrs}
+}
+
+func (h *hook) DialHook(next goredis.DialHook) goredis.DialHook {
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		ctx, span := h.tracer.Start(ctx, "redis.dial",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(h.attrs...))
+		defer span.End()
+
+		conn, err := next(ctx, network, addr)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		return conn, err
+	}
+}
+
+func (h *hook) ProcessHook(next goredis.ProcessHook) goredis.ProcessHook {
+	return func(ctx context.Context, cmd goredis.Cmder) error {
+		name := strings.ToUpper(cmd.Name())
+		ctx, span := h.tracer.Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(h.attrs...),
+			trace.WithAttributes(
+				semconv.DBOperation(name),
+				semconv.DBStatement(tracing.Truncate(statement(cmd), h.cfg.MaxStatementLength)),
+			))
+		defer span.End()
+
+		start := time.Now()
+		err := next(ctx, cmd)
+		h.finish(ctx, span, name, time.Since(start), err)
+		return err
+	}
+}
+
+func (h *hook) ProcessPipelineHook(next goredis.ProcessPipelineHook) goredis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []goredis.Cmder) error {
+		names := make([]string, 0, len(cmds))
+		for _, cmd := range cmds {
+			names = append(names, strings.ToUpper(cmd.Name()))
+		}
+		ctx, span := h.tracer.Start(ctx, "PIPELINE",
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(h.attrs...),
+			trace.WithAttributes(
+				semconv.DBOperation("PIPELINE"),
+				attribute.Int("db.redis.pipeline_length", len(cmds)),
+				semconv.DBStatement(tracing.Truncate(strings.Join(names, " "), h.cfg.MaxStatementLength)),
+			))
+		defer span.End()
+
+		start := time.Now()
+		err := next(ctx, cmds)
+		h.finish(ctx, span, "PIPELINE", time.Since(start), err)
+		return err
+	}
+}
+
+func (h *hook) finish(ctx context.Context, span trace.Span, name string, elapsed time.Duration, err error) {
+	outcome := "ok"
+	switch {
+	case errors.Is(err, goredis.Nil):
+		// A missing key is a cache miss, not a failure
+		outcome = "nil"
+	case err != nil:
+		outcome = "error"
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	commandDuration.WithLabelValues(name, outcome).Observe(elapsed.Seconds())
+
+	if h.cfg.Logger == nil || h.cfg.SlowThreshold < 0 || elapsed < h.cfg.SlowThreshold {
+		return
+	}
+	slowLog := h.cfg.Logger.WithFields(ctx, map[string]interface{}{
+		"command":      name,
+		"duration_ms":  elapsed.Milliseconds(),
+		"threshold_ms": h.cfg.SlowThreshold.Milliseconds(),
+		"outcome":      outcome,
+	})
+	slowLog.Warn().Msg("Slow Redis command")
+}
+
+// statement returns the command name and key; values are left out, as they
+// may hold session data or tokens
+func statement(cmd goredis.Cmder) string {
+	args := cmd.Args()
+	if len(args) < 2 {
+		return strings.ToUpper(cmd.Name())
+	}
+	return fmt.Sprintf("%s %v", strings.ToUpper(cmd.Name()), args[1])
+}
@@ -0,0 +1,90 @@
+//go:build redis
+
+package redis
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	goredis "github.com/redis/go-redis/v9"
+)
+
+// Cache stores byte values under a key prefix, for caches shared by all
+// replicas
+type Cache struct {
+	client *Client
+	prefix string
+}
+
+// NewCache creates a cache whose keys are prefixed with prefix, e.g. "weather:"
+func NewCache(client *Client, prefix string) *Cache {
+	return &Cache{client: client, prefix: prefix}
+}
+
+// Get returns the value for key, and false when it is not cached
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	val, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if errors.Is(err, goredis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get cached %s: %w", key, err)
+	}
+	return val, true, nil
+}
+
+// Set caches val for ttl
+func (c *Cache) Set(ctx context.Context, key string, val []byte, ttl time.Duration) error {
+	if err := c.client.Set(ctx, c.prefix+key, val, ttl).Err(); err != nil {
+		return fmt.Errorf("failed to cache %s: %w", key, err)
+	}
+	return nil
+}
+
+// Incr increments the counter at key and returns its new value. The
+// counter does not expire.
+func (c *Cache) Incr(ctx context.Context, key string) (int64, error) {
+	n, err := c.client.Incr(ctx, c.prefix+key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment %s: %w", key, err)
+	}
+	return n, nil
+}
+
+// RateLimiter counts requests per key in fixed windows shared by all
+// replicas
+type RateLimiter struct {
+	client *Client
+	prefix string
+	limit  int64
+	window time.Duration
+}
+
+// NewRateLimiter allows limit requests per key per window
+func NewRateLimiter(client *Client, prefix string, limit int64, window time.Duration) *RateLimiter {
+	return &RateLimiter{client: client, prefix: prefix, limit: limit, window: window}
+}
+
+// Allow counts a request for key and reports whether it is within the limit.
+// When it is not, retryAfter is the time left in the current window.
+func (l *RateLimiter) Allow(ctx context.Context, key string) (allowed bool, retryAfter time.Duration, err error) {
+	windowKey := fmt.Sprintf("%s%s:%d", l.prefix, key, time.Now().UnixNano()/int64(l.window))
+
+	var count *goredis.IntCmd
+	var ttl *goredis.DurationCmd
+	_, err = l.client.TxPipelined(ctx, func(pipe goredis.Pipeliner) error {
+		count = pipe.Incr(ctx, windowKey)
+		pipe.ExpireNX(ctx, windowKey, l.window)
+		ttl = pipe.PTTL(ctx, windowKey)
+		return nil
+	})
+	if err != nil {
+		return false, 0, fmt.Errorf("failed to count request for %s: %w", key, err)
+	}
+	if count.Val() <= l.limit {
+		return true, 0, nil
+	}
+	return false, ttl.Val(), nil
+}
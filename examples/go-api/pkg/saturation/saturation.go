@@ -0,0 +1,207 @@
+// Package saturation decides whether a replica is too busy to take new
+// traffic, from internal queue and pool signals, so readiness can fail
+// before requests start timing out
+package saturation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/rs/zerolog"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+var (
+	signalRatio = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "saturation_signal_ratio",
+			Help: "Latest value of each saturation signal; 1 means fully saturated",
+		},
+		[]string{"signal"},
+	)
+	saturated = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "saturation_ready_failing",
+			Help: "Whether readiness is failing because the replica is saturated (1) or not (0)",
+		},
+	)
+	transitions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "saturation_state_changes_total",
+			Help: "Saturation state changes by new state (saturated, recovered)",
+		},
+		[]string{"state"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(signalRatio)
+	prometheus.MustRegister(saturated)
+	prometheus.MustRegister(transitions)
+}
+
+// Signal reports how close one resource is to saturation, where 1 is full.
+// Values above 1 are allowed, e.g. for wait time per second.
+type Signal func() float64
+
+// Rate turns a cumulative total, such as sql.DBStats.WaitDuration in
+// seconds, into its increase per second since the previous call
+func Rate(total func() float64) Signal {
+	var mu sync.Mutex
+	prev, prevAt := total(), time.Now()
+	return func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		cur, now := total(), time.Now()
+		elapsed := now.Sub(prevAt).Seconds()
+		delta := cur - prev
+		prev, prevAt = cur, now
+		if elapsed <= 0 || delta < 0 {
+			return 0
+		}
+		return delta / elapsed
+	}
+}
+
+// Config tunes saturation detection. High and Low form a hysteresis band:
+// the replica turns saturated once any signal reaches High, and recovers
+// only after every signal is back under Low.
+type Config struct {
+	Interval time.Duration // How often signals are sampled (default 1s)
+	High     float64       // Signal value that marks the replica saturated (default 0.9)
+	Low      float64       // Every signal must drop below this to recover (default 0.7)
+	MinHold  time.Duration // Minimum time spent in a state before leaving it (default 10s)
+}
+
+// Monitor samples signals and tracks whether the replica is saturated
+type Monitor struct {
+	cfg Config
+	log *logger.Logger
+
+	mu      sync.RWMutex
+	signals map[string]Signal
+	active  bool
+	reason  string
+	since   time.Time
+}
+
+// New creates a new Monitor
+func New(cfg Config, log *logger.Logger) *Monitor {
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Second
+	}
+	if cfg.High <= 0 {
+		cfg.High = 0.9
+	}
+	if cfg.Low <= 0 {
+		cfg.Low = 0.7
+	}
+	if cfg.Low > cfg.High {
+		cfg.Low = cfg.High
+	}
+	if cfg.MinHold <= 0 {
+		cfg.MinHold = 10 * time.Second
+	}
+	return &Monitor{cfg: cfg, log: log, signals: make(map[string]Signal), since: time.Now()}
+}
+
+// Add registers a named signal
+func (m *Monitor) Add(name string, s Signal) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.signals[name] = s
+}
+
+// Saturated reports whether the replica is saturated and, if so, which
+// signal tipped it over
+func (m *Monitor) Saturated() (bool, string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.active, m.reason
+}
+
+// Run samples the signals every interval until ctx is cancelled
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.cfg.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			m.sample(ctx)
+		}
+	}
+}
+
+func (m *Monitor) sample(ctx context.Context) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	names := make([]string, 0, len(m.signals))
+	for name := range m.signals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make(map[string]float64, len(names))
+	var worst string
+	for _, name := range names {
+		v := m.signals[name]()
+		values[name] = v
+		signalRatio.WithLabelValues(name).Set(v)
+		if worst == "" || v > values[worst] {
+			worst = name
+		}
+	}
+	if worst == "" {
+		return
+	}
+
+	now := time.Now()
+	if now.Sub(m.since) < m.cfg.MinHold {
+		return
+	}
+
+	peak := values[worst]
+	switch {
+	case !m.active && peak >= m.cfg.High:
+		m.active = true
+		m.reason = fmt.Sprintf("%s saturated", worst)
+		m.transition(ctx, now, zerolog.WarnLevel, "Replica saturated, failing readiness", worst, values)
+	case m.active && peak < m.cfg.Low:
+		m.active = false
+		m.reason = ""
+		m.transition(ctx, now, zerolog.InfoLevel, "Replica recovered from saturation, readiness restored", worst, values)
+	}
+}
+
+func (m *Monitor) transition(ctx context.Context, now time.Time, level zerolog.Level, msg, worst string, values map[string]float64) {
+	state := "recovered"
+	if m.active {
+		state = "saturated"
+		saturated.Set(1)
+	} else {
+		saturated.Set(0)
+	}
+	transitions.WithLabelValues(state).Inc()
+
+	if m.log != nil {
+		stateLog := m.log.WithFields(ctx, map[string]interface{}{
+			"event":    "saturation_state_change",
+			"state":    state,
+			"signal":   worst,
+			"signals":  values,
+			"high":     m.cfg.High,
+			"low":      m.cfg.Low,
+			"held_for": now.Sub(m.since).Round(time.Second).String(),
+		})
+		stateLog.WithLevel(level).Msg(msg)
+	}
+	m.since = now
+}
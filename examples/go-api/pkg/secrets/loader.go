@@ -0,0 +1,148 @@
+package secrets
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+var refreshFailures = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Name: "secret_refresh_failures_total",
+		Help: "Failed attempts to re-read a secret from its file or Vault",
+	},
+	[]string{"secret"},
+)
+
+func init() {
+	prometheus.MustRegister(refreshFailures)
+}
+
+// Loader resolves secrets by name. For a secret NAME it tries, in order:
+//
+//   - NAME_FILE: path to a file holding the secret, e.g. a mounted
+//     Kubernetes Secret
+//   - NAME_VAULT: "path#key" of a Vault KV v2 secret (needs a Vault client)
+//   - NAME: the raw environment variable
+//
+// File and Vault secrets are re-read by Run, so rotations reach new
+// connections without a restart.
+type Loader struct {
+	vault *VaultClient   // Optional
+	log   *logger.Logger // Optional
+
+	mu      sync.Mutex
+	sources []source
+}
+
+// source remembers where a rotatable secret came from
+type source struct {
+	name  string
+	value *Value
+	read  func(ctx context.Context) (string, error)
+}
+
+// NewLoader creates a Loader. vault may be nil when Vault is not used.
+func NewLoader(vault *VaultClient, log *logger.Logger) *Loader {
+	return &Loader{vault: vault, log: log}
+}
+
+// Load resolves the secret called name. A secret that is not configured
+// anywhere is returned empty; a configured source that cannot be read is
+// an error.
+func (l *Loader) Load(ctx context.Context, name string) (*Value, error) {
+	var read func(ctx context.Context) (string, error)
+
+	if path := os.Getenv(name + "_FILE"); path != "" {
+		read = func(context.Context) (string, error) { return readFile(path) }
+	} else if ref := os.Getenv(name + "_VAULT"); ref != "" {
+		if l.vault == nil {
+			return nil, fmt.Errorf("%s_VAULT is set but Vault is not configured", name)
+		}
+		path, key, ok := strings.Cut(ref, "#")
+		if !ok {
+			return nil, fmt.Errorf("%s_VAULT must be path#key", name)
+		}
+		read = func(ctx context.Context) (string, error) { return l.vault.Read(ctx, path, key) }
+	} else {
+		return NewValue(os.Getenv(name)), nil
+	}
+
+	s, err := read(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load secret %s: %w", name, err)
+	}
+	v := NewValue(s)
+
+	l.mu.Lock()
+	l.sources = append(l.sources, source{name: name, value: v, read: read})
+	l.mu.Unlock()
+	return v, nil
+}
+
+// Run renews the Vault token and re-reads file and Vault secrets every
+// interval until ctx is cancelled. A non-positive interval disables it.
+func (l *Loader) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if l.vault != nil {
+			if err := l.vault.Renew(ctx); err != nil && l.log != nil {
+				renewLog := l.log.WithContext(ctx)
+				renewLog.Warn().Err(err).Msg("Failed to renew Vault token")
+			}
+		}
+		l.refresh(ctx)
+	}
+}
+
+func (l *Loader) refresh(ctx context.Context) {
+	l.mu.Lock()
+	sources := append([]source(nil), l.sources...)
+	l.mu.Unlock()
+
+	for _, src := range sources {
+		s, err := src.read(ctx)
+		if err != nil {
+			refreshFailures.WithLabelValues(src.name).Inc()
+			if l.log != nil {
+				refreshLog := l.log.WithFields(ctx, map[string]interface{}{"secret": src.name})
+				refreshLog.Warn().Err(err).Msg("Failed to refresh secret, keeping previous value")
+			}
+			continue
+		}
+		if s != src.value.Reveal() {
+			src.value.Set(s)
+			if l.log != nil {
+				rotateLog := l.log.WithFields(ctx, map[string]interface{}{"secret": src.name})
+				rotateLog.Info().Msg("Secret rotated")
+			}
+		}
+	}
+}
+
+func readFile(path string) (string, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	// Secret files commonly end with a newline that is not part of the value
+	return strings.TrimRight(string(b), "\r\n"), nil
+}
@@ -0,0 +1,62 @@
+package secrets
+
+import (
+	"encoding/json"
+	"sync/atomic"
+)
+
+// Redacted replaces secret values in logs, JSON and diagnostics
+const Redacted = "REDACTED"
+
+// Value holds a secret that can be rotated at runtime. It never prints or
+// marshals its content; callers that need it must call Reveal. A nil *Value
+// is an empty secret.
+type Value struct {
+	v atomic.Pointer[string]
+}
+
+// NewValue creates a Value holding s
+func NewValue(s string) *Value {
+	v := &Value{}
+	v.Set(s)
+	return v
+}
+
+// Reveal returns the current secret
+func (v *Value) Reveal() string {
+	if v == nil {
+		return ""
+	}
+	if s := v.v.Load(); s != nil {
+		return *s
+	}
+	return ""
+}
+
+// Set replaces the secret, e.g. after rotation
+func (v *Value) Set(s string) {
+	v.v.Store(&s)
+}
+
+// IsSet reports whether the secret is non-empty
+func (v *Value) IsSet() bool {
+	return v.Reveal() != ""
+}
+
+// String implements fmt.Stringer without revealing the secret
+func (v *Value) String() string {
+	if !v.IsSet() {
+		return ""
+	}
+	return Redacted
+}
+
+// GoString implements fmt.GoStringer so %#v does not reveal the secret
+func (v *Value) GoString() string {
+	return `secrets.Value("` + v.String() + `")`
+}
+
+// MarshalJSON implements json.Marshaler without revealing the secret
+func (v *Value) MarshalJSON() ([]byte, error) {
+	return json.Marshal(v.String())
+}
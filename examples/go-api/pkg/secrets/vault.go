@@ -0,0 +1,179 @@
+package secrets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultJWTPath is the projected service account token used for Vault's
+// Kubernetes auth method
+const defaultJWTPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// VaultConfig configures access to HashiCorp Vault's KV v2 engine
+type VaultConfig struct {
+	Addr     string        // e.g. "http://vault.vault:8200"
+	Mount    string        // KV v2 mount (default "secret")
+	Token    *Value        // Static token; used when Role is empty
+	Role     string        // Kubernetes auth role; logs in with the pod's service account
+	AuthPath string        // Kubernetes auth mount (default "kubernetes")
+	JWTPath  string        // Service account token (default the in-pod path)
+	Timeout  time.Duration // Per-request timeout (default 10s)
+}
+
+// VaultClient reads KV v2 secrets and keeps its token alive
+type VaultClient struct {
+	cfg  VaultConfig
+	http *http.Client
+
+	mu        sync.Mutex
+	token     string
+	renewable bool
+}
+
+// NewVaultClient creates a Vault client, logging in first when a
+// Kubernetes auth role is configured
+func NewVaultClient(ctx context.Context, cfg VaultConfig) (*VaultClient, error) {
+	if cfg.Mount == "" {
+		cfg.Mount = "secret"
+	}
+	if cfg.AuthPath == "" {
+		cfg.AuthPath = "kubernetes"
+	}
+	if cfg.JWTPath == "" {
+		cfg.JWTPath = defaultJWTPath
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+
+	c := &VaultClient{
+		cfg:   cfg,
+		http:  &http.Client{Timeout: cfg.Timeout},
+		token: cfg.Token.Reveal(),
+	}
+	if cfg.Role != "" {
+		if err := c.login(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if c.token == "" {
+		return nil, fmt.Errorf("vault: no token and no kubernetes auth role configured")
+	}
+	return c, nil
+}
+
+// Read returns one key of the KV v2 secret at path
+func (c *VaultClient) Read(ctx context.Context, path, key string) (string, error) {
+	var resp struct {
+		Data struct {
+			Data map[string]interface{} `json:"data"`
+		} `json:"data"`
+	}
+	url := fmt.Sprintf("/v1/%s/data/%s", c.cfg.Mount, strings.TrimLeft(path, "/"))
+	if err := c.do(ctx, http.MethodGet, url, nil, &resp); err != nil {
+		return "", err
+	}
+	v, ok := resp.Data.Data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("vault: key %q not found in %s", key, path)
+	}
+	return v, nil
+}
+
+// Renew extends the token's lease, logging in again if the token can no
+// longer be renewed. Static tokens that are not renewable are left alone.
+func (c *VaultClient) Renew(ctx context.Context) error {
+	c.mu.Lock()
+	renewable := c.renewable
+	c.mu.Unlock()
+
+	if renewable {
+		var resp authResponse
+		err := c.do(ctx, http.MethodPost, "/v1/auth/token/renew-self", struct{}{}, &resp)
+		if err == nil {
+			c.setToken(resp)
+			return nil
+		}
+		if c.cfg.Role == "" {
+			return fmt.Errorf("failed to renew token: %w", err)
+		}
+	}
+	if c.cfg.Role != "" {
+		return c.login(ctx)
+	}
+	return nil
+}
+
+type authResponse struct {
+	Auth struct {
+		ClientToken   string `json:"client_token"`
+		LeaseDuration int    `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	} `json:"auth"`
+}
+
+func (c *VaultClient) login(ctx context.Context) error {
+	jwt, err := readFile(c.cfg.JWTPath)
+	if err != nil {
+		return fmt.Errorf("failed to read service account token: %w", err)
+	}
+
+	var resp authResponse
+	body := map[string]string{"role": c.cfg.Role, "jwt": jwt}
+	if err := c.do(ctx, http.MethodPost, "/v1/auth/"+c.cfg.AuthPath+"/login", body, &resp); err != nil {
+		return fmt.Errorf("failed to log in to vault: %w", err)
+	}
+	c.setToken(resp)
+	return nil
+}
+
+func (c *VaultClient) setToken(resp authResponse) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if resp.Auth.ClientToken != "" {
+		c.token = resp.Auth.ClientToken
+	}
+	c.renewable = resp.Auth.Renewable
+}
+
+func (c *VaultClient) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader = http.NoBody
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(c.cfg.Addr, "/")+path, reader)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	c.mu.Lock()
+	if c.token != "" {
+		req.Header.Set("X-Vault-Token", c.token)
+	}
+	c.mu.Unlock()
+
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("vault request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("vault returned status %d for %s", resp.StatusCode, path)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode vault response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,315 @@
+// Package seed fills the database with generated users and quotes for demo
+// environments and load tests. The data is derived from a random seed, so
+// the same seed and volumes always give the same dataset, and seeding again
+// only adds what is missing. Every batch runs under its own span and is
+// logged with the trace ID, so a seeding run can be followed in Grafana.
+package seed
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/go-api/pkg/database"
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/metrics"
+)
+
+// tracerName is the instrumentation scope of seeding spans
+const tracerName = "github.com/example/go-api/pkg/seed"
+
+// Actor stamps the created_by column of seeded rows, so they can be told
+// apart from real ones
+const Actor = "seed"
+
+// Source is the source column of seeded quotes
+const Source = "seed"
+
+// Store is the part of database.Store that seeding writes through
+type Store interface {
+	InsertUsers(ctx context.Context, users []database.User) ([]bool, error)
+	SaveQuote(ctx context.Context, content, author, source string) (bool, error)
+}
+
+// Config sets the volume of a seeding run
+type Config struct {
+	Users     int   // Users to generate
+	Quotes    int   // Quotes to generate, at most MaxQuotes
+	BatchSize int   // Rows written per span (default 100)
+	Seed      int64 // Random seed of the dataset
+}
+
+// Result counts the rows of a run by whether they were new
+type Result struct {
+	UsersInserted  int `json:"users_inserted"`
+	UsersExisting  int `json:"users_existing"`
+	QuotesInserted int `json:"quotes_inserted"`
+	QuotesExisting int `json:"quotes_existing"`
+}
+
+// Seeder writes generated datasets to a Store
+type Seeder struct {
+	store   Store
+	log     *logger.Logger
+	tracer  trace.Tracer
+	rows    *metrics.Counter
+	batches *metrics.Histogram
+}
+
+// New creates a Seeder writing to store. It counts rows in
+// seed_rows_total by table and result (inserted, existing) and times
+// batches in seed_batch_duration_seconds.
+func New(store Store, reg *metrics.Registry, log *logger.Logger) *Seeder {
+	return &Seeder{
+		store:   store,
+		log:     logger.OrDefault(log),
+		tracer:  otel.Tracer(tracerName),
+		rows:    reg.Counter("seed_rows_total", "Rows written by database seeding by table and result", "table", "result"),
+		batches: reg.Histogram("seed_batch_duration_seconds", "Duration of database seeding batches", nil, "table"),
+	}
+}
+
+// Run generates the dataset of cfg and writes it in batches under a seed
+// span. Rows already present are counted as existing, so a run can be
+// repeated, or resumed after a failure.
+func (s *Seeder) Run(ctx context.Context, cfg Config) (Result, error) {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	var res Result
+	if cfg.Users < 0 || cfg.Quotes < 0 {
+		return res, fmt.Errorf("volumes must not be negative")
+	}
+	if cfg.Quotes > MaxQuotes {
+		return res, fmt.Errorf("at most %d distinct quotes can be generated", MaxQuotes)
+	}
+
+	ctx, span := s.tracer.Start(database.WithActor(ctx, Actor), "seed", trace.WithAttributes(
+		attribute.Int64("seed.random_seed", cfg.Seed),
+		attribute.Int("seed.users", cfg.Users),
+		attribute.Int("seed.quotes", cfg.Quotes),
+		attribute.Int("seed.batch_size", cfg.BatchSize),
+	))
+	defer span.End()
+	start := time.Now()
+
+	l := s.log.WithContext(ctx)
+	l.Info().
+		Int64("random_seed", cfg.Seed).
+		Int("users", cfg.Users).
+		Int("quotes", cfg.Quotes).
+		Msg("Seeding database")
+
+	err := s.seedUsers(ctx, Users(cfg.Seed, cfg.Users), cfg.BatchSize, &res)
+	if err == nil {
+		err = s.seedQuotes(ctx, Quotes(cfg.Seed, cfg.Quotes), cfg.BatchSize, &res)
+	}
+
+	span.SetAttributes(
+		attribute.Int("seed.users_inserted", res.UsersInserted),
+		attribute.Int("seed.quotes_inserted", res.QuotesInserted),
+	)
+	event := l.Info()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		event = l.Error().Err(err)
+	}
+	event.
+		Int("users_inserted", res.UsersInserted).
+		Int("users_existing", res.UsersExisting).
+		Int("quotes_inserted", res.QuotesInserted).
+		Int("quotes_existing", res.QuotesExisting).
+		Dur("duration", time.Since(start)).
+		Msg("Seeding finished")
+	return res, err
+}
+
+func (s *Seeder) seedUsers(ctx context.Context, users []database.User, size int, res *Result) error {
+	for start := 0; start < len(users); start += size {
+		batch := users[start:min(start+size, len(users))]
+		n, err := s.batch(ctx, "users", start/size, len(batch), func(ctx context.Context) (int, error) {
+			inserted, err := s.store.InsertUsers(ctx, batch)
+			if err != nil {
+				return 0, err
+			}
+			n := 0
+			for _, ok := range inserted {
+				if ok {
+					n++
+				}
+			}
+			return n, nil
+		})
+		if err != nil {
+			return err
+		}
+		res.UsersInserted += n
+		res.UsersExisting += len(batch) - n
+	}
+	return nil
+}
+
+func (s *Seeder) seedQuotes(ctx context.Context, quotes []Quote, size int, res *Result) error {
+	for start := 0; start < len(quotes); start += size {
+		batch := quotes[start:min(start+size, len(quotes))]
+		n, err := s.batch(ctx, "quotes", start/size, len(batch), func(ctx context.Context) (int, error) {
+			n := 0
+			for _, q := range batch {
+				inserted, err := s.store.SaveQuote(ctx, q.Content, q.Author, Source)
+				if err != nil {
+					return n, err
+				}
+				if inserted {
+					n++
+				}
+			}
+			return n, nil
+		})
+		res.QuotesInserted += n
+		if err != nil {
+			return err
+		}
+		res.QuotesExisting += len(batch) - n
+	}
+	return nil
+}
+
+// batch runs write, which returns how many of rows it inserted, under a
+// seed.batch span, then counts and logs the batch. It returns the number
+// inserted, also on error.
+func (s *Seeder) batch(ctx context.Context, table string, index, rows int, write func(ctx context.Context) (int, error)) (int, error) {
+	ctx, span := s.tracer.Start(ctx, "seed.batch", trace.WithAttributes(
+		attribute.String("seed.table", table),
+		attribute.Int("seed.batch", index),
+		attribute.Int("seed.batch_size", rows),
+	))
+	defer span.End()
+	start := time.Now()
+	defer s.batches.Since(start, table)
+
+	inserted, err := write(ctx)
+	s.rows.Add(float64(inserted), table, "inserted")
+	span.SetAttributes(attribute.Int("seed.inserted", inserted))
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return inserted, fmt.Errorf("failed to seed %s batch %d: %w", table, index, err)
+	}
+	s.rows.Add(float64(rows-inserted), table, "existing")
+
+	l := s.log.WithContext(ctx)
+	l.Debug().
+		Str("table", table).
+		Int("batch", index).
+		Int("rows", rows).
+		Int("inserted", inserted).
+		Msg("Seeded batch")
+	return inserted, nil
+}
+
+// Quote is a generated quote
+type Quote struct {
+	Content string
+	Author  string
+}
+
+var (
+	firstNames = []string{
+		"amara", "ben", "chloe", "dmitri", "elena", "farid", "grace", "hiro",
+		"isla", "jonas", "kavya", "liam", "mei", "nadia", "oscar", "priya",
+		"quentin", "rosa", "samir", "tara", "umar", "vera", "wen", "yusuf", "zoe",
+	}
+	lastNames = []string{
+		"adeyemi", "bauer", "castillo", "dubois", "eriksen", "fischer", "garcia",
+		"haddad", "ivanova", "jensen", "kowalski", "larsen", "moreau", "nakamura",
+		"okafor", "petrov", "quinn", "rossi", "schmidt", "tanaka", "urban",
+		"varga", "walsh", "xu", "yilmaz", "zhang",
+	}
+	emailDomains = []string{"example.com", "example.org", "example.net"}
+
+	authors = []string{
+		"Ada Lovelace", "Alan Kay", "Barbara Liskov", "Donald Knuth",
+		"Edsger Dijkstra", "Fred Brooks", "Grace Hopper", "Ken Thompson",
+		"Margaret Hamilton", "Rob Pike", "Tony Hoare", "Leslie Lamport",
+	}
+	openings = []string{
+		"In the long run,", "More often than not,", "Above all,", "In practice,",
+		"Sooner or later,", "Without exception,", "Paradoxically,", "Quite simply,",
+		"In my experience,", "At scale,",
+	}
+	subjects = []string{
+		"simplicity", "a good abstraction", "every deadline", "the second system",
+		"a clear interface", "premature optimization", "a careful test",
+		"the smallest program", "shared state", "a well-named function",
+		"every cache", "the last bug",
+	}
+	verbs = []string{
+		"outlives", "reveals", "costs more than", "beats", "hides",
+		"is worth more than", "depends on", "grows faster than", "explains",
+		"survives",
+	}
+	objects = []string{
+		"cleverness", "the original plan", "a thousand comments", "raw speed",
+		"its documentation", "every framework", "the first prototype",
+		"a late meeting", "the critical path", "good intentions", "its author",
+		"the requirements",
+	}
+)
+
+// MaxQuotes is the number of distinct quotes Quotes can generate
+var MaxQuotes = len(authors) * len(openings) * len(subjects) * len(verbs) * len(objects)
+
+// Users generates n users with distinct usernames from seed
+func Users(seed int64, n int) []database.User {
+	r := rand.New(rand.NewSource(seed))
+	seen := make(map[string]int, n)
+	users := make([]database.User, 0, n)
+	for len(users) < n {
+		first := firstNames[r.Intn(len(firstNames))]
+		last := lastNames[r.Intn(len(lastNames))]
+		username := first + "." + last
+		if k := seen[username]; k > 0 {
+			seen[username]++
+			username = fmt.Sprintf("%s%d", username, k+1)
+		} else {
+			seen[username] = 1
+		}
+		users = append(users, database.User{
+			Username: username,
+			Email:    username + "@" + emailDomains[r.Intn(len(emailDomains))],
+		})
+	}
+	return users
+}
+
+// Quotes generates n distinct quotes from seed, n at most MaxQuotes. The
+// stream of quotes does not depend on the number of users, so changing one
+// volume keeps the rest of the dataset.
+func Quotes(seed int64, n int) []Quote {
+	r := rand.New(rand.NewSource(seed + 1))
+	n = min(n, MaxQuotes)
+	seen := make(map[Quote]bool, n)
+	quotes := make([]Quote, 0, n)
+	for len(quotes) < n {
+		var content strings.Builder
+		content.WriteString(openings[r.Intn(len(openings))])
+		content.WriteString(" " + subjects[r.Intn(len(subjects))])
+		content.WriteString(" " + verbs[r.Intn(len(verbs))])
+		content.WriteString(" " + objects[r.Intn(len(objects))] + ".")
+		q := Quote{Content: content.String(), Author: authors[r.Intn(len(authors))]}
+		if seen[q] {
+			continue
+		}
+		seen[q] = true
+		quotes = append(quotes, q)
+	}
+	return quotes
+}
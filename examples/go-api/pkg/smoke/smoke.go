@@ -0,0 +1,555 @@
+// Package smoke checks a running instance end to end, as a post-deploy
+// gate. It calls every endpoint with a sampled traceparent and verifies
+// that the trace and request IDs come back in the response headers. When
+// their URLs are set, it then waits for Tempo to hold the traces, Loki the
+// log lines and Prometheus the request counts of those calls.
+package smoke
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds the instance to check and the telemetry backends to query
+type Config struct {
+	BaseURL       string        // Public server, e.g. http://localhost:8080
+	AdminURL      string        // Admin server, e.g. http://localhost:9091; empty skips the admin checks
+	TempoURL      string        // Tempo HTTP API; empty skips the trace check
+	LokiURL       string        // Loki HTTP API; empty skips the log check
+	LokiSelector  string        // Stream selector of the service's logs (default {app="go-api"})
+	PrometheusURL string        // Prometheus HTTP API; empty skips the metrics check
+	Wait          time.Duration // How long to wait for telemetry to be ingested (default 1m)
+	Timeout       time.Duration // Timeout of each request (default 10s)
+	ReadOnly      bool          // Skip the checks that write: user import, update and delete
+	SkipDatabase  bool          // Skip the endpoints that need the database
+	MetricsAuth   func(*http.Request)
+}
+
+// Result is the outcome of one check. Err is nil when it passed; Skipped
+// says why it did not run.
+type Result struct {
+	Check    string
+	Err      error
+	Skipped  string
+	Duration time.Duration
+}
+
+// Failed reports whether any of results failed
+func Failed(results []Result) bool {
+	for _, res := range results {
+		if res.Err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+type runner struct {
+	cfg     Config
+	client  *http.Client
+	results []Result
+
+	traceIDs map[string]string // Trace ID of each API call, by check
+	routes   map[string]bool   // Route templates called, as labelled in http_requests_total
+}
+
+// pollInterval is the delay between telemetry queries while waiting for
+// ingestion
+const pollInterval = 2 * time.Second
+
+// Run runs every check against cfg and returns the results in order
+func Run(ctx context.Context, cfg Config) []Result {
+	if cfg.Wait <= 0 {
+		cfg.Wait = time.Minute
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.LokiSelector == "" {
+		cfg.LokiSelector = `{app="go-api"}`
+	}
+	r := &runner{
+		cfg:      cfg,
+		client:   &http.Client{Timeout: cfg.Timeout},
+		traceIDs: map[string]string{},
+		routes:   map[string]bool{},
+	}
+	start := time.Now()
+
+	var baseline map[string]float64
+	if cfg.PrometheusURL != "" {
+		r.check("prometheus_baseline", func() error {
+			var err error
+			baseline, err = r.requestCounts(ctx, apiRoutes)
+			return err
+		})
+	}
+
+	r.check("hello", func() error { return r.api(ctx, "GET", "/api/hello", "/api/hello", http.StatusOK) })
+	r.check("error", func() error { return r.api(ctx, "GET", "/api/error", "/api/error", http.StatusInternalServerError) })
+	r.check("weather", func() error {
+		return r.api(ctx, "GET", "/api/weather/London", "/api/weather/{location}", http.StatusOK)
+	})
+	r.check("weather_default", func() error { return r.api(ctx, "GET", "/api/weather", "/api/weather", http.StatusOK) })
+	r.check("quote", func() error { return r.api(ctx, "GET", "/api/quote", "/api/quote", http.StatusOK) })
+	r.check("dashboard", func() error { return r.api(ctx, "GET", "/api/dashboard", "/api/dashboard", http.StatusOK) })
+
+	dbChecks := []struct {
+		name, method, path, route string
+		status                    int
+	}{
+		{"users", "GET", "/api/users", "/api/users", http.StatusOK},
+		{"quote_stats", "GET", "/api/quotes/stats", "/api/quotes/stats", http.StatusOK},
+		{"quote_search", "GET", "/api/quotes/search?q=simplicity", "/api/quotes/search", http.StatusOK},
+	}
+	for _, c := range dbChecks {
+		if cfg.SkipDatabase {
+			r.skip(c.name, "database checks disabled")
+			continue
+		}
+		r.check(c.name, func() error { return r.api(ctx, c.method, c.path, c.route, c.status) })
+	}
+	r.writeChecks(ctx)
+
+	if cfg.AdminURL != "" {
+		r.adminChecks(ctx)
+	} else {
+		r.skip("admin", "no admin URL")
+	}
+
+	// Telemetry is ingested asynchronously, so each backend is polled
+	// until it has everything or the wait, shared by all three, runs out
+	deadline := time.Now().Add(cfg.Wait)
+	if cfg.TempoURL != "" {
+		r.check("tempo_traces", func() error { return r.waitTraces(ctx, deadline) })
+	} else {
+		r.skip("tempo_traces", "no Tempo URL")
+	}
+	if cfg.LokiURL != "" {
+		r.check("loki_logs", func() error { return r.waitLogs(ctx, deadline, start) })
+	} else {
+		r.skip("loki_logs", "no Loki URL")
+	}
+	switch {
+	case cfg.PrometheusURL == "":
+		r.skip("prometheus_metrics", "no Prometheus URL")
+	case baseline == nil:
+		r.skip("prometheus_metrics", "baseline query failed")
+	default:
+		r.check("prometheus_metrics", func() error { return r.waitMetrics(ctx, deadline, baseline) })
+	}
+	return r.results
+}
+
+// apiRoutes are the route templates of the API, as labelled in
+// http_requests_total
+var apiRoutes = []string{
+	"/api/hello", "/api/error", "/api/weather/{location}", "/api/weather", "/api/quote",
+	"/api/dashboard", "/api/users", "/api/quotes/stats", "/api/quotes/search",
+	"/api/quotes/{id:[0-9]+}", "/api/users/import", "/api/users/import/{id:[0-9]+}",
+	"/api/users/{username}", "/api/users/import/{id:[0-9]+}/events",
+}
+
+func (r *runner) check(name string, fn func() error) {
+	start := time.Now()
+	err := fn()
+	r.results = append(r.results, Result{Check: name, Err: err, Duration: time.Since(start)})
+}
+
+func (r *runner) skip(name, reason string) {
+	r.results = append(r.results, Result{Check: name, Skipped: reason})
+}
+
+// writeChecks deletes a quote that does not exist, so no data is lost,
+// then imports a throwaway user, follows the import's event stream, and
+// updates and deletes the user
+func (r *runner) writeChecks(ctx context.Context) {
+	names := []string{"quote_delete", "user_import", "user_import_events", "user_import_status", "user_update", "user_delete"}
+	if r.cfg.ReadOnly || r.cfg.SkipDatabase {
+		reason := "read-only"
+		if r.cfg.SkipDatabase {
+			reason = "database checks disabled"
+		}
+		for _, name := range names {
+			r.skip(name, reason)
+		}
+		return
+	}
+
+	r.check("quote_delete", func() error {
+		return r.api(ctx, "DELETE", "/api/quotes/2147483647", "/api/quotes/{id:[0-9]+}", http.StatusNotFound)
+	})
+
+	username := fmt.Sprintf("smoke-%d", time.Now().UnixNano())
+	var started struct {
+		Import struct {
+			ID int `json:"id"`
+		} `json:"import"`
+		EventsURL string `json:"events_url"`
+	}
+	r.check("user_import", func() error {
+		body, _ := json.Marshal([]map[string]string{{"username": username, "email": username + "@example.com"}})
+		resp, err := r.call(ctx, r.cfg.BaseURL, "POST", "/api/users/import", "/api/users/import", "application/json", bytes.NewReader(body), http.StatusAccepted)
+		if err != nil {
+			return err
+		}
+		if err := json.Unmarshal(resp, &started); err != nil || started.EventsURL == "" {
+			return fmt.Errorf("unexpected response %s", truncate(resp))
+		}
+		return nil
+	})
+	if started.EventsURL == "" {
+		for _, name := range names[2:] {
+			r.skip(name, "user import failed")
+		}
+		return
+	}
+
+	r.check("user_import_events", func() error { return r.events(ctx, started.EventsURL) })
+	r.check("user_import_status", func() error {
+		path := fmt.Sprintf("/api/users/import/%d", started.Import.ID)
+		resp, err := r.call(ctx, r.cfg.BaseURL, "GET", path, "/api/users/import/{id:[0-9]+}", "", nil, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		var status struct {
+			Import struct {
+				Status   string `json:"status"`
+				Imported int    `json:"imported"`
+			} `json:"import"`
+		}
+		if err := json.Unmarshal(resp, &status); err != nil {
+			return fmt.Errorf("unexpected response %s", truncate(resp))
+		}
+		if status.Import.Status != "done" || status.Import.Imported != 1 {
+			return fmt.Errorf("import %s with %d users imported, want done with 1", status.Import.Status, status.Import.Imported)
+		}
+		return nil
+	})
+	r.check("user_update", func() error {
+		body, _ := json.Marshal(map[string]interface{}{"email": username + "@example.org", "version": 1})
+		_, err := r.call(ctx, r.cfg.BaseURL, "PUT", "/api/users/"+username, "/api/users/{username}", "application/json", bytes.NewReader(body), http.StatusOK)
+		return err
+	})
+	r.check("user_delete", func() error {
+		_, err := r.call(ctx, r.cfg.BaseURL, "DELETE", "/api/users/"+username, "/api/users/{username}", "", nil, http.StatusNoContent)
+		return err
+	})
+}
+
+// events reads the import's event stream until its done event
+func (r *runner) events(ctx context.Context, path string) error {
+	req, traceID, requestID, err := r.newRequest(ctx, "GET", r.cfg.BaseURL+path, "", nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if err := checkResponse(resp, traceID, requestID, http.StatusOK); err != nil {
+		return err
+	}
+	r.track("GET "+path, traceID, "/api/users/import/{id:[0-9]+}/events")
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/event-stream") {
+		return fmt.Errorf("content type %q, want text/event-stream", ct)
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if strings.TrimSpace(scanner.Text()) == "event: done" {
+			return nil
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return errors.New("stream ended without a done event")
+}
+
+func (r *runner) adminChecks(ctx context.Context) {
+	for _, c := range []struct{ name, path string }{
+		{"health", "/health"},
+		{"ready", "/ready"},
+		{"version", "/version"},
+	} {
+		r.check(c.name, func() error {
+			_, err := r.call(ctx, r.cfg.AdminURL, "GET", c.path, "", "", nil, http.StatusOK)
+			return err
+		})
+	}
+	r.check("metrics", func() error {
+		req, err := http.NewRequestWithContext(ctx, "GET", r.cfg.AdminURL+"/metrics", nil)
+		if err != nil {
+			return err
+		}
+		if r.cfg.MetricsAuth != nil {
+			r.cfg.MetricsAuth(req)
+		}
+		resp, err := r.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode != http.StatusOK {
+			return fmt.Errorf("status %d, want 200: %s", resp.StatusCode, truncate(body))
+		}
+		if !bytes.Contains(body, []byte(`http_requests_total{method="GET",path="/api/hello"`)) {
+			return errors.New("http_requests_total has no series for /api/hello")
+		}
+		return nil
+	})
+}
+
+// api calls an API route and records its trace for the telemetry checks
+func (r *runner) api(ctx context.Context, method, path, route string, status int) error {
+	_, err := r.call(ctx, r.cfg.BaseURL, method, path, route, "", nil, status)
+	return err
+}
+
+// call sends a request with a new sampled traceparent and request ID and
+// checks its status. For API routes, those named by route, it also checks
+// that both IDs came back and records the trace.
+func (r *runner) call(ctx context.Context, base, method, path, route, contentType string, body io.Reader, status int) ([]byte, error) {
+	req, traceID, requestID, err := r.newRequest(ctx, method, base+path, contentType, body)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, _ := io.ReadAll(resp.Body)
+
+	if route == "" {
+		if resp.StatusCode != status {
+			return nil, fmt.Errorf("status %d, want %d: %s", resp.StatusCode, status, truncate(respBody))
+		}
+		return respBody, nil
+	}
+	if err := checkResponse(resp, traceID, requestID, status); err != nil {
+		return nil, fmt.Errorf("%w: %s", err, truncate(respBody))
+	}
+	r.track(method+" "+path, traceID, route)
+	return respBody, nil
+}
+
+func (r *runner) newRequest(ctx context.Context, method, url, contentType string, body io.Reader) (*http.Request, string, string, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
+	if err != nil {
+		return nil, "", "", err
+	}
+	traceID, spanID := randomHex(16), randomHex(8)
+	requestID := "smoke-" + spanID
+	req.Header.Set("traceparent", "00-"+traceID+"-"+spanID+"-01")
+	req.Header.Set("X-Request-ID", requestID)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	return req, traceID, requestID, nil
+}
+
+// checkResponse checks the status and that the trace and request IDs sent
+// came back in X-Trace-ID and X-Request-ID
+func checkResponse(resp *http.Response, traceID, requestID string, status int) error {
+	if resp.StatusCode != status {
+		return fmt.Errorf("status %d, want %d", resp.StatusCode, status)
+	}
+	if got := resp.Header.Get("X-Trace-ID"); got != traceID {
+		return fmt.Errorf("X-Trace-ID %q, want the traceparent's %q", got, traceID)
+	}
+	if got := resp.Header.Get("X-Request-ID"); got != requestID {
+		return fmt.Errorf("X-Request-ID %q, want %q", got, requestID)
+	}
+	return nil
+}
+
+func (r *runner) track(call, traceID, route string) {
+	r.traceIDs[call] = traceID
+	r.routes[route] = true
+}
+
+// waitTraces polls Tempo until it returns every recorded trace
+func (r *runner) waitTraces(ctx context.Context, deadline time.Time) error {
+	return poll(ctx, deadline, func() error {
+		var missing []string
+		for call, traceID := range r.traceIDs {
+			req, err := http.NewRequestWithContext(ctx, "GET", r.cfg.TempoURL+"/api/traces/"+traceID, nil)
+			if err != nil {
+				return err
+			}
+			resp, err := r.client.Do(req)
+			if err != nil {
+				return err
+			}
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				missing = append(missing, call+" ("+traceID+")")
+			}
+		}
+		return missingError("traces", missing)
+	})
+}
+
+// waitLogs polls Loki until it has a log line with each recorded trace ID
+func (r *runner) waitLogs(ctx context.Context, deadline, since time.Time) error {
+	ids := make([]string, 0, len(r.traceIDs))
+	for _, id := range r.traceIDs {
+		ids = append(ids, id)
+	}
+	query := fmt.Sprintf("%s |~ %q", r.cfg.LokiSelector, strings.Join(ids, "|"))
+
+	return poll(ctx, deadline, func() error {
+		params := url.Values{
+			"query": {query},
+			"start": {strconv.FormatInt(since.Add(-time.Minute).UnixNano(), 10)},
+			"limit": {"5000"},
+		}
+		var res struct {
+			Data struct {
+				Result []struct {
+					Values [][2]string `json:"values"`
+				} `json:"result"`
+			} `json:"data"`
+		}
+		if err := r.getJSON(ctx, r.cfg.LokiURL+"/loki/api/v1/query_range?"+params.Encode(), &res); err != nil {
+			return err
+		}
+		var lines strings.Builder
+		for _, stream := range res.Data.Result {
+			for _, v := range stream.Values {
+				lines.WriteString(v[1])
+			}
+		}
+		var missing []string
+		for call, traceID := range r.traceIDs {
+			if !strings.Contains(lines.String(), traceID) {
+				missing = append(missing, call+" ("+traceID+")")
+			}
+		}
+		return missingError("log lines", missing)
+	})
+}
+
+// waitMetrics polls Prometheus until http_requests_total has grown past
+// baseline for every route called
+func (r *runner) waitMetrics(ctx context.Context, deadline time.Time, baseline map[string]float64) error {
+	var routes []string
+	for route := range r.routes {
+		routes = append(routes, route)
+	}
+	return poll(ctx, deadline, func() error {
+		counts, err := r.requestCounts(ctx, routes)
+		if err != nil {
+			return err
+		}
+		var missing []string
+		for _, route := range routes {
+			if counts[route] <= baseline[route] {
+				missing = append(missing, route)
+			}
+		}
+		return missingError("request counts", missing)
+	})
+}
+
+// requestCounts returns http_requests_total summed per route
+func (r *runner) requestCounts(ctx context.Context, routes []string) (map[string]float64, error) {
+	quoted := make([]string, len(routes))
+	for i, route := range routes {
+		quoted[i] = regexp.QuoteMeta(route)
+	}
+	query := fmt.Sprintf("sum by (path) (http_requests_total{path=~%q})", strings.Join(quoted, "|"))
+
+	var res struct {
+		Data struct {
+			Result []struct {
+				Metric map[string]string `json:"metric"`
+				Value  [2]interface{}    `json:"value"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := r.getJSON(ctx, r.cfg.PrometheusURL+"/api/v1/query?"+url.Values{"query": {query}}.Encode(), &res); err != nil {
+		return nil, err
+	}
+	counts := map[string]float64{}
+	for _, s := range res.Data.Result {
+		value, _ := s.Value[1].(string)
+		counts[s.Metric["path"]], _ = strconv.ParseFloat(value, 64)
+	}
+	return counts, nil
+}
+
+func (r *runner) getJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d: %s", req.URL.Host, resp.StatusCode, truncate(body))
+	}
+	return json.Unmarshal(body, v)
+}
+
+// poll calls fn until it succeeds or deadline passes, and returns its last
+// error
+func poll(ctx context.Context, deadline time.Time, fn func() error) error {
+	for {
+		err := fn()
+		if err == nil || time.Now().Add(pollInterval).After(deadline) {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return err
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+func missingError(what string, missing []string) error {
+	if len(missing) == 0 {
+		return nil
+	}
+	sort.Strings(missing)
+	return fmt.Errorf("no %s for %s", what, strings.Join(missing, ", "))
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// truncate shortens a response body for an error message
+func truncate(body []byte) string {
+	const max = 200
+	s := strings.TrimSpace(string(body))
+	if len(s) > max {
+		return s[:max] + "..."
+	}
+	return s
+}
@@ -0,0 +1,226 @@
+package startup
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+// Config holds startup wait configuration
+type Config struct {
+	Timeout        time.Duration // Total window to wait for dependencies
+	InitialBackoff time.Duration // Delay before the second attempt
+	MaxBackoff     time.Duration // Upper bound for the exponential backoff
+}
+
+// Probe checks connectivity to a single dependency
+type Probe func(ctx context.Context) error
+
+type check struct {
+	name  string
+	probe Probe
+}
+
+// Waiter retries dependency probes with exponential backoff until they all
+// succeed or the startup window expires
+type Waiter struct {
+	cfg    Config
+	log    *logger.Logger
+	checks []check
+
+	mu     sync.RWMutex
+	status map[string]error
+	ready  atomic.Bool
+}
+
+// NewWaiter creates a new startup Waiter
+func NewWaiter(cfg Config, log *logger.Logger) *Waiter {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 30 * time.Second
+	}
+	if cfg.InitialBackoff <= 0 {
+		cfg.InitialBackoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 5 * time.Second
+	}
+
+	return &Waiter{
+		cfg:    cfg,
+		log:    log,
+		status: make(map[string]error),
+	}
+}
+
+// Add registers a dependency probe. Must be called before Wait.
+func (w *Waiter) Add(name string, probe Probe) {
+	w.checks = append(w.checks, check{name: name, probe: probe})
+	w.status[name] = fmt.Errorf("not checked yet")
+}
+
+// Wait runs all probes concurrently until each succeeds or the window
+// expires, then marks the service ready. It returns the dependencies that
+// never became reachable so the caller can degrade gracefully.
+func (w *Waiter) Wait(ctx context.Context) map[string]error {
+	ctx, cancel := context.WithTimeout(ctx, w.cfg.Timeout)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	for _, c := range w.checks {
+		wg.Add(1)
+		go func(c check) {
+			defer wg.Done()
+			w.setStatus(c.name, w.run(ctx, c))
+		}(c)
+	}
+	wg.Wait()
+
+	failed := make(map[string]error)
+	w.mu.RLock()
+	for name, err := range w.status {
+		if err != nil {
+			failed[name] = err
+		}
+	}
+	w.mu.RUnlock()
+
+	w.ready.Store(true)
+	return failed
+}
+
+func (w *Waiter) run(ctx context.Context, c check) error {
+	backoff := w.cfg.InitialBackoff
+	start := time.Now()
+
+	for attempt := 1; ; attempt++ {
+		err := c.probe(ctx)
+		if err == nil {
+			if w.log != nil {
+				okLog := w.log.WithFields(ctx, map[string]interface{}{
+					"dependency": c.name,
+					"attempt":    attempt,
+					"elapsed_ms": time.Since(start).Milliseconds(),
+				})
+				okLog.Info().Msg("Startup dependency reachable")
+			}
+			return nil
+		}
+
+		w.setStatus(c.name, err)
+		if w.log != nil {
+			retryLog := w.log.WithFields(ctx, map[string]interface{}{
+				"dependency": c.name,
+				"attempt":    attempt,
+				"backoff_ms": backoff.Milliseconds(),
+			})
+			retryLog.Warn().Err(err).Msg("Startup dependency unreachable, retrying")
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			if w.log != nil {
+				giveUpLog := w.log.WithFields(ctx, map[string]interface{}{
+					"dependency": c.name,
+					"attempts":   attempt,
+					"elapsed_ms": time.Since(start).Milliseconds(),
+				})
+				giveUpLog.Error().Err(err).Msg("Startup dependency wait window expired")
+			}
+			return err
+		case <-timer.C:
+		}
+
+		backoff *= 2
+		if backoff > w.cfg.MaxBackoff {
+			backoff = w.cfg.MaxBackoff
+		}
+	}
+}
+
+func (w *Waiter) setStatus(name string, err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.status[name] = err
+}
+
+// Ready reports whether the startup wait has finished
+func (w *Waiter) Ready() bool {
+	return w.ready.Load()
+}
+
+// Status returns the last probe result for every dependency
+func (w *Waiter) Status() map[string]string {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	status := make(map[string]string, len(w.status))
+	for name, err := range w.status {
+		if err != nil {
+			status[name] = err.Error()
+		} else {
+			status[name] = "ok"
+		}
+	}
+	return status
+}
+
+// Gate creates a middleware that answers 503 until the startup wait has
+// finished. Exempt paths (e.g. liveness and metrics) are always served.
+func (w *Waiter) Gate(exempt ...string) func(http.Handler) http.Handler {
+	skip := make(map[string]bool, len(exempt))
+	for _, p := range exempt {
+		skip[p] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			if w.Ready() || skip[r.URL.Path] {
+				next.ServeHTTP(rw, r)
+				return
+			}
+			rw.Header().Set("Content-Type", "application/json")
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			rw.Write([]byte(`{"status":"not ready","reason":"waiting for dependencies"}`))
+		})
+	}
+}
+
+// TCPProbe returns a Probe that dials addr (host:port)
+func TCPProbe(addr string) Probe {
+	return func(ctx context.Context) error {
+		var d net.Dialer
+		conn, err := d.DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		return conn.Close()
+	}
+}
+
+// HTTPProbe returns a Probe that expects a 2xx response from url
+func HTTPProbe(url string) Probe {
+	client := &http.Client{Timeout: 5 * time.Second}
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+		if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+			return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+		}
+		return nil
+	}
+}
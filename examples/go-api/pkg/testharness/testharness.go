@@ -0,0 +1,696 @@
+//go:build integration
+
+// Package testharness runs the service against real backends in
+// integration tests. Postgres, Tempo, Loki and Grafana run in containers
+// started with testcontainers, and the app runs in-process. Tests call it
+// over HTTP, then assert on the traces and logs it emitted. Those are
+// queried through Grafana's datasource proxy, so the test covers the whole
+// pipeline from the app to the dashboards. The package needs Docker, the
+// integration build tag and testcontainers-go:
+//
+//	go get github.com/testcontainers/testcontainers-go@v0.26.0
+//	go test -tags integration ./...
+//
+// The app is configured through the environment, as in production, so a
+// test in package main boots it with NewApp and Run:
+//
+//	func TestUsersTrace(t *testing.T) {
+//		h := testharness.Start(t, testharness.Config{
+//			Run: func(ctx context.Context) error {
+//				app, err := NewApp(ctx, LoadConfig())
+//				if err != nil {
+//					return err
+//				}
+//				return app.Run(ctx)
+//			},
+//		})
+//		resp, _, traceID := h.Get(t, "/api/users")
+//		if resp.StatusCode != http.StatusOK {
+//			t.Fatalf("status %d", resp.StatusCode)
+//		}
+//		h.AssertSpan(t, traceID, "GET /api/users")
+//		h.AssertLog(t, traceID, "HTTP request completed")
+//		h.AssertMetric(t, "http_requests_total", map[string]string{"path": "/api/users"})
+//	}
+//
+// Start sets the environment with t.Setenv, so harness tests cannot run in
+// parallel.
+package testharness
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	_ "github.com/lib/pq"
+	"github.com/prometheus/common/expfmt"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+)
+
+// Config holds how the harness boots the app and which images it runs
+type Config struct {
+	// Run runs the app until ctx is cancelled. It must read its
+	// configuration from the environment Start sets.
+	Run func(ctx context.Context) error
+
+	Env    map[string]string // Extra environment for the app, applied last
+	Schema string            // SQL run when Postgres starts (default the init script in k8s/postgres/configmap.yaml)
+	Wait   time.Duration     // How long assertions wait for telemetry (default 30s)
+
+	PostgresImage string // Default postgres:16-alpine
+	TempoImage    string // Default grafana/tempo:2.3.1
+	LokiImage     string // Default grafana/loki:2.9.2
+	GrafanaImage  string // Default grafana/grafana:10.2.2
+}
+
+// Harness is a running app and its backends
+type Harness struct {
+	URL         string // Public server of the app
+	AdminURL    string // Admin server of the app
+	PostgresDSN string
+	TempoURL    string
+	LokiURL     string
+	GrafanaURL  string
+
+	cfg    Config
+	client *http.Client
+}
+
+// Network aliases of the backends, used by Grafana's datasources
+const (
+	tempoAlias = "tempo"
+	lokiAlias  = "loki"
+)
+
+const tempoConfig = `
+server:
+  http_listen_port: 3200
+distributor:
+  receivers:
+    otlp:
+      protocols:
+        grpc:
+          endpoint: 0.0.0.0:4317
+ingester:
+  trace_idle_period: 1s
+  max_block_duration: 1m
+storage:
+  trace:
+    backend: local
+    local:
+      path: /tmp/tempo/blocks
+    wal:
+      path: /tmp/tempo/wal
+`
+
+// grafanaDatasources provisions Tempo and Loki with fixed UIDs, so the
+// proxy paths of the assertions are known
+const grafanaDatasources = `
+apiVersion: 1
+datasources:
+  - name: Tempo
+    uid: tempo
+    type: tempo
+    access: proxy
+    url: http://tempo:3200
+  - name: Loki
+    uid: loki
+    type: loki
+    access: proxy
+    url: http://loki:3100
+`
+
+// Start runs the backends and the app and waits until the app is ready.
+// Everything is stopped when the test ends.
+func Start(t testing.TB, cfg Config) *Harness {
+	t.Helper()
+	if cfg.Run == nil {
+		t.Fatal("testharness: Config.Run is required")
+	}
+	if cfg.Wait <= 0 {
+		cfg.Wait = 30 * time.Second
+	}
+	if cfg.Schema == "" {
+		schema, err := defaultSchema()
+		if err != nil {
+			t.Fatalf("testharness: %v", err)
+		}
+		cfg.Schema = schema
+	}
+	ctx := context.Background()
+	h := &Harness{cfg: cfg, client: &http.Client{Timeout: 30 * time.Second}}
+
+	netName := "go-api-harness-" + randomHex(4)
+	network, err := testcontainers.GenericNetwork(ctx, testcontainers.GenericNetworkRequest{
+		NetworkRequest: testcontainers.NetworkRequest{Name: netName, CheckDuplicate: true},
+	})
+	if err != nil {
+		t.Fatalf("testharness: failed to create network: %v", err)
+	}
+	t.Cleanup(func() { network.Remove(context.Background()) })
+
+	postgres := startContainer(ctx, t, testcontainers.ContainerRequest{
+		Image:        orDefault(cfg.PostgresImage, "postgres:16-alpine"),
+		ExposedPorts: []string{"5432/tcp"},
+		Env:          map[string]string{"POSTGRES_USER": "goapi", "POSTGRES_PASSWORD": "goapi", "POSTGRES_DB": "goapi"},
+		Files: []testcontainers.ContainerFile{{
+			Reader:            strings.NewReader(cfg.Schema),
+			ContainerFilePath: "/docker-entrypoint-initdb.d/init.sql",
+			FileMode:          0o644,
+		}},
+		Networks: []string{netName},
+		// The server restarts once after running the init scripts
+		WaitingFor: wait.ForLog("database system is ready to accept connections").WithOccurrence(2).WithStartupTimeout(2 * time.Minute),
+	})
+	tempo := startContainer(ctx, t, testcontainers.ContainerRequest{
+		Image:        orDefault(cfg.TempoImage, "grafana/tempo:2.3.1"),
+		Cmd:          []string{"-config.file=/etc/tempo.yaml"},
+		ExposedPorts: []string{"3200/tcp", "4317/tcp"},
+		Files: []testcontainers.ContainerFile{{
+			Reader:            strings.NewReader(tempoConfig),
+			ContainerFilePath: "/etc/tempo.yaml",
+			FileMode:          0o644,
+		}},
+		Networks:       []string{netName},
+		NetworkAliases: map[string][]string{netName: {tempoAlias}},
+		WaitingFor:     wait.ForHTTP("/ready").WithPort("3200/tcp").WithStartupTimeout(2 * time.Minute),
+	})
+	loki := startContainer(ctx, t, testcontainers.ContainerRequest{
+		Image:          orDefault(cfg.LokiImage, "grafana/loki:2.9.2"),
+		Cmd:            []string{"-config.file=/etc/loki/local-config.yaml"},
+		ExposedPorts:   []string{"3100/tcp"},
+		Networks:       []string{netName},
+		NetworkAliases: map[string][]string{netName: {lokiAlias}},
+		WaitingFor:     wait.ForHTTP("/ready").WithPort("3100/tcp").WithStartupTimeout(2 * time.Minute),
+	})
+	grafana := startContainer(ctx, t, testcontainers.ContainerRequest{
+		Image:        orDefault(cfg.GrafanaImage, "grafana/grafana:10.2.2"),
+		ExposedPorts: []string{"3000/tcp"},
+		Env: map[string]string{
+			"GF_AUTH_ANONYMOUS_ENABLED":      "true",
+			"GF_AUTH_ANONYMOUS_ORG_ROLE":     "Admin",
+			"GF_AUTH_DISABLE_LOGIN_FORM":     "true",
+			"GF_ANALYTICS_REPORTING_ENABLED": "false",
+		},
+		Files: []testcontainers.ContainerFile{{
+			Reader:            strings.NewReader(grafanaDatasources),
+			ContainerFilePath: "/etc/grafana/provisioning/datasources/harness.yaml",
+			FileMode:          0o644,
+		}},
+		Networks:   []string{netName},
+		WaitingFor: wait.ForHTTP("/api/health").WithPort("3000/tcp").WithStartupTimeout(2 * time.Minute),
+	})
+
+	pgAddr := endpoint(ctx, t, postgres, "5432/tcp")
+	pgHost, pgPort, _ := net.SplitHostPort(pgAddr)
+	h.PostgresDSN = fmt.Sprintf("postgres://goapi:goapi@%s/goapi?sslmode=disable", pgAddr)
+	h.TempoURL = "http://" + endpoint(ctx, t, tempo, "3200/tcp")
+	h.LokiURL = "http://" + endpoint(ctx, t, loki, "3100/tcp")
+	h.GrafanaURL = "http://" + endpoint(ctx, t, grafana, "3000/tcp")
+
+	port, adminPort := freePort(t), freePort(t)
+	h.URL = "http://localhost:" + port
+	h.AdminURL = "http://localhost:" + adminPort
+
+	env := map[string]string{
+		"PORT":                        port,
+		"ADMIN_PORT":                  adminPort,
+		"ENVIRONMENT":                 "test",
+		"DB_HOST":                     pgHost,
+		"DB_PORT":                     pgPort,
+		"DB_USER":                     "goapi",
+		"DB_PASSWORD":                 "goapi",
+		"DB_NAME":                     "goapi",
+		"DB_SSLMODE":                  "disable",
+		"TRACING_ENABLED":             "true",
+		"OTEL_EXPORTER_OTLP_ENDPOINT": endpoint(ctx, t, tempo, "4317/tcp"),
+		"OTEL_BSP_SCHEDULE_DELAY":     "200", // Export spans quickly, so assertions wait less
+		"LOKI_URL":                    h.LokiURL,
+		"LOG_LOKI_PUSH":               "true",
+		"GRAFANA_URL":                 h.GrafanaURL,
+		"SHUTDOWN_READINESS_LAG":      "0",
+	}
+	for k, v := range cfg.Env {
+		env[k] = v
+	}
+	for k, v := range env {
+		t.Setenv(k, v)
+	}
+
+	h.run(t)
+	return h
+}
+
+// run starts the app and waits for it to be ready. It is stopped before
+// the containers, since cleanups run last registered first.
+func (h *Harness) run(t testing.TB) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- h.cfg.Run(ctx) }()
+	t.Cleanup(func() {
+		cancel()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Errorf("testharness: app exited with error: %v", err)
+			}
+		case <-time.After(time.Minute):
+			t.Errorf("testharness: app did not stop within a minute")
+		}
+	})
+
+	deadline := time.Now().Add(2 * time.Minute)
+	for {
+		resp, err := h.client.Get(h.AdminURL + "/ready")
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode == http.StatusOK {
+				return
+			}
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("testharness: app not ready after 2m (last error: %v)", err)
+		}
+		select {
+		case err := <-done:
+			t.Fatalf("testharness: app exited before it was ready: %v", err)
+		case <-time.After(500 * time.Millisecond):
+		}
+	}
+}
+
+// Do sends req to the app with a new sampled traceparent and returns the
+// response, its body and the trace ID. A relative URL is resolved against
+// the public server.
+func (h *Harness) Do(t testing.TB, req *http.Request) (*http.Response, []byte, string) {
+	t.Helper()
+	if !req.URL.IsAbs() {
+		base, _ := url.Parse(h.URL)
+		req.URL = base.ResolveReference(req.URL)
+		req.Host = req.URL.Host
+	}
+	traceID := randomHex(16)
+	req.Header.Set("traceparent", "00-"+traceID+"-"+randomHex(8)+"-01")
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		t.Fatalf("testharness: %s %s: %v", req.Method, req.URL.Path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("testharness: %s %s: %v", req.Method, req.URL.Path, err)
+	}
+	return resp, body, traceID
+}
+
+// Get sends GET path to the public server, see Do
+func (h *Harness) Get(t testing.TB, path string) (*http.Response, []byte, string) {
+	t.Helper()
+	req, err := http.NewRequest("GET", path, nil)
+	if err != nil {
+		t.Fatalf("testharness: %v", err)
+	}
+	return h.Do(t, req)
+}
+
+// DB opens a connection to the app's database, closed when the test ends
+func (h *Harness) DB(t testing.TB) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("postgres", h.PostgresDSN)
+	if err != nil {
+		t.Fatalf("testharness: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// Span is a span of a trace stored in Tempo
+type Span struct {
+	Name         string
+	SpanID       string
+	ParentSpanID string
+	Attributes   map[string]string // Values as JSON, strings unquoted
+}
+
+// Trace returns the spans Tempo holds for traceID, through Grafana, waiting
+// until it holds any
+func (h *Harness) Trace(t testing.TB, traceID string) []Span {
+	t.Helper()
+	var spans []Span
+	h.eventually(t, "trace "+traceID, func() error {
+		var err error
+		spans, err = h.trace(traceID)
+		if err == nil && len(spans) == 0 {
+			err = errors.New("no spans")
+		}
+		return err
+	})
+	return spans
+}
+
+// AssertSpan waits until the trace holds a span called name and returns it
+func (h *Harness) AssertSpan(t testing.TB, traceID, name string) Span {
+	t.Helper()
+	var found Span
+	h.eventually(t, fmt.Sprintf("span %q in trace %s", name, traceID), func() error {
+		spans, err := h.trace(traceID)
+		if err != nil {
+			return err
+		}
+		var names []string
+		for _, s := range spans {
+			if s.Name == name {
+				found = s
+				return nil
+			}
+			names = append(names, s.Name)
+		}
+		return fmt.Errorf("spans: %s", strings.Join(names, ", "))
+	})
+	return found
+}
+
+// trace is the body of Tempo's trace by ID API, in OTLP JSON
+type tempoTrace struct {
+	Batches []struct {
+		ScopeSpans []struct {
+			Spans []tempoSpan `json:"spans"`
+		} `json:"scopeSpans"`
+	} `json:"batches"`
+}
+
+type tempoSpan struct {
+	Name         string `json:"name"`
+	SpanID       string `json:"spanId"`
+	ParentSpanID string `json:"parentSpanId"`
+	Attributes   []struct {
+		Key   string                     `json:"key"`
+		Value map[string]json.RawMessage `json:"value"`
+	} `json:"attributes"`
+}
+
+func (h *Harness) trace(traceID string) ([]Span, error) {
+	var tr tempoTrace
+	if err := h.getJSON(h.GrafanaURL+"/api/datasources/proxy/uid/tempo/api/traces/"+traceID, &tr); err != nil {
+		return nil, err
+	}
+	var spans []Span
+	for _, b := range tr.Batches {
+		for _, ss := range b.ScopeSpans {
+			for _, s := range ss.Spans {
+				span := Span{Name: s.Name, SpanID: s.SpanID, ParentSpanID: s.ParentSpanID, Attributes: map[string]string{}}
+				for _, a := range s.Attributes {
+					for _, v := range a.Value {
+						var str string
+						if json.Unmarshal(v, &str) == nil {
+							span.Attributes[a.Key] = str
+						} else {
+							span.Attributes[a.Key] = string(v)
+						}
+					}
+				}
+				spans = append(spans, span)
+			}
+		}
+	}
+	return spans, nil
+}
+
+// Logs returns the lines Loki holds for a LogQL query, through Grafana,
+// over the last hour
+func (h *Harness) Logs(t testing.TB, query string) []string {
+	t.Helper()
+	lines, err := h.logs(query)
+	if err != nil {
+		t.Fatalf("testharness: %v", err)
+	}
+	return lines
+}
+
+// AssertLog waits until Loki holds a line of the app with the trace ID and
+// message msg, and returns it
+func (h *Harness) AssertLog(t testing.TB, traceID, msg string) string {
+	t.Helper()
+	query := fmt.Sprintf(`{app="go-api"} |= %q | json | msg = %q`, traceID, msg)
+	var line string
+	h.eventually(t, fmt.Sprintf("log %q with trace %s", msg, traceID), func() error {
+		lines, err := h.logs(query)
+		if err != nil {
+			return err
+		}
+		if len(lines) == 0 {
+			return errors.New("no matching lines")
+		}
+		line = lines[0]
+		return nil
+	})
+	return line
+}
+
+func (h *Harness) logs(query string) ([]string, error) {
+	params := url.Values{
+		"query": {query},
+		"start": {strconv.FormatInt(time.Now().Add(-time.Hour).UnixNano(), 10)},
+		"limit": {"1000"},
+	}
+	var res struct {
+		Data struct {
+			Result []struct {
+				Values [][2]string `json:"values"`
+			} `json:"result"`
+		} `json:"data"`
+	}
+	if err := h.getJSON(h.GrafanaURL+"/api/datasources/proxy/uid/loki/loki/api/v1/query_range?"+params.Encode(), &res); err != nil {
+		return nil, err
+	}
+	var lines []string
+	for _, stream := range res.Data.Result {
+		for _, v := range stream.Values {
+			lines = append(lines, v[1])
+		}
+	}
+	return lines, nil
+}
+
+// Metric returns the value of the series of name whose labels include
+// labels, summed over the matching series, as scraped from the app's
+// /metrics now. It reports whether any series matched.
+func (h *Harness) Metric(t testing.TB, name string, labels map[string]string) (float64, bool) {
+	t.Helper()
+	resp, err := h.client.Get(h.AdminURL + "/metrics")
+	if err != nil {
+		t.Fatalf("testharness: %v", err)
+	}
+	defer resp.Body.Close()
+	var parser expfmt.TextParser
+	families, err := parser.TextToMetricFamilies(bufio.NewReader(resp.Body))
+	if err != nil {
+		t.Fatalf("testharness: failed to parse metrics: %v", err)
+	}
+
+	family, ok := families[name]
+	if !ok {
+		return 0, false
+	}
+	var sum float64
+	matched := false
+	for _, m := range family.GetMetric() {
+		have := map[string]string{}
+		for _, l := range m.GetLabel() {
+			have[l.GetName()] = l.GetValue()
+		}
+		match := true
+		for k, v := range labels {
+			if have[k] != v {
+				match = false
+				break
+			}
+		}
+		if !match {
+			continue
+		}
+		matched = true
+		switch {
+		case m.Counter != nil:
+			sum += m.GetCounter().GetValue()
+		case m.Gauge != nil:
+			sum += m.GetGauge().GetValue()
+		case m.Histogram != nil:
+			sum += float64(m.GetHistogram().GetSampleCount())
+		case m.Untyped != nil:
+			sum += m.GetUntyped().GetValue()
+		}
+	}
+	return sum, matched
+}
+
+// AssertMetric fails the test unless the app exports a series of name
+// whose labels include labels, and returns its value; see Metric
+func (h *Harness) AssertMetric(t testing.TB, name string, labels map[string]string) float64 {
+	t.Helper()
+	v, ok := h.Metric(t, name, labels)
+	if !ok {
+		t.Fatalf("testharness: no %s series with labels %v", name, labels)
+	}
+	return v
+}
+
+// AssertAnnotation waits until Grafana holds an annotation tagged tag, as
+// the app creates for deployments and shutdowns
+func (h *Harness) AssertAnnotation(t testing.TB, tag string) {
+	t.Helper()
+	h.eventually(t, "annotation tagged "+tag, func() error {
+		var annotations []json.RawMessage
+		if err := h.getJSON(h.GrafanaURL+"/api/annotations?tags="+url.QueryEscape(tag), &annotations); err != nil {
+			return err
+		}
+		if len(annotations) == 0 {
+			return errors.New("no annotations")
+		}
+		return nil
+	})
+}
+
+// eventually calls fn until it succeeds or the configured wait passes, then
+// fails the test with fn's last error
+func (h *Harness) eventually(t testing.TB, what string, fn func() error) {
+	t.Helper()
+	deadline := time.Now().Add(h.cfg.Wait)
+	for {
+		err := fn()
+		if err == nil {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("testharness: no %s after %s: %v", what, h.cfg.Wait, err)
+		}
+		time.Sleep(500 * time.Millisecond)
+	}
+}
+
+func (h *Harness) getJSON(url string, v interface{}) error {
+	resp, err := h.client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s returned status %d: %s", url, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, v)
+}
+
+func startContainer(ctx context.Context, t testing.TB, req testcontainers.ContainerRequest) testcontainers.Container {
+	t.Helper()
+	c, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	if err != nil {
+		t.Fatalf("testharness: failed to start %s: %v", req.Image, err)
+	}
+	t.Cleanup(func() { c.Terminate(context.Background()) })
+	return c
+}
+
+// endpoint returns the host:port the container's port is mapped to
+func endpoint(ctx context.Context, t testing.TB, c testcontainers.Container, port string) string {
+	t.Helper()
+	host, err := c.Host(ctx)
+	if err != nil {
+		t.Fatalf("testharness: %v", err)
+	}
+	mapped, err := c.MappedPort(ctx, nat.Port(port))
+	if err != nil {
+		t.Fatalf("testharness: %v", err)
+	}
+	return net.JoinHostPort(host, mapped.Port())
+}
+
+// freePort returns a port nothing listens on
+func freePort(t testing.TB) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("testharness: %v", err)
+	}
+	defer l.Close()
+	return strconv.Itoa(l.Addr().(*net.TCPAddr).Port)
+}
+
+// schemaPath is the Postgres init script, relative to the repository root
+var schemaPath = filepath.Join("k8s", "postgres", "configmap.yaml")
+
+// defaultSchema reads the init.sql block of the Postgres ConfigMap, found
+// by walking up from the working directory, so tests create the schema
+// production does
+func defaultSchema() (string, error) {
+	dir, err := os.Getwd()
+	if err != nil {
+		return "", err
+	}
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, schemaPath))
+		if err == nil {
+			return initSQL(string(data))
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("%s not found above the working directory: set Config.Schema", schemaPath)
+		}
+		dir = parent
+	}
+}
+
+// initSQL extracts the init.sql block scalar of a ConfigMap
+func initSQL(configMap string) (string, error) {
+	lines := strings.Split(configMap, "\n")
+	for i, line := range lines {
+		if strings.TrimSpace(line) != "init.sql: |" {
+			continue
+		}
+		keyIndent := len(line) - len(strings.TrimLeft(line, " "))
+		var b strings.Builder
+		for _, l := range lines[i+1:] {
+			if strings.TrimSpace(l) != "" && len(l)-len(strings.TrimLeft(l, " ")) <= keyIndent {
+				break
+			}
+			b.WriteString(strings.TrimPrefix(l, strings.Repeat(" ", keyIndent+2)) + "\n")
+		}
+		return b.String(), nil
+	}
+	return "", errors.New("no init.sql in the Postgres ConfigMap")
+}
+
+func orDefault(v, def string) string {
+	if v == "" {
+		return def
+	}
+	return v
+}
+
+func randomHex(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,196 @@
+package tracing
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+var (
+	exporterQueueSize = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "otel_exporter_queue_size",
+			Help: "Spans ended but not yet handed to the exporter",
+		},
+	)
+	exporterQueueCapacity = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "otel_exporter_queue_capacity",
+			Help: "Maximum number of spans buffered before new spans are dropped",
+		},
+	)
+	exporterSpansDropped = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "otel_exporter_spans_dropped_total",
+			Help: "Spans dropped because the export queue was full",
+		},
+	)
+	exporterSpansExported = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "otel_exporter_spans_total",
+			Help: "Spans handed to the exporter by result (success, failure)",
+		},
+		[]string{"result"},
+	)
+	exporterExportDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "otel_exporter_export_duration_seconds",
+			Help:    "Latency of span export calls",
+			Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10, 30},
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(exporterQueueSize)
+	prometheus.MustRegister(exporterQueueCapacity)
+	prometheus.MustRegister(exporterSpansDropped)
+	prometheus.MustRegister(exporterSpansExported)
+	prometheus.MustRegister(exporterExportDuration)
+}
+
+// dropLogInterval rate-limits the on-drop warning
+const dropLogInterval = 10 * time.Second
+
+// QueueStats is a snapshot of the export pipeline
+type QueueStats struct {
+	Size     int64 `json:"size"`
+	Capacity int64 `json:"capacity"`
+	Dropped  int64 `json:"dropped"`
+}
+
+// exportQueue counts spans between OnEnd and export so the otherwise
+// invisible BatchSpanProcessor queue can be measured and bounded
+type exportQueue struct {
+	capacity int64
+	log      *logger.Logger
+
+	pending atomic.Int64
+	dropped atomic.Int64
+
+	mu              sync.Mutex
+	lastDropLog     time.Time
+	droppedSinceLog int64
+	failing         bool
+}
+
+func newExportQueue(capacity int, log *logger.Logger) *exportQueue {
+	exporterQueueCapacity.Set(float64(capacity))
+	return &exportQueue{capacity: int64(capacity), log: log}
+}
+
+// admit reserves a queue slot, or records a drop when the queue is full
+func (q *exportQueue) admit() bool {
+	if q.pending.Add(1) > q.capacity {
+		q.pending.Add(-1)
+		q.drop()
+		return false
+	}
+	exporterQueueSize.Inc()
+	return true
+}
+
+// release frees n slots once spans reach the exporter
+func (q *exportQueue) release(n int) {
+	q.pending.Add(-int64(n))
+	exporterQueueSize.Sub(float64(n))
+}
+
+func (q *exportQueue) drop() {
+	q.dropped.Add(1)
+	exporterSpansDropped.Inc()
+
+	q.mu.Lock()
+	q.droppedSinceLog++
+	if time.Since(q.lastDropLog) < dropLogInterval {
+		q.mu.Unlock()
+		return
+	}
+	dropped := q.droppedSinceLog
+	q.droppedSinceLog = 0
+	q.lastDropLog = time.Now()
+	q.mu.Unlock()
+
+	if q.log != nil {
+		dropLog := q.log.WithFields(context.Background(), map[string]interface{}{
+			"dropped":        dropped,
+			"queue_capacity": q.capacity,
+		})
+		dropLog.Warn().Msg("Span export queue full, dropping spans")
+	}
+}
+
+// exportResult logs transitions between healthy and failing exports
+func (q *exportQueue) exportResult(err error, spans int) {
+	q.mu.Lock()
+	wasFailing := q.failing
+	q.failing = err != nil
+	q.mu.Unlock()
+
+	if q.log == nil || wasFailing == (err != nil) {
+		return
+	}
+	exportLog := q.log.WithFields(context.Background(), map[string]interface{}{
+		"spans": spans,
+	})
+	if err != nil {
+		exportLog.Warn().Err(err).Msg("Span export failing, traces are being lost")
+	} else {
+		exportLog.Info().Msg("Span export recovered")
+	}
+}
+
+func (q *exportQueue) stats() QueueStats {
+	return QueueStats{
+		Size:     q.pending.Load(),
+		Capacity: q.capacity,
+		Dropped:  q.dropped.Load(),
+	}
+}
+
+// boundedProcessor admits spans into the wrapped BatchSpanProcessor only
+// while the export queue has room
+type boundedProcessor struct {
+	sdktrace.SpanProcessor
+	queue *exportQueue
+}
+
+// OnEnd implements sdktrace.SpanProcessor
+func (p *boundedProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if !s.SpanContext().IsSampled() {
+		return
+	}
+	if p.queue.admit() {
+		p.SpanProcessor.OnEnd(s)
+	}
+}
+
+// instrumentedExporter records export latency and outcomes
+type instrumentedExporter struct {
+	sdktrace.SpanExporter
+	queue *exportQueue
+}
+
+// ExportSpans implements sdktrace.SpanExporter
+func (e *instrumentedExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.queue.release(len(spans))
+
+	start := time.Now()
+	err := e.SpanExporter.ExportSpans(ctx, spans)
+	exporterExportDuration.Observe(time.Since(start).Seconds())
+
+	result := "success"
+	if err != nil {
+		result = "failure"
+	}
+	exporterSpansExported.WithLabelValues(result).Add(float64(len(spans)))
+	e.queue.exportResult(err, len(spans))
+
+	return err
+}
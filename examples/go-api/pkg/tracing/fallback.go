@@ -0,0 +1,167 @@
+package tracing
+
+import (
+	"context"
+	"encoding/binary"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+var (
+	fallbackActive = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "otel_exporter_fallback_active",
+			Help: "Whether spans are being diverted to the fallback exporter (1) or sent over OTLP (0)",
+		},
+	)
+	fallbackSpans = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "otel_exporter_fallback_spans_total",
+			Help: "Spans written to the fallback exporter while OTLP was down",
+		},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(fallbackActive)
+	prometheus.MustRegister(fallbackSpans)
+}
+
+// FallbackConfig controls diverting spans away from a failing OTLP endpoint
+type FallbackConfig struct {
+	Enabled       bool
+	Path          string        // File to append spans to; stdout when empty
+	FailThreshold int           // Consecutive OTLP failures before failing over (default 3)
+	RetryInterval time.Duration // How often OTLP is retried while failed over (default 30s)
+	SampleRatio   float64       // Fraction of traces kept in the fallback (default 0.1)
+}
+
+// fallbackExporter sends spans to primary and, after FailThreshold
+// consecutive failures, diverts a sample of them to fallback until primary
+// recovers. Sampling is by trace ID so kept traces stay complete.
+type fallbackExporter struct {
+	primary  sdktrace.SpanExporter
+	fallback sdktrace.SpanExporter
+	cfg      FallbackConfig
+	log      *logger.Logger
+
+	mu          sync.Mutex
+	failures    int
+	active      bool
+	activeSince time.Time
+	lastTry     time.Time
+}
+
+func newFallbackExporter(primary, fallback sdktrace.SpanExporter, cfg FallbackConfig, log *logger.Logger) *fallbackExporter {
+	if cfg.FailThreshold <= 0 {
+		cfg.FailThreshold = 3
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = 30 * time.Second
+	}
+	if cfg.SampleRatio <= 0 || cfg.SampleRatio > 1 {
+		cfg.SampleRatio = 0.1
+	}
+	return &fallbackExporter{primary: primary, fallback: fallback, cfg: cfg, log: log}
+}
+
+// ExportSpans implements sdktrace.SpanExporter
+func (e *fallbackExporter) ExportSpans(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	e.mu.Lock()
+	skipPrimary := e.active && time.Since(e.lastTry) < e.cfg.RetryInterval
+	if !skipPrimary {
+		e.lastTry = time.Now()
+	}
+	e.mu.Unlock()
+
+	if !skipPrimary {
+		err := e.primary.ExportSpans(ctx, spans)
+		if e.record(err) {
+			return err
+		}
+	}
+
+	return e.exportFallback(ctx, spans)
+}
+
+// record tracks the primary outcome and reports whether the batch is done
+// (sent, or failed while still below the threshold)
+func (e *fallbackExporter) record(err error) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if err == nil {
+		if e.active {
+			e.active = false
+			fallbackActive.Set(0)
+			if e.log != nil {
+				recoveredLog := e.log.WithFields(context.Background(), map[string]interface{}{
+					"fallback_duration_ms": time.Since(e.activeSince).Milliseconds(),
+				})
+				recoveredLog.Info().Msg("OTLP export recovered, leaving fallback exporter")
+			}
+		}
+		e.failures = 0
+		return true
+	}
+
+	e.failures++
+	if e.active || e.failures < e.cfg.FailThreshold {
+		return !e.active
+	}
+
+	e.active = true
+	e.activeSince = time.Now()
+	fallbackActive.Set(1)
+	if e.log != nil {
+		failoverLog := e.log.WithFields(context.Background(), map[string]interface{}{
+			"consecutive_failures": e.failures,
+			"sample_ratio":         e.cfg.SampleRatio,
+			"retry_interval_ms":    e.cfg.RetryInterval.Milliseconds(),
+		})
+		failoverLog.Warn().Err(err).Msg("OTLP export failing, diverting spans to fallback exporter")
+	}
+	return false
+}
+
+func (e *fallbackExporter) exportFallback(ctx context.Context, spans []sdktrace.ReadOnlySpan) error {
+	sampled := make([]sdktrace.ReadOnlySpan, 0, len(spans))
+	for _, s := range spans {
+		if e.keep(s) {
+			sampled = append(sampled, s)
+		}
+	}
+	if len(sampled) == 0 {
+		return nil
+	}
+
+	if err := e.fallback.ExportSpans(ctx, sampled); err != nil {
+		return err
+	}
+	fallbackSpans.Add(float64(len(sampled)))
+	return nil
+}
+
+// keep samples by trace ID, the same way TraceIDRatioBased does
+func (e *fallbackExporter) keep(s sdktrace.ReadOnlySpan) bool {
+	if e.cfg.SampleRatio >= 1 {
+		return true
+	}
+	traceID := s.SpanContext().TraceID()
+	bound := uint64(e.cfg.SampleRatio * (1 << 63))
+	return binary.BigEndian.Uint64(traceID[8:16])>>1 < bound
+}
+
+// Shutdown implements sdktrace.SpanExporter
+func (e *fallbackExporter) Shutdown(ctx context.Context) error {
+	err := e.primary.Shutdown(ctx)
+	if ferr := e.fallback.Shutdown(ctx); err == nil {
+		err = ferr
+	}
+	return err
+}
@@ -0,0 +1,58 @@
+package tracing
+
+import (
+	"unicode/utf8"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+// DefaultMaxAttributeValueLength caps string attributes so a single span
+// cannot push a trace past Tempo's per-trace size limit
+const DefaultMaxAttributeValueLength = 2048
+
+// SpanLimits bounds the size of every span. Zero fields keep the SDK
+// default, which can also be set with the OTEL_SPAN_*_LIMIT variables.
+type SpanLimits struct {
+	MaxAttributes           int // Attributes per span
+	MaxEvents               int // Events per span
+	MaxLinks                int // Links per span
+	MaxAttributeValueLength int // Longer string values are truncated (default DefaultMaxAttributeValueLength)
+}
+
+func (l SpanLimits) sdk() sdktrace.SpanLimits {
+	limits := sdktrace.NewSpanLimits()
+	if l.MaxAttributes > 0 {
+		limits.AttributeCountLimit = l.MaxAttributes
+	}
+	if l.MaxEvents > 0 {
+		limits.EventCountLimit = l.MaxEvents
+	}
+	if l.MaxLinks > 0 {
+		limits.LinkCountLimit = l.MaxLinks
+	}
+	if l.MaxAttributeValueLength > 0 {
+		limits.AttributeValueLengthLimit = l.MaxAttributeValueLength
+	} else if limits.AttributeValueLengthLimit < 0 {
+		limits.AttributeValueLengthLimit = DefaultMaxAttributeValueLength
+	}
+	return limits
+}
+
+// Truncate shortens s to at most limit bytes without splitting a UTF-8
+// character, marking the cut with "...". Use it for attributes built from
+// request input or upstream payloads.
+func Truncate(s string, limit int) string {
+	if limit <= 0 || len(s) <= limit {
+		return s
+	}
+	const marker = "..."
+	if limit <= len(marker) {
+		return s[:limit]
+	}
+
+	cut := limit - len(marker)
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + marker
+}
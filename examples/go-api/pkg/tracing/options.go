@@ -0,0 +1,37 @@
+package tracing
+
+import (
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
+	"github.com/example/go-api/pkg/logger"
+)
+
+// Option adjusts a Config before InitTracer builds the provider
+type Option func(*Config)
+
+// WithSampler replaces the per-route ratio sampler built from Sampling
+func WithSampler(s sdktrace.Sampler) Option {
+	return func(c *Config) { c.Sampler = s }
+}
+
+// WithSpanProcessor registers an extra span processor. The provider is
+// created even with tracing disabled, so in-memory recorders work in tests.
+func WithSpanProcessor(sp sdktrace.SpanProcessor) Option {
+	return func(c *Config) { c.SpanProcessors = append(c.SpanProcessors, sp) }
+}
+
+// WithResourceAttributes adds resource attributes, e.g. build info
+func WithResourceAttributes(attrs ...attribute.KeyValue) Option {
+	return func(c *Config) { c.ResourceAttributes = append(c.ResourceAttributes, attrs...) }
+}
+
+// WithLogger sets the logger for dropped spans and export failures
+func WithLogger(l *logger.Logger) Option {
+	return func(c *Config) { c.Logger = l }
+}
+
+// WithMaxQueueSize sets how many spans are buffered before new ones are dropped
+func WithMaxQueueSize(n int) Option {
+	return func(c *Config) { c.MaxQueueSize = n }
+}
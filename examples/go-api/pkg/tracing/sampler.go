@@ -0,0 +1,126 @@
+package tracing
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RouteRule sets the sampling ratio for one route template, e.g.
+// "/api/weather/{location}". 1 always samples, 0 never does.
+type RouteRule struct {
+	Route string
+	Ratio float64
+}
+
+// SamplingConfig controls which root spans are sampled. Spans with a parent
+// follow the parent's decision.
+type SamplingConfig struct {
+	DefaultRatio float64 // Ratio for routes without a rule; 0 samples none
+	Routes       []RouteRule
+}
+
+// ParseRouteRules parses "route=ratio" pairs separated by commas, e.g.
+// "/api/error=1,/api/hello=0.01"
+func ParseRouteRules(s string) ([]RouteRule, error) {
+	var rules []RouteRule
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		route, ratio, ok := strings.Cut(pair, "=")
+		if !ok {
+			return nil, fmt.Errorf("rule %q: expected route=ratio", pair)
+		}
+		r, err := strconv.ParseFloat(strings.TrimSpace(ratio), 64)
+		if err != nil || r < 0 || r > 1 {
+			return nil, fmt.Errorf("rule %q: ratio must be between 0 and 1", pair)
+		}
+		rules = append(rules, RouteRule{Route: strings.TrimSpace(route), Ratio: r})
+	}
+	return rules, nil
+}
+
+// newSampler builds a ParentBased sampler whose root decision depends on
+// the http.route attribute set by otelmux. With recordUnsampled, dropped
+// spans are still recorded (not exported) so span metrics stay complete.
+func newSampler(cfg SamplingConfig, recordUnsampled bool) sdktrace.Sampler {
+	root := &routeSampler{
+		routes:          make(map[string]sdktrace.Sampler, len(cfg.Routes)),
+		fallback:        ratioSampler(cfg.DefaultRatio),
+		recordUnsampled: recordUnsampled,
+	}
+	for _, r := range cfg.Routes {
+		root.routes[r.Route] = ratioSampler(r.Ratio)
+	}
+
+	if !recordUnsampled {
+		return sdktrace.ParentBased(root)
+	}
+	return sdktrace.ParentBased(root,
+		sdktrace.WithRemoteParentNotSampled(recordOnly{}),
+	)
+}
+
+// ratioSampler maps a ratio to a sampler
+func ratioSampler(ratio float64) sdktrace.Sampler {
+	switch {
+	case ratio >= 1:
+		return sdktrace.AlwaysSample()
+	case ratio <= 0:
+		return sdktrace.NeverSample()
+	default:
+		return sdktrace.TraceIDRatioBased(ratio)
+	}
+}
+
+type routeSampler struct {
+	routes          map[string]sdktrace.Sampler
+	fallback        sdktrace.Sampler
+	recordUnsampled bool
+}
+
+// ShouldSample implements sdktrace.Sampler
+func (s *routeSampler) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	sampler := s.fallback
+	for _, attr := range p.Attributes {
+		if attr.Key == semconv.HTTPRouteKey {
+			if rs, ok := s.routes[attr.Value.AsString()]; ok {
+				sampler = rs
+			}
+			break
+		}
+	}
+
+	result := sampler.ShouldSample(p)
+	if result.Decision == sdktrace.Drop && s.recordUnsampled {
+		result.Decision = sdktrace.RecordOnly
+	}
+	return result
+}
+
+// Description implements sdktrace.Sampler
+func (s *routeSampler) Description() string {
+	return fmt.Sprintf("RouteSampler{routes:%d,default:%s}", len(s.routes), s.fallback.Description())
+}
+
+// recordOnly records spans without sampling them for export
+type recordOnly struct{}
+
+// ShouldSample implements sdktrace.Sampler
+func (recordOnly) ShouldSample(p sdktrace.SamplingParameters) sdktrace.SamplingResult {
+	return sdktrace.SamplingResult{
+		Decision:   sdktrace.RecordOnly,
+		Tracestate: trace.SpanContextFromContext(p.ParentContext).TraceState(),
+	}
+}
+
+// Description implements sdktrace.Sampler
+func (recordOnly) Description() string {
+	return "RecordOnly"
+}
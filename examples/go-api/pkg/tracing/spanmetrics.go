@@ -0,0 +1,105 @@
+package tracing
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Metric names and labels follow Tempo's metrics-generator so dashboards
+// work the same with or without a collector
+var (
+	spanCalls = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "traces_spanmetrics_calls_total",
+			Help: "Server spans by name and status, derived in-process",
+		},
+		[]string{"service", "span_name", "span_kind", "status_code"},
+	)
+	spanLatency = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "traces_spanmetrics_latency",
+			Help:    "Server span duration in seconds, derived in-process",
+			Buckets: []float64{.002, .004, .008, .016, .032, .064, .128, .256, .512, 1.02, 2.05, 4.1},
+		},
+		[]string{"service", "span_name", "span_kind", "status_code"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(spanCalls)
+	prometheus.MustRegister(spanLatency)
+}
+
+// spanMetricsProcessor derives RED metrics from ended server spans, with
+// the trace ID attached as an exemplar
+type spanMetricsProcessor struct {
+	service string
+}
+
+func newSpanMetricsProcessor(service string) *spanMetricsProcessor {
+	return &spanMetricsProcessor{service: service}
+}
+
+// OnStart implements sdktrace.SpanProcessor
+func (p *spanMetricsProcessor) OnStart(context.Context, sdktrace.ReadWriteSpan) {}
+
+// OnEnd implements sdktrace.SpanProcessor
+func (p *spanMetricsProcessor) OnEnd(s sdktrace.ReadOnlySpan) {
+	if s.SpanKind() != trace.SpanKindServer {
+		return
+	}
+
+	labels := prometheus.Labels{
+		"service":     p.service,
+		"span_name":   s.Name(),
+		"span_kind":   "SPAN_KIND_SERVER",
+		"status_code": statusCode(s.Status().Code),
+	}
+	duration := s.EndTime().Sub(s.StartTime())
+
+	var exemplar prometheus.Labels
+	if sc := s.SpanContext(); sc.IsSampled() {
+		exemplar = prometheus.Labels{"trace_id": sc.TraceID().String()}
+	}
+
+	observeWithExemplar(spanLatency.With(labels), duration, exemplar)
+	addWithExemplar(spanCalls.With(labels), exemplar)
+}
+
+// Shutdown implements sdktrace.SpanProcessor
+func (p *spanMetricsProcessor) Shutdown(context.Context) error { return nil }
+
+// ForceFlush implements sdktrace.SpanProcessor
+func (p *spanMetricsProcessor) ForceFlush(context.Context) error { return nil }
+
+func statusCode(c codes.Code) string {
+	switch c {
+	case codes.Error:
+		return "STATUS_CODE_ERROR"
+	case codes.Ok:
+		return "STATUS_CODE_OK"
+	default:
+		return "STATUS_CODE_UNSET"
+	}
+}
+
+func observeWithExemplar(o prometheus.Observer, d time.Duration, exemplar prometheus.Labels) {
+	if eo, ok := o.(prometheus.ExemplarObserver); ok && exemplar != nil {
+		eo.ObserveWithExemplar(d.Seconds(), exemplar)
+		return
+	}
+	o.Observe(d.Seconds())
+}
+
+func addWithExemplar(c prometheus.Counter, exemplar prometheus.Labels) {
+	if ea, ok := c.(prometheus.ExemplarAdder); ok && exemplar != nil {
+		ea.AddWithExemplar(1, exemplar)
+		return
+	}
+	c.Inc()
+}
@@ -3,10 +3,13 @@ package tracing
 import (
 	"context"
 	"fmt"
+	"os"
 
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
 	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/resource"
 	sdktrace "go.opentelemetry.io/otel/sdk/trace"
@@ -14,6 +17,8 @@ import (
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
+
+	"github.com/example/go-api/pkg/logger"
 )
 
 // Config holds tracing configuration
@@ -23,33 +28,40 @@ type Config struct {
 	Environment    string
 	OTLPEndpoint   string // e.g., "tempo:4317"
 	Enabled        bool
+	MaxQueueSize   int            // Spans buffered before new ones are dropped (default 2048)
+	Logger         *logger.Logger // Optional logger for dropped spans and export failures
+	Fallback       FallbackConfig // Divert spans to stdout or a file while OTLP is down
+	SpanMetrics    bool           // Derive RED metrics from server spans in-process
+	Limits         SpanLimits     // Attribute, event and link limits per span
+	Sampling       SamplingConfig // Root span sampling, optionally per route
+
+	ResourceAttributes []attribute.KeyValue     // Extra resource attributes, e.g. build info
+	Sampler            sdktrace.Sampler         // Optional: replaces the sampler built from Sampling
+	SpanProcessors     []sdktrace.SpanProcessor // Optional: extra processors, e.g. for tests
 }
 
 // Provider wraps the OpenTelemetry tracer provider
 type Provider struct {
 	provider *sdktrace.TracerProvider
 	tracer   trace.Tracer
+	queue    *exportQueue
+	fallback *os.File // Fallback span file, closed on shutdown
 }
 
-// InitTracer initializes the OpenTelemetry tracer
-func InitTracer(ctx context.Context, cfg Config) (*Provider, error) {
-	if !cfg.Enabled {
+// InitTracer initializes the OpenTelemetry tracer. Options are applied on
+// top of cfg.
+func InitTracer(ctx context.Context, cfg Config, opts ...Option) (*Provider, error) {
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if !cfg.Enabled && !cfg.SpanMetrics && len(cfg.SpanProcessors) == 0 {
 		// Return a no-op tracer provider
 		return &Provider{
 			tracer: otel.Tracer(cfg.ServiceName),
 		}, nil
 	}
 
-	// Create OTLP exporter using the endpoint directly
-	exporter, err := otlptracegrpc.New(ctx,
-		otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint),
-		otlptracegrpc.WithInsecure(),
-		otlptracegrpc.WithDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
-	}
-
 	// Create resource with service information
 	res, err := resource.New(ctx,
 		resource.WithAttributes(
@@ -57,6 +69,7 @@ func InitTracer(ctx context.Context, cfg Config) (*Provider, error) {
 			semconv.ServiceVersion(cfg.ServiceVersion),
 			attribute.String("environment", cfg.Environment),
 		),
+		resource.WithAttributes(cfg.ResourceAttributes...),
 		resource.WithHost(),
 		resource.WithProcess(),
 	)
@@ -64,12 +77,36 @@ func InitTracer(ctx context.Context, cfg Config) (*Provider, error) {
 		return nil, fmt.Errorf("failed to create resource: %w", err)
 	}
 
-	// Create tracer provider
-	tp := sdktrace.NewTracerProvider(
-		sdktrace.WithBatcher(exporter),
+	sampler := cfg.Sampler
+	if sampler == nil {
+		sampler = newSampler(cfg.Sampling, cfg.SpanMetrics)
+	}
+	tpOpts := []sdktrace.TracerProviderOption{
 		sdktrace.WithResource(res),
-		sdktrace.WithSampler(sdktrace.AlwaysSample()),
-	)
+		sdktrace.WithSampler(sampler),
+		sdktrace.WithRawSpanLimits(cfg.Limits.sdk()),
+	}
+
+	// RED metrics from server spans work even without an exporter
+	if cfg.SpanMetrics {
+		tpOpts = append(tpOpts, sdktrace.WithSpanProcessor(newSpanMetricsProcessor(cfg.ServiceName)))
+	}
+	for _, sp := range cfg.SpanProcessors {
+		tpOpts = append(tpOpts, sdktrace.WithSpanProcessor(sp))
+	}
+
+	p := &Provider{}
+	if cfg.Enabled {
+		var processor sdktrace.SpanProcessor
+		processor, p.queue, p.fallback, err = newExportPipeline(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		tpOpts = append(tpOpts, sdktrace.WithSpanProcessor(processor))
+	}
+
+	// Create tracer provider
+	tp := sdktrace.NewTracerProvider(tpOpts...)
 
 	// Set global tracer provider and propagator
 	otel.SetTracerProvider(tp)
@@ -78,18 +115,78 @@ func InitTracer(ctx context.Context, cfg Config) (*Provider, error) {
 		propagation.Baggage{},
 	))
 
-	return &Provider{
-		provider: tp,
-		tracer:   tp.Tracer(cfg.ServiceName),
-	}, nil
+	p.provider = tp
+	p.tracer = tp.Tracer(cfg.ServiceName)
+	return p, nil
+}
+
+// newExportPipeline builds the OTLP exporter with its optional fallback
+// behind a bounded, instrumented batch processor
+func newExportPipeline(ctx context.Context, cfg Config) (sdktrace.SpanProcessor, *exportQueue, *os.File, error) {
+	// Create OTLP exporter using the endpoint directly
+	otlpExporter, err := otlptracegrpc.New(ctx,
+		otlptracegrpc.WithEndpoint(cfg.OTLPEndpoint),
+		otlptracegrpc.WithInsecure(),
+		otlptracegrpc.WithDialOption(grpc.WithTransportCredentials(insecure.NewCredentials())),
+	)
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create OTLP exporter: %w", err)
+	}
+
+	var (
+		exporter     sdktrace.SpanExporter = otlpExporter
+		fallbackFile *os.File
+	)
+	if cfg.Fallback.Enabled {
+		out := os.Stdout
+		if cfg.Fallback.Path != "" {
+			fallbackFile, err = os.OpenFile(cfg.Fallback.Path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+			if err != nil {
+				return nil, nil, nil, fmt.Errorf("failed to open fallback span file: %w", err)
+			}
+			out = fallbackFile
+		}
+		stdoutExporter, err := stdouttrace.New(stdouttrace.WithWriter(out))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("failed to create fallback exporter: %w", err)
+		}
+		exporter = newFallbackExporter(otlpExporter, stdoutExporter, cfg.Fallback, cfg.Logger)
+	}
+
+	// Bound the batch queue ourselves so its depth and drops are observable
+	if cfg.MaxQueueSize <= 0 {
+		cfg.MaxQueueSize = sdktrace.DefaultMaxQueueSize
+	}
+	queue := newExportQueue(cfg.MaxQueueSize, cfg.Logger)
+	batcher := sdktrace.NewBatchSpanProcessor(
+		&instrumentedExporter{SpanExporter: exporter, queue: queue},
+		sdktrace.WithMaxQueueSize(cfg.MaxQueueSize),
+	)
+
+	return &boundedProcessor{SpanProcessor: batcher, queue: queue}, queue, fallbackFile, nil
 }
 
 // Shutdown gracefully shuts down the tracer provider
 func (p *Provider) Shutdown(ctx context.Context) error {
-	if p.provider != nil {
-		return p.provider.Shutdown(ctx)
+	if p.provider == nil {
+		return nil
+	}
+	err := p.provider.Shutdown(ctx)
+	if p.fallback != nil {
+		if cerr := p.fallback.Close(); err == nil {
+			err = cerr
+		}
+	}
+	return err
+}
+
+// QueueStats returns the current export queue depth and drop count. It is
+// zero when tracing is disabled.
+func (p *Provider) QueueStats() QueueStats {
+	if p.queue == nil {
+		return QueueStats{}
 	}
-	return nil
+	return p.queue.stats()
 }
 
 // Tracer returns the tracer instance
@@ -137,8 +234,51 @@ func RecordError(ctx context.Context, err error) {
 	span.RecordError(err)
 }
 
-// SetSpanStatus sets the status of the current span
-func SetSpanStatus(ctx context.Context, code trace.SpanKind, description string) {
+// SetSpanStatus sets the status of the current span. The description is
+// kept only with codes.Error, as the OTel API ignores it otherwise.
+func SetSpanStatus(ctx context.Context, code codes.Code, description string) {
+	span := trace.SpanFromContext(ctx)
+	span.SetStatus(code, description)
+}
+
+// SetSpanOK marks the current span as successful. Ok overrides any Error
+// set later, so use it only where the outcome is known to be final.
+func SetSpanOK(ctx context.Context) {
+	trace.SpanFromContext(ctx).SetStatus(codes.Ok, "")
+}
+
+// SetSpanError records err on the current span and marks the span failed.
+// It does nothing when err is nil.
+func SetSpanError(ctx context.Context, err error) {
+	if err == nil {
+		return
+	}
 	span := trace.SpanFromContext(ctx)
-	span.SetAttributes(attribute.String("status.description", description))
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+}
+
+// HTTPStatusCode returns the span status for an HTTP response status per
+// the OTel HTTP semantic conventions: 5xx fails both server and client
+// spans, 4xx only client spans, since for a server it is the caller's
+// mistake. Other codes leave the status Unset; invalid codes are errors.
+func HTTPStatusCode(status int, kind trace.SpanKind) (codes.Code, string) {
+	switch {
+	case status < 100 || status >= 600:
+		return codes.Error, fmt.Sprintf("Invalid HTTP status code %d", status)
+	case status >= 500:
+		return codes.Error, fmt.Sprintf("HTTP %d", status)
+	case status >= 400 && kind == trace.SpanKindClient:
+		return codes.Error, fmt.Sprintf("HTTP %d", status)
+	}
+	return codes.Unset, ""
+}
+
+// SetHTTPSpanStatus sets the status of span, of the given kind, from an
+// HTTP response status (see HTTPStatusCode). An Unset result leaves the
+// span as it is, so an earlier error is not cleared.
+func SetHTTPSpanStatus(span trace.Span, status int, kind trace.SpanKind) {
+	if code, description := HTTPStatusCode(status, kind); code != codes.Unset {
+		span.SetStatus(code, description)
+	}
 }
@@ -0,0 +1,100 @@
+package upstream
+
+import (
+	"fmt"
+	"time"
+)
+
+// Dependency describes an upstream for the dependency graph
+type Dependency struct {
+	ID      string // Probe name, see Prober.Add
+	Title   string
+	Kind    string // e.g. "database", "http", "traces", "logs"
+	Version string // Empty when unknown
+}
+
+// Node is a vertex of the dependency graph. Field names follow the data
+// frame contract of Grafana's node graph panel.
+type Node struct {
+	ID          string  `json:"id"`
+	Title       string  `json:"title"`
+	SubTitle    string  `json:"subTitle,omitempty"`
+	MainStat    string  `json:"mainStat,omitempty"`
+	ArcSuccess  float64 `json:"arc__success"`
+	ArcFailed   float64 `json:"arc__failed"`
+	Health      string  `json:"detail__health"`
+	Version     string  `json:"detail__version,omitempty"`
+	LastError   string  `json:"detail__last_error,omitempty"`
+	LastChecked string  `json:"detail__last_checked,omitempty"`
+}
+
+// Edge is a call path from the service to one upstream
+type Edge struct {
+	ID       string `json:"id"`
+	Source   string `json:"source"`
+	Target   string `json:"target"`
+	MainStat string `json:"mainStat,omitempty"`
+}
+
+// Graph is the service and its upstreams
+type Graph struct {
+	Nodes []Node `json:"nodes"`
+	Edges []Edge `json:"edges"`
+}
+
+// Health values reported per node
+const (
+	HealthUp      = "up"
+	HealthDown    = "down"
+	HealthUnknown = "unknown" // Not probed yet
+)
+
+// BuildGraph links service to every dependency, annotating each node with
+// its latest probe result
+func BuildGraph(service Dependency, deps []Dependency, results map[string]Result) Graph {
+	g := Graph{
+		Nodes: []Node{{
+			ID:         service.ID,
+			Title:      service.Title,
+			SubTitle:   service.Kind,
+			ArcSuccess: 1,
+			Health:     HealthUp,
+			Version:    service.Version,
+		}},
+		Edges: make([]Edge, 0, len(deps)),
+	}
+
+	for _, d := range deps {
+		node := Node{
+			ID:       d.ID,
+			Title:    d.Title,
+			SubTitle: d.Kind,
+			Health:   HealthUnknown,
+			Version:  d.Version,
+		}
+
+		edge := Edge{
+			ID:     service.ID + "->" + d.ID,
+			Source: service.ID,
+			Target: d.ID,
+		}
+
+		if r, ok := results[d.ID]; ok {
+			node.MainStat = fmt.Sprintf("%d ms", r.LatencyMs)
+			node.LastError = r.LastError
+			node.LastChecked = r.LastChecked.Format(time.RFC3339)
+			if r.Up {
+				node.Health = HealthUp
+				node.ArcSuccess = 1
+			} else {
+				node.Health = HealthDown
+				node.ArcFailed = 1
+			}
+			edge.MainStat = node.Health
+		}
+
+		g.Nodes = append(g.Nodes, node)
+		g.Edges = append(g.Edges, edge)
+	}
+	return g
+}
@@ -0,0 +1,193 @@
+package upstream
+
+import (
+	"context"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/startup"
+)
+
+var (
+	upstreamUp = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "upstream_up",
+			Help: "Whether the last active probe of an upstream succeeded (1) or failed (0)",
+		},
+		[]string{"upstream"},
+	)
+	probeDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "upstream_probe_duration_seconds",
+			Help:    "Latency of active upstream probes",
+			Buckets: []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+		},
+		[]string{"upstream"},
+	)
+)
+
+func init() {
+	prometheus.MustRegister(upstreamUp)
+	prometheus.MustRegister(probeDuration)
+}
+
+// Config holds active probing configuration
+type Config struct {
+	Interval time.Duration // Base delay between probes of one upstream
+	Timeout  time.Duration // Per-probe timeout
+	Jitter   float64       // Fraction of Interval added or removed at random
+}
+
+// Result is the latest probe outcome for one upstream
+type Result struct {
+	Up          bool      `json:"up"`
+	LatencyMs   int64     `json:"latency_ms"`
+	LastError   string    `json:"last_error,omitempty"`
+	LastChecked time.Time `json:"last_checked"`
+	LastSuccess time.Time `json:"last_success,omitempty"`
+}
+
+type target struct {
+	name  string
+	probe startup.Probe
+}
+
+// Prober probes upstreams in the background so their health is known
+// before a request depends on them
+type Prober struct {
+	cfg     Config
+	log     *logger.Logger
+	targets []target
+
+	mu      sync.RWMutex
+	results map[string]Result
+}
+
+// NewProber creates a new Prober
+func NewProber(cfg Config, log *logger.Logger) *Prober {
+	if cfg.Interval <= 0 {
+		cfg.Interval = 30 * time.Second
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = 5 * time.Second
+	}
+	if cfg.Jitter < 0 || cfg.Jitter > 1 {
+		cfg.Jitter = 0.2
+	}
+
+	return &Prober{
+		cfg:     cfg,
+		log:     log,
+		results: make(map[string]Result),
+	}
+}
+
+// Add registers an upstream. Must be called before Run.
+func (p *Prober) Add(name string, probe startup.Probe) {
+	p.targets = append(p.targets, target{name: name, probe: probe})
+}
+
+// Run probes every upstream until ctx is cancelled. Each upstream runs on
+// its own jittered schedule so probes do not fire in lockstep across pods.
+func (p *Prober) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, t := range p.targets {
+		wg.Add(1)
+		go func(t target) {
+			defer wg.Done()
+			p.loop(ctx, t)
+		}(t)
+	}
+	wg.Wait()
+}
+
+func (p *Prober) loop(ctx context.Context, t target) {
+	timer := time.NewTimer(p.jittered(p.cfg.Interval / 4))
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+
+		p.check(ctx, t)
+		timer.Reset(p.jittered(p.cfg.Interval))
+	}
+}
+
+func (p *Prober) check(ctx context.Context, t target) {
+	probeCtx, cancel := context.WithTimeout(ctx, p.cfg.Timeout)
+	start := time.Now()
+	err := t.probe(probeCtx)
+	latency := time.Since(start)
+	cancel()
+
+	if ctx.Err() != nil {
+		return
+	}
+
+	probeDuration.WithLabelValues(t.name).Observe(latency.Seconds())
+
+	p.mu.Lock()
+	previous, seen := p.results[t.name]
+	result := Result{
+		Up:          err == nil,
+		LatencyMs:   latency.Milliseconds(),
+		LastChecked: start.UTC(),
+		LastSuccess: previous.LastSuccess,
+	}
+	if err != nil {
+		result.LastError = err.Error()
+	} else {
+		result.LastSuccess = result.LastChecked
+	}
+	p.results[t.name] = result
+	p.mu.Unlock()
+
+	if result.Up {
+		upstreamUp.WithLabelValues(t.name).Set(1)
+	} else {
+		upstreamUp.WithLabelValues(t.name).Set(0)
+	}
+
+	// Only log transitions; steady state is visible in upstream_up
+	if p.log == nil || (seen && previous.Up == result.Up) {
+		return
+	}
+	probeLog := p.log.WithFields(ctx, map[string]interface{}{
+		"upstream":   t.name,
+		"latency_ms": result.LatencyMs,
+	})
+	if result.Up {
+		probeLog.Info().Msg("Upstream is up")
+	} else {
+		probeLog.Warn().Err(err).Msg("Upstream is down")
+	}
+}
+
+// jittered spreads d by up to ±Jitter
+func (p *Prober) jittered(d time.Duration) time.Duration {
+	if p.cfg.Jitter == 0 {
+		return d
+	}
+	spread := (rand.Float64()*2 - 1) * p.cfg.Jitter
+	return time.Duration(float64(d) * (1 + spread))
+}
+
+// Results returns the latest result for every upstream probed so far
+func (p *Prober) Results() map[string]Result {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	results := make(map[string]Result, len(p.results))
+	for name, r := range p.results {
+		results[name] = r
+	}
+	return results
+}
@@ -0,0 +1,358 @@
+// Package userimport imports users in bulk from CSV or JSON. An import runs
+// as a background job in batches: each batch is inserted in one transaction
+// under its own span, linked to the request that started the import, and
+// progress is saved to the database after every batch so any replica can
+// report it.
+package userimport
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/rs/zerolog"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/example/go-api/pkg/database"
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/metrics"
+	"github.com/example/go-api/pkg/tracing"
+)
+
+// tracerName is the instrumentation scope of import spans
+const tracerName = "github.com/example/go-api/pkg/userimport"
+
+// Column limits of the users table
+const (
+	maxUsernameLength = 100
+	maxEmailLength    = 255
+)
+
+// ErrUnsupportedFormat is returned by Parse for content types other than
+// CSV and JSON
+var ErrUnsupportedFormat = errors.New("unsupported import format: use text/csv or application/json")
+
+// Row is one user to import. Line is its line in a CSV file or its
+// position, from 1, in a JSON array.
+type Row struct {
+	Line     int    `json:"-"`
+	Username string `json:"username"`
+	Email    string `json:"email"`
+}
+
+// Parse reads rows from body by contentType: CSV with a header naming the
+// username and email columns, or a JSON array of {"username","email"}
+// objects. More than maxRows rows is an error.
+func Parse(contentType string, body io.Reader, maxRows int) ([]Row, error) {
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "text/csv":
+		return parseCSV(body, maxRows)
+	case "application/json":
+		return parseJSON(body, maxRows)
+	default:
+		return nil, ErrUnsupportedFormat
+	}
+}
+
+func parseCSV(body io.Reader, maxRows int) ([]Row, error) {
+	r := csv.NewReader(body)
+	r.FieldsPerRecord = -1
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+	cols := map[string]int{}
+	for i, name := range header {
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	userCol, ok1 := cols["username"]
+	emailCol, ok2 := cols["email"]
+	if !ok1 || !ok2 {
+		return nil, errors.New("CSV header must name the username and email columns")
+	}
+
+	var rows []Row
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		if len(rows) == maxRows {
+			return nil, fmt.Errorf("too many rows: at most %d per import", maxRows)
+		}
+		line, _ := r.FieldPos(0)
+		row := Row{Line: line}
+		if userCol < len(record) {
+			row.Username = record[userCol]
+		}
+		if emailCol < len(record) {
+			row.Email = record[emailCol]
+		}
+		rows = append(rows, row)
+	}
+}
+
+func parseJSON(body io.Reader, maxRows int) ([]Row, error) {
+	var rows []Row
+	if err := json.NewDecoder(body).Decode(&rows); err != nil {
+		return nil, fmt.Errorf("failed to decode JSON: %w", err)
+	}
+	if len(rows) > maxRows {
+		return nil, fmt.Errorf("too many rows: at most %d per import", maxRows)
+	}
+	for i := range rows {
+		rows[i].Line = i + 1
+	}
+	return rows, nil
+}
+
+// validate returns why row cannot be imported, or ""
+func validate(row Row) string {
+	switch n := utf8.RuneCountInString(row.Username); {
+	case n == 0:
+		return "username is required"
+	case n > maxUsernameLength:
+		return fmt.Sprintf("username must be at most %d characters", maxUsernameLength)
+	}
+	if utf8.RuneCountInString(row.Email) > maxEmailLength {
+		return fmt.Sprintf("email must be at most %d characters", maxEmailLength)
+	}
+	if addr, err := mail.ParseAddress(row.Email); err != nil || addr.Address != row.Email {
+		return "email is not a valid address"
+	}
+	return ""
+}
+
+// Config holds import limits
+type Config struct {
+	BatchSize int // Rows inserted per transaction (default 100)
+	MaxRows   int // Rows accepted per import (default 10000)
+	MaxErrors int // Failed rows reported per import (default 100); all are counted
+}
+
+// Importer runs imports in the background
+type Importer struct {
+	cfg     Config
+	store   func() database.Store
+	ctx     context.Context // Cancelled on shutdown
+	log     *logger.Logger
+	tracer  trace.Tracer
+	running sync.WaitGroup // Imports in progress, see Wait
+
+	rows    *metrics.Counter
+	jobs    *metrics.Counter
+	batches *metrics.Histogram
+}
+
+// New creates an Importer whose jobs run until ctx is cancelled. It counts
+// rows in user_import_rows_total by result (imported, duplicate, invalid),
+// finished jobs in user_import_jobs_total by status and times batches in
+// user_import_batch_duration_seconds.
+func New(ctx context.Context, cfg Config, store func() database.Store, reg *metrics.Registry, log *logger.Logger) *Importer {
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.MaxRows <= 0 {
+		cfg.MaxRows = 10000
+	}
+	if cfg.MaxErrors <= 0 {
+		cfg.MaxErrors = 100
+	}
+	return &Importer{
+		cfg:     cfg,
+		store:   store,
+		ctx:     ctx,
+		log:     logger.OrDefault(log),
+		tracer:  otel.Tracer(tracerName),
+		rows:    reg.Counter("user_import_rows_total", "Rows processed by user imports by result", "result"),
+		jobs:    reg.Counter("user_import_jobs_total", "Finished user imports by status", "status"),
+		batches: reg.Histogram("user_import_batch_duration_seconds", "Duration of user import batches", nil),
+	}
+}
+
+// MaxRows is the number of rows accepted per import
+func (im *Importer) MaxRows() int {
+	return im.cfg.MaxRows
+}
+
+// Start records a new import of rows and processes it in the background.
+// The batch spans link to the span in ctx, the request that started it, and
+// the users are stamped with its actor.
+func (im *Importer) Start(ctx context.Context, rows []Row) (*database.UserImport, error) {
+	db := im.store()
+	if db == nil {
+		return nil, database.ErrNotConfigured
+	}
+	imp, err := db.CreateUserImport(ctx, len(rows), tracing.GetTraceID(ctx))
+	if err != nil {
+		return nil, err
+	}
+
+	trace.SpanFromContext(ctx).SetAttributes(
+		attribute.Int("import.id", imp.ID),
+		attribute.Int("import.rows", len(rows)),
+	)
+	job := *imp
+	im.running.Add(1)
+	go func() {
+		defer im.running.Done()
+		im.run(&job, rows, trace.LinkFromContext(ctx), database.ActorFromContext(ctx))
+	}()
+	return imp, nil
+}
+
+// Wait blocks until every running import has returned. After the context
+// given to New is cancelled, imports stop at their next batch and record
+// themselves as interrupted.
+func (im *Importer) Wait() {
+	im.running.Wait()
+}
+
+// run processes rows in batches. Its log lines carry the trace ID of the
+// request that started the import, so they are found next to its logs.
+func (im *Importer) run(imp *database.UserImport, rows []Row, link trace.Link, actor string) {
+	log := im.log.WithFields(logger.WithTraceID(im.ctx, imp.TraceID), map[string]interface{}{"import_id": imp.ID})
+	seen := make(map[string]bool, len(rows))
+
+	for start := 0; start < len(rows); start += im.cfg.BatchSize {
+		if err := im.ctx.Err(); err != nil {
+			im.finish(imp, &log, errors.New("interrupted by shutdown"))
+			return
+		}
+		batch := rows[start:min(start+im.cfg.BatchSize, len(rows))]
+		if err := im.runBatch(imp, batch, start/im.cfg.BatchSize, seen, link, actor); err != nil {
+			im.finish(imp, &log, err)
+			return
+		}
+	}
+	im.finish(imp, &log, nil)
+}
+
+// runBatch inserts the valid rows of batch and saves the progress
+func (im *Importer) runBatch(imp *database.UserImport, batch []Row, index int, seen map[string]bool, link trace.Link, actor string) error {
+	ctx := im.ctx
+	if actor != "" {
+		ctx = database.WithActor(ctx, actor)
+	}
+	ctx, span := im.tracer.Start(ctx, "user_import.batch",
+		trace.WithNewRoot(),
+		trace.WithLinks(link),
+		trace.WithAttributes(
+			attribute.Int("import.id", imp.ID),
+			attribute.Int("import.batch", index),
+			attribute.Int("import.batch_size", len(batch)),
+		))
+	defer span.End()
+	start := time.Now()
+	defer im.batches.Since(start)
+
+	db := im.store()
+	if db == nil {
+		err := errors.New("database not available")
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+
+	var (
+		users []database.User
+		valid []Row
+	)
+	for _, row := range batch {
+		msg := validate(row)
+		if msg == "" && seen[row.Username] {
+			msg = "username appears earlier in the import"
+		}
+		if msg != "" {
+			im.fail(imp, row, msg, "invalid")
+			continue
+		}
+		seen[row.Username] = true
+		users = append(users, database.User{Username: row.Username, Email: row.Email})
+		valid = append(valid, row)
+	}
+
+	if len(users) > 0 {
+		inserted, err := db.InsertUsers(ctx, users)
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return err
+		}
+		for i, ok := range inserted {
+			if ok {
+				imp.Imported++
+				im.rows.Inc("imported")
+			} else {
+				im.fail(imp, valid[i], "username already exists", "duplicate")
+			}
+		}
+	}
+	imp.Processed += len(batch)
+	span.SetAttributes(attribute.Int("import.processed", imp.Processed), attribute.Int("import.failed", imp.Failed))
+
+	if err := db.UpdateUserImport(ctx, imp); err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		return err
+	}
+	return nil
+}
+
+// fail counts row as failed and reports it while under MaxErrors
+func (im *Importer) fail(imp *database.UserImport, row Row, msg, result string) {
+	imp.Failed++
+	im.rows.Inc(result)
+	if len(imp.Errors) < im.cfg.MaxErrors {
+		imp.Errors = append(imp.Errors, database.ImportError{Line: row.Line, Username: row.Username, Message: msg})
+	}
+}
+
+// finish marks the import done, or failed with err, and logs the outcome.
+// It saves the status even during shutdown.
+func (im *Importer) finish(imp *database.UserImport, log *zerolog.Logger, err error) {
+	now := time.Now().UTC()
+	imp.FinishedAt = &now
+	imp.Status = database.ImportDone
+	if err != nil {
+		imp.Status = database.ImportFailed
+		imp.Error = err.Error()
+	}
+	im.jobs.Inc(imp.Status)
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(im.ctx), 5*time.Second)
+	defer cancel()
+	if db := im.store(); db != nil {
+		if uerr := db.UpdateUserImport(ctx, imp); uerr != nil {
+			log.Warn().Err(uerr).Msg("Failed to save user import status")
+		}
+	}
+
+	event := log.Info()
+	if err != nil {
+		event = log.Warn().Err(err)
+	}
+	event.
+		Str("status", imp.Status).
+		Int("total", imp.Total).
+		Int("imported", imp.Imported).
+		Int("failed", imp.Failed).
+		Msg("User import finished")
+}
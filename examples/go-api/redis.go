@@ -0,0 +1,18 @@
+//go:build redis
+
+package main
+
+import (
+	"github.com/example/go-api/pkg/database"
+	"github.com/example/go-api/pkg/redis"
+)
+
+// Ensure *redis.Cache can back the users cache
+var _ database.Cache = (*redis.Cache)(nil)
+
+func init() {
+	userCaches["redis"] = func(cfg Config) (database.Cache, error) {
+		rdb := redis.New(redis.Config{Addr: cfg.RedisAddr, Password: cfg.RedisPassword})
+		return redis.NewCache(rdb, "go-api:"), nil
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/secrets"
+)
+
+// loadSecrets resolves the database password, Grafana token, Elasticsearch
+// API key, MongoDB URI, Redis password and metrics scrape credentials into
+// cfg.
+// Each may come from NAME_FILE, NAME_VAULT (when VAULT_ADDR is set) or the
+// NAME environment variable.
+func (a *App) loadSecrets(ctx context.Context, cfg *Config) error {
+	var vault *secrets.VaultClient
+	if cfg.Vault.Addr != "" {
+		// The Vault token itself may come from VAULT_TOKEN_FILE or VAULT_TOKEN
+		token, err := secrets.NewLoader(nil, a.logger).Load(ctx, "VAULT_TOKEN")
+		if err != nil {
+			return err
+		}
+		cfg.Vault.Token = token
+		if vault, err = secrets.NewVaultClient(ctx, cfg.Vault); err != nil {
+			return fmt.Errorf("failed to initialize vault client: %w", err)
+		}
+	}
+	a.secrets = secrets.NewLoader(vault, a.logger)
+
+	var err error
+	if cfg.Database.Password, err = a.secrets.Load(ctx, "DB_PASSWORD"); err != nil {
+		return err
+	}
+	if cfg.GrafanaToken, err = a.secrets.Load(ctx, "GRAFANA_API_TOKEN"); err != nil {
+		return err
+	}
+	if cfg.ElasticsearchURL != "" {
+		if cfg.ElasticsearchAPIKey, err = a.secrets.Load(ctx, "ELASTICSEARCH_API_KEY"); err != nil {
+			return err
+		}
+	}
+	if cfg.MetricsAuthPassword, err = a.secrets.Load(ctx, "METRICS_AUTH_PASSWORD"); err != nil {
+		return err
+	}
+	if cfg.MetricsAuthToken, err = a.secrets.Load(ctx, "METRICS_AUTH_TOKEN"); err != nil {
+		return err
+	}
+	if cfg.DocumentStore == "mongo" {
+		if cfg.MongoURI, err = a.secrets.Load(ctx, "MONGO_URI"); err != nil {
+			return err
+		}
+	}
+	if cfg.UsersCache == "redis" {
+		if cfg.RedisPassword, err = a.secrets.Load(ctx, "REDIS_PASSWORD"); err != nil {
+			return err
+		}
+	}
+
+	logger.Ctx(ctx).Info().
+		Bool("vault", vault != nil).
+		Bool("db_password_set", cfg.Database.Password.IsSet()).
+		Bool("grafana_token_set", cfg.GrafanaToken.IsSet()).
+		Bool("metrics_auth_set", cfg.MetricsAuthPassword.IsSet() || cfg.MetricsAuthToken.IsSet()).
+		Msg("Secrets loaded")
+	return nil
+}
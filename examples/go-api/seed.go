@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/example/go-api/pkg/database"
+	"github.com/example/go-api/pkg/logger"
+	"github.com/example/go-api/pkg/seed"
+	"github.com/example/go-api/pkg/tracing"
+)
+
+// runSeed fills the configured database with a generated dataset. It reads
+// the same DB_*, LOG_* and OTEL settings as the server, so its spans and
+// logs land next to the service's, and prints the row counts as JSON.
+func runSeed(args []string) int {
+	fs := flag.NewFlagSet("seed", flag.ContinueOnError)
+	users := fs.Int("users", 100, "users to generate")
+	quotes := fs.Int("quotes", 200, fmt.Sprintf("quotes to generate (at most %d)", seed.MaxQuotes))
+	batch := fs.Int("batch", 100, "rows written per batch span")
+	randomSeed := fs.Int64("seed", 1, "random seed; the same seed and volumes give the same dataset")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	res, err := seedDatabase(ctx, LoadConfig(), seed.Config{
+		Users:     *users,
+		Quotes:    *quotes,
+		BatchSize: *batch,
+		Seed:      *randomSeed,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "seed: %v\n", err)
+		return 1
+	}
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.Encode(res)
+	return 0
+}
+
+// seedDatabase sets up logging, tracing and the database connection the way
+// NewApp does, without the server, and runs the seeder
+func seedDatabase(ctx context.Context, cfg Config, sc seed.Config) (seed.Result, error) {
+	if !cfg.DatabaseEnabled {
+		return seed.Result{}, errors.New("no database configured: set DB_HOST, or DB_DRIVER=sqlite")
+	}
+
+	a := &App{}
+	a.logger = logger.New(logger.Config{
+		AppName:       cfg.AppName,
+		Version:       cfg.Version,
+		Level:         cfg.LogLevel,
+		Levels:        cfg.LogLevels,
+		Format:        cfg.LogFormat,
+		TimeFormat:    cfg.LogTimeFormat,
+		TimePrecision: cfg.LogTimePrecision,
+		TimeUTC:       cfg.LogTimeUTC,
+	})
+	logger.SetDefault(a.logger)
+	cfg.Database.Logger = a.logger.Named("db")
+	if err := a.loadSecrets(ctx, &cfg); err != nil {
+		return seed.Result{}, err
+	}
+
+	provider, err := tracing.InitTracer(ctx, tracing.Config{
+		ServiceName:    cfg.AppName,
+		ServiceVersion: cfg.Version,
+		Environment:    cfg.Environment,
+		OTLPEndpoint:   cfg.OTLPEndpoint,
+		Enabled:        cfg.TracingEnabled,
+		MaxQueueSize:   cfg.TraceQueueSize,
+		Logger:         a.logger,
+	})
+	if err != nil {
+		return seed.Result{}, fmt.Errorf("failed to initialize tracer: %w", err)
+	}
+	// Flush the seeding spans before exiting
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+		provider.Shutdown(shutdownCtx)
+	}()
+
+	db, err := database.New(ctx, cfg.Database)
+	if err != nil {
+		return seed.Result{}, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	defer db.Close()
+
+	return seed.New(db, appMetrics, a.logger.Named("seed")).Run(ctx, sc)
+}
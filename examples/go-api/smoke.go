@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/example/go-api/pkg/secrets"
+	"github.com/example/go-api/pkg/smoke"
+)
+
+// runSmoke checks a running instance end to end and exits non-zero when a
+// check fails, for use as a post-deploy gate. The telemetry checks run only
+// when the URL of their backend is set.
+func runSmoke(args []string) int {
+	fs := flag.NewFlagSet("smoke", flag.ContinueOnError)
+	base := fs.String("url", "http://localhost:"+getEnvOrDefault("PORT", "8080"), "public server base URL")
+	admin := fs.String("admin", "http://localhost:"+getEnvOrDefault("ADMIN_PORT", "9091"), `admin server base URL ("" skips the admin checks)`)
+	tempo := fs.String("tempo", getEnvOrDefault("SMOKE_TEMPO_URL", ""), "Tempo HTTP API URL checked for the traces")
+	loki := fs.String("loki", getEnvOrDefault("SMOKE_LOKI_URL", getEnvOrDefault("LOKI_URL", "")), "Loki HTTP API URL checked for the logs")
+	selector := fs.String("loki-selector", `{app="go-api"}`, "Loki stream selector of the service's logs")
+	prometheus := fs.String("prometheus", getEnvOrDefault("SMOKE_PROMETHEUS_URL", ""), "Prometheus HTTP API URL checked for the request metrics")
+	wait := fs.Duration("wait", time.Minute, "how long to wait for telemetry to be ingested")
+	timeout := fs.Duration("timeout", 10*time.Second, "timeout of each request")
+	readOnly := fs.Bool("read-only", false, "skip the checks that write")
+	noDB := fs.Bool("no-db", false, "skip the endpoints that need the database")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	auth, err := metricsScrapeAuth(ctx)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "smoke: %v\n", err)
+		return 2
+	}
+
+	results := smoke.Run(ctx, smoke.Config{
+		BaseURL:       *base,
+		AdminURL:      *admin,
+		TempoURL:      *tempo,
+		LokiURL:       *loki,
+		LokiSelector:  *selector,
+		PrometheusURL: *prometheus,
+		Wait:          *wait,
+		Timeout:       *timeout,
+		ReadOnly:      *readOnly,
+		SkipDatabase:  *noDB,
+		MetricsAuth:   auth,
+	})
+
+	failed := 0
+	for _, res := range results {
+		switch {
+		case res.Skipped != "":
+			fmt.Printf("SKIP %-20s %s\n", res.Check, res.Skipped)
+		case res.Err != nil:
+			failed++
+			fmt.Printf("FAIL %-20s %v\n", res.Check, res.Err)
+		default:
+			fmt.Printf("PASS %-20s %s\n", res.Check, res.Duration.Round(time.Millisecond))
+		}
+	}
+	if smoke.Failed(results) {
+		fmt.Printf("smoke: %d of %d checks failed\n", failed, len(results))
+		return 1
+	}
+	fmt.Println("smoke: all checks passed")
+	return 0
+}
+
+// metricsScrapeAuth returns a function setting the scrape credentials of
+// METRICS_AUTH_* on /metrics requests, or nil when none are configured
+func metricsScrapeAuth(ctx context.Context) (func(*http.Request), error) {
+	loader := secrets.NewLoader(nil, nil)
+	password, err := loader.Load(ctx, "METRICS_AUTH_PASSWORD")
+	if err != nil {
+		return nil, err
+	}
+	token, err := loader.Load(ctx, "METRICS_AUTH_TOKEN")
+	if err != nil {
+		return nil, err
+	}
+	username := getEnvOrDefault("METRICS_AUTH_USERNAME", "prometheus")
+
+	switch {
+	case token.IsSet():
+		return func(r *http.Request) { r.Header.Set("Authorization", "Bearer "+token.Reveal()) }, nil
+	case password.IsSet():
+		return func(r *http.Request) { r.SetBasicAuth(username, password.Reveal()) }, nil
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/example/go-api/pkg/httperr"
+	"github.com/example/go-api/pkg/logger"
+)
+
+// invalidateUsersCacheHandler serves POST /admin/cache/users/invalidate,
+// for users changed outside the API, e.g. with psql. With the memory cache
+// only this replica's cache is invalidated.
+func (a *App) invalidateUsersCacheHandler(w http.ResponseWriter, r *http.Request) {
+	if a.userCache == nil {
+		httperr.Write(w, r, http.StatusNotFound, "users cache not enabled")
+		return
+	}
+	if err := a.userCache.Invalidate(r.Context(), "admin"); err != nil {
+		l := logger.Ctx(r.Context())
+		l.Warn().Err(err).Msg("Failed to invalidate users cache")
+		httperr.Write(w, r, http.StatusInternalServerError, "failed to invalidate users cache")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{"invalidated": true, "cache": a.cfg.UsersCache})
+}